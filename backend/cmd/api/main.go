@@ -11,10 +11,26 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/musefolio/backend/internal/audit"
 	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/block"
 	"github.com/musefolio/backend/internal/config"
+	"github.com/musefolio/backend/internal/cv"
 	"github.com/musefolio/backend/internal/database"
+	"github.com/musefolio/backend/internal/deploy"
+	"github.com/musefolio/backend/internal/explore"
+	"github.com/musefolio/backend/internal/export"
+	"github.com/musefolio/backend/internal/github"
+	"github.com/musefolio/backend/internal/importer"
 	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/resume"
+	"github.com/musefolio/backend/internal/scheduler"
+	"github.com/musefolio/backend/internal/section"
+	"github.com/musefolio/backend/internal/seed"
+	"github.com/musefolio/backend/internal/site"
+	"github.com/musefolio/backend/internal/storage"
+	"github.com/musefolio/backend/internal/template"
+	"github.com/musefolio/backend/internal/theme"
 	"github.com/musefolio/backend/internal/user"
 )
 
@@ -48,17 +64,145 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Initialize media storage
+	mediaStorage, err := storage.New(&cfg.Storage)
+	if err != nil {
+		logger.Error("failed to initialize storage", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize repositories
+	auditRepo := audit.NewRepository(db)
 	userRepo := user.NewRepository(db)
-	portfolioRepo := portfolio.NewRepository(db)
+	templateRepo := template.NewRepository(db)
+	themeRepo := theme.NewRepository(db)
+	cvRepo := cv.NewRepository(db)
+	portfolioRepo := portfolio.NewRepository(db, portfolio.RevisionRetention{
+		MaxRevisions: cfg.Revisions.MaxPerPortfolio,
+		MaxAge:       cfg.Revisions.MaxAge,
+	})
+
+	// Move projects still embedded in portfolios into their own collection
+	migrated, err := portfolioRepo.MigrateEmbeddedProjects(context.Background())
+	if err != nil {
+		logger.Error("failed to migrate embedded projects", "error", err)
+		os.Exit(1)
+	}
+	if migrated > 0 {
+		logger.Info("migrated embedded projects", "portfolios", migrated)
+	}
 
 	// Initialize services
 	userService := user.NewService(userRepo, cfg.Auth.JWTSecret)
-	portfolioService := portfolio.NewService(portfolioRepo, "/media") // Media path for file storage
+	templateService := template.NewService(templateRepo)
+	themeService := theme.NewService(themeRepo)
+	cvService := cv.NewService(cvRepo)
+	portfolioService := portfolio.NewService(portfolioRepo, auditRepo, mediaStorage, templateService, themeService)
+
+	// Store the preset themes, which the default theme of portfolios, sites
+	// and exports relies on; templates are left to cmd/seed
+	catalog, err := seed.Load()
+	if err != nil {
+		logger.Error("failed to load seed data", "error", err)
+		os.Exit(1)
+	}
+	if _, err := seed.Themes(context.Background(), catalog, themeService); err != nil {
+		logger.Error("failed to store theme presets", "error", err)
+		os.Exit(1)
+	}
+
+	// Notify about portfolios going live or being taken down
+	portfolioService.OnPublishStateChange(func(ctx context.Context, event portfolio.PublishEvent) {
+		logger.Info("portfolio publish state changed",
+			"portfolioId", event.Portfolio.ID.Hex(),
+			"subdomain", event.Portfolio.Subdomain,
+			"published", event.Published,
+			"scheduled", event.Scheduled,
+		)
+	})
+
+	// Deployments to hosting providers render portfolios the way the static
+	// export does
+	siteRenderer, err := site.NewRenderer()
+	if err != nil {
+		logger.Error("failed to load site templates", "error", err)
+		os.Exit(1)
+	}
+	siteBuilder := export.NewBuilder(themeService, cvService, mediaStorage, siteRenderer, cfg.Sites.BaseDomain)
+
+	// Stored tokens must stay safe if the JWT secret leaks or is rotated
+	if cfg.Deploy.CredentialsKey == "" {
+		logger.Error("DEPLOY_CREDENTIALS_KEY is required")
+		os.Exit(1)
+	}
+	if cfg.Deploy.CredentialsKey == cfg.Auth.JWTSecret {
+		logger.Error("DEPLOY_CREDENTIALS_KEY must differ from JWT_SECRET")
+		os.Exit(1)
+	}
+	credentialsCipher, err := deploy.NewCipher(cfg.Deploy.CredentialsKey)
+	if err != nil {
+		logger.Error("failed to initialize credentials encryption", "error", err)
+		os.Exit(1)
+	}
+	deployService := deploy.NewService(deploy.NewRepository(db), portfolioService, siteBuilder, credentialsCipher, map[string]deploy.Deployer{
+		deploy.ProviderGitHub:  deploy.NewGitHub(cfg.Deploy.GitHubAPIURL),
+		deploy.ProviderNetlify: deploy.NewNetlify(cfg.Deploy.NetlifyAPIURL),
+		deploy.ProviderVercel:  deploy.NewVercel(cfg.Deploy.VercelAPIURL),
+	}, cfg.Deploy.Timeout)
+
+	// Redeploy portfolios to their hosting providers when they go live
+	portfolioService.OnPublishStateChange(deployService.RedeployOnPublish)
+
+	githubClient := github.NewClient(cfg.GitHub.APIURL, nil)
+	githubService := github.NewService(github.NewRepository(db), portfolioService, githubClient, credentialsCipher, cfg.GitHub.SyncInterval)
+	importService := importer.NewService(importer.NewRepository(db), portfolioService, cfg.Import.Timeout)
+	exploreService := explore.NewService(explore.NewRepository(db), portfolioService, userService)
+
+	// List portfolios in the explore feed when they go live
+	portfolioService.OnPublishStateChange(exploreService.SyncOnPublish)
+
+	// Start background jobs
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+
+	if cfg.Scheduler.Enabled {
+		jobs := scheduler.New(db)
+		jobs.Add("portfolio-publish", cfg.Scheduler.PublishInterval, func(ctx context.Context) error {
+			return portfolioService.ApplyDueSchedules(ctx, time.Now())
+		})
+		jobs.Add("deploy-cleanup", cfg.Deploy.Timeout, func(ctx context.Context) error {
+			return deployService.FailStale(ctx, time.Now())
+		})
+		jobs.Add("github-sync", cfg.GitHub.SyncInterval, func(ctx context.Context) error {
+			return githubService.SyncDue(ctx, time.Now())
+		})
+		jobs.Add("import-cleanup", cfg.Import.Timeout, func(ctx context.Context) error {
+			return importService.FailStale(ctx, time.Now())
+		})
+		jobs.Add("explore-refresh", cfg.Explore.RefreshInterval, func(ctx context.Context) error {
+			return exploreService.Refresh(ctx, time.Now())
+		})
+		jobs.Start(schedulerCtx)
+	}
 
 	// Initialize handlers
 	userHandler := user.NewHandler(userService)
 	portfolioHandler := portfolio.NewHandler(portfolioService)
+	templateHandler := template.NewHandler(templateService)
+	themeHandler := theme.NewHandler(themeService)
+	cvHandler := cv.NewHandler(cvService)
+	resumeHandler := resume.NewHandler(resume.NewService(userService, cvService, portfolioService))
+	sectionHandler := section.NewHandler()
+	blockHandler := block.NewHandler()
+	deployHandler := deploy.NewHandler(deployService)
+	githubHandler := github.NewHandler(githubService)
+	importHandler := importer.NewHandler(importService, cfg.Import.MaxArchiveSize)
+	exploreHandler := explore.NewHandler(exploreService)
+	siteHandler := site.NewHandler(portfolioService, themeService, cvService, siteRenderer, "/api/v1/themes")
+	exportHandler := export.NewHandler(portfolioService, userService, siteBuilder)
+
+	// Site visits make portfolios popular in the explore feed
+	siteHandler.OnView(exploreService.RecordView)
 	authHandler := auth.NewHandler(userService, cfg.Auth.JWTSecret, cfg.Auth.TokenExpiry)
 
 	// Initialize router
@@ -126,7 +270,8 @@ func main() {
 
 			// Enhanced CORS headers for better browser compatibility
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, HEAD, PATCH")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-CSRF-Token, X-Requested-With")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-CSRF-Token, X-Requested-With, If-Match")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Link, X-Next-Cursor")
 
 			// CRITICAL: Always allow credentials for cookie-based auth
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -145,9 +290,12 @@ func main() {
 	})
 
 	// Media file server
-	fileServer := http.FileServer(http.Dir("./media"))
+	fileServer := http.FileServer(http.Dir(cfg.Storage.LocalDir))
 	r.Get("/media/*", http.StripPrefix("/media", fileServer).ServeHTTP)
 
+	// Public portfolio sites
+	siteHandler.RegisterRoutes(r)
+
 	// Serve frontend static files
 	frontendFS := http.FileServer(http.Dir("../frontend/dist"))
 	r.Get("/*", func(w http.ResponseWriter, r *http.Request) {
@@ -218,6 +366,9 @@ func main() {
 
 		// Public routes
 		r.Post("/users", userHandler.Create)
+		templateHandler.RegisterPublicRoutes(r)
+		themeHandler.RegisterPublicRoutes(r)
+		exploreHandler.RegisterPublicRoutes(r)
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
@@ -232,9 +383,27 @@ func main() {
 			r.Get("/users/me", userHandler.GetCurrentUser)
 			r.Put("/users/me", userHandler.UpdateCurrentUser)
 			r.Post("/users/me/avatar", userHandler.UploadAvatar)
+			cvHandler.RegisterRoutes(r)
+			resumeHandler.RegisterRoutes(r)
+			githubHandler.RegisterRoutes(r)
 
 			// Portfolio routes
 			portfolioHandler.RegisterRoutes(r)
+			sectionHandler.RegisterRoutes(r)
+			blockHandler.RegisterRoutes(r)
+			exportHandler.RegisterRoutes(r)
+			deployHandler.RegisterRoutes(r)
+			importHandler.RegisterRoutes(r)
+
+			// Theme routes
+			themeHandler.RegisterRoutes(r)
+
+			// Admin routes
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireAdmin(userService))
+				templateHandler.RegisterAdminRoutes(r)
+				exploreHandler.RegisterAdminRoutes(r)
+			})
 		})
 	})
 
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/musefolio/backend/internal/config"
+	"github.com/musefolio/backend/internal/database"
+	"github.com/musefolio/backend/internal/seed"
+	"github.com/musefolio/backend/internal/template"
+	"github.com/musefolio/backend/internal/theme"
+	"github.com/musefolio/backend/internal/user"
+)
+
+func main() {
+	admin := flag.String("admin", "", "email of an existing user to give the admin role")
+	flag.Parse()
+
+	// Initialize logger
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Load the embedded catalog before touching the database
+	catalog, err := seed.Load()
+	if err != nil {
+		logger.Error("failed to load seed data", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize MongoDB
+	db, err := database.New(&cfg.MongoDB)
+	if err != nil {
+		logger.Error("failed to connect to MongoDB", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := db.Close(context.Background()); err != nil {
+			logger.Error("failed to close MongoDB connection", "error", err)
+		}
+	}()
+
+	// The upserts rely on the unique slug indexes
+	if err := db.EnsureIndexes(context.Background()); err != nil {
+		logger.Error("failed to create indexes", "error", err)
+		os.Exit(1)
+	}
+
+	templateService := template.NewService(template.NewRepository(db))
+	themeService := theme.NewService(theme.NewRepository(db))
+
+	result, err := seed.Run(context.Background(), catalog, templateService, themeService)
+	if err != nil {
+		logger.Error("failed to seed catalog", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("catalog seeded",
+		"themesApplied", result.ThemesApplied,
+		"themesSkipped", result.ThemesSkipped,
+		"templatesApplied", result.TemplatesApplied,
+		"templatesSkipped", result.TemplatesSkipped,
+	)
+
+	// Administrators manage the template catalog and curate the explore feed
+	if *admin != "" {
+		userService := user.NewService(user.NewRepository(db), cfg.Auth.JWTSecret)
+		if err := userService.GrantAdmin(context.Background(), *admin); err != nil {
+			logger.Error("failed to grant the admin role", "email", *admin, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("admin role granted", "email", *admin)
+	}
+}
@@ -0,0 +1,18 @@
+package audit
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Entry represents a single audited state change
+type Entry struct {
+	ID           primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ActorID      *primitive.ObjectID    `bson:"actorId,omitempty" json:"actorId,omitempty"`
+	Action       string                 `bson:"action" json:"action"`
+	ResourceType string                 `bson:"resourceType" json:"resourceType"`
+	ResourceID   primitive.ObjectID     `bson:"resourceId" json:"resourceId"`
+	Metadata     map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	CreatedAt    time.Time              `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/database"
+)
+
+// Repository handles audit log data operations
+type Repository struct {
+	db         *database.DB
+	collection *mongo.Collection
+}
+
+// NewRepository creates a new audit repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{
+		db:         db,
+		collection: db.Collection(database.AuditCollection),
+	}
+}
+
+// Record stores an audit entry. The actor is taken from the request context
+// when the entry doesn't name one; entries without an actor were made by the
+// system.
+func (r *Repository) Record(ctx context.Context, entry Entry) error {
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+	if entry.ActorID == nil {
+		if actorID, ok := ctx.Value(auth.UserIDKey).(primitive.ObjectID); ok {
+			entry.ActorID = &actorID
+		}
+	}
+
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
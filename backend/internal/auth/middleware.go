@@ -78,3 +78,33 @@ func Middleware(jwtSecret string) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// AdminChecker reports whether a user has administrative rights
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID primitive.ObjectID) (bool, error)
+}
+
+// RequireAdmin restricts access to administrators. It must run after Middleware.
+func RequireAdmin(checker AdminChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(UserIDKey).(primitive.ObjectID)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			isAdmin, err := checker.IsAdmin(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !isAdmin {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
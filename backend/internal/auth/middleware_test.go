@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// admins is an AdminChecker backed by a fixed set of users
+type admins struct {
+	ids map[primitive.ObjectID]bool
+	err error
+}
+
+func (a admins) IsAdmin(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	return a.ids[userID], a.err
+}
+
+func TestRequireAdmin(t *testing.T) {
+	admin, member := primitive.NewObjectID(), primitive.NewObjectID()
+	checker := admins{ids: map[primitive.ObjectID]bool{admin: true}}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name    string
+		checker AdminChecker
+		userID  *primitive.ObjectID
+		want    int
+	}{
+		{"admin", checker, &admin, http.StatusNoContent},
+		{"member", checker, &member, http.StatusForbidden},
+		{"anonymous", checker, nil, http.StatusUnauthorized},
+		{"lookup failure", admins{err: errors.New("down")}, &admin, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/templates/1", nil)
+		if tt.userID != nil {
+			r = r.WithContext(context.WithValue(r.Context(), UserIDKey, *tt.userID))
+		}
+		w := httptest.NewRecorder()
+		RequireAdmin(tt.checker)(ok).ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
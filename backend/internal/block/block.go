@@ -0,0 +1,106 @@
+// Package block implements the canvas of a project: a tree of typed blocks
+// with per-breakpoint layout.
+package block
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/schema"
+)
+
+// Block is a node of a project canvas. Container blocks such as grids and
+// columns hold further blocks as children.
+type Block struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	Type     string             `bson:"type" json:"type"`
+	Props    Props              `bson:"props,omitempty" json:"props,omitempty"`
+	Layout   *Layout            `bson:"layout,omitempty" json:"layout,omitempty"`
+	Children []Block            `bson:"children,omitempty" json:"children,omitempty"`
+}
+
+// Props holds the type-specific properties of a block, described by the
+// schema of its type
+type Props map[string]interface{}
+
+// UnmarshalBSONValue decodes props to the same types JSON decoding produces
+func (p *Props) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.EmbeddedDocument:
+		m, err := schema.DecodeDocument(data)
+		if err != nil {
+			return err
+		}
+		*p = m
+		return nil
+	case bsontype.Null, bsontype.Undefined:
+		*p = nil
+		return nil
+	default:
+		return fmt.Errorf("block props: cannot decode %s", t)
+	}
+}
+
+// String returns the string property name, if set
+func (p Props) String(name string) string {
+	s, _ := p[name].(string)
+	return s
+}
+
+// Breakpoints lists the viewport sizes a block can be laid out for, from
+// smallest to largest. Each breakpoint inherits the placement of the
+// previous one unless it sets its own.
+var Breakpoints = []string{"mobile", "tablet", "desktop"}
+
+// Layout holds the placement of a block per breakpoint
+type Layout struct {
+	Mobile  *Placement `bson:"mobile,omitempty" json:"mobile,omitempty"`
+	Tablet  *Placement `bson:"tablet,omitempty" json:"tablet,omitempty"`
+	Desktop *Placement `bson:"desktop,omitempty" json:"desktop,omitempty"`
+}
+
+// At returns the placement set for a breakpoint, or nil
+func (l *Layout) At(breakpoint string) *Placement {
+	if l == nil {
+		return nil
+	}
+	switch breakpoint {
+	case "mobile":
+		return l.Mobile
+	case "tablet":
+		return l.Tablet
+	case "desktop":
+		return l.Desktop
+	}
+	return nil
+}
+
+// Placement positions a block on the 12-column grid of its parent. Zero
+// values mean "automatic".
+type Placement struct {
+	Column  int    `bson:"column,omitempty" json:"column,omitempty"`
+	Span    int    `bson:"span,omitempty" json:"span,omitempty"`
+	Row     int    `bson:"row,omitempty" json:"row,omitempty"`
+	RowSpan int    `bson:"rowSpan,omitempty" json:"rowSpan,omitempty"`
+	Align   string `bson:"align,omitempty" json:"align,omitempty"`
+	Hidden  bool   `bson:"hidden,omitempty" json:"hidden,omitempty"`
+}
+
+// Clone returns a deep copy of blocks
+func Clone(blocks []Block) ([]Block, error) {
+	if blocks == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return nil, fmt.Errorf("block: clone: %w", err)
+	}
+	var clone []Block
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("block: clone: %w", err)
+	}
+	return clone, nil
+}
@@ -0,0 +1,27 @@
+package block
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler handles HTTP requests for block types
+type Handler struct{}
+
+// NewHandler creates a new block type handler
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+// RegisterRoutes registers the block type routes
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/block-types", h.List)
+}
+
+// List handles listing the registered block types with their schemas
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Types())
+}
@@ -0,0 +1,137 @@
+package block
+
+import (
+	"github.com/musefolio/backend/internal/schema"
+)
+
+// Type describes a kind of block and the shape of its props
+type Type struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// Container blocks lay out their children on a grid
+	Container bool           `json:"container"`
+	Schema    *schema.Schema `json:"schema"`
+}
+
+// EmbedHosts lists the hosts embed blocks may point at
+var EmbedHosts = []string{
+	"www.youtube.com",
+	"www.youtube-nocookie.com",
+	"player.vimeo.com",
+	"codepen.io",
+	"www.figma.com",
+	"open.spotify.com",
+	"w.soundcloud.com",
+	"sketchfab.com",
+}
+
+var gap = schema.Enum("Gap", "none", "sm", "md", "lg")
+
+// registry lists the block types in the order editors should offer them
+var registry = []Type{
+	{
+		Name:  "text",
+		Label: "Text",
+		Schema: schema.Object([]string{"text"}, map[string]*schema.Schema{
+			"text": schema.String("Text", 20000),
+		}),
+	},
+	{
+		Name:  "image",
+		Label: "Image",
+		Schema: schema.Object([]string{"url"}, map[string]*schema.Schema{
+			"url":     schema.Format("Image URL", "uri-reference"),
+			"alt":     schema.String("Alternative text", 300),
+			"caption": schema.String("Caption", 500),
+			"link":    schema.Format("Link", "uri"),
+		}),
+	},
+	{
+		Name:  "gallery",
+		Label: "Gallery",
+		Schema: schema.Object([]string{"images"}, map[string]*schema.Schema{
+			"images": schema.Array("Images", 100, schema.Object([]string{"url"}, map[string]*schema.Schema{
+				"url":     schema.Format("Image URL", "uri-reference"),
+				"alt":     schema.String("Alternative text", 300),
+				"caption": schema.String("Caption", 500),
+			})),
+			"mode":     schema.Enum("Display", "grid", "masonry", "slider"),
+			"autoplay": schema.Boolean("Autoplay slider"),
+			"interval": schema.Integer("Slide interval in seconds", 1, 60),
+		}),
+	},
+	{
+		Name:  "video",
+		Label: "Video",
+		Schema: schema.Object([]string{"url"}, map[string]*schema.Schema{
+			"url":      schema.Format("Video URL", "uri-reference"),
+			"poster":   schema.Format("Poster image URL", "uri-reference"),
+			"caption":  schema.String("Caption", 500),
+			"autoplay": schema.Boolean("Autoplay"),
+			"loop":     schema.Boolean("Loop"),
+			"muted":    schema.Boolean("Muted"),
+		}),
+	},
+	{
+		Name:  "embed",
+		Label: "Embed",
+		Schema: schema.Object([]string{"url"}, map[string]*schema.Schema{
+			"url":         schema.Format("Embed URL", "uri"),
+			"title":       schema.String("Title", 200),
+			"aspectRatio": schema.Enum("Aspect ratio", "16:9", "4:3", "1:1", "9:16"),
+		}),
+	},
+	{
+		Name:      "grid",
+		Label:     "Grid",
+		Container: true,
+		Schema: schema.Object(nil, map[string]*schema.Schema{
+			"columns": schema.Integer("Columns", 1, 12),
+			"gap":     gap,
+		}),
+	},
+	{
+		Name:      "columns",
+		Label:     "Columns",
+		Container: true,
+		Schema: schema.Object(nil, map[string]*schema.Schema{
+			"gap":           gap,
+			"verticalAlign": schema.Enum("Vertical alignment", "top", "center", "bottom"),
+		}),
+	},
+	{
+		Name:  "quote",
+		Label: "Quote",
+		Schema: schema.Object([]string{"text"}, map[string]*schema.Schema{
+			"text":   schema.String("Quote", 2000),
+			"author": schema.String("Author", 200),
+			"source": schema.String("Source", 300),
+		}),
+	},
+	{
+		Name:  "code",
+		Label: "Code",
+		Schema: schema.Object([]string{"code"}, map[string]*schema.Schema{
+			"code":     schema.String("Code", 20000),
+			"language": schema.String("Language", 40),
+			"caption":  schema.String("Caption", 500),
+		}),
+	},
+}
+
+// Types returns all registered block types
+func Types() []Type {
+	types := make([]Type, len(registry))
+	copy(types, registry)
+	return types
+}
+
+// Lookup finds a registered block type by name
+func Lookup(name string) (Type, bool) {
+	for _, t := range registry {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Type{}, false
+}
@@ -0,0 +1,237 @@
+package block
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/schema"
+)
+
+const (
+	// MaxDepth is how deeply containers may be nested
+	MaxDepth = 4
+	// MaxBlocks caps the number of blocks on one canvas
+	MaxBlocks = 500
+)
+
+var (
+	ErrNotFound = errors.New("block not found")
+	ErrInvalid  = errors.New("invalid block")
+)
+
+var languagePattern = regexp.MustCompile(`^[a-z0-9+#-]*$`)
+
+// Validate checks a whole canvas: block types, props, layout, nesting and
+// the uniqueness of block IDs
+func Validate(blocks []Block) error {
+	v := &validator{seen: map[primitive.ObjectID]bool{}}
+	v.validateList("blocks", blocks, 1)
+	if v.count > MaxBlocks {
+		v.fail("blocks", fmt.Sprintf("must contain at most %d blocks", MaxBlocks))
+	}
+	if len(v.errs) > 0 {
+		return fmt.Errorf("%w: %v", ErrInvalid, &schema.ValidationError{Errors: v.errs})
+	}
+	return nil
+}
+
+type validator struct {
+	seen  map[primitive.ObjectID]bool
+	count int
+	errs  []schema.FieldError
+}
+
+func (v *validator) fail(path, message string) {
+	v.errs = append(v.errs, schema.FieldError{Path: path, Message: message})
+}
+
+func (v *validator) validateList(path string, blocks []Block, depth int) {
+	for i := range blocks {
+		v.validateBlock(fmt.Sprintf("%s[%d]", path, i), &blocks[i], depth)
+	}
+}
+
+func (v *validator) validateBlock(path string, b *Block, depth int) {
+	v.count++
+
+	if b.ID.IsZero() {
+		v.fail(path+".id", "is required")
+	} else if v.seen[b.ID] {
+		v.fail(path+".id", "is used by another block")
+	}
+	v.seen[b.ID] = true
+
+	t, ok := Lookup(b.Type)
+	if !ok {
+		v.fail(path+".type", fmt.Sprintf("unknown block type %q", b.Type))
+		return
+	}
+
+	props := b.Props
+	if props == nil {
+		props = Props{}
+	}
+	if err := t.Schema.Validate(path+".props", map[string]interface{}(props)); err != nil {
+		var verr *schema.ValidationError
+		if errors.As(err, &verr) {
+			v.errs = append(v.errs, verr.Errors...)
+		}
+	}
+
+	switch b.Type {
+	case "embed":
+		if u, err := url.Parse(props.String("url")); err == nil && u.Scheme == "https" {
+			if !contains(EmbedHosts, u.Host) {
+				v.fail(path+".props.url", "host is not allowed for embeds")
+			}
+		} else if props.String("url") != "" {
+			v.fail(path+".props.url", "must be an https URL")
+		}
+	case "code":
+		if !languagePattern.MatchString(props.String("language")) {
+			v.fail(path+".props.language", "must be a lowercase language name")
+		}
+	}
+
+	v.validateLayout(path+".layout", b.Layout)
+
+	if len(b.Children) > 0 {
+		if !t.Container {
+			v.fail(path+".children", fmt.Sprintf("%s blocks cannot have children", b.Type))
+			return
+		}
+		if depth >= MaxDepth {
+			v.fail(path+".children", fmt.Sprintf("containers may be nested at most %d levels deep", MaxDepth))
+			return
+		}
+		v.validateList(path+".children", b.Children, depth+1)
+	}
+}
+
+func (v *validator) validateLayout(path string, layout *Layout) {
+	for _, bp := range Breakpoints {
+		p := layout.At(bp)
+		if p == nil {
+			continue
+		}
+		at := path + "." + bp
+		if p.Column < 0 || p.Column > 12 {
+			v.fail(at+".column", "must be between 1 and 12")
+		}
+		if p.Span < 0 || p.Span > 12 {
+			v.fail(at+".span", "must be between 1 and 12")
+		}
+		if p.Column > 0 && p.Span > 0 && p.Column+p.Span-1 > 12 {
+			v.fail(at, "must fit within 12 columns")
+		}
+		if p.Row < 0 || p.Row > 1000 {
+			v.fail(at+".row", "must be between 1 and 1000")
+		}
+		if p.RowSpan < 0 || p.RowSpan > 100 {
+			v.fail(at+".rowSpan", "must be between 1 and 100")
+		}
+		switch p.Align {
+		case "", "start", "center", "end", "stretch":
+		default:
+			v.fail(at+".align", "must be one of start, center, end, stretch")
+		}
+	}
+}
+
+// AssignIDs gives b and all of its descendants fresh IDs
+func AssignIDs(b *Block) {
+	b.ID = primitive.NewObjectID()
+	for i := range b.Children {
+		AssignIDs(&b.Children[i])
+	}
+}
+
+// Find returns the block with the given ID anywhere in the tree, or nil
+func Find(blocks []Block, id primitive.ObjectID) *Block {
+	for i := range blocks {
+		if blocks[i].ID == id {
+			return &blocks[i]
+		}
+		if found := Find(blocks[i].Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Insert adds b to the children of the parent block, or to the root when
+// parentID is nil, at index. An index out of range appends.
+func Insert(blocks []Block, parentID *primitive.ObjectID, index int, b Block) ([]Block, error) {
+	if parentID == nil {
+		return insertAt(blocks, index, b), nil
+	}
+
+	parent := Find(blocks, *parentID)
+	if parent == nil {
+		return nil, fmt.Errorf("%w: parent %s", ErrNotFound, parentID.Hex())
+	}
+	if t, _ := Lookup(parent.Type); !t.Container {
+		return nil, fmt.Errorf("%w: %s blocks cannot have children", ErrInvalid, parent.Type)
+	}
+	parent.Children = insertAt(parent.Children, index, b)
+	return blocks, nil
+}
+
+// Remove takes the block with the given ID out of the tree and returns it
+func Remove(blocks []Block, id primitive.ObjectID) ([]Block, *Block, error) {
+	for i := range blocks {
+		if blocks[i].ID == id {
+			removed := blocks[i]
+			return append(blocks[:i:i], blocks[i+1:]...), &removed, nil
+		}
+		children, removed, err := Remove(blocks[i].Children, id)
+		if err == nil {
+			blocks[i].Children = children
+			return blocks, removed, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, id.Hex())
+}
+
+// Move relocates a block, with its children, under a new parent (nil for the
+// root) at index
+func Move(blocks []Block, id primitive.ObjectID, parentID *primitive.ObjectID, index int) ([]Block, error) {
+	if parentID != nil {
+		moved := Find(blocks, id)
+		if moved == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, id.Hex())
+		}
+		if *parentID == id || Find(moved.Children, *parentID) != nil {
+			return nil, fmt.Errorf("%w: a block cannot be moved into itself", ErrInvalid)
+		}
+	}
+
+	blocks, removed, err := Remove(blocks, id)
+	if err != nil {
+		return nil, err
+	}
+	return Insert(blocks, parentID, index, *removed)
+}
+
+func insertAt(blocks []Block, index int, b Block) []Block {
+	if index < 0 || index >= len(blocks) {
+		return append(blocks, b)
+	}
+	blocks = append(blocks, Block{})
+	copy(blocks[index+1:], blocks[index:])
+	blocks[index] = b
+	return blocks
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
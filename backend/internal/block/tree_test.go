@@ -0,0 +1,93 @@
+package block
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newBlock(typ string, props Props, children ...Block) Block {
+	return Block{ID: primitive.NewObjectID(), Type: typ, Props: props, Children: children}
+}
+
+func TestMove(t *testing.T) {
+	text := newBlock("text", Props{"text": "Hello"})
+	inner := newBlock("columns", nil)
+	grid := newBlock("grid", Props{"columns": float64(2)}, inner)
+	quote := newBlock("quote", Props{"text": "Less is more"})
+	blocks := []Block{text, grid, quote}
+
+	blocks, err := Move(blocks, quote.ID, &inner.ID, 0)
+	if err != nil {
+		t.Fatalf("Move into nested container: %v", err)
+	}
+	if len(blocks) != 2 || Find(blocks, inner.ID).Children[0].ID != quote.ID {
+		t.Fatalf("quote not moved into columns: %+v", blocks)
+	}
+
+	clone := func() []Block {
+		t.Helper()
+		c, err := Clone(blocks)
+		if err != nil {
+			t.Fatalf("Clone: %v", err)
+		}
+		return c
+	}
+	if _, err := Move(clone(), grid.ID, &inner.ID, 0); !errors.Is(err, ErrInvalid) {
+		t.Errorf("moving a container into its descendant: got %v, want ErrInvalid", err)
+	}
+	if _, err := Move(clone(), inner.ID, &text.ID, 0); !errors.Is(err, ErrInvalid) {
+		t.Errorf("moving into a non-container: got %v, want ErrInvalid", err)
+	}
+
+	blocks, err = Move(blocks, text.ID, nil, -1)
+	if err != nil {
+		t.Fatalf("Move to root end: %v", err)
+	}
+	if blocks[len(blocks)-1].ID != text.ID {
+		t.Errorf("text block not appended to root")
+	}
+	if err := Validate(blocks); err != nil {
+		t.Errorf("Validate after moves: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []Block
+	}{
+		{"unknown type", []Block{newBlock("carousel", nil)}},
+		{"missing required prop", []Block{newBlock("image", Props{"alt": "x"})}},
+		{"children on leaf", []Block{newBlock("text", Props{"text": "x"}, newBlock("text", Props{"text": "y"}))}},
+		{"embed host", []Block{newBlock("embed", Props{"url": "https://evil.example.com/x"})}},
+		{"layout overflow", []Block{{
+			ID:     primitive.NewObjectID(),
+			Type:   "text",
+			Props:  Props{"text": "x"},
+			Layout: &Layout{Desktop: &Placement{Column: 8, Span: 6}},
+		}}},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.blocks); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", tt.name, err)
+		}
+	}
+
+	valid := []Block{
+		newBlock("embed", Props{"url": "https://player.vimeo.com/video/1", "aspectRatio": "16:9"}),
+		newBlock("gallery", Props{"images": []interface{}{map[string]interface{}{"url": "/media/a.jpg"}}, "mode": "slider"}),
+	}
+	if err := Validate(valid); err != nil {
+		t.Errorf("valid blocks: %v", err)
+	}
+}
+
+func TestCloneReportsUnencodableProps(t *testing.T) {
+	blocks := []Block{newBlock("text", Props{"text": math.NaN()})}
+	if _, err := Clone(blocks); err == nil {
+		t.Error("Clone of a block with a NaN prop succeeded, want an error")
+	}
+}
@@ -8,10 +8,17 @@ import (
 
 // Config holds all configuration for our application
 type Config struct {
-	Server  ServerConfig
-	MongoDB MongoDBConfig
-	Auth    AuthConfig
-	Storage StorageConfig
+	Server    ServerConfig
+	MongoDB   MongoDBConfig
+	Auth      AuthConfig
+	Storage   StorageConfig
+	Revisions RevisionConfig
+	Scheduler SchedulerConfig
+	Sites     SitesConfig
+	Deploy    DeployConfig
+	GitHub    GitHubConfig
+	Import    ImportConfig
+	Explore   ExploreConfig
 }
 
 type ServerConfig struct {
@@ -32,9 +39,58 @@ type AuthConfig struct {
 }
 
 type StorageConfig struct {
-	Provider string
-	Bucket   string
-	Region   string
+	Provider  string
+	Bucket    string
+	Region    string
+	LocalDir  string
+	PublicURL string
+}
+
+type RevisionConfig struct {
+	MaxPerPortfolio int
+	MaxAge          time.Duration
+}
+
+type SchedulerConfig struct {
+	Enabled         bool
+	PublishInterval time.Duration
+}
+
+type SitesConfig struct {
+	// BaseDomain is the domain portfolios are served under as
+	// <subdomain>.<BaseDomain>
+	BaseDomain string
+}
+
+type DeployConfig struct {
+	// CredentialsKey encrypts the hosting provider and GitHub tokens of
+	// users
+	CredentialsKey string
+	Timeout        time.Duration
+	// API base URLs of the hosting providers
+	GitHubAPIURL  string
+	NetlifyAPIURL string
+	VercelAPIURL  string
+}
+
+type GitHubConfig struct {
+	APIURL string
+	// SyncInterval is how often the stats of imported repositories are
+	// refreshed
+	SyncInterval time.Duration
+}
+
+type ImportConfig struct {
+	// MaxArchiveSize is the largest Behance or Dribbble export accepted, in
+	// bytes
+	MaxArchiveSize int64
+	Timeout        time.Duration
+}
+
+type ExploreConfig struct {
+	// RefreshInterval is how often the explore feed catches up with edits
+	// to published portfolios
+	RefreshInterval time.Duration
 }
 
 // Load returns a Config struct populated with values from environment variables
@@ -55,9 +111,40 @@ func Load() (*Config, error) {
 			RefreshToken: getEnvAsDuration("REFRESH_TOKEN_EXPIRY", 7*24*time.Hour),
 		},
 		Storage: StorageConfig{
-			Provider: getEnv("STORAGE_PROVIDER", "local"),
-			Bucket:   getEnv("STORAGE_BUCKET", "musefolio"),
-			Region:   getEnv("STORAGE_REGION", "us-east-1"),
+			Provider:  getEnv("STORAGE_PROVIDER", "local"),
+			Bucket:    getEnv("STORAGE_BUCKET", "musefolio"),
+			Region:    getEnv("STORAGE_REGION", "us-east-1"),
+			LocalDir:  getEnv("STORAGE_LOCAL_DIR", "./media"),
+			PublicURL: getEnv("STORAGE_PUBLIC_URL", "/media"),
+		},
+		Revisions: RevisionConfig{
+			MaxPerPortfolio: getEnvAsInt("REVISION_MAX_PER_PORTFOLIO", 100),
+			MaxAge:          getEnvAsDuration("REVISION_MAX_AGE", 90*24*time.Hour),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:         getEnvAsBool("SCHEDULER_ENABLED", true),
+			PublishInterval: getEnvAsDuration("SCHEDULER_PUBLISH_INTERVAL", time.Minute),
+		},
+		Sites: SitesConfig{
+			BaseDomain: getEnv("SITES_BASE_DOMAIN", "musefolio.com"),
+		},
+		Deploy: DeployConfig{
+			CredentialsKey: getEnv("DEPLOY_CREDENTIALS_KEY", ""),
+			Timeout:        getEnvAsDuration("DEPLOY_TIMEOUT", 10*time.Minute),
+			GitHubAPIURL:   getEnv("DEPLOY_GITHUB_API_URL", "https://api.github.com"),
+			NetlifyAPIURL:  getEnv("DEPLOY_NETLIFY_API_URL", "https://api.netlify.com/api/v1"),
+			VercelAPIURL:   getEnv("DEPLOY_VERCEL_API_URL", "https://api.vercel.com"),
+		},
+		GitHub: GitHubConfig{
+			APIURL:       getEnv("GITHUB_API_URL", "https://api.github.com"),
+			SyncInterval: getEnvAsDuration("GITHUB_SYNC_INTERVAL", 6*time.Hour),
+		},
+		Import: ImportConfig{
+			MaxArchiveSize: int64(getEnvAsInt("IMPORT_MAX_ARCHIVE_MB", 512)) << 20,
+			Timeout:        getEnvAsDuration("IMPORT_TIMEOUT", 30*time.Minute),
+		},
+		Explore: ExploreConfig{
+			RefreshInterval: getEnvAsDuration("EXPLORE_REFRESH_INTERVAL", 15*time.Minute),
 		},
 	}, nil
 }
@@ -85,3 +172,11 @@ func getEnvAsDuration(key string, defaultVal time.Duration) time.Duration {
 	}
 	return defaultVal
 }
+
+func getEnvAsBool(key string, defaultVal bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultVal
+}
@@ -0,0 +1,103 @@
+package cv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		date    Date
+		valid   bool
+		display string
+	}{
+		{"2021", true, "2021"},
+		{"2021-05", true, "May 2021"},
+		{"2021-05-03", true, "3 May 2021"},
+		{"2021-13", false, "2021-13"},
+		{"May 2021", false, "May 2021"},
+		{"21-05", false, "21-05"},
+	}
+
+	for _, tt := range tests {
+		if _, ok := tt.date.Time(); ok != tt.valid {
+			t.Errorf("Date(%q).Time() valid = %v, want %v", tt.date, ok, tt.valid)
+		}
+		if got := tt.date.Display(); got != tt.display {
+			t.Errorf("Date(%q).Display() = %q, want %q", tt.date, got, tt.display)
+		}
+	}
+}
+
+func TestCheckDates(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		valid bool
+	}{
+		{"range", &Experience{StartDate: "2019-01", EndDate: "2021-06"}, true},
+		{"current", &Experience{StartDate: "2019-01", Current: true}, true},
+		{"end before start", &Experience{StartDate: "2021-06", EndDate: "2019"}, false},
+		{"current with end", &Education{StartDate: "2019", EndDate: "2020", Current: true}, false},
+		{"malformed", &Award{Date: "last year"}, false},
+		{"expiry before issue", &Certification{IssueDate: "2022-01-01", ExpiryDate: "2021-12-31"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.entry.checkDates()
+			if tt.valid && err != nil {
+				t.Errorf("checkDates() = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidCV) {
+				t.Errorf("checkDates() = %v, want ErrInvalidCV", err)
+			}
+		})
+	}
+}
+
+func TestSort(t *testing.T) {
+	cv := &CV{
+		Experience: []Experience{
+			{Role: "Junior", StartDate: "2015-01", EndDate: "2017-12"},
+			{Role: "Lead", StartDate: "2021-03", Current: true},
+			{Role: "Senior", StartDate: "2018-01", EndDate: "2021-02"},
+		},
+		Awards: []Award{
+			{Title: "Old", Date: "2016"},
+			{Title: "New", Date: "2022-05"},
+		},
+	}
+	cv.Sort()
+
+	var roles []string
+	for _, e := range cv.Experience {
+		roles = append(roles, e.Role)
+	}
+	if got := roles; got[0] != "Lead" || got[1] != "Senior" || got[2] != "Junior" {
+		t.Errorf("experience order = %v, want [Lead Senior Junior]", got)
+	}
+	if cv.Awards[0].Title != "New" {
+		t.Errorf("awards not sorted most recent first: %v", cv.Awards)
+	}
+}
+
+func TestMaxEntriesMatchReplaceLimits(t *testing.T) {
+	input := reflect.TypeOf(UpdateCVInput{})
+	for i := 0; i < input.NumField(); i++ {
+		field := input.Field(i)
+		if field.Type.Kind() != reflect.Slice {
+			continue
+		}
+		list := field.Tag.Get("json")
+		if _, ok := lists[list]; !ok {
+			t.Errorf("list %s has no entry constructor", list)
+		}
+		want := fmt.Sprintf("max=%d,dive", maxEntries[list])
+		if got := field.Tag.Get("validate"); got != want {
+			t.Errorf("%s is validated as %q, want %q", list, got, want)
+		}
+	}
+}
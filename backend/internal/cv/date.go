@@ -0,0 +1,50 @@
+package cv
+
+import (
+	"time"
+)
+
+// Date is a calendar date of a CV entry at year, month or day precision,
+// written as YYYY, YYYY-MM or YYYY-MM-DD
+type Date string
+
+// dateLayouts lists the accepted date layouts, from most to least precise
+var dateLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+// Time parses the date, reporting whether it is valid. Partial dates resolve
+// to the start of their month or year.
+func (d Date) Time() (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if len(d) != len(layout) {
+			continue
+		}
+		t, err := time.Parse(layout, string(d))
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Display formats the date for readers, e.g. "May 2021"
+func (d Date) Display() string {
+	t, ok := d.Time()
+	if !ok {
+		return string(d)
+	}
+	switch len(d) {
+	case len("2006"):
+		return t.Format("2006")
+	case len("2006-01"):
+		return t.Format("Jan 2006")
+	default:
+		return t.Format("2 Jan 2006")
+	}
+}
+
+// before reports whether d lies before other. Empty dates sort first.
+func (d Date) before(other Date) bool {
+	a, _ := d.Time()
+	b, _ := other.Time()
+	return a.Before(b)
+}
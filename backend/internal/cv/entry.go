@@ -0,0 +1,174 @@
+package cv
+
+import (
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Entry is an item of one of the lists of a CV
+type Entry interface {
+	entryID() primitive.ObjectID
+	setEntryID(id primitive.ObjectID)
+	// checkDates validates the dates of the entry and their order
+	checkDates() error
+}
+
+// lists maps the name of each CV list, as used in URLs and as the BSON field,
+// to a constructor for its entries
+var lists = map[string]func() Entry{
+	"experience":     func() Entry { return &Experience{} },
+	"education":      func() Entry { return &Education{} },
+	"skills":         func() Entry { return &Skill{} },
+	"certifications": func() Entry { return &Certification{} },
+	"languages":      func() Entry { return &Language{} },
+	"awards":         func() Entry { return &Award{} },
+}
+
+// maxEntries is the most entries each list can hold, as validated on
+// UpdateCVInput, so that the CV document stays well below the size limit of
+// MongoDB
+var maxEntries = map[string]int{
+	"experience":     100,
+	"education":      100,
+	"skills":         200,
+	"certifications": 100,
+	"languages":      50,
+	"awards":         100,
+}
+
+// NewEntry returns an empty entry of the named list
+func NewEntry(list string) (Entry, error) {
+	newEntry, ok := lists[list]
+	if !ok {
+		return nil, ErrUnknownList
+	}
+	return newEntry(), nil
+}
+
+func (e *Experience) entryID() primitive.ObjectID      { return e.ID }
+func (e *Experience) setEntryID(id primitive.ObjectID) { e.ID = id }
+
+func (e *Experience) checkDates() error {
+	return checkRange(e.StartDate, e.EndDate, e.Current)
+}
+
+func (e *Education) entryID() primitive.ObjectID      { return e.ID }
+func (e *Education) setEntryID(id primitive.ObjectID) { e.ID = id }
+
+func (e *Education) checkDates() error {
+	return checkRange(e.StartDate, e.EndDate, e.Current)
+}
+
+func (s *Skill) entryID() primitive.ObjectID      { return s.ID }
+func (s *Skill) setEntryID(id primitive.ObjectID) { s.ID = id }
+func (s *Skill) checkDates() error                { return nil }
+
+func (c *Certification) entryID() primitive.ObjectID      { return c.ID }
+func (c *Certification) setEntryID(id primitive.ObjectID) { c.ID = id }
+
+func (c *Certification) checkDates() error {
+	return checkRange(c.IssueDate, c.ExpiryDate, false)
+}
+
+func (l *Language) entryID() primitive.ObjectID      { return l.ID }
+func (l *Language) setEntryID(id primitive.ObjectID) { l.ID = id }
+func (l *Language) checkDates() error                { return nil }
+
+func (a *Award) entryID() primitive.ObjectID      { return a.ID }
+func (a *Award) setEntryID(id primitive.ObjectID) { a.ID = id }
+
+func (a *Award) checkDates() error {
+	return checkDate("date", a.Date)
+}
+
+// checkDate validates an optional date
+func checkDate(field string, d Date) error {
+	if d == "" {
+		return nil
+	}
+	if _, ok := d.Time(); !ok {
+		return fmt.Errorf("%w: %s must be formatted as YYYY, YYYY-MM or YYYY-MM-DD", ErrInvalidCV, field)
+	}
+	return nil
+}
+
+// checkRange validates a date range. Current entries are still ongoing and
+// must not have an end.
+func checkRange(start, end Date, current bool) error {
+	if err := checkDate("start date", start); err != nil {
+		return err
+	}
+	if err := checkDate("end date", end); err != nil {
+		return err
+	}
+	if current && end != "" {
+		return fmt.Errorf("%w: current entries cannot have an end date", ErrInvalidCV)
+	}
+	if start != "" && end != "" && end.before(start) {
+		return fmt.Errorf("%w: end date is before start date", ErrInvalidCV)
+	}
+	return nil
+}
+
+// entries returns the entries of the CV as a flat list for validation
+func (c *CV) entries() []Entry {
+	var entries []Entry
+	for i := range c.Experience {
+		entries = append(entries, &c.Experience[i])
+	}
+	for i := range c.Education {
+		entries = append(entries, &c.Education[i])
+	}
+	for i := range c.Skills {
+		entries = append(entries, &c.Skills[i])
+	}
+	for i := range c.Certifications {
+		entries = append(entries, &c.Certifications[i])
+	}
+	for i := range c.Languages {
+		entries = append(entries, &c.Languages[i])
+	}
+	for i := range c.Awards {
+		entries = append(entries, &c.Awards[i])
+	}
+	return entries
+}
+
+// Sort orders the dated lists of the CV most recent first, with ongoing
+// entries on top. Skills and languages keep the order they were entered in.
+func (c *CV) Sort() {
+	sort.SliceStable(c.Experience, func(i, j int) bool {
+		a, b := c.Experience[i], c.Experience[j]
+		return recentFirst(a.StartDate, a.EndDate, a.Current, b.StartDate, b.EndDate, b.Current)
+	})
+	sort.SliceStable(c.Education, func(i, j int) bool {
+		a, b := c.Education[i], c.Education[j]
+		return recentFirst(a.StartDate, a.EndDate, a.Current, b.StartDate, b.EndDate, b.Current)
+	})
+	sort.SliceStable(c.Certifications, func(i, j int) bool {
+		return c.Certifications[j].IssueDate.before(c.Certifications[i].IssueDate)
+	})
+	sort.SliceStable(c.Awards, func(i, j int) bool {
+		return c.Awards[j].Date.before(c.Awards[i].Date)
+	})
+}
+
+// recentFirst reports whether the range a sorts before the range b: ongoing
+// ranges first, then by end and start date, latest first
+func recentFirst(aStart, aEnd Date, aCurrent bool, bStart, bEnd Date, bCurrent bool) bool {
+	if aCurrent != bCurrent {
+		return aCurrent
+	}
+	if aEnd == "" {
+		aEnd = aStart
+	}
+	if bEnd == "" {
+		bEnd = bStart
+	}
+	if aEnd != bEnd {
+		return bEnd.before(aEnd)
+	}
+	return bStart.before(aStart)
+}
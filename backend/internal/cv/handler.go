@@ -0,0 +1,195 @@
+package cv
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/auth"
+)
+
+// Handler handles HTTP requests for CVs
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new CV handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the CV routes of the current user
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/users/me/cv", func(r chi.Router) {
+		r.Get("/", h.Get)
+		r.Put("/", h.Replace)
+		r.Delete("/", h.Delete)
+		r.Post("/{list}", h.AddEntry)
+		r.Put("/{list}/{entryID}", h.UpdateEntry)
+		r.Delete("/{list}/{entryID}", h.DeleteEntry)
+	})
+}
+
+// Get handles getting the CV of the current user
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cv, err := h.service.Get(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cv)
+}
+
+// Replace handles replacing the CV of the current user
+func (h *Handler) Replace(w http.ResponseWriter, r *http.Request) {
+	var input UpdateCVInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cv, err := h.service.Replace(r.Context(), userID, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cv)
+}
+
+// Delete handles deleting the CV of the current user
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), userID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddEntry handles adding an entry to a list of the current user's CV
+func (h *Handler) AddEntry(w http.ResponseWriter, r *http.Request) {
+	entry, err := NewEntry(chi.URLParam(r, "list"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(entry); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	added, err := h.service.AddEntry(r.Context(), userID, chi.URLParam(r, "list"), entry)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+// UpdateEntry handles replacing an entry of the current user's CV
+func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
+	entryID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "entryID"))
+	if err != nil {
+		http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := NewEntry(chi.URLParam(r, "list"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(entry); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	updated, err := h.service.UpdateEntry(r.Context(), userID, chi.URLParam(r, "list"), entryID, entry)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteEntry handles removing an entry from the current user's CV
+func (h *Handler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	entryID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "entryID"))
+	if err != nil {
+		http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.DeleteEntry(r.Context(), userID, chi.URLParam(r, "list"), entryID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError maps service errors to responses
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrCVNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrEntryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrUnknownList):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidCV):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
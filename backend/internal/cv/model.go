@@ -0,0 +1,107 @@
+// Package cv implements the structured CV of a user, which is rendered into
+// every portfolio of type "cv".
+package cv
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CV represents the structured curriculum vitae of a user
+type CV struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         primitive.ObjectID `bson:"userId" json:"userId"`
+	Headline       string             `bson:"headline" json:"headline"`
+	Summary        string             `bson:"summary" json:"summary"`
+	Experience     []Experience       `bson:"experience" json:"experience"`
+	Education      []Education        `bson:"education" json:"education"`
+	Skills         []Skill            `bson:"skills" json:"skills"`
+	Certifications []Certification    `bson:"certifications" json:"certifications"`
+	Languages      []Language         `bson:"languages" json:"languages"`
+	Awards         []Award            `bson:"awards" json:"awards"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// IsEmpty reports whether the CV has no content
+func (c *CV) IsEmpty() bool {
+	return c.Headline == "" && c.Summary == "" &&
+		len(c.Experience) == 0 && len(c.Education) == 0 && len(c.Skills) == 0 &&
+		len(c.Certifications) == 0 && len(c.Languages) == 0 && len(c.Awards) == 0
+}
+
+// Experience is a position held
+type Experience struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	Role         string             `bson:"role" json:"role" validate:"required,max=200"`
+	Organization string             `bson:"organization" json:"organization" validate:"required,max=200"`
+	Location     string             `bson:"location" json:"location" validate:"max=200"`
+	StartDate    Date               `bson:"startDate" json:"startDate" validate:"required"`
+	EndDate      Date               `bson:"endDate,omitempty" json:"endDate,omitempty"`
+	Current      bool               `bson:"current" json:"current"`
+	Description  string             `bson:"description" json:"description" validate:"max=5000"`
+	Highlights   []string           `bson:"highlights,omitempty" json:"highlights,omitempty" validate:"max=20,dive,max=500"`
+}
+
+// Education is a degree, diploma or course
+type Education struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Institution string             `bson:"institution" json:"institution" validate:"required,max=200"`
+	Degree      string             `bson:"degree" json:"degree" validate:"max=200"`
+	Field       string             `bson:"field" json:"field" validate:"max=200"`
+	StartDate   Date               `bson:"startDate,omitempty" json:"startDate,omitempty"`
+	EndDate     Date               `bson:"endDate,omitempty" json:"endDate,omitempty"`
+	Current     bool               `bson:"current" json:"current"`
+	Grade       string             `bson:"grade" json:"grade" validate:"max=100"`
+	Description string             `bson:"description" json:"description" validate:"max=5000"`
+}
+
+// Skill is a skill with an optional proficiency from 1 (beginner) to 5
+// (expert)
+type Skill struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Name        string             `bson:"name" json:"name" validate:"required,max=100"`
+	Category    string             `bson:"category" json:"category" validate:"max=100"`
+	Proficiency int                `bson:"proficiency,omitempty" json:"proficiency,omitempty" validate:"min=0,max=5"`
+}
+
+// Certification is a professional certification or license
+type Certification struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	Name         string             `bson:"name" json:"name" validate:"required,max=200"`
+	Issuer       string             `bson:"issuer" json:"issuer" validate:"max=200"`
+	IssueDate    Date               `bson:"issueDate,omitempty" json:"issueDate,omitempty"`
+	ExpiryDate   Date               `bson:"expiryDate,omitempty" json:"expiryDate,omitempty"`
+	CredentialID string             `bson:"credentialId" json:"credentialId" validate:"max=200"`
+	URL          string             `bson:"url" json:"url" validate:"omitempty,url"`
+}
+
+// Language is a spoken language with its proficiency
+type Language struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Name        string             `bson:"name" json:"name" validate:"required,max=100"`
+	Proficiency string             `bson:"proficiency" json:"proficiency" validate:"omitempty,oneof=elementary limited professional fluent native"`
+}
+
+// Award is an award, grant or honour
+type Award struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Title       string             `bson:"title" json:"title" validate:"required,max=200"`
+	Issuer      string             `bson:"issuer" json:"issuer" validate:"max=200"`
+	Date        Date               `bson:"date,omitempty" json:"date,omitempty"`
+	Description string             `bson:"description" json:"description" validate:"max=2000"`
+}
+
+// UpdateCVInput represents the input for replacing a CV. Entries without an
+// ID are given one.
+type UpdateCVInput struct {
+	Headline       string          `json:"headline" validate:"max=200"`
+	Summary        string          `json:"summary" validate:"max=5000"`
+	Experience     []Experience    `json:"experience" validate:"max=100,dive"`
+	Education      []Education     `json:"education" validate:"max=100,dive"`
+	Skills         []Skill         `json:"skills" validate:"max=200,dive"`
+	Certifications []Certification `json:"certifications" validate:"max=100,dive"`
+	Languages      []Language      `json:"languages" validate:"max=50,dive"`
+	Awards         []Award         `json:"awards" validate:"max=100,dive"`
+}
@@ -0,0 +1,148 @@
+package cv
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/musefolio/backend/internal/database"
+)
+
+// Repository handles CV data operations
+type Repository struct {
+	db         *database.DB
+	collection *mongo.Collection
+}
+
+// NewRepository creates a new CV repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{
+		db:         db,
+		collection: db.Collection(database.CVsCollection),
+	}
+}
+
+// FindByUserID finds the CV of a user
+func (r *Repository) FindByUserID(ctx context.Context, userID primitive.ObjectID) (*CV, error) {
+	var cv CV
+	err := r.collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&cv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cv, nil
+}
+
+// Replace replaces the content of a user's CV, creating it if needed
+func (r *Repository) Replace(ctx context.Context, userID primitive.ObjectID, cv *CV) (*CV, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"headline":       cv.Headline,
+			"summary":        cv.Summary,
+			"experience":     cv.Experience,
+			"education":      cv.Education,
+			"skills":         cv.Skills,
+			"certifications": cv.Certifications,
+			"languages":      cv.Languages,
+			"awards":         cv.Awards,
+			"updatedAt":      now,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+	return r.upsert(ctx, userID, update)
+}
+
+// PushEntry appends an entry to a list of a user's CV unless the list holds
+// max entries already, creating the CV if needed. It returns nil if the list
+// is full.
+func (r *Repository) PushEntry(ctx context.Context, userID primitive.ObjectID, list string, max int, entry Entry) (*CV, error) {
+	now := time.Now()
+	filter := bson.M{
+		"userId":                          userID,
+		fmt.Sprintf("%s.%d", list, max-1): bson.M{"$exists": false},
+	}
+	update := bson.M{
+		"$push": bson.M{list: entry},
+		"$set":  bson.M{"updatedAt": now},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+
+	cv, err := r.findOneAndUpdate(ctx, filter, update, true)
+	if mongo.IsDuplicateKeyError(err) {
+		// The CV exists, so the upsert collided with it: the list is full,
+		// unless the CV was created concurrently
+		return r.findOneAndUpdate(ctx, filter, update, false)
+	}
+	return cv, err
+}
+
+// SetEntry replaces an entry of a list of a user's CV. It returns nil if the
+// entry doesn't exist.
+func (r *Repository) SetEntry(ctx context.Context, userID primitive.ObjectID, list string, entry Entry) (*CV, error) {
+	filter := bson.M{
+		"userId":      userID,
+		list + "._id": entry.entryID(),
+	}
+	update := bson.M{
+		"$set": bson.M{
+			list + ".$": entry,
+			"updatedAt": time.Now(),
+		},
+	}
+	return r.findOneAndUpdate(ctx, filter, update, false)
+}
+
+// PullEntry removes an entry from a list of a user's CV. It returns nil if
+// the entry doesn't exist.
+func (r *Repository) PullEntry(ctx context.Context, userID primitive.ObjectID, list string, id primitive.ObjectID) (*CV, error) {
+	filter := bson.M{
+		"userId":      userID,
+		list + "._id": id,
+	}
+	update := bson.M{
+		"$pull": bson.M{list: bson.M{"_id": id}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}
+	return r.findOneAndUpdate(ctx, filter, update, false)
+}
+
+// Delete deletes the CV of a user
+func (r *Repository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *Repository) upsert(ctx context.Context, userID primitive.ObjectID, update bson.M) (*CV, error) {
+	return r.findOneAndUpdate(ctx, bson.M{"userId": userID}, update, true)
+}
+
+func (r *Repository) findOneAndUpdate(ctx context.Context, filter, update bson.M, upsert bool) (*CV, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetUpsert(upsert)
+	var cv CV
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&cv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cv, nil
+}
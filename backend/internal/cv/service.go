@@ -0,0 +1,189 @@
+package cv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrCVNotFound    = errors.New("cv not found")
+	ErrEntryNotFound = errors.New("cv entry not found")
+	ErrUnknownList   = errors.New("unknown cv list")
+	ErrInvalidCV     = errors.New("invalid cv")
+)
+
+// Service handles CV business logic
+type Service struct {
+	repo     *Repository
+	validate *validator.Validate
+}
+
+// NewService creates a new CV service
+func NewService(repo *Repository) *Service {
+	return &Service{
+		repo:     repo,
+		validate: validator.New(),
+	}
+}
+
+// Get returns the CV of a user. Users who haven't written one get an empty
+// CV.
+func (s *Service) Get(ctx context.Context, userID primitive.ObjectID) (*CV, error) {
+	cv, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cv == nil {
+		cv = &CV{UserID: userID}
+	}
+	return normalize(cv), nil
+}
+
+// Check validates the input for replacing a CV without storing anything
+func (s *Service) Check(input UpdateCVInput) error {
+	if err := s.validate.Struct(input); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCV, err)
+	}
+	for _, entry := range fromInput(input).entries() {
+		if err := entry.checkDates(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace replaces the whole CV of a user
+func (s *Service) Replace(ctx context.Context, userID primitive.ObjectID, input UpdateCVInput) (*CV, error) {
+	if err := s.Check(input); err != nil {
+		return nil, err
+	}
+
+	cv := normalize(fromInput(input))
+	for _, entry := range cv.entries() {
+		if entry.entryID().IsZero() {
+			entry.setEntryID(primitive.NewObjectID())
+		}
+	}
+
+	updated, err := s.repo.Replace(ctx, userID, cv)
+	if err != nil {
+		return nil, err
+	}
+	return normalize(updated), nil
+}
+
+// Delete deletes the CV of a user
+func (s *Service) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	if err := s.repo.Delete(ctx, userID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrCVNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// AddEntry adds an entry to the named list of a user's CV
+func (s *Service) AddEntry(ctx context.Context, userID primitive.ObjectID, list string, entry Entry) (Entry, error) {
+	if err := s.checkEntry(list, entry); err != nil {
+		return nil, err
+	}
+	entry.setEntryID(primitive.NewObjectID())
+
+	max := maxEntries[list]
+	updated, err := s.repo.PushEntry(ctx, userID, list, max, entry)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, fmt.Errorf("%w: %s can hold at most %d entries", ErrInvalidCV, list, max)
+	}
+	return entry, nil
+}
+
+// UpdateEntry replaces an entry of the named list of a user's CV
+func (s *Service) UpdateEntry(ctx context.Context, userID primitive.ObjectID, list string, id primitive.ObjectID, entry Entry) (Entry, error) {
+	if err := s.checkEntry(list, entry); err != nil {
+		return nil, err
+	}
+	entry.setEntryID(id)
+
+	updated, err := s.repo.SetEntry(ctx, userID, list, entry)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+// DeleteEntry removes an entry from the named list of a user's CV
+func (s *Service) DeleteEntry(ctx context.Context, userID primitive.ObjectID, list string, id primitive.ObjectID) error {
+	if _, ok := lists[list]; !ok {
+		return ErrUnknownList
+	}
+
+	updated, err := s.repo.PullEntry(ctx, userID, list, id)
+	if err != nil {
+		return err
+	}
+	if updated == nil {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+// checkEntry validates an entry of the named list
+func (s *Service) checkEntry(list string, entry Entry) error {
+	if _, ok := lists[list]; !ok {
+		return ErrUnknownList
+	}
+	if err := s.validate.Struct(entry); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCV, err)
+	}
+	return entry.checkDates()
+}
+
+// fromInput builds a CV from the input for replacing one
+func fromInput(input UpdateCVInput) *CV {
+	return &CV{
+		Headline:       input.Headline,
+		Summary:        input.Summary,
+		Experience:     input.Experience,
+		Education:      input.Education,
+		Skills:         input.Skills,
+		Certifications: input.Certifications,
+		Languages:      input.Languages,
+		Awards:         input.Awards,
+	}
+}
+
+// normalize replaces missing lists with empty ones and sorts the CV
+func normalize(cv *CV) *CV {
+	if cv.Experience == nil {
+		cv.Experience = []Experience{}
+	}
+	if cv.Education == nil {
+		cv.Education = []Education{}
+	}
+	if cv.Skills == nil {
+		cv.Skills = []Skill{}
+	}
+	if cv.Certifications == nil {
+		cv.Certifications = []Certification{}
+	}
+	if cv.Languages == nil {
+		cv.Languages = []Language{}
+	}
+	if cv.Awards == nil {
+		cv.Awards = []Award{}
+	}
+	cv.Sort()
+	return cv
+}
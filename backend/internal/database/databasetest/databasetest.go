@@ -0,0 +1,62 @@
+// Package databasetest connects tests to a MongoDB server
+package databasetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/config"
+	"github.com/musefolio/backend/internal/database"
+)
+
+var (
+	mu sync.Mutex
+	// unavailable remembers a failed connection so the other tests of a
+	// package skip without waiting for the timeout again
+	unavailable error
+)
+
+// New connects to the MongoDB server at TEST_MONGODB_URI, by default a local
+// one, and returns a fresh database that is dropped when the test ends. The
+// test is skipped if SKIP_INTEGRATION_TESTS is "true" or the server can't be
+// reached.
+func New(t testing.TB) *database.DB {
+	t.Helper()
+	if os.Getenv("SKIP_INTEGRATION_TESTS") == "true" {
+		t.Skip("integration tests disabled")
+	}
+
+	uri := os.Getenv("TEST_MONGODB_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	cfg := &config.MongoDBConfig{
+		URI:      uri,
+		Database: fmt.Sprintf("musefolio_test_%s", primitive.NewObjectID().Hex()),
+		Timeout:  2 * time.Second,
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if unavailable != nil {
+		t.Skipf("MongoDB not available at %s: %v", uri, unavailable)
+	}
+	db, err := database.New(cfg)
+	if err != nil {
+		unavailable = err
+		t.Skipf("MongoDB not available at %s: %v", uri, err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Collection(database.UsersCollection).Database().Drop(ctx)
+		db.Close(ctx)
+	})
+	return db
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AcquireLease tries to take the named lease for owner until ttl elapses.
+// It returns true if the lease is now held by owner, either because it was
+// free, had expired or was already held by the same owner.
+func (db *DB) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expiresAt": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"owner":     owner,
+			"expiresAt": now.Add(ttl),
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	_, err := db.Collection(LeasesCollection).UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		// The upsert collides with the existing document when another owner
+		// holds an unexpired lease
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,33 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/musefolio/backend/internal/database/databasetest"
+)
+
+func TestLease(t *testing.T) {
+	ctx := context.Background()
+	db := databasetest.New(t)
+
+	acquire := func(owner string, ttl time.Duration, want bool) {
+		t.Helper()
+		got, err := db.AcquireLease(ctx, "job", owner, ttl)
+		if err != nil || got != want {
+			t.Fatalf("AcquireLease(%s) = %v, %v; want %v", owner, got, err, want)
+		}
+	}
+
+	acquire("a", time.Hour, true)
+	// The holder renews its lease, others have to wait
+	acquire("a", 200*time.Millisecond, true)
+	acquire("b", time.Hour, false)
+
+	// An expired lease is up for grabs
+	time.Sleep(300 * time.Millisecond)
+	acquire("b", time.Hour, true)
+	acquire("a", time.Hour, false)
+
+}
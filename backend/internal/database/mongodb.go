@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -19,10 +20,21 @@ type DB struct {
 
 // Collections holds all collection names
 const (
-	UsersCollection      = "users"
-	PortfoliosCollection = "portfolios"
-	TemplatesCollection  = "templates"
-	ThemesCollection     = "themes"
+	UsersCollection             = "users"
+	PortfoliosCollection        = "portfolios"
+	TemplatesCollection         = "templates"
+	ThemesCollection            = "themes"
+	RevisionsCollection         = "portfolio_revisions"
+	AuditCollection             = "audit_log"
+	LeasesCollection            = "leases"
+	CVsCollection               = "cvs"
+	ProjectsCollection          = "projects"
+	DeployCredentialsCollection = "deploy_credentials"
+	DeployTargetsCollection     = "deploy_targets"
+	DeploymentsCollection       = "deployments"
+	GitHubConnectionsCollection = "github_connections"
+	ImportJobsCollection        = "import_jobs"
+	ExploreCollection           = "explore_entries"
 )
 
 // New creates a new MongoDB connection
@@ -95,6 +107,13 @@ func (db *DB) EnsureIndexes(ctx context.Context) error {
 			},
 			Options: options.Index().SetUnique(true),
 		},
+		{
+			// Pages of the user list
+			Keys: bson.D{
+				{Key: "createdAt", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+		},
 	}
 
 	// Portfolios collection indexes
@@ -110,8 +129,266 @@ func (db *DB) EnsureIndexes(ctx context.Context) error {
 			},
 			Options: options.Index().SetUnique(true),
 		},
+		{
+			Keys: map[string]interface{}{
+				"publishAt": 1,
+			},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: map[string]interface{}{
+				"unpublishAt": 1,
+			},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: map[string]interface{}{
+				"projectRefs._id": 1,
+			},
+		},
+		{
+			// Pages of a user's portfolios
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "createdAt", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "updatedAt", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+		},
+		{
+			// Owners of published portfolios, for the explore feed
+			Keys: bson.D{
+				{Key: "isPublished", Value: 1},
+				{Key: "userId", Value: 1},
+			},
+		},
+		{
+			// Search of a user's portfolios and their sections
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "title", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "sections.title", Value: "text"},
+				{Key: "sections.content", Value: "text"},
+				{Key: "sections.content.headline", Value: "text"},
+				{Key: "sections.content.text", Value: "text"},
+				{Key: "sections.content.items.title", Value: "text"},
+				{Key: "sections.content.items.role", Value: "text"},
+				{Key: "sections.content.items.organization", Value: "text"},
+				{Key: "sections.content.items.institution", Value: "text"},
+				{Key: "sections.content.items.name", Value: "text"},
+				{Key: "sections.content.items.quote", Value: "text"},
+				{Key: "sections.content.items.description", Value: "text"},
+			},
+			Options: options.Index().SetName("portfolio_search").SetWeights(bson.M{
+				"title":          10,
+				"sections.title": 5,
+				"description":    3,
+			}),
+		},
+	}
+
+	// Projects collection indexes
+	projectIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{
+				"userId": 1,
+			},
+		},
+		{
+			// A project is imported once per user
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "source.provider", Value: 1},
+				{Key: "source.externalId", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"source": bson.M{"$exists": true}}),
+		},
+		{
+			// Search of a user's projects
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "title", Value: "text"},
+				{Key: "description", Value: "text"},
+				{Key: "content", Value: "text"},
+				{Key: "tags", Value: "text"},
+				{Key: "caseStudy.stages.title", Value: "text"},
+				{Key: "caseStudy.stages.body", Value: "text"},
+				{Key: "blocks.props.text", Value: "text"},
+				{Key: "blocks.children.props.text", Value: "text"},
+			},
+			Options: options.Index().SetName("project_search").SetWeights(bson.M{
+				"title":       10,
+				"tags":        5,
+				"description": 3,
+			}),
+		},
+	}
+
+	// Portfolio revisions collection indexes
+	revisionIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "portfolioId", Value: 1},
+				{Key: "_id", Value: -1},
+			},
+		},
+	}
+
+	// Audit log collection indexes
+	auditIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "resourceType", Value: 1},
+				{Key: "resourceId", Value: 1},
+				{Key: "createdAt", Value: -1},
+			},
+		},
+	}
+
+	// Templates collection indexes
+	templateIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{
+				"slug": 1,
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: map[string]interface{}{
+				"profession": 1,
+			},
+		},
+	}
+
+	// Themes collection indexes
+	themeIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{
+				"slug": 1,
+			},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys: map[string]interface{}{
+				"userId": 1,
+			},
+		},
+	}
+
+	// CVs collection indexes
+	cvIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{
+				"userId": 1,
+			},
+			Options: options.Index().SetUnique(true),
+		},
 	}
 
+	// Deployment collections indexes
+	credentialIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "provider", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	targetIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "portfolioId", Value: 1},
+				{Key: "provider", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	deploymentIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "portfolioId", Value: 1},
+				{Key: "createdAt", Value: -1},
+			},
+		},
+		{
+			// Only one deployment of a portfolio to a provider runs at a time
+			Keys: bson.D{
+				{Key: "portfolioId", Value: 1},
+				{Key: "provider", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "running"}),
+		},
+	}
+
+	// GitHub connections collection indexes
+	githubConnectionIndexes := []mongo.IndexModel{
+		{
+			Keys: map[string]interface{}{
+				"userId": 1,
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	// Import jobs collection indexes
+	importJobIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "portfolioId", Value: 1},
+				{Key: "createdAt", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "createdAt", Value: 1},
+			},
+		},
+	}
+
+	// Explore feed indexes. Every feed query filters on listed and kind and
+	// sorts by publishedAt or views, with _id breaking ties for cursors.
+	exploreIndexes := []mongo.IndexModel{}
+	for _, sort := range []string{"publishedAt", "views"} {
+		for _, filter := range []string{"", "owner.profession", "tags", "types", "featured", "staffPick"} {
+			keys := bson.D{{Key: "listed", Value: 1}, {Key: "kind", Value: 1}}
+			if filter != "" {
+				keys = append(keys, bson.E{Key: filter, Value: 1})
+			}
+			keys = append(keys, bson.E{Key: sort, Value: -1}, bson.E{Key: "_id", Value: -1})
+			exploreIndexes = append(exploreIndexes, mongo.IndexModel{Keys: keys})
+		}
+	}
+	exploreIndexes = append(exploreIndexes,
+		mongo.IndexModel{
+			// Counting site views
+			Keys: map[string]interface{}{
+				"portfolios._id": 1,
+			},
+		},
+		mongo.IndexModel{
+			// Unlisting entries a refresh didn't find
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "syncedAt", Value: 1},
+			},
+		},
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "listed", Value: 1},
+				{Key: "syncedAt", Value: 1},
+			},
+		},
+	)
+
 	// Create indexes
 	if _, err := db.Collection(UsersCollection).Indexes().CreateMany(ctx, userIndexes); err != nil {
 		return err
@@ -121,5 +398,53 @@ func (db *DB) EnsureIndexes(ctx context.Context) error {
 		return err
 	}
 
+	if _, err := db.Collection(ProjectsCollection).Indexes().CreateMany(ctx, projectIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(RevisionsCollection).Indexes().CreateMany(ctx, revisionIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(AuditCollection).Indexes().CreateMany(ctx, auditIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(TemplatesCollection).Indexes().CreateMany(ctx, templateIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(ThemesCollection).Indexes().CreateMany(ctx, themeIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(CVsCollection).Indexes().CreateMany(ctx, cvIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(DeployCredentialsCollection).Indexes().CreateMany(ctx, credentialIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(DeployTargetsCollection).Indexes().CreateMany(ctx, targetIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(DeploymentsCollection).Indexes().CreateMany(ctx, deploymentIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(GitHubConnectionsCollection).Indexes().CreateMany(ctx, githubConnectionIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(ImportJobsCollection).Indexes().CreateMany(ctx, importJobIndexes); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection(ExploreCollection).Indexes().CreateMany(ctx, exploreIndexes); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// ErrInvalidCiphertext is returned when a stored token can't be decrypted,
+// e.g. after the credentials key changed
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Cipher encrypts provider tokens at rest with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher whose key is derived from secret
+func NewCipher(secret string) (*Cipher, error) {
+	if secret == "" {
+		return nil, errors.New("empty credentials key")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts plaintext under a random nonce, which is prepended to
+// the result
+func (c *Cipher) Encrypt(plaintext string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, []byte(plaintext), nil), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt
+func (c *Cipher) Decrypt(ciphertext []byte) (string, error) {
+	size := c.aead.NonceSize()
+	if len(ciphertext) < size {
+		return "", ErrInvalidCiphertext
+	}
+	plaintext, err := c.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,275 @@
+package deploy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testFiles() map[string][]byte {
+	return map[string][]byte{
+		"index.html":        []byte("<h1>Home</h1>"),
+		"logo-design.html":  []byte("<h1>Logo</h1>"),
+		"media/p/cover.png": []byte("cover"),
+		"media/p/copy.png":  []byte("cover"),
+	}
+}
+
+// recorder is a provider stand-in that records the requests it receives
+type recorder struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string][]byte
+}
+
+func (rec *recorder) record(t *testing.T, r *http.Request) []byte {
+	t.Helper()
+	if r.Header.Get("Authorization") != "Bearer secret-token" {
+		t.Errorf("%s %s: missing token", r.Method, r.URL.Path)
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests = append(rec.requests, r.Method+" "+r.URL.Path)
+	if rec.bodies == nil {
+		rec.bodies = map[string][]byte{}
+	}
+	rec.bodies[r.Method+" "+r.URL.Path] = body
+	return body
+}
+
+func (rec *recorder) count(prefix string) int {
+	n := 0
+	for _, request := range rec.requests {
+		if strings.HasPrefix(request, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGitHubDeploy(t *testing.T) {
+	rec := &recorder{}
+	blobs := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := rec.record(t, r)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /repos/ada/site/git/blobs":
+			var in map[string]string
+			json.Unmarshal(body, &in)
+			content := in["content"]
+			if in["encoding"] == "base64" {
+				data, _ := base64.StdEncoding.DecodeString(content)
+				content = string(data)
+			}
+			sha := sha1Hex([]byte(content))
+			blobs[sha] = content
+			json.NewEncoder(w).Encode(map[string]string{"sha": sha})
+		case "POST /repos/ada/site/git/trees":
+			json.NewEncoder(w).Encode(map[string]string{"sha": "tree1"})
+		case "GET /repos/ada/site/git/ref/heads/gh-pages":
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		case "POST /repos/ada/site/git/commits":
+			json.NewEncoder(w).Encode(map[string]string{"sha": "commit1"})
+		case "POST /repos/ada/site/git/refs":
+			w.WriteHeader(http.StatusCreated)
+		case "POST /repos/ada/site/pages":
+			http.Error(w, `{"message":"already enabled"}`, http.StatusConflict)
+		default:
+			http.Error(w, "unexpected request", http.StatusTeapot)
+		}
+	}))
+	defer server.Close()
+
+	target := Target{Provider: ProviderGitHub, Site: "ada/site"}
+	result, err := NewGitHub(server.URL).Deploy(context.Background(), "secret-token", target, testFiles())
+	if err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+	if result.ID != "commit1" || result.URL != "https://ada.github.io/site/" {
+		t.Errorf("result = %+v", result)
+	}
+
+	var tree struct {
+		Tree []gitTreeEntry `json:"tree"`
+	}
+	json.Unmarshal(rec.bodies["POST /repos/ada/site/git/trees"], &tree)
+	paths := []string{}
+	for _, entry := range tree.Tree {
+		paths = append(paths, entry.Path)
+		if _, ok := blobs[entry.SHA]; !ok {
+			t.Errorf("tree entry %s references an unknown blob", entry.Path)
+		}
+	}
+	if got := strings.Join(paths, ","); got != ".nojekyll,index.html,logo-design.html,media/p/copy.png,media/p/cover.png" {
+		t.Errorf("tree = %s", got)
+	}
+
+	var commit map[string]interface{}
+	json.Unmarshal(rec.bodies["POST /repos/ada/site/git/commits"], &commit)
+	if parents := commit["parents"].([]interface{}); len(parents) != 0 {
+		t.Errorf("first commit has parents %v", parents)
+	}
+	var ref map[string]string
+	json.Unmarshal(rec.bodies["POST /repos/ada/site/git/refs"], &ref)
+	if ref["ref"] != "refs/heads/gh-pages" || ref["sha"] != "commit1" {
+		t.Errorf("ref = %v", ref)
+	}
+}
+
+func TestGitHubURL(t *testing.T) {
+	g := NewGitHub("")
+	if got := g.URL(Target{Site: "Ada/ada.github.io"}); got != "https://ada.github.io/" {
+		t.Errorf("user site URL = %s", got)
+	}
+	if got := g.URL(Target{Site: "ada/work"}); got != "https://ada.github.io/work/" {
+		t.Errorf("project site URL = %s", got)
+	}
+	if _, _, err := splitRepository("ada"); !errors.Is(err, ErrInvalidRepository) {
+		t.Errorf("splitRepository(ada) = %v", err)
+	}
+}
+
+func TestNetlifyDeploy(t *testing.T) {
+	rec := &recorder{}
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := rec.record(t, r)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/sites/my-site/deploys":
+			var in struct {
+				Files map[string]string `json:"files"`
+			}
+			json.Unmarshal(body, &in)
+			if len(in.Files) != 4 || in.Files["/index.html"] != sha1Hex([]byte("<h1>Home</h1>")) {
+				t.Errorf("digests = %v", in.Files)
+			}
+			// Netlify already has the project page
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":       "d1",
+				"state":    "prepared",
+				"required": []string{in.Files["/index.html"], in.Files["/media/p/cover.png"]},
+			})
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/deploys/d1/files/"):
+			if r.Header.Get("Content-Type") != "application/octet-stream" {
+				t.Errorf("upload content type = %s", r.Header.Get("Content-Type"))
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/deploys/d1":
+			polls++
+			state := "processing"
+			if polls > 1 {
+				state = "ready"
+			}
+			json.NewEncoder(w).Encode(map[string]string{"id": "d1", "state": state, "ssl_url": "https://my-site.netlify.app"})
+		default:
+			http.Error(w, "unexpected request", http.StatusTeapot)
+		}
+	}))
+	defer server.Close()
+
+	netlify := NewNetlify(server.URL)
+	netlify.PollInterval = time.Millisecond
+	result, err := netlify.Deploy(context.Background(), "secret-token", Target{Site: "my-site"}, testFiles())
+	if err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+	if result.ID != "d1" || result.URL != "https://my-site.netlify.app" {
+		t.Errorf("result = %+v", result)
+	}
+	// Identical media is uploaded once, files Netlify has not at all
+	if n := rec.count("PUT /deploys/d1/files/"); n != 2 {
+		t.Errorf("uploaded %d files, want 2: %v", n, rec.requests)
+	}
+	if rec.count("PUT /deploys/d1/files/logo-design.html") != 0 {
+		t.Error("uploaded a file Netlify didn't require")
+	}
+}
+
+func TestVercelDeploy(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := rec.record(t, r)
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v2/files":
+			if r.Header.Get("x-vercel-digest") != sha1Hex(body) {
+				t.Errorf("digest header doesn't match the upload")
+			}
+		case "POST /v13/deployments":
+			var in struct {
+				Name  string       `json:"name"`
+				Files []vercelFile `json:"files"`
+			}
+			json.Unmarshal(body, &in)
+			if in.Name != "portfolio" || len(in.Files) != 4 {
+				t.Errorf("deployment = %+v", in)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": "dpl_1", "url": "portfolio-abc.vercel.app", "readyState": "BUILDING"})
+		case "GET /v13/deployments/dpl_1":
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": "dpl_1", "readyState": "READY", "alias": []string{"portfolio.vercel.app"}})
+		default:
+			http.Error(w, "unexpected request", http.StatusTeapot)
+		}
+	}))
+	defer server.Close()
+
+	vercel := NewVercel(server.URL)
+	vercel.PollInterval = time.Millisecond
+	result, err := vercel.Deploy(context.Background(), "secret-token", Target{Site: "portfolio"}, testFiles())
+	if err != nil {
+		t.Fatalf("Deploy: %v", err)
+	}
+	if result.ID != "dpl_1" || result.URL != "https://portfolio.vercel.app" {
+		t.Errorf("result = %+v", result)
+	}
+	if n := rec.count("POST /v2/files"); n != 3 {
+		t.Errorf("uploaded %d files, want 3", n)
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewNetlify(server.URL).Deploy(context.Background(), "secret-token", Target{Site: "my-site"}, testFiles())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || !strings.Contains(apiErr.Message, "Bad credentials") {
+		t.Errorf("err = %v, want the API error", err)
+	}
+}
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher("key")
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+
+	sealed, err := c.Encrypt("secret-token")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if strings.Contains(string(sealed), "secret-token") {
+		t.Error("token stored in plain text")
+	}
+	if token, err := c.Decrypt(sealed); err != nil || token != "secret-token" {
+		t.Errorf("Decrypt = %q, %v", token, err)
+	}
+
+	other, _ := NewCipher("other key")
+	if _, err := other.Decrypt(sealed); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Decrypt with another key = %v, want ErrInvalidCiphertext", err)
+	}
+	if _, err := NewCipher(""); err == nil {
+		t.Error("NewCipher accepted an empty key")
+	}
+}
@@ -0,0 +1,144 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Deployer uploads static sites to a hosting provider
+type Deployer interface {
+	// URL returns the address the site of target is served at
+	URL(target Target) string
+	// Deploy replaces the site of target with files, keyed by path, and
+	// waits for the provider to serve it
+	Deploy(ctx context.Context, token string, target Target, files map[string][]byte) (*Result, error)
+}
+
+// Result describes a finished deployment on the provider's side
+type Result struct {
+	// ID is the provider's ID of the deployment
+	ID string
+	// URL is the address the deployed site is served at
+	URL string
+}
+
+// APIError is returned when a provider rejects a request
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API returned %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// defaultPollInterval is how often providers that build sites asynchronously
+// are asked whether a deployment is live
+const defaultPollInterval = 2 * time.Second
+
+// maxErrorBody bounds how much of an error response is kept as the message
+const maxErrorBody = 1 << 10
+
+// client makes authenticated JSON requests to a provider's API
+type client struct {
+	provider string
+	baseURL  string
+	http     *http.Client
+}
+
+func newClient(provider, baseURL string) client {
+	return client{
+		provider: provider,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		http:     &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// doJSON sends in as the JSON body of a request and decodes the response
+// into out. Either may be nil.
+func (c client) doJSON(ctx context.Context, method, path, token string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	return c.do(ctx, method, path, token, "application/json", body, nil, out)
+}
+
+// do sends a request and decodes its JSON response into out. Responses
+// outside of the 2xx range are returned as an *APIError.
+func (c client) do(ctx context.Context, method, path, token, contentType string, body io.Reader, header http.Header, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &APIError{Provider: c.provider, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// sha1Hex returns the hex-encoded SHA-1 digest providers identify files by
+func sha1Hex(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sortedPaths returns the paths of files in order, so uploads are
+// reproducible
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// poll calls check every interval until it reports done or fails
+func poll(ctx context.Context, interval time.Duration, check func() (bool, error)) error {
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package deploy
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidRepository is returned for GitHub sites not of the form
+// "owner/repo"
+var ErrInvalidRepository = errors.New(`github site must be "owner/repo"`)
+
+// defaultPagesBranch is the branch Pages are published from by default
+const defaultPagesBranch = "gh-pages"
+
+// GitHub deploys sites to GitHub Pages by committing them to the Pages
+// branch of a repository through the Git data API
+type GitHub struct {
+	client
+}
+
+// NewGitHub creates a GitHub Pages deployer for the API at baseURL
+func NewGitHub(baseURL string) *GitHub {
+	return &GitHub{client: newClient("GitHub", baseURL)}
+}
+
+// gitObject is the part of Git data API responses deployments use
+type gitObject struct {
+	SHA string `json:"sha"`
+}
+
+// gitTreeEntry is a file in a Git tree
+type gitTreeEntry struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+}
+
+// splitRepository splits a GitHub site into the repository's owner and name
+func splitRepository(site string) (string, string, error) {
+	owner, repo, ok := strings.Cut(site, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", ErrInvalidRepository
+	}
+	return owner, repo, nil
+}
+
+// URL returns the Pages address of the repository
+func (g *GitHub) URL(target Target) string {
+	owner, repo, err := splitRepository(target.Site)
+	if err != nil {
+		return ""
+	}
+	owner = strings.ToLower(owner)
+	// User and organization sites are served from the root
+	if strings.EqualFold(repo, owner+".github.io") {
+		return "https://" + owner + ".github.io/"
+	}
+	return "https://" + owner + ".github.io/" + repo + "/"
+}
+
+// Deploy commits files as the whole content of the Pages branch. The commit
+// replaces whatever the branch held before, and Pages are switched on for
+// the branch the first time it is created.
+func (g *GitHub) Deploy(ctx context.Context, token string, target Target, files map[string][]byte) (*Result, error) {
+	owner, repo, err := splitRepository(target.Site)
+	if err != nil {
+		return nil, err
+	}
+	branch := target.Branch
+	if branch == "" {
+		branch = defaultPagesBranch
+	}
+	base := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+
+	// Keep Jekyll from skipping files that start with an underscore
+	var noJekyll gitObject
+	if err := g.doJSON(ctx, http.MethodPost, base+"/git/blobs", token, map[string]string{"content": "", "encoding": "utf-8"}, &noJekyll); err != nil {
+		return nil, err
+	}
+	tree := []gitTreeEntry{{Path: ".nojekyll", Mode: "100644", Type: "blob", SHA: noJekyll.SHA}}
+
+	for _, path := range sortedPaths(files) {
+		var blob gitObject
+		in := map[string]string{"content": base64.StdEncoding.EncodeToString(files[path]), "encoding": "base64"}
+		if err := g.doJSON(ctx, http.MethodPost, base+"/git/blobs", token, in, &blob); err != nil {
+			return nil, fmt.Errorf("upload %s: %w", path, err)
+		}
+		tree = append(tree, gitTreeEntry{Path: path, Mode: "100644", Type: "blob", SHA: blob.SHA})
+	}
+
+	var newTree gitObject
+	if err := g.doJSON(ctx, http.MethodPost, base+"/git/trees", token, map[string]interface{}{"tree": tree}, &newTree); err != nil {
+		return nil, err
+	}
+
+	var ref struct {
+		Object gitObject `json:"object"`
+	}
+	parents := []string{}
+	err = g.doJSON(ctx, http.MethodGet, base+"/git/ref/heads/"+url.PathEscape(branch), token, nil, &ref)
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		parents = append(parents, ref.Object.SHA)
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		// First deployment to the branch
+	default:
+		return nil, err
+	}
+
+	var commit gitObject
+	in := map[string]interface{}{"message": "Deploy portfolio", "tree": newTree.SHA, "parents": parents}
+	if err := g.doJSON(ctx, http.MethodPost, base+"/git/commits", token, in, &commit); err != nil {
+		return nil, err
+	}
+
+	if len(parents) > 0 {
+		in := map[string]interface{}{"sha": commit.SHA, "force": true}
+		if err := g.doJSON(ctx, http.MethodPatch, base+"/git/refs/heads/"+url.PathEscape(branch), token, in, nil); err != nil {
+			return nil, err
+		}
+	} else {
+		in := map[string]string{"ref": "refs/heads/" + branch, "sha": commit.SHA}
+		if err := g.doJSON(ctx, http.MethodPost, base+"/git/refs", token, in, nil); err != nil {
+			return nil, err
+		}
+		if err := g.enablePages(ctx, token, base, branch); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Result{ID: commit.SHA, URL: g.URL(target)}, nil
+}
+
+// enablePages serves Pages from the root of branch. Repositories that
+// already have Pages set up are left alone.
+func (g *GitHub) enablePages(ctx context.Context, token, base, branch string) error {
+	in := map[string]interface{}{"source": map[string]string{"branch": branch, "path": "/"}}
+	err := g.doJSON(ctx, http.MethodPost, base+"/pages", token, in, nil)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		return nil
+	}
+	return err
+}
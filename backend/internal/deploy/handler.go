@@ -0,0 +1,292 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+// Handler handles HTTP requests for deployments
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new deployment handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the deployment routes
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/users/me/deploy-credentials", func(r chi.Router) {
+		r.Get("/", h.ListCredentials)
+		r.Put("/{provider}", h.SaveCredential)
+		r.Delete("/{provider}", h.DeleteCredential)
+	})
+
+	r.Get("/portfolios/{id}/deploy-targets", h.ListTargets)
+	r.Put("/portfolios/{id}/deploy-targets/{provider}", h.SaveTarget)
+	r.Delete("/portfolios/{id}/deploy-targets/{provider}", h.DeleteTarget)
+
+	r.Get("/portfolios/{id}/deployments", h.ListDeployments)
+	r.Post("/portfolios/{id}/deployments", h.Deploy)
+	r.Get("/portfolios/{id}/deployments/{deploymentID}", h.GetDeployment)
+}
+
+// ListCredentials handles listing the providers the current user has stored
+// tokens for
+func (h *Handler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	credentials, err := h.service.ListCredentials(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credentials)
+}
+
+// SaveCredential handles storing the current user's token for a provider
+func (h *Handler) SaveCredential(w http.ResponseWriter, r *http.Request) {
+	var input SaveCredentialInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	credential, err := h.service.SaveCredential(r.Context(), userID, chi.URLParam(r, "provider"), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(credential)
+}
+
+// DeleteCredential handles deleting the current user's token for a provider
+func (h *Handler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.DeleteCredential(r.Context(), userID, chi.URLParam(r, "provider")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTargets handles listing where a portfolio is deployed to
+func (h *Handler) ListTargets(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	targets, err := h.service.ListTargets(r.Context(), portfolioID, userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(targets)
+}
+
+// SaveTarget handles configuring where a portfolio is deployed to on a
+// provider
+func (h *Handler) SaveTarget(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	var input SaveTargetInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	target, err := h.service.SaveTarget(r.Context(), portfolioID, userID, chi.URLParam(r, "provider"), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(target)
+}
+
+// DeleteTarget handles removing the target of a portfolio on a provider
+func (h *Handler) DeleteTarget(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.DeleteTarget(r.Context(), portfolioID, userID, chi.URLParam(r, "provider")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deploy handles starting a deployment of a portfolio. The deployment runs
+// in the background; clients poll it until it has finished.
+func (h *Handler) Deploy(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	var input CreateDeploymentInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deployment, err := h.service.Deploy(r.Context(), portfolioID, userID, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deployment)
+}
+
+// ListDeployments handles listing the deployment history of a portfolio
+func (h *Handler) ListDeployments(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deployments, err := h.service.ListDeployments(r.Context(), portfolioID, userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deployments)
+}
+
+// GetDeployment handles getting the status of a deployment
+func (h *Handler) GetDeployment(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	deploymentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "deploymentID"))
+	if err != nil {
+		http.Error(w, "Invalid deployment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	deployment, err := h.service.GetDeployment(r.Context(), portfolioID, deploymentID, userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deployment)
+}
+
+// writeError maps service errors to responses
+func writeError(w http.ResponseWriter, err error) {
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.Is(err, portfolio.ErrPortfolioNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, portfolio.ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrUnknownProvider):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrCredentialNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrTargetNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrDeploymentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrDeploymentInProgress):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalidTarget):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.As(err, &validationErrors):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
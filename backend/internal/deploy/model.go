@@ -0,0 +1,94 @@
+package deploy
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hosting providers portfolios can be deployed to
+const (
+	ProviderGitHub  = "github"
+	ProviderNetlify = "netlify"
+	ProviderVercel  = "vercel"
+)
+
+// Deployment statuses
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// What started a deployment
+const (
+	TriggerManual  = "manual"
+	TriggerPublish = "publish"
+)
+
+// Credential is a user's access token for a hosting provider. The token is
+// stored encrypted and never returned.
+type Credential struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID   primitive.ObjectID `bson:"userId" json:"userId"`
+	Provider string             `bson:"provider" json:"provider"`
+	Token    []byte             `bson:"token" json:"-"`
+	// TokenHint is the end of the token, to tell tokens apart
+	TokenHint string    `bson:"tokenHint" json:"tokenHint"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Target is the site on a hosting provider a portfolio is deployed to
+type Target struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PortfolioID primitive.ObjectID `bson:"portfolioId" json:"portfolioId"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Provider    string             `bson:"provider" json:"provider"`
+	// Site identifies the site on the provider: "owner/repo" on GitHub, the
+	// site ID or domain on Netlify and the project name on Vercel
+	Site string `bson:"site" json:"site"`
+	// Branch is the GitHub branch Pages are served from
+	Branch string `bson:"branch,omitempty" json:"branch,omitempty"`
+	// AutoDeploy redeploys the portfolio whenever it is published
+	AutoDeploy bool      `bson:"autoDeploy" json:"autoDeploy"`
+	CreatedAt  time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Deployment is a single upload of a portfolio to a hosting provider
+type Deployment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PortfolioID primitive.ObjectID `bson:"portfolioId" json:"portfolioId"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Provider    string             `bson:"provider" json:"provider"`
+	Site        string             `bson:"site" json:"site"`
+	Trigger     string             `bson:"trigger" json:"trigger"`
+	Status      string             `bson:"status" json:"status"`
+	// PortfolioVersion is the version of the portfolio that was deployed
+	PortfolioVersion int64 `bson:"portfolioVersion,omitempty" json:"portfolioVersion,omitempty"`
+	// RemoteID is the provider's ID of the deployment
+	RemoteID   string     `bson:"remoteId,omitempty" json:"remoteId,omitempty"`
+	URL        string     `bson:"url,omitempty" json:"url,omitempty"`
+	Error      string     `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	FinishedAt *time.Time `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+}
+
+// SaveCredentialInput represents the input for storing a provider token
+type SaveCredentialInput struct {
+	Token string `json:"token" validate:"required,max=1024"`
+}
+
+// SaveTargetInput represents the input for configuring where a portfolio is
+// deployed to on a provider
+type SaveTargetInput struct {
+	Site       string `json:"site" validate:"required,max=200"`
+	Branch     string `json:"branch,omitempty" validate:"max=200"`
+	AutoDeploy bool   `json:"autoDeploy"`
+}
+
+// CreateDeploymentInput represents the input for deploying a portfolio
+type CreateDeploymentInput struct {
+	Provider string `json:"provider" validate:"required"`
+}
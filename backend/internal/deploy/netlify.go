@@ -0,0 +1,112 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Netlify deploys sites to Netlify with file digest deploys: the digests of
+// all files are sent up front and only files Netlify doesn't have yet are
+// uploaded
+type Netlify struct {
+	client
+	// PollInterval is how often a deployment is checked until it is live
+	PollInterval time.Duration
+}
+
+// NewNetlify creates a Netlify deployer for the API at baseURL
+func NewNetlify(baseURL string) *Netlify {
+	return &Netlify{client: newClient("Netlify", baseURL), PollInterval: defaultPollInterval}
+}
+
+// netlifyDeploy is the part of Netlify's deploy object deployments use
+type netlifyDeploy struct {
+	ID           string   `json:"id"`
+	State        string   `json:"state"`
+	Required     []string `json:"required"`
+	SSLURL       string   `json:"ssl_url"`
+	URL          string   `json:"url"`
+	ErrorMessage string   `json:"error_message"`
+}
+
+// URL returns the address of the Netlify site. Sites given by ID are served
+// from their netlify.app subdomain.
+func (n *Netlify) URL(target Target) string {
+	if strings.Contains(target.Site, ".") {
+		return "https://" + target.Site
+	}
+	return "https://" + target.Site + ".netlify.app"
+}
+
+// Deploy creates a production deploy of the site and uploads the files it
+// requires
+func (n *Netlify) Deploy(ctx context.Context, token string, target Target, files map[string][]byte) (*Result, error) {
+	digests := make(map[string]string, len(files))
+	for path, data := range files {
+		digests["/"+path] = sha1Hex(data)
+	}
+
+	var deploy netlifyDeploy
+	in := map[string]interface{}{"files": digests}
+	if err := n.doJSON(ctx, http.MethodPost, "/sites/"+url.PathEscape(target.Site)+"/deploys", token, in, &deploy); err != nil {
+		return nil, err
+	}
+
+	required := make(map[string]bool, len(deploy.Required))
+	for _, digest := range deploy.Required {
+		required[digest] = true
+	}
+	for _, path := range sortedPaths(files) {
+		digest := digests["/"+path]
+		if !required[digest] {
+			continue
+		}
+		// Files with the same content only need to be uploaded once
+		delete(required, digest)
+
+		err := n.do(ctx, http.MethodPut, "/deploys/"+url.PathEscape(deploy.ID)+"/files/"+escapePath(path), token, "application/octet-stream", bytes.NewReader(files[path]), nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %w", path, err)
+		}
+	}
+
+	err := poll(ctx, n.PollInterval, func() (bool, error) {
+		if err := n.doJSON(ctx, http.MethodGet, "/deploys/"+url.PathEscape(deploy.ID), token, nil, &deploy); err != nil {
+			return false, err
+		}
+		switch deploy.State {
+		case "ready":
+			return true, nil
+		case "error":
+			return false, fmt.Errorf("netlify deploy failed: %s", deploy.ErrorMessage)
+		default:
+			return false, nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	siteURL := deploy.SSLURL
+	if siteURL == "" {
+		siteURL = deploy.URL
+	}
+	if siteURL == "" {
+		siteURL = n.URL(target)
+	}
+	return &Result{ID: deploy.ID, URL: siteURL}, nil
+}
+
+// escapePath escapes every segment of a file path for use in a URL
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,255 @@
+package deploy
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/musefolio/backend/internal/database"
+)
+
+// maxHistory is the number of deployments listed per portfolio
+const maxHistory = 50
+
+// Repository handles deployment data operations
+type Repository struct {
+	db          *database.DB
+	credentials *mongo.Collection
+	targets     *mongo.Collection
+	deployments *mongo.Collection
+}
+
+// NewRepository creates a new deployment repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{
+		db:          db,
+		credentials: db.Collection(database.DeployCredentialsCollection),
+		targets:     db.Collection(database.DeployTargetsCollection),
+		deployments: db.Collection(database.DeploymentsCollection),
+	}
+}
+
+// SaveCredential stores the token of a user for a provider, replacing any
+// previous one
+func (r *Repository) SaveCredential(ctx context.Context, credential *Credential) (*Credential, error) {
+	now := time.Now()
+	filter := bson.M{"userId": credential.UserID, "provider": credential.Provider}
+	update := bson.M{
+		"$set": bson.M{
+			"token":     credential.Token,
+			"tokenHint": credential.TokenHint,
+			"updatedAt": now,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved Credential
+	if err := r.credentials.FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// FindCredential finds the token of a user for a provider
+func (r *Repository) FindCredential(ctx context.Context, userID primitive.ObjectID, provider string) (*Credential, error) {
+	var credential Credential
+	err := r.credentials.FindOne(ctx, bson.M{"userId": userID, "provider": provider}).Decode(&credential)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// FindCredentialsByUserID finds the tokens a user has stored
+func (r *Repository) FindCredentialsByUserID(ctx context.Context, userID primitive.ObjectID) ([]*Credential, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "provider", Value: 1}})
+	cursor, err := r.credentials.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	credentials := []*Credential{}
+	if err := cursor.All(ctx, &credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// DeleteCredential deletes the token of a user for a provider. It reports
+// whether there was one.
+func (r *Repository) DeleteCredential(ctx context.Context, userID primitive.ObjectID, provider string) (bool, error) {
+	result, err := r.credentials.DeleteOne(ctx, bson.M{"userId": userID, "provider": provider})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// SaveTarget stores where a portfolio is deployed to on a provider,
+// replacing any previous target
+func (r *Repository) SaveTarget(ctx context.Context, target *Target) (*Target, error) {
+	now := time.Now()
+	filter := bson.M{"portfolioId": target.PortfolioID, "provider": target.Provider}
+	update := bson.M{
+		"$set": bson.M{
+			"userId":     target.UserID,
+			"site":       target.Site,
+			"branch":     target.Branch,
+			"autoDeploy": target.AutoDeploy,
+			"updatedAt":  now,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved Target
+	if err := r.targets.FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// FindTarget finds the target of a portfolio on a provider
+func (r *Repository) FindTarget(ctx context.Context, portfolioID primitive.ObjectID, provider string) (*Target, error) {
+	var target Target
+	err := r.targets.FindOne(ctx, bson.M{"portfolioId": portfolioID, "provider": provider}).Decode(&target)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &target, nil
+}
+
+// FindTargetsByPortfolioID finds every target of a portfolio
+func (r *Repository) FindTargetsByPortfolioID(ctx context.Context, portfolioID primitive.ObjectID) ([]*Target, error) {
+	return r.findTargets(ctx, bson.M{"portfolioId": portfolioID})
+}
+
+// FindAutoDeployTargets finds the targets a portfolio is redeployed to when
+// it is published
+func (r *Repository) FindAutoDeployTargets(ctx context.Context, portfolioID primitive.ObjectID) ([]*Target, error) {
+	return r.findTargets(ctx, bson.M{"portfolioId": portfolioID, "autoDeploy": true})
+}
+
+func (r *Repository) findTargets(ctx context.Context, filter bson.M) ([]*Target, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "provider", Value: 1}})
+	cursor, err := r.targets.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	targets := []*Target{}
+	if err := cursor.All(ctx, &targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// DeleteTarget deletes the target of a portfolio on a provider. It reports
+// whether there was one.
+func (r *Repository) DeleteTarget(ctx context.Context, portfolioID primitive.ObjectID, provider string) (bool, error) {
+	result, err := r.targets.DeleteOne(ctx, bson.M{"portfolioId": portfolioID, "provider": provider})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// InsertDeployment records a deployment that is starting. It returns
+// ErrDeploymentInProgress if the portfolio is already being deployed to
+// the same provider.
+func (r *Repository) InsertDeployment(ctx context.Context, deployment *Deployment) error {
+	deployment.ID = primitive.NewObjectID()
+	deployment.Status = StatusRunning
+	deployment.CreatedAt = time.Now()
+
+	if _, err := r.deployments.InsertOne(ctx, deployment); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrDeploymentInProgress
+		}
+		return err
+	}
+	return nil
+}
+
+// FinishDeployment records the outcome of a running deployment
+func (r *Repository) FinishDeployment(ctx context.Context, id primitive.ObjectID, status string, result *Result, message string) error {
+	set := bson.M{
+		"status":     status,
+		"finishedAt": time.Now(),
+	}
+	if result != nil {
+		set["remoteId"] = result.ID
+		set["url"] = result.URL
+	}
+	if message != "" {
+		set["error"] = message
+	}
+
+	_, err := r.deployments.UpdateOne(ctx, bson.M{"_id": id, "status": StatusRunning}, bson.M{"$set": set})
+	return err
+}
+
+// FailRunningDeployments marks deployments started before a point in time
+// that are still recorded as running as failed
+func (r *Repository) FailRunningDeployments(ctx context.Context, before time.Time, message string) (int64, error) {
+	update := bson.M{"$set": bson.M{
+		"status":     StatusFailed,
+		"error":      message,
+		"finishedAt": time.Now(),
+	}}
+	filter := bson.M{"status": StatusRunning, "createdAt": bson.M{"$lt": before}}
+	result, err := r.deployments.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// FindDeployment finds a deployment of a portfolio
+func (r *Repository) FindDeployment(ctx context.Context, portfolioID, id primitive.ObjectID) (*Deployment, error) {
+	var deployment Deployment
+	err := r.deployments.FindOne(ctx, bson.M{"_id": id, "portfolioId": portfolioID}).Decode(&deployment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+// FindDeploymentsByPortfolioID finds the most recent deployments of a
+// portfolio, newest first
+func (r *Repository) FindDeploymentsByPortfolioID(ctx context.Context, portfolioID primitive.ObjectID) ([]*Deployment, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(maxHistory)
+	cursor, err := r.deployments.Find(ctx, bson.M{"portfolioId": portfolioID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deployments := []*Deployment{}
+	if err := cursor.All(ctx, &deployments); err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
@@ -0,0 +1,332 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/export"
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+var (
+	ErrUnknownProvider      = errors.New("unknown deployment provider")
+	ErrCredentialNotFound   = errors.New("no credentials stored for provider")
+	ErrTargetNotFound       = errors.New("deployment target not found")
+	ErrDeploymentNotFound   = errors.New("deployment not found")
+	ErrDeploymentInProgress = errors.New("deployment already in progress")
+	ErrInvalidTarget        = errors.New("invalid deployment target")
+)
+
+// tokenHintLength is the number of trailing token characters kept readable
+const tokenHintLength = 4
+
+// Service handles deployment business logic
+type Service struct {
+	repo       *Repository
+	portfolios *portfolio.Service
+	sites      *export.Builder
+	cipher     *Cipher
+	deployers  map[string]Deployer
+	timeout    time.Duration
+	validate   *validator.Validate
+}
+
+// NewService creates a new deployment service. deployers maps provider names
+// to their deployers; timeout bounds a single deployment.
+func NewService(repo *Repository, portfolios *portfolio.Service, sites *export.Builder, cipher *Cipher, deployers map[string]Deployer, timeout time.Duration) *Service {
+	return &Service{
+		repo:       repo,
+		portfolios: portfolios,
+		sites:      sites,
+		cipher:     cipher,
+		deployers:  deployers,
+		timeout:    timeout,
+		validate:   validator.New(),
+	}
+}
+
+// SaveCredential stores a user's token for a provider, encrypted
+func (s *Service) SaveCredential(ctx context.Context, userID primitive.ObjectID, provider string, input SaveCredentialInput) (*Credential, error) {
+	if _, ok := s.deployers[provider]; !ok {
+		return nil, ErrUnknownProvider
+	}
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+
+	token, err := s.cipher.Encrypt(input.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	hint := input.Token
+	if len(hint) > tokenHintLength {
+		hint = hint[len(hint)-tokenHintLength:]
+	}
+
+	return s.repo.SaveCredential(ctx, &Credential{
+		UserID:    userID,
+		Provider:  provider,
+		Token:     token,
+		TokenHint: hint,
+	})
+}
+
+// ListCredentials lists the providers a user has stored tokens for
+func (s *Service) ListCredentials(ctx context.Context, userID primitive.ObjectID) ([]*Credential, error) {
+	return s.repo.FindCredentialsByUserID(ctx, userID)
+}
+
+// DeleteCredential deletes a user's token for a provider
+func (s *Service) DeleteCredential(ctx context.Context, userID primitive.ObjectID, provider string) error {
+	deleted, err := s.repo.DeleteCredential(ctx, userID, provider)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// SaveTarget configures where a portfolio is deployed to on a provider
+func (s *Service) SaveTarget(ctx context.Context, portfolioID, userID primitive.ObjectID, provider string, input SaveTargetInput) (*Target, error) {
+	if _, ok := s.deployers[provider]; !ok {
+		return nil, ErrUnknownProvider
+	}
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+	if provider == ProviderGitHub {
+		if _, _, err := splitRepository(input.Site); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTarget, err)
+		}
+	} else if input.Branch != "" {
+		return nil, fmt.Errorf("%w: only GitHub targets have a branch", ErrInvalidTarget)
+	}
+
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.SaveTarget(ctx, &Target{
+		PortfolioID: portfolioID,
+		UserID:      userID,
+		Provider:    provider,
+		Site:        input.Site,
+		Branch:      input.Branch,
+		AutoDeploy:  input.AutoDeploy,
+	})
+}
+
+// ListTargets lists the targets of a portfolio
+func (s *Service) ListTargets(ctx context.Context, portfolioID, userID primitive.ObjectID) ([]*Target, error) {
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindTargetsByPortfolioID(ctx, portfolioID)
+}
+
+// DeleteTarget deletes the target of a portfolio on a provider
+func (s *Service) DeleteTarget(ctx context.Context, portfolioID, userID primitive.ObjectID, provider string) error {
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteTarget(ctx, portfolioID, provider)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTargetNotFound
+	}
+	return nil
+}
+
+// Deploy starts deploying a portfolio to the target configured for a
+// provider. The deployment runs in the background; its status is tracked
+// in the returned record.
+func (s *Service) Deploy(ctx context.Context, portfolioID, userID primitive.ObjectID, input CreateDeploymentInput) (*Deployment, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+	if _, ok := s.deployers[input.Provider]; !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	p, err := s.getOwned(ctx, portfolioID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.repo.FindTarget(ctx, portfolioID, input.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrTargetNotFound
+	}
+
+	return s.start(ctx, p, target, TriggerManual)
+}
+
+// ListDeployments lists the most recent deployments of a portfolio
+func (s *Service) ListDeployments(ctx context.Context, portfolioID, userID primitive.ObjectID) ([]*Deployment, error) {
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindDeploymentsByPortfolioID(ctx, portfolioID)
+}
+
+// GetDeployment gets a deployment of a portfolio
+func (s *Service) GetDeployment(ctx context.Context, portfolioID, deploymentID, userID primitive.ObjectID) (*Deployment, error) {
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+
+	deployment, err := s.repo.FindDeployment(ctx, portfolioID, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+	if deployment == nil {
+		return nil, ErrDeploymentNotFound
+	}
+	return deployment, nil
+}
+
+// RedeployOnPublish is a portfolio publish hook that redeploys a portfolio
+// to every target with auto-deploy on when it is published
+func (s *Service) RedeployOnPublish(ctx context.Context, event portfolio.PublishEvent) {
+	if !event.Published {
+		return
+	}
+
+	targets, err := s.repo.FindAutoDeployTargets(ctx, event.Portfolio.ID)
+	if err != nil || len(targets) == 0 {
+		if err != nil {
+			slog.Error("failed to load deployment targets", "portfolioId", event.Portfolio.ID.Hex(), "error", err)
+		}
+		return
+	}
+
+	// Rendering rewrites the portfolio, so each deployment gets its own
+	// copy rather than the one the publisher holds
+	for _, target := range targets {
+		p, err := s.portfolios.GetByID(ctx, event.Portfolio.ID)
+		if err == nil {
+			_, err = s.start(ctx, p, target, TriggerPublish)
+		}
+		if err != nil {
+			slog.Warn("failed to start deployment on publish",
+				"portfolioId", event.Portfolio.ID.Hex(),
+				"provider", target.Provider,
+				"error", err,
+			)
+		}
+	}
+}
+
+// FailStale marks deployments that outlived the deployment timeout as
+// failed. They were left running by a process that stopped before they
+// finished.
+func (s *Service) FailStale(ctx context.Context, now time.Time) error {
+	failed, err := s.repo.FailRunningDeployments(ctx, now.Add(-s.timeout), "deployment was interrupted")
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		slog.Info("failed interrupted deployments", "count", failed)
+	}
+	return nil
+}
+
+// start records a deployment of a portfolio to a target and runs it in the
+// background
+func (s *Service) start(ctx context.Context, p *portfolio.Portfolio, target *Target, trigger string) (*Deployment, error) {
+	credential, err := s.repo.FindCredential(ctx, p.UserID, target.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil {
+		return nil, ErrCredentialNotFound
+	}
+
+	deployment := &Deployment{
+		PortfolioID:      p.ID,
+		UserID:           p.UserID,
+		Provider:         target.Provider,
+		Site:             target.Site,
+		Trigger:          trigger,
+		PortfolioVersion: p.Version,
+	}
+	if err := s.repo.InsertDeployment(ctx, deployment); err != nil {
+		return nil, err
+	}
+
+	go s.run(deployment, p, target, credential)
+
+	return deployment, nil
+}
+
+// run performs a deployment and records its outcome
+func (s *Service) run(deployment *Deployment, p *portfolio.Portfolio, target *Target, credential *Credential) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	result, err := s.upload(ctx, p, target, credential)
+	status, message := StatusSucceeded, ""
+	if err != nil {
+		status, message = StatusFailed, err.Error()
+		slog.Warn("deployment failed",
+			"deploymentId", deployment.ID.Hex(),
+			"portfolioId", p.ID.Hex(),
+			"provider", target.Provider,
+			"error", err,
+		)
+	}
+
+	// The deployment context may have run out; recording the outcome
+	// still has to happen
+	if err := s.repo.FinishDeployment(context.Background(), deployment.ID, status, result, message); err != nil {
+		slog.Error("failed to record deployment outcome", "deploymentId", deployment.ID.Hex(), "error", err)
+	}
+}
+
+// upload renders a portfolio and hands its files to the provider's deployer
+func (s *Service) upload(ctx context.Context, p *portfolio.Portfolio, target *Target, credential *Credential) (*Result, error) {
+	deployer := s.deployers[target.Provider]
+
+	token, err := s.cipher.Decrypt(credential.Token)
+	if err != nil {
+		return nil, fmt.Errorf("stored %s token can't be read, save it again: %w", target.Provider, err)
+	}
+
+	site, err := s.sites.Build(ctx, p, deployer.URL(*target))
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.sites.Files(ctx, site)
+	if err != nil {
+		return nil, err
+	}
+
+	return deployer.Deploy(ctx, token, *target, files)
+}
+
+// getOwned loads a portfolio and checks that it belongs to the user
+func (s *Service) getOwned(ctx context.Context, portfolioID, userID primitive.ObjectID) (*portfolio.Portfolio, error) {
+	p, err := s.portfolios.GetByID(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != userID {
+		return nil, portfolio.ErrUnauthorized
+	}
+	return p, nil
+}
@@ -0,0 +1,99 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Vercel deploys sites to Vercel by uploading every file and then creating a
+// production deployment of the project that references them by digest
+type Vercel struct {
+	client
+	// PollInterval is how often a deployment is checked until it is live
+	PollInterval time.Duration
+}
+
+// NewVercel creates a Vercel deployer for the API at baseURL
+func NewVercel(baseURL string) *Vercel {
+	return &Vercel{client: newClient("Vercel", baseURL), PollInterval: defaultPollInterval}
+}
+
+// vercelFile is a file of a Vercel deployment
+type vercelFile struct {
+	File string `json:"file"`
+	SHA  string `json:"sha"`
+	Size int    `json:"size"`
+}
+
+// vercelDeployment is the part of Vercel's deployment object deployments use
+type vercelDeployment struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	ReadyState string   `json:"readyState"`
+	Alias      []string `json:"alias"`
+}
+
+// URL returns the production address of the Vercel project
+func (v *Vercel) URL(target Target) string {
+	return "https://" + target.Site + ".vercel.app"
+}
+
+// Deploy uploads files and creates a production deployment of the project
+// from them
+func (v *Vercel) Deploy(ctx context.Context, token string, target Target, files map[string][]byte) (*Result, error) {
+	uploaded := map[string]bool{}
+	refs := make([]vercelFile, 0, len(files))
+	for _, path := range sortedPaths(files) {
+		data := files[path]
+		digest := sha1Hex(data)
+		refs = append(refs, vercelFile{File: path, SHA: digest, Size: len(data)})
+		if uploaded[digest] {
+			continue
+		}
+		uploaded[digest] = true
+
+		header := http.Header{}
+		header.Set("x-vercel-digest", digest)
+		header.Set("x-vercel-size", strconv.Itoa(len(data)))
+		if err := v.do(ctx, http.MethodPost, "/v2/files", token, "application/octet-stream", bytes.NewReader(data), header, nil); err != nil {
+			return nil, fmt.Errorf("upload %s: %w", path, err)
+		}
+	}
+
+	var deployment vercelDeployment
+	in := map[string]interface{}{
+		"name":    target.Site,
+		"project": target.Site,
+		"target":  "production",
+		"files":   refs,
+		// Serve the files as they are instead of building them
+		"projectSettings": map[string]interface{}{"framework": nil},
+	}
+	if err := v.doJSON(ctx, http.MethodPost, "/v13/deployments", token, in, &deployment); err != nil {
+		return nil, err
+	}
+
+	err := poll(ctx, v.PollInterval, func() (bool, error) {
+		switch deployment.ReadyState {
+		case "READY":
+			return true, nil
+		case "ERROR", "CANCELED":
+			return false, fmt.Errorf("vercel deployment %s", deployment.ReadyState)
+		}
+		return false, v.doJSON(ctx, http.MethodGet, "/v13/deployments/"+url.PathEscape(deployment.ID), token, nil, &deployment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	siteURL := v.URL(target)
+	if len(deployment.Alias) > 0 {
+		siteURL = "https://" + deployment.Alias[0]
+	}
+	return &Result{ID: deployment.ID, URL: siteURL}, nil
+}
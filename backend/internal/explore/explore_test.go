@@ -0,0 +1,203 @@
+package explore
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/audit"
+	"github.com/musefolio/backend/internal/database"
+	"github.com/musefolio/backend/internal/database/databasetest"
+	"github.com/musefolio/backend/internal/pagination"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/storage"
+	"github.com/musefolio/backend/internal/template"
+	"github.com/musefolio/backend/internal/theme"
+	"github.com/musefolio/backend/internal/user"
+)
+
+func TestBuildEntries(t *testing.T) {
+	now := time.Now()
+	created := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	owner := &user.User{ID: primitive.NewObjectID(), Name: "Ada", Username: "ada", Profession: "Illustrator"}
+
+	shared := portfolio.Project{
+		ID:        primitive.NewObjectID(),
+		Title:     "Poster",
+		Tags:      []string{"print", "poster"},
+		Media:     []portfolio.Media{{Type: "video", URL: "/media/a.mp4"}, {Type: "image", URL: "/media/b.png"}},
+		CreatedAt: created,
+	}
+	hidden := portfolio.Project{ID: primitive.NewObjectID(), Title: "Draft", Tags: []string{"secret"}, Hidden: true}
+	other := portfolio.Project{ID: primitive.NewObjectID(), Title: "Logo", Tags: []string{"print"}}
+
+	portfolios := []*portfolio.Portfolio{
+		{ID: primitive.NewObjectID(), Title: "Prints", Type: "creative", Subdomain: "prints", IsPublished: true, Projects: []portfolio.Project{shared, hidden}},
+		{ID: primitive.NewObjectID(), Title: "Draft site", Type: "creative", Subdomain: "draft", Projects: []portfolio.Project{other}},
+		{ID: primitive.NewObjectID(), Title: "Work", Type: "developer", Subdomain: "work", IsPublished: true, Projects: []portfolio.Project{other, shared}},
+	}
+
+	entries := buildEntries(owner, portfolios, now)
+	var titles []string
+	for _, e := range entries {
+		titles = append(titles, e.Kind+":"+e.Title)
+	}
+	if got := strings.Join(titles, ","); got != "portfolio:Prints,project:Poster,portfolio:Work,project:Logo" {
+		t.Fatalf("entries = %s", got)
+	}
+
+	prints := entries[0]
+	if prints.Owner.Profession != "Illustrator" || prints.UserID != owner.ID || !prints.PublishedAt.Equal(now) {
+		t.Errorf("portfolio entry = %+v", prints)
+	}
+	if prints.Cover != "/media/b.png" || strings.Join(prints.Tags, ",") != "print,poster" {
+		t.Errorf("portfolio cover and tags = %q, %v", prints.Cover, prints.Tags)
+	}
+
+	poster := entries[1]
+	if poster.ID != shared.ID || !poster.PublishedAt.Equal(created) {
+		t.Errorf("project entry = %+v", poster)
+	}
+	if strings.Join(poster.Types, ",") != "creative,developer" || len(poster.Portfolios) != 2 || poster.Portfolios[1].Subdomain != "work" {
+		t.Errorf("project portfolios = %v, %+v", poster.Types, poster.Portfolios)
+	}
+}
+
+func TestUpsertListsUnlistedPortfolioAsNew(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(databasetest.New(t))
+	userID := primitive.NewObjectID()
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := Entry{ID: primitive.NewObjectID(), Kind: KindPortfolio, UserID: userID, Title: "Work", PublishedAt: first}
+
+	publishedAt := func() time.Time {
+		t.Helper()
+		q := Query{Kind: KindPortfolio, Page: pagination.Params{Limit: 10, Sort: listOptions.Sorts[0]}}
+		entries, err := repo.Find(ctx, q)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("Find = %v, %v; want one listed entry", entries, err)
+		}
+		return entries[0].PublishedAt.UTC()
+	}
+
+	if err := repo.Upsert(ctx, []Entry{entry}, first); err != nil {
+		t.Fatal(err)
+	}
+	// Listing it again while it is listed keeps its time
+	entry.PublishedAt = first.Add(time.Hour)
+	if err := repo.Upsert(ctx, []Entry{entry}, entry.PublishedAt); err != nil {
+		t.Fatal(err)
+	}
+	if got := publishedAt(); !got.Equal(first) {
+		t.Errorf("publishedAt after a refresh = %v, want %v", got, first)
+	}
+
+	if err := repo.UnlistOwnerStale(ctx, userID, first.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	entry.PublishedAt = first.Add(3 * time.Hour)
+	if err := repo.Upsert(ctx, []Entry{entry}, entry.PublishedAt); err != nil {
+		t.Fatal(err)
+	}
+	if got := publishedAt(); !got.Equal(entry.PublishedAt) {
+		t.Errorf("publishedAt after being listed again = %v, want %v", got, entry.PublishedAt)
+	}
+}
+
+func TestRefreshOnlyRebuildsChangedOwners(t *testing.T) {
+	ctx := context.Background()
+	db := databasetest.New(t)
+	users := user.NewService(user.NewRepository(db), "secret")
+	portfolioRepo := portfolio.NewRepository(db, portfolio.RevisionRetention{})
+	portfolios := portfolio.NewService(
+		portfolioRepo,
+		audit.NewRepository(db),
+		storage.NewLocal(t.TempDir(), "/media"),
+		template.NewService(template.NewRepository(db)),
+		theme.NewService(theme.NewRepository(db)),
+	)
+	s := NewService(NewRepository(db), portfolios, users)
+
+	publish := func(name string) (*user.User, *portfolio.Portfolio) {
+		t.Helper()
+		u, err := users.Create(ctx, user.CreateUserInput{Name: name, Username: name, Email: name + "@example.com", Password: "password"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := portfolioRepo.Create(ctx, u.ID, portfolio.CreatePortfolioInput{
+			Title: name, Description: name, Theme: "minimal", Layout: "grid", Subdomain: name,
+		}, portfolio.PortfolioSeed{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		published := true
+		p, err = portfolios.Update(ctx, p.ID, u.ID, portfolio.AnyVersion, portfolio.UpdatePortfolioInput{IsPublished: &published})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u, p
+	}
+	// Changes made long before the last refresh
+	backdate := func() {
+		t.Helper()
+		update := bson.M{"$set": bson.M{"updatedAt": time.Now().Add(-time.Hour)}}
+		for _, name := range []string{database.UsersCollection, database.PortfoliosCollection} {
+			if _, err := db.Collection(name).UpdateMany(ctx, bson.M{}, update); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	owners := func() map[string]string {
+		t.Helper()
+		page, err := s.List(ctx, Query{Page: pagination.Params{Limit: 10, Sort: listOptions.Sorts[0]}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := map[string]string{}
+		for _, e := range page.Entries {
+			names[e.Title] = e.Owner.Name
+		}
+		return names
+	}
+
+	ada, adaPortfolio := publish("ada")
+	bob, _ := publish("bob")
+	backdate()
+	if err := s.Refresh(ctx, time.Now()); err != nil {
+		t.Fatalf("first Refresh: %v", err)
+	}
+	if got := owners(); got["ada"] != "ada" || got["bob"] != "bob" {
+		t.Fatalf("entries after the first refresh = %v", got)
+	}
+
+	name := "Ada"
+	if _, err := users.Update(ctx, ada.ID, user.UpdateUserInput{Name: &name}); err != nil {
+		t.Fatal(err)
+	}
+	// Without updatedAt, as if nothing had changed
+	rename := bson.M{"$set": bson.M{"name": "Bob"}}
+	if _, err := db.Collection(database.UsersCollection).UpdateByID(ctx, bob.ID, rename); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Refresh(ctx, time.Now()); err != nil {
+		t.Fatalf("second Refresh: %v", err)
+	}
+	if got := owners(); got["ada"] != "Ada" || got["bob"] != "bob" {
+		t.Errorf("entries after a refresh = %v, want only ada's rebuilt", got)
+	}
+
+	if err := portfolios.Delete(ctx, adaPortfolio.ID, ada.ID, portfolio.AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+	backdate()
+	if err := s.Refresh(ctx, time.Now()); err != nil {
+		t.Fatalf("third Refresh: %v", err)
+	}
+	if got := owners(); len(got) != 1 || got["bob"] != "bob" {
+		t.Errorf("entries after deleting a portfolio = %v, want it unlisted", got)
+	}
+}
@@ -0,0 +1,115 @@
+package explore
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/pagination"
+)
+
+// Handler handles HTTP requests for the explore feed
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new explore feed handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterPublicRoutes registers the explore feed routes
+func (h *Handler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/explore", h.List)
+}
+
+// RegisterAdminRoutes registers the feed curation routes. The router is
+// expected to restrict access to administrators.
+func (h *Handler) RegisterAdminRoutes(r chi.Router) {
+	r.Put("/admin/explore/{id}", h.Curate)
+}
+
+// listOptions describe the pages of the feed
+var listOptions = pagination.Options{
+	DefaultLimit: 24,
+	MaxLimit:     100,
+	Sorts: []pagination.Sort{
+		{Name: "recent", Field: "publishedAt", Desc: true},
+		{Name: "popular", Field: "views", Desc: true},
+	},
+	Filters: []pagination.Filter{
+		{Param: "profession", Field: "owner.profession", Type: pagination.FilterString},
+		{Param: "type", Field: "types", Type: pagination.FilterString},
+		{Param: "featured", Field: "featured", Type: pagination.FilterBool},
+		{Param: "staffPick", Field: "staffPick", Type: pagination.FilterBool},
+	},
+}
+
+// List handles listing a page of published portfolios or projects. Tags are
+// given comma-separated or as repeated parameters. The next page is linked
+// from the Link header as well as the response.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	p, err := listOptions.Parse(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q := Query{Kind: values.Get("kind"), Page: p}
+	for _, value := range values["tags"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				q.Tags = append(q.Tags, tag)
+			}
+		}
+	}
+
+	page, err := h.service.List(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, ErrInvalidKind) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	pagination.SetNext(w, r, page.NextCursor)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// Curate handles featuring an entry or marking it as a staff pick
+func (h *Handler) Curate(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid entry ID", http.StatusBadRequest)
+		return
+	}
+
+	var input CurateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.Curate(r.Context(), id, input)
+	if err != nil {
+		if errors.Is(err, ErrEntryNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
@@ -0,0 +1,86 @@
+package explore
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/pagination"
+)
+
+// Entry kinds
+const (
+	KindPortfolio = "portfolio"
+	KindProject   = "project"
+)
+
+// Entry is a published portfolio or project listed in the explore feed. It
+// is derived from the published portfolios of its owner, except for its
+// views and curation, which belong to the feed.
+type Entry struct {
+	// ID is the ID of the portfolio or project
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Kind        string             `bson:"kind" json:"kind"`
+	UserID      primitive.ObjectID `bson:"userId" json:"-"`
+	Owner       Owner              `bson:"owner" json:"owner"`
+	Title       string             `bson:"title" json:"title"`
+	Description string             `bson:"description" json:"description"`
+	// Cover is the URL of the first image of the project, or of the first
+	// project of the portfolio that has one
+	Cover string   `bson:"cover,omitempty" json:"cover,omitempty"`
+	Tags  []string `bson:"tags" json:"tags"`
+	// Types are the types of the portfolios showing the entry
+	Types []string `bson:"types" json:"types"`
+	// Portfolios are the published portfolios showing the entry. A
+	// portfolio entry lists itself.
+	Portfolios []PortfolioRef `bson:"portfolios" json:"portfolios"`
+	// Views counts the visits of the portfolios showing the entry while it
+	// was listed
+	Views     int64 `bson:"views" json:"views"`
+	Featured  bool  `bson:"featured" json:"featured"`
+	StaffPick bool  `bson:"staffPick" json:"staffPick"`
+	// PublishedAt is when a portfolio was listed, again if it was unlisted
+	// since, or when a project was created
+	PublishedAt time.Time `bson:"publishedAt" json:"publishedAt"`
+	// Listed is false once none of the entry's portfolios are published
+	Listed   bool      `bson:"listed" json:"-"`
+	SyncedAt time.Time `bson:"syncedAt" json:"-"`
+}
+
+// Owner is the creator of an entry
+type Owner struct {
+	Name       string `bson:"name" json:"name"`
+	Username   string `bson:"username" json:"username"`
+	Avatar     string `bson:"avatar,omitempty" json:"avatar,omitempty"`
+	Profession string `bson:"profession,omitempty" json:"profession,omitempty"`
+}
+
+// PortfolioRef identifies a published portfolio
+type PortfolioRef struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Title     string             `bson:"title" json:"title"`
+	Subdomain string             `bson:"subdomain" json:"subdomain"`
+}
+
+// Query selects a page of the feed
+type Query struct {
+	// Kind defaults to KindPortfolio
+	Kind string
+	// Tags must all be on an entry
+	Tags []string
+	// Page orders the feed and filters it on profession, type and curation
+	Page pagination.Params
+}
+
+// Page is a page of the feed. NextCursor continues after its last entry and
+// is empty on the last page.
+type Page struct {
+	Entries    []Entry `json:"entries"`
+	NextCursor string  `json:"nextCursor,omitempty"`
+}
+
+// CurateInput represents the input for curating an entry
+type CurateInput struct {
+	Featured  *bool `json:"featured,omitempty"`
+	StaffPick *bool `json:"staffPick,omitempty"`
+}
@@ -0,0 +1,157 @@
+package explore
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/musefolio/backend/internal/database"
+)
+
+// Repository handles explore feed data operations
+type Repository struct {
+	db      *database.DB
+	entries *mongo.Collection
+}
+
+// NewRepository creates a new explore feed repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{
+		db:      db,
+		entries: db.Collection(database.ExploreCollection),
+	}
+}
+
+// Upsert lists entries, keeping the views and curation of those listed
+// before. Portfolios keep the time they were listed, unless they were
+// unlisted since.
+func (r *Repository) Upsert(ctx context.Context, entries []Entry, now time.Time) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(entries))
+	for _, e := range entries {
+		set := bson.M{
+			"kind":        e.Kind,
+			"userId":      e.UserID,
+			"owner":       e.Owner,
+			"title":       e.Title,
+			"description": e.Description,
+			"cover":       e.Cover,
+			"tags":        e.Tags,
+			"types":       e.Types,
+			"portfolios":  e.Portfolios,
+			"listed":      true,
+			"syncedAt":    now,
+		}
+		setOnInsert := bson.M{
+			"views":     0,
+			"featured":  false,
+			"staffPick": false,
+		}
+		if e.Kind == KindPortfolio {
+			setOnInsert["publishedAt"] = e.PublishedAt
+			// Must run before the upsert below lists the entry again
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": e.ID, "listed": false}).
+				SetUpdate(bson.M{"$set": bson.M{"publishedAt": e.PublishedAt}}))
+		} else {
+			set["publishedAt"] = e.PublishedAt
+		}
+
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": e.ID}).
+			SetUpdate(bson.M{"$set": set, "$setOnInsert": setOnInsert}).
+			SetUpsert(true))
+	}
+
+	_, err := r.entries.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(true))
+	return err
+}
+
+// UnlistOwnerStale unlists the entries of a user that weren't listed again
+// since a point in time
+func (r *Repository) UnlistOwnerStale(ctx context.Context, userID primitive.ObjectID, before time.Time) error {
+	filter := bson.M{"userId": userID, "listed": true, "syncedAt": bson.M{"$lt": before}}
+	_, err := r.entries.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"listed": false}})
+	return err
+}
+
+// FindOwnerIDsListingOtherThan finds the users with listed portfolio
+// entries other than those of some portfolios
+func (r *Repository) FindOwnerIDsListingOtherThan(ctx context.Context, portfolioIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := bson.M{"listed": true, "kind": KindPortfolio, "_id": bson.M{"$nin": portfolioIDs}}
+	values, err := r.entries.Distinct(ctx, "userId", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Find finds a page of the listed entries matching a query
+func (r *Repository) Find(ctx context.Context, q Query) ([]Entry, error) {
+	filter := bson.M{"listed": true, "kind": q.Kind}
+	if len(q.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": q.Tags}
+	}
+
+	cursor, err := r.entries.Find(ctx, q.Page.Query(filter), q.Page.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []Entry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// IncrementViews counts a visit of a portfolio for the listed entries it
+// shows
+func (r *Repository) IncrementViews(ctx context.Context, portfolioID primitive.ObjectID) error {
+	filter := bson.M{"listed": true, "portfolios._id": portfolioID}
+	_, err := r.entries.UpdateMany(ctx, filter, bson.M{"$inc": bson.M{"views": 1}})
+	return err
+}
+
+// SetCuration updates the curation of an entry. It returns nil if there is
+// no such entry.
+func (r *Repository) SetCuration(ctx context.Context, id primitive.ObjectID, input CurateInput) (*Entry, error) {
+	set := bson.M{}
+	if input.Featured != nil {
+		set["featured"] = *input.Featured
+	}
+	if input.StaffPick != nil {
+		set["staffPick"] = *input.StaffPick
+	}
+
+	var entry Entry
+	var err error
+	if len(set) == 0 {
+		err = r.entries.FindOne(ctx, bson.M{"_id": id}).Decode(&entry)
+	} else {
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = r.entries.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": set}, opts).Decode(&entry)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
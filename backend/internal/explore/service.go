@@ -0,0 +1,305 @@
+package explore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/pagination"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/user"
+)
+
+var (
+	ErrEntryNotFound = errors.New("explore entry not found")
+	ErrInvalidKind   = errors.New("invalid kind")
+)
+
+// refreshOverlap is how far back each refresh looks past the previous one,
+// for writes in flight when it ran and clock skew between servers
+const refreshOverlap = time.Minute
+
+// Service handles the explore feed of published portfolios and projects
+type Service struct {
+	repo       *Repository
+	portfolios *portfolio.Service
+	users      *user.Service
+	refreshed  time.Time
+}
+
+// NewService creates a new explore feed service
+func NewService(repo *Repository, portfolios *portfolio.Service, users *user.Service) *Service {
+	return &Service{
+		repo:       repo,
+		portfolios: portfolios,
+		users:      users,
+	}
+}
+
+// List returns a page of the feed
+func (s *Service) List(ctx context.Context, q Query) (*Page, error) {
+	switch q.Kind {
+	case "":
+		q.Kind = KindPortfolio
+	case KindPortfolio, KindProject:
+	default:
+		return nil, ErrInvalidKind
+	}
+
+	entries, err := s.repo.Find(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	entries, next, err := pagination.Trim(entries, q.Page)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Entries: entries, NextCursor: next}, nil
+}
+
+// Curate features an entry or marks it as a staff pick, or takes that back
+func (s *Service) Curate(ctx context.Context, id primitive.ObjectID, input CurateInput) (*Entry, error) {
+	entry, err := s.repo.SetCuration(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+// RecordView counts a visit of a published portfolio for it and its
+// projects. It is meant to be registered as a site view hook.
+func (s *Service) RecordView(ctx context.Context, p *portfolio.Portfolio) {
+	if err := s.repo.IncrementViews(ctx, p.ID); err != nil {
+		slog.Warn("failed to record portfolio view", "portfolioId", p.ID.Hex(), "error", err)
+	}
+}
+
+// SyncOnPublish lists or unlists the entries of the owner of a portfolio
+// being published or unpublished. It is meant to be registered as a
+// publish hook.
+func (s *Service) SyncOnPublish(ctx context.Context, event portfolio.PublishEvent) {
+	if err := s.refreshOwner(ctx, event.Portfolio.UserID, time.Now()); err != nil {
+		slog.Error("failed to update explore feed",
+			"portfolioId", event.Portfolio.ID.Hex(),
+			"published", event.Published,
+			"error", err,
+		)
+	}
+}
+
+// Refresh rebuilds the entries of the users whose portfolios or user record
+// changed since the last refresh, and of those listing portfolios that are
+// no longer published. It catches up with edits to published portfolios and
+// changes to their owners, which don't update the feed by themselves. The
+// first refresh rebuilds the entries of every user with published
+// portfolios. It must not run concurrently with itself.
+func (s *Service) Refresh(ctx context.Context, now time.Time) error {
+	userIDs, err := s.staleOwners(ctx, s.refreshed)
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	for _, userID := range userIDs {
+		if err := s.refreshOwner(ctx, userID, now); err != nil {
+			slog.Warn("failed to refresh explore entries", "userId", userID.Hex(), "error", err)
+			failed = err
+		}
+	}
+	// Keep the time of the last refresh so the next one retries the users
+	// that failed
+	if failed != nil {
+		return failed
+	}
+	s.refreshed = now
+	return nil
+}
+
+// staleOwners finds the users whose entries may be out of date: the users
+// with published portfolios, only those whose portfolios or user record
+// changed since a point in time unless it is zero, and the users listing
+// portfolios that are no longer published
+func (s *Service) staleOwners(ctx context.Context, since time.Time) ([]primitive.ObjectID, error) {
+	publishers, err := s.portfolios.ListPublishers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !since.IsZero() {
+		since = since.Add(-refreshOverlap)
+		owners, err := s.portfolios.ListOwnersUpdatedSince(ctx, since)
+		if err != nil {
+			return nil, err
+		}
+		users, err := s.users.ListUpdatedSince(ctx, since)
+		if err != nil {
+			return nil, err
+		}
+		publishers = intersectIDs(publishers, append(owners, users...))
+	}
+
+	published, err := s.portfolios.ListPublishedIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unpublished, err := s.repo.FindOwnerIDsListingOtherThan(ctx, published)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[primitive.ObjectID]bool, len(publishers))
+	for _, id := range publishers {
+		seen[id] = true
+	}
+	for _, id := range unpublished {
+		if !seen[id] {
+			publishers = append(publishers, id)
+		}
+	}
+	return publishers, nil
+}
+
+// intersectIDs returns the IDs in a that are also in b
+func intersectIDs(a, b []primitive.ObjectID) []primitive.ObjectID {
+	in := make(map[primitive.ObjectID]bool, len(b))
+	for _, id := range b {
+		in[id] = true
+	}
+
+	ids := []primitive.ObjectID{}
+	for _, id := range a {
+		if in[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// refreshOwner lists the entries of the published portfolios of a user and
+// unlists their other entries
+func (s *Service) refreshOwner(ctx context.Context, userID primitive.ObjectID, now time.Time) error {
+	owner, err := s.users.GetByID(ctx, userID)
+	if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+		return err
+	}
+
+	var entries []Entry
+	if owner != nil {
+		portfolios, err := s.portfolios.GetByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		entries = buildEntries(owner, portfolios, now)
+	}
+
+	if err := s.repo.Upsert(ctx, entries, now); err != nil {
+		return err
+	}
+	return s.repo.UnlistOwnerStale(ctx, userID, now)
+}
+
+// buildEntries derives the entries of the published portfolios of a user
+// and of the visible projects in them. A project shown in several of them
+// gets one entry listing each.
+func buildEntries(u *user.User, portfolios []*portfolio.Portfolio, now time.Time) []Entry {
+	owner := Owner{
+		Name:       u.Name,
+		Username:   u.Username,
+		Avatar:     u.Avatar,
+		Profession: u.Profession,
+	}
+
+	entries := []Entry{}
+	projects := map[primitive.ObjectID]int{}
+	for _, p := range portfolios {
+		if !p.IsPublished {
+			continue
+		}
+		ref := PortfolioRef{ID: p.ID, Title: p.Title, Subdomain: p.Subdomain}
+		visible := p.VisibleProjects()
+
+		entry := Entry{
+			ID:          p.ID,
+			Kind:        KindPortfolio,
+			UserID:      u.ID,
+			Owner:       owner,
+			Title:       p.Title,
+			Description: p.Description,
+			Tags:        []string{},
+			Types:       appendUnique(nil, p.Type),
+			Portfolios:  []PortfolioRef{ref},
+			PublishedAt: now,
+		}
+		for i := range visible {
+			if entry.Cover == "" {
+				entry.Cover = coverOf(&visible[i])
+			}
+			for _, tag := range visible[i].Tags {
+				entry.Tags = appendUnique(entry.Tags, tag)
+			}
+		}
+		entries = append(entries, entry)
+
+		for i := range visible {
+			project := &visible[i]
+			if j, ok := projects[project.ID]; ok {
+				entries[j].Types = appendUnique(entries[j].Types, p.Type)
+				entries[j].Portfolios = append(entries[j].Portfolios, ref)
+				continue
+			}
+			projects[project.ID] = len(entries)
+			entries = append(entries, Entry{
+				ID:          project.ID,
+				Kind:        KindProject,
+				UserID:      u.ID,
+				Owner:       owner,
+				Title:       project.Title,
+				Description: project.Description,
+				Cover:       coverOf(project),
+				Tags:        append([]string{}, project.Tags...),
+				Types:       appendUnique(nil, p.Type),
+				Portfolios:  []PortfolioRef{ref},
+				PublishedAt: project.CreatedAt,
+			})
+		}
+	}
+	return entries
+}
+
+// coverOf returns the URL of the first image of a project, looking at the
+// stages of case studies after its own media
+func coverOf(project *portfolio.Project) string {
+	media := project.Media
+	if project.CaseStudy != nil {
+		for _, stage := range project.CaseStudy.Stages {
+			media = append(media[:len(media):len(media)], stage.Media...)
+		}
+	}
+	for _, m := range media {
+		if m.Type == "image" {
+			return m.URL
+		}
+	}
+	return ""
+}
+
+// appendUnique appends a non-empty value to values unless it is there already
+func appendUnique(values []string, value string) []string {
+	if values == nil {
+		values = []string{}
+	}
+	if value == "" {
+		return values
+	}
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
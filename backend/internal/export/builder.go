@@ -0,0 +1,98 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/site"
+	"github.com/musefolio/backend/internal/storage"
+	"github.com/musefolio/backend/internal/theme"
+)
+
+// Builder renders portfolios into static sites
+type Builder struct {
+	themes   *theme.Service
+	cvs      *cv.Service
+	storage  storage.Storage
+	renderer *site.Renderer
+	// baseDomain is the domain portfolios are served under by subdomain
+	baseDomain string
+}
+
+// NewBuilder creates a new static site builder
+func NewBuilder(themes *theme.Service, cvs *cv.Service, store storage.Storage, renderer *site.Renderer, baseDomain string) *Builder {
+	return &Builder{
+		themes:     themes,
+		cvs:        cvs,
+		storage:    store,
+		renderer:   renderer,
+		baseDomain: baseDomain,
+	}
+}
+
+// Build renders a portfolio into a static site served from baseURL
+func (b *Builder) Build(ctx context.Context, p *portfolio.Portfolio, baseURL string) (*StaticSite, error) {
+	t, c, err := b.load(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return BuildStaticSite(b.renderer, b.storage, site.Page{Portfolio: p, CV: c}, t, baseURL)
+}
+
+// Files returns every file of a static site by path, reading media files
+// from storage. Media files that no longer exist are left out.
+func (b *Builder) Files(ctx context.Context, s *StaticSite) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(s.Files)+len(s.Media))
+	for name, data := range s.Files {
+		files[name] = data
+	}
+
+	for name, key := range s.Media {
+		file, err := b.storage.Open(ctx, key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				slog.Warn("media file missing from static site", "key", key)
+				continue
+			}
+			return nil, err
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+
+	return files, nil
+}
+
+// SiteURL returns the URL a portfolio is published at
+func (b *Builder) SiteURL(p *portfolio.Portfolio) string {
+	if p.CustomDomain != nil && *p.CustomDomain != "" {
+		return "https://" + *p.CustomDomain
+	}
+	return "https://" + p.Subdomain + "." + b.baseDomain
+}
+
+// load resolves the theme of a portfolio and, for CV portfolios, the owner's
+// structured CV they are laid out from
+func (b *Builder) load(ctx context.Context, p *portfolio.Portfolio) (*theme.Theme, *cv.CV, error) {
+	t, err := b.themes.ResolveOrDefault(ctx, p.Theme)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if p.Type != "cv" {
+		return t, nil, nil
+	}
+	c, err := b.cvs.Get(ctx, p.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, c, nil
+}
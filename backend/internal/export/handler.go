@@ -0,0 +1,274 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/resume"
+	"github.com/musefolio/backend/internal/site"
+	"github.com/musefolio/backend/internal/user"
+)
+
+// maxAvatarSize bounds the avatar image embedded into exports
+const maxAvatarSize = 5 << 20
+
+// Handler handles exporting portfolios
+type Handler struct {
+	portfolios *portfolio.Service
+	users      *user.Service
+	builder    *Builder
+}
+
+// NewHandler creates a new export handler
+func NewHandler(portfolios *portfolio.Service, users *user.Service, builder *Builder) *Handler {
+	return &Handler{
+		portfolios: portfolios,
+		users:      users,
+		builder:    builder,
+	}
+}
+
+// RegisterRoutes registers the export routes
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/portfolios/{id}/export.pdf", h.PDF)
+	r.Get("/portfolios/{id}/export.zip", h.ZIP)
+	r.Get("/portfolios/{id}/export.json", h.JSON)
+}
+
+// PDF handles exporting a portfolio as a PDF. The page size is chosen with
+// the "size" query parameter, "a4" (default) or "letter". Owners can export
+// their portfolios at any time, everyone else only published ones.
+func (h *Handler) PDF(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	pageSize := r.URL.Query().Get("size")
+	if pageSize == "" {
+		pageSize = "a4"
+	}
+
+	doc, err := h.document(r.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, portfolio.ErrPortfolioNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, *doc, pageSize); err != nil {
+		if errors.Is(err, ErrUnsupportedPageSize) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.Error("failed to export portfolio as PDF", "portfolioId", id.Hex(), "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename(doc.Portfolio)))
+	w.Write(buf.Bytes())
+}
+
+// ZIP handles exporting a portfolio as a static site in a ZIP archive. The
+// sitemap lists pages under the "baseUrl" query parameter, defaulting to the
+// portfolio's custom domain or subdomain.
+func (h *Handler) ZIP(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	doc, err := h.document(r.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, portfolio.ErrPortfolioNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	baseURL := r.URL.Query().Get("baseUrl")
+	if baseURL == "" {
+		baseURL = h.builder.SiteURL(doc.Portfolio)
+	} else if !isSiteURL(baseURL) {
+		http.Error(w, "Invalid base URL", http.StatusBadRequest)
+		return
+	}
+
+	page := site.Page{Portfolio: doc.Portfolio, CV: doc.CV}
+	static, err := BuildStaticSite(h.builder.renderer, h.builder.storage, page, doc.Theme, baseURL)
+	if err != nil {
+		slog.Error("failed to render static site", "portfolioId", id.Hex(), "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// The archive is streamed since media files can be large. Failures past
+	// this point leave a truncated download behind.
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename(doc.Portfolio)))
+	if err := static.WriteZIP(r.Context(), w, h.builder.storage); err != nil {
+		slog.Error("failed to write static site archive", "portfolioId", id.Hex(), "error", err)
+	}
+}
+
+// JSON handles exporting a portfolio as a JSON Resume. Owners' résumés
+// include their CV whatever the portfolio's type; everyone else only sees
+// the CV through CV portfolios.
+func (h *Handler) JSON(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	doc, err := h.document(r.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, portfolio.ErrPortfolioNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if doc.CV == nil && doc.Portfolio.UserID == userID {
+		doc.CV, err = h.builder.cvs.Get(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename(doc.Portfolio)))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(resume.FromProfile(doc.Owner, doc.CV, doc.Portfolio.Projects, userID))
+}
+
+// isSiteURL reports whether raw is an absolute http(s) URL
+func isSiteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// document loads everything an export of a portfolio is built from. Only
+// owners can export unpublished portfolios.
+func (h *Handler) document(ctx context.Context, id, userID primitive.ObjectID) (*Document, error) {
+	p, err := h.portfolios.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != userID && !p.IsPublished {
+		return nil, portfolio.ErrPortfolioNotFound
+	}
+
+	doc := &Document{Portfolio: p}
+	doc.Theme, doc.CV, err = h.builder.load(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := h.users.GetByID(ctx, p.UserID)
+	if err != nil && !errors.Is(err, user.ErrUserNotFound) {
+		return nil, err
+	}
+	if owner != nil {
+		doc.Owner = publicOwner(owner, userID)
+		doc.Avatar = h.avatar(ctx, owner.Avatar)
+	}
+
+	return doc, nil
+}
+
+// publicOwner returns the owner as shown in an export by userID. The account
+// email is private, so only the owner's own exports include it.
+func publicOwner(owner *user.User, userID primitive.ObjectID) *user.User {
+	if owner.ID == userID {
+		return owner
+	}
+	public := *owner
+	public.Email = ""
+	return &public
+}
+
+// avatar loads an avatar image from storage. Avatars that can't be loaded
+// are left out of the export.
+func (h *Handler) avatar(ctx context.Context, avatarURL string) []byte {
+	key, ok := h.builder.storage.KeyFromURL(avatarURL)
+	if !ok {
+		return nil
+	}
+
+	file, err := h.builder.storage.Open(ctx, key)
+	if err != nil {
+		slog.Warn("failed to open avatar for export", "key", key, "error", err)
+		return nil
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarSize+1))
+	if err != nil || len(data) > maxAvatarSize {
+		return nil
+	}
+	return data
+}
+
+// unsafeFilename matches runs of characters not kept in file names
+var unsafeFilename = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns text into a lowercase, dash-separated file name
+func slugify(text string) string {
+	return strings.Trim(unsafeFilename.ReplaceAllString(strings.ToLower(text), "-"), "-")
+}
+
+// filename derives the download filename of an export from the portfolio
+func filename(p *portfolio.Portfolio) string {
+	name := slugify(p.Title)
+	if name == "" {
+		name = p.Subdomain
+	}
+	return name
+}
@@ -0,0 +1,574 @@
+// Package export renders portfolios into standalone files for download.
+package export
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/section"
+	"github.com/musefolio/backend/internal/theme"
+	"github.com/musefolio/backend/internal/user"
+)
+
+// ErrUnsupportedPageSize is returned for page sizes other than A4 and Letter
+var ErrUnsupportedPageSize = errors.New("unsupported page size")
+
+// pageSizes maps the accepted page size names to their fpdf names
+var pageSizes = map[string]string{
+	"a4":     "A4",
+	"letter": "Letter",
+}
+
+// Page geometry in millimetres and type sizes in points
+const (
+	margin     = 18.0
+	avatarSize = 26.0
+	baseSize   = 10.0
+)
+
+// Document holds everything a PDF export is laid out from
+type Document struct {
+	Portfolio *portfolio.Portfolio
+	// CV is the owner's structured CV, set for portfolios of type "cv"
+	CV    *cv.CV
+	Theme *theme.Theme
+	Owner *user.User
+	// Avatar is the owner's avatar image, if it could be loaded
+	Avatar []byte
+}
+
+// WritePDF lays out a document as a PDF on A4 or Letter paper. Text is
+// written with the standard PDF fonts, so it stays selectable and
+// searchable.
+func WritePDF(w io.Writer, doc Document, pageSize string) error {
+	size, ok := pageSizes[strings.ToLower(pageSize)]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedPageSize, pageSize)
+	}
+
+	pdf := fpdf.New("P", "mm", size, "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.SetTitle(doc.Portfolio.Title, true)
+	if doc.Owner != nil {
+		pdf.SetAuthor(doc.Owner.Name, true)
+	}
+	pdf.SetCreator("MuseFolio", true)
+
+	l := &layout{
+		pdf:   pdf,
+		tr:    pdf.UnicodeTranslatorFromDescriptor(""),
+		style: styleOf(doc.Theme),
+	}
+
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin + 6)
+		l.font(l.style.body, "", baseSize-2, l.style.muted)
+		pdf.CellFormat(0, 4, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	l.header(doc)
+	if doc.CV != nil {
+		l.cv(doc.CV)
+	}
+	for _, s := range doc.Portfolio.Sections {
+		l.section(s)
+	}
+	if projects := doc.Portfolio.VisibleProjects(); len(projects) > 0 {
+		l.heading("Projects")
+		for _, project := range projects {
+			l.project(project)
+		}
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+// layout writes the parts of a document onto the pages of a PDF
+type layout struct {
+	pdf *fpdf.Fpdf
+	// tr converts UTF-8 text to the encoding of the standard fonts
+	tr    func(string) string
+	style style
+}
+
+// header writes the owner's name, avatar, headline and contact links
+func (l *layout) header(doc Document) {
+	name := doc.Portfolio.Title
+	if doc.Owner != nil && doc.Owner.Name != "" {
+		name = doc.Owner.Name
+	}
+	headline := doc.Portfolio.Description
+	if doc.CV != nil && doc.CV.Headline != "" {
+		headline = doc.CV.Headline
+	}
+
+	left, top, right, _ := l.pdf.GetMargins()
+	if l.avatar(doc.Avatar, left, top) {
+		l.pdf.SetLeftMargin(left + avatarSize + 6)
+		l.pdf.SetX(left + avatarSize + 6)
+	}
+
+	l.font(l.style.heading, "B", baseSize*l.style.scale*l.style.scale*l.style.scale, l.style.primary)
+	l.pdf.MultiCell(0, 10, l.tr(name), "", "L", false)
+	if headline != "" {
+		l.font(l.style.body, "", baseSize+2, l.style.muted)
+		l.pdf.MultiCell(0, 6, l.tr(headline), "", "L", false)
+	}
+	if doc.Owner != nil {
+		l.contacts(doc.Owner)
+	}
+
+	l.pdf.SetLeftMargin(left)
+	if doc.Avatar != nil && l.pdf.GetY() < top+avatarSize {
+		l.pdf.SetY(top + avatarSize)
+	}
+	l.pdf.Ln(4)
+	w, _ := l.pdf.GetPageSize()
+	l.rule(left, w-right)
+}
+
+// avatar draws the avatar image at x, y and reports whether it could
+func (l *layout) avatar(data []byte, x, y float64) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	var imageType string
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		imageType = "JPG"
+	case "image/png":
+		imageType = "PNG"
+	case "image/gif":
+		imageType = "GIF"
+	default:
+		return false
+	}
+
+	opts := fpdf.ImageOptions{ImageType: imageType}
+	l.pdf.RegisterImageOptionsReader("avatar", opts, bytes.NewReader(data))
+	if l.pdf.Err() {
+		// A broken avatar shouldn't fail the export
+		l.pdf.ClearError()
+		return false
+	}
+	l.pdf.ImageOptions("avatar", x, y, avatarSize, avatarSize, false, opts, 0, "")
+	return true
+}
+
+// contacts writes the owner's email, when known, and social links as
+// clickable links
+func (l *layout) contacts(owner *user.User) {
+	type contact struct{ label, url string }
+	contacts := []contact{{owner.Email, "mailto:" + owner.Email}}
+	if links := owner.SocialLinks; links != nil {
+		for _, link := range []string{links.Website, links.LinkedIn, links.GitHub, links.Twitter, links.Instagram} {
+			if link != "" {
+				contacts = append(contacts, contact{displayURL(link), link})
+			}
+		}
+	}
+
+	l.font(l.style.body, "", baseSize-1, l.style.primary)
+	l.pdf.Ln(1)
+	written := 0
+	for _, c := range contacts {
+		if c.label == "" || !linkable(c.url) {
+			continue
+		}
+		if written > 0 {
+			l.pdf.Write(5, l.tr("  ·  "))
+		}
+		l.pdf.WriteLinkString(5, l.tr(c.label), c.url)
+		written++
+	}
+	l.pdf.Ln(6)
+}
+
+// cv writes the structured CV
+func (l *layout) cv(c *cv.CV) {
+	if c.Summary != "" {
+		l.heading("Summary")
+		l.paragraph(c.Summary)
+	}
+
+	if len(c.Experience) > 0 {
+		l.heading("Experience")
+		for _, e := range c.Experience {
+			l.entry(e.Role, joinNonEmpty(" · ", e.Organization, e.Location), dateRange(e.StartDate, e.EndDate, e.Current))
+			l.paragraph(e.Description)
+			for _, highlight := range e.Highlights {
+				l.bullet(highlight)
+			}
+		}
+	}
+
+	if len(c.Education) > 0 {
+		l.heading("Education")
+		for _, e := range c.Education {
+			l.entry(e.Institution, joinNonEmpty(", ", e.Degree, e.Field, e.Grade), dateRange(e.StartDate, e.EndDate, e.Current))
+			l.paragraph(e.Description)
+		}
+	}
+
+	if len(c.Skills) > 0 {
+		l.heading("Skills")
+		var categories []string
+		byCategory := map[string][]string{}
+		for _, s := range c.Skills {
+			if _, ok := byCategory[s.Category]; !ok {
+				categories = append(categories, s.Category)
+			}
+			byCategory[s.Category] = append(byCategory[s.Category], s.Name)
+		}
+		for _, category := range categories {
+			l.labelled(category, strings.Join(byCategory[category], ", "))
+		}
+	}
+
+	if len(c.Certifications) > 0 {
+		l.heading("Certifications")
+		for _, cert := range c.Certifications {
+			l.entry(cert.Name, cert.Issuer, dateRange(cert.IssueDate, cert.ExpiryDate, false))
+			l.link(cert.URL)
+		}
+	}
+
+	if len(c.Languages) > 0 {
+		l.heading("Languages")
+		for _, language := range c.Languages {
+			l.labelled(language.Name, language.Proficiency)
+		}
+	}
+
+	if len(c.Awards) > 0 {
+		l.heading("Awards")
+		for _, award := range c.Awards {
+			l.entry(award.Title, award.Issuer, award.Date.Display())
+			l.paragraph(award.Description)
+		}
+	}
+}
+
+// section writes a portfolio section from its structured content
+func (l *layout) section(s portfolio.Section) {
+	l.heading(s.Title)
+	c := s.Content
+	if c == nil {
+		return
+	}
+
+	if headline := str(c["headline"]); headline != "" {
+		l.font(l.style.body, "I", baseSize+1, l.style.text)
+		l.pdf.MultiCell(0, 5.5, l.tr(headline), "", "L", false)
+	}
+	l.paragraph(c.Text())
+
+	items, _ := c["items"].([]interface{})
+	for _, raw := range items {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		l.item(section.Content(item))
+	}
+
+	for _, line := range []string{str(c["phone"]), str(c["location"])} {
+		l.paragraph(line)
+	}
+	if email := str(c["email"]); email != "" {
+		l.linkLabelled(email, "mailto:"+email)
+	}
+	links, _ := c["links"].([]interface{})
+	for _, raw := range links {
+		link, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		label := str(link["label"])
+		if label == "" {
+			label = displayURL(str(link["url"]))
+		}
+		l.linkLabelled(label, str(link["url"]))
+	}
+}
+
+// item writes an item of a list section. Items of all list types share one
+// layout: a title made of the fields identifying the item, a subtitle and
+// dates.
+func (l *layout) item(c section.Content) {
+	title := joinNonEmpty("", str(c["role"]), str(c["institution"]), str(c["name"]), str(c["title"]))
+	subtitle := joinNonEmpty(" · ",
+		str(c["organization"]), str(c["degree"]), str(c["field"]), str(c["category"]),
+		str(c["issuer"]), str(c["publisher"]), str(c["location"]))
+
+	dates := str(c["date"])
+	if start, end := str(c["startDate"]), str(c["endDate"]); start != "" || end != "" {
+		current, _ := c["current"].(bool)
+		dates = dateRange(cv.Date(start), cv.Date(end), current)
+	}
+
+	if quote := str(c["quote"]); quote != "" {
+		l.font(l.style.body, "I", baseSize, l.style.text)
+		l.pdf.MultiCell(0, 5, l.tr("“"+quote+"”"), "", "L", false)
+		title = "– " + joinNonEmpty(", ", str(c["author"]), str(c["role"]), str(c["company"]))
+		subtitle = ""
+	}
+
+	l.entry(title, subtitle, dates)
+	l.paragraph(str(c["description"]))
+	l.link(str(c["url"]))
+}
+
+// project writes a project with its text content. Media is left out; the
+// PDF links to nothing that only makes sense on screen.
+func (l *layout) project(p portfolio.Project) {
+	l.entry(p.Title, p.Description, "")
+
+	if p.IsCaseStudy() && p.CaseStudy != nil {
+		cs := p.CaseStudy
+		l.labelled("Role", cs.Role)
+		l.labelled("Client", cs.Client)
+		l.labelled("Duration", cs.Duration)
+		l.labelled("Team", strings.Join(cs.Team, ", "))
+		for _, stage := range cs.Stages {
+			l.subheading(stage.Title)
+			l.paragraph(stage.Body)
+			for _, m := range stage.Metrics {
+				l.labelled(m.Value, joinNonEmpty(" – ", m.Label, m.Description))
+			}
+		}
+	} else if len(p.Blocks) > 0 {
+		l.blocks(p)
+	} else {
+		l.paragraph(p.Content)
+	}
+
+	if len(p.Tags) > 0 {
+		l.font(l.style.body, "", baseSize-1, l.style.muted)
+		l.pdf.MultiCell(0, 4.5, l.tr(strings.Join(p.Tags, " · ")), "", "L", false)
+	}
+	l.pdf.Ln(2)
+}
+
+// blocks writes the text blocks of a project canvas, in canvas order
+func (l *layout) blocks(p portfolio.Project) {
+	var walk func(blocks []block.Block)
+	walk = func(blocks []block.Block) {
+		for _, b := range blocks {
+			if b.Type == "text" {
+				l.paragraph(str(b.Props["text"]))
+			}
+			walk(b.Children)
+		}
+	}
+	walk(p.Blocks)
+}
+
+// heading starts a top-level part of the document, moving to a new page
+// rather than leaving the heading alone at the bottom of one
+func (l *layout) heading(text string) {
+	l.ensureSpace(24)
+	l.pdf.Ln(3)
+	l.font(l.style.heading, "B", baseSize*l.style.scale*l.style.scale, l.style.primary)
+	l.pdf.MultiCell(0, 8, l.tr(text), "", "L", false)
+
+	left, _, right, _ := l.pdf.GetMargins()
+	w, _ := l.pdf.GetPageSize()
+	l.rule(left, w-right)
+	l.pdf.Ln(2)
+}
+
+// subheading writes a heading within an entry
+func (l *layout) subheading(text string) {
+	if text == "" {
+		return
+	}
+	l.ensureSpace(14)
+	l.font(l.style.heading, "B", baseSize, l.style.text)
+	l.pdf.MultiCell(0, 5.5, l.tr(text), "", "L", false)
+}
+
+// entry writes the title line of a dated entry, with its dates right-aligned
+func (l *layout) entry(title, subtitle, dates string) {
+	l.ensureSpace(16)
+	left, _, right, _ := l.pdf.GetMargins()
+	w, _ := l.pdf.GetPageSize()
+	width := w - left - right
+
+	l.font(l.style.body, "", baseSize-1, l.style.muted)
+	datesWidth := 0.0
+	if dates != "" {
+		datesWidth = l.pdf.GetStringWidth(l.tr(dates)) + 2
+	}
+
+	l.font(l.style.heading, "B", baseSize*l.style.scale, l.style.text)
+	lines := l.pdf.SplitText(l.tr(title), width-datesWidth)
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	l.pdf.CellFormat(width-datesWidth, 6, lines[0], "", 0, "L", false, 0, "")
+	l.font(l.style.body, "", baseSize-1, l.style.muted)
+	l.pdf.CellFormat(datesWidth, 6, l.tr(dates), "", 1, "R", false, 0, "")
+	if len(lines) > 1 {
+		l.font(l.style.heading, "B", baseSize*l.style.scale, l.style.text)
+		l.pdf.MultiCell(width-datesWidth, 6, strings.Join(lines[1:], " "), "", "L", false)
+	}
+
+	if subtitle != "" {
+		l.font(l.style.body, "", baseSize, l.style.muted)
+		l.pdf.MultiCell(0, 5, l.tr(subtitle), "", "L", false)
+	}
+	l.pdf.Ln(1)
+}
+
+// labelled writes a line with a bold label
+func (l *layout) labelled(label, text string) {
+	if text == "" {
+		return
+	}
+	if label != "" {
+		l.font(l.style.body, "B", baseSize, l.style.text)
+		l.pdf.Write(5, l.tr(label+": "))
+	}
+	l.font(l.style.body, "", baseSize, l.style.text)
+	l.pdf.Write(5, l.tr(text))
+	l.pdf.Ln(5.5)
+}
+
+// paragraph writes body text, keeping its line breaks
+func (l *layout) paragraph(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	l.font(l.style.body, "", baseSize, l.style.text)
+	l.pdf.MultiCell(0, 5, l.tr(text), "", "L", false)
+	l.pdf.Ln(1.5)
+}
+
+// bullet writes an indented list item
+func (l *layout) bullet(text string) {
+	if text == "" {
+		return
+	}
+	left, _, _, _ := l.pdf.GetMargins()
+	l.font(l.style.body, "", baseSize, l.style.text)
+	l.pdf.SetX(left + 2)
+	l.pdf.CellFormat(4, 5, l.tr("•"), "", 0, "L", false, 0, "")
+	l.pdf.SetLeftMargin(left + 6)
+	l.pdf.MultiCell(0, 5, l.tr(text), "", "L", false)
+	l.pdf.SetLeftMargin(left)
+	l.pdf.SetX(left)
+}
+
+// link writes a URL as a clickable link labelled with the URL itself
+func (l *layout) link(url string) {
+	l.linkLabelled(displayURL(url), url)
+}
+
+// linkLabelled writes a clickable link on a line of its own. Anything but
+// web and mail links is written as plain text.
+func (l *layout) linkLabelled(label, url string) {
+	if label == "" {
+		return
+	}
+	l.font(l.style.body, "U", baseSize, l.style.primary)
+	if linkable(url) {
+		l.pdf.WriteLinkString(5, l.tr(label), url)
+	} else {
+		l.pdf.Write(5, l.tr(label))
+	}
+	l.pdf.Ln(5.5)
+}
+
+// rule draws a horizontal line in the theme's primary color
+func (l *layout) rule(x1, x2 float64) {
+	c := l.style.primary
+	l.pdf.SetDrawColor(c.r, c.g, c.b)
+	l.pdf.SetLineWidth(0.3)
+	y := l.pdf.GetY()
+	l.pdf.Line(x1, y, x2, y)
+	l.pdf.Ln(1)
+}
+
+// ensureSpace starts a new page unless h millimetres are left on this one
+func (l *layout) ensureSpace(h float64) {
+	_, pageHeight := l.pdf.GetPageSize()
+	_, _, _, bottom := l.pdf.GetMargins()
+	if l.pdf.GetY()+h > pageHeight-bottom {
+		l.pdf.AddPage()
+	}
+}
+
+// font selects a font and text color
+func (l *layout) font(family, style string, size float64, c rgb) {
+	l.pdf.SetFont(family, style, size)
+	l.pdf.SetTextColor(c.r, c.g, c.b)
+}
+
+// dateRange formats the dates of an entry, e.g. "May 2021 – Present"
+func dateRange(start, end cv.Date, current bool) string {
+	to := end.Display()
+	if current {
+		to = "Present"
+	}
+	switch {
+	case start == "":
+		return to
+	case to == "":
+		return start.Display()
+	default:
+		return start.Display() + " – " + to
+	}
+}
+
+// joinNonEmpty joins the non-empty parts with sep
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
+
+// str returns a content value if it is a string
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// linkable reports whether url may be written as a link annotation
+func linkable(url string) bool {
+	for _, scheme := range []string{"https://", "http://", "mailto:"} {
+		if strings.HasPrefix(strings.ToLower(url), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// displayURL shortens a URL for display by dropping its scheme
+func displayURL(url string) string {
+	for _, scheme := range []string{"https://", "http://"} {
+		url = strings.TrimPrefix(url, scheme)
+	}
+	return strings.TrimSuffix(url, "/")
+}
@@ -0,0 +1,148 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/section"
+	"github.com/musefolio/backend/internal/theme"
+	"github.com/musefolio/backend/internal/user"
+)
+
+func testDocument(t *testing.T) Document {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var avatar bytes.Buffer
+	if err := png.Encode(&avatar, img); err != nil {
+		t.Fatalf("encode avatar: %v", err)
+	}
+
+	experience := make([]cv.Experience, 30)
+	for i := range experience {
+		experience[i] = cv.Experience{
+			Role:         "Designer",
+			Organization: "Studio Café",
+			StartDate:    "2019-03",
+			EndDate:      "2021",
+			Description:  "Led the redesign of the booking flow.",
+			Highlights:   []string{"Cut drop-off by 20%"},
+		}
+	}
+
+	return Document{
+		Portfolio: &portfolio.Portfolio{
+			Title: "Ada's CV",
+			Type:  "cv",
+			Sections: []portfolio.Section{{
+				ID:    primitive.NewObjectID(),
+				Title: "Publications",
+				Type:  "publications",
+				Content: section.Content{"items": []interface{}{
+					map[string]interface{}{"title": "On Engines", "url": "https://example.com/engines"},
+				}},
+			}},
+			Projects: []portfolio.Project{
+				{Title: "Shown", Content: "Visible project"},
+				{Title: "Secret", Content: "Hidden project", Hidden: true},
+			},
+		},
+		CV: &cv.CV{
+			Headline:   "Product designer",
+			Experience: experience,
+			Skills:     []cv.Skill{{Name: "Figma", Category: "Tools"}},
+		},
+		Theme: &theme.Theme{Tokens: theme.Tokens{
+			Palette:    theme.Palette{Primary: "#336699", Text: "rgb(10, 10, 10)"},
+			Typography: theme.Typography{HeadingFont: "'Playfair Display', serif", BodyFont: "Inter, sans-serif", Scale: 1.5},
+		}},
+		Owner: &user.User{
+			Name:        "Ada Lovelace",
+			Email:       "ada@example.com",
+			SocialLinks: &user.SocialLinks{GitHub: "https://github.com/ada"},
+		},
+		Avatar: avatar.Bytes(),
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	doc := testDocument(t)
+
+	for size, mediaBox := range map[string]string{
+		"a4":     "/MediaBox [0 0 595.28 841.89]",
+		"Letter": "/MediaBox [0 0 612.00 792.00]",
+	} {
+		var buf bytes.Buffer
+		if err := WritePDF(&buf, doc, size); err != nil {
+			t.Fatalf("WritePDF(%s): %v", size, err)
+		}
+		out := buf.String()
+
+		if !strings.HasPrefix(out, "%PDF-") {
+			t.Fatalf("%s: output is not a PDF", size)
+		}
+		if !strings.Contains(out, mediaBox) {
+			t.Errorf("%s: missing %s", size, mediaBox)
+		}
+		for _, link := range []string{"mailto:ada@example.com", "https://github.com/ada", "https://example.com/engines"} {
+			if !strings.Contains(out, "/URI ("+link+")") {
+				t.Errorf("%s: missing link to %s", size, link)
+			}
+		}
+		if !strings.Contains(out, "/Subtype /Image") {
+			t.Errorf("%s: avatar not embedded", size)
+		}
+		// Thirty positions don't fit on one page
+		if strings.Count(out, "/Type /Page\n") < 2 {
+			t.Errorf("%s: expected the CV to break across pages", size)
+		}
+	}
+}
+
+func TestWritePDFLeavesOutEmailForOthers(t *testing.T) {
+	doc := testDocument(t)
+	doc.Owner.ID = primitive.NewObjectID()
+	doc.Owner = publicOwner(doc.Owner, primitive.NewObjectID())
+
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, doc, "a4"); err != nil {
+		t.Fatalf("WritePDF: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "mailto:") || !strings.Contains(out, "/URI (https://github.com/ada)") {
+		t.Error("export by another user should keep the social links but not the account email")
+	}
+}
+
+func TestWritePDFRejectsUnknownPageSize(t *testing.T) {
+	err := WritePDF(&bytes.Buffer{}, testDocument(t), "a5")
+	if !errors.Is(err, ErrUnsupportedPageSize) {
+		t.Errorf("err = %v, want ErrUnsupportedPageSize", err)
+	}
+}
+
+func TestStyleOf(t *testing.T) {
+	s := styleOf(testDocument(t).Theme)
+
+	if s.heading != "Times" || s.body != "Helvetica" {
+		t.Errorf("fonts = %s/%s, want Times/Helvetica", s.heading, s.body)
+	}
+	if s.primary != (rgb{0x33, 0x66, 0x99}) || s.text != (rgb{10, 10, 10}) {
+		t.Errorf("colors = %v/%v", s.primary, s.text)
+	}
+	if s.muted != defaultStyle.muted {
+		t.Errorf("muted = %v, want the default", s.muted)
+	}
+	if s.scale != maxPrintScale {
+		t.Errorf("scale = %v, want it capped at %v", s.scale, maxPrintScale)
+	}
+}
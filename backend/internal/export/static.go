@@ -0,0 +1,236 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/site"
+	"github.com/musefolio/backend/internal/storage"
+	"github.com/musefolio/backend/internal/theme"
+)
+
+// StaticSite is a portfolio rendered into a self-contained set of files:
+// index.html, a page per project, the theme stylesheet, a sitemap and the
+// media files the pages reference. All links between them are relative, so
+// the site can be hosted under any path.
+type StaticSite struct {
+	// Files maps file paths to their content
+	Files map[string][]byte
+	// Media maps the paths of media files in the site to their storage keys
+	Media map[string]string
+}
+
+// BuildStaticSite renders page.Portfolio through the public site templates.
+// Stored media is copied into the site and the portfolio's media URLs are
+// rewritten to point at the copies. baseURL is the absolute URL the site
+// will be served from; it is only used by the sitemap.
+func BuildStaticSite(renderer *site.Renderer, store storage.Storage, page site.Page, t *theme.Theme, baseURL string) (*StaticSite, error) {
+	s := &StaticSite{
+		Files: map[string][]byte{},
+		Media: map[string]string{},
+	}
+	p := page.Portfolio
+
+	for i := range p.Projects {
+		s.rewriteProject(store, &p.Projects[i])
+	}
+
+	// Every visible project gets a page named after its title
+	projects := p.VisibleProjects()
+	page.ProjectPages = map[primitive.ObjectID]string{}
+	taken := map[string]bool{"index": true, "theme": true, "sitemap": true}
+	for _, project := range projects {
+		page.ProjectPages[project.ID] = pageName(project, taken) + ".html"
+	}
+
+	page.StylesheetURL = "theme.css"
+	page.HomeURL = "index.html"
+	s.Files["theme.css"] = []byte(theme.CSS(t))
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, page); err != nil {
+		return nil, err
+	}
+	s.Files["index.html"] = bytes.Clone(buf.Bytes())
+
+	for i := range projects {
+		page.Project = &projects[i]
+		buf.Reset()
+		if err := renderer.RenderProject(&buf, page); err != nil {
+			return nil, err
+		}
+		s.Files[page.ProjectPages[projects[i].ID]] = bytes.Clone(buf.Bytes())
+	}
+
+	sitemap, err := buildSitemap(baseURL, s.Files)
+	if err != nil {
+		return nil, err
+	}
+	s.Files["sitemap.xml"] = sitemap
+
+	return s, nil
+}
+
+// WriteZIP writes the site as a ZIP archive, reading media files from store.
+// Media files that no longer exist are left out.
+func (s *StaticSite) WriteZIP(ctx context.Context, w io.Writer, store storage.Storage) error {
+	zw := zip.NewWriter(w)
+
+	for _, name := range sortedKeys(s.Files) {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(s.Files[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedKeys(s.Media) {
+		if err := s.copyMedia(ctx, zw, store, name); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// copyMedia copies a stored media file into the archive
+func (s *StaticSite) copyMedia(ctx context.Context, zw *zip.Writer, store storage.Storage, name string) error {
+	key := s.Media[name]
+	file, err := store.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			slog.Warn("media file missing from static export", "key", key)
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	// Media is mostly already compressed
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, file)
+	return err
+}
+
+// rewriteProject points the media of a project, its case-study stages and
+// its canvas at copies inside the site
+func (s *StaticSite) rewriteProject(store storage.Storage, project *portfolio.Project) {
+	for i := range project.Media {
+		project.Media[i].URL = s.localURL(store, project.Media[i].URL)
+	}
+	if cs := project.CaseStudy; cs != nil {
+		for i := range cs.Stages {
+			for j := range cs.Stages[i].Media {
+				cs.Stages[i].Media[j].URL = s.localURL(store, cs.Stages[i].Media[j].URL)
+			}
+		}
+	}
+	s.rewriteBlocks(store, project.Blocks)
+}
+
+// rewriteBlocks points the media properties of blocks at copies inside the
+// site
+func (s *StaticSite) rewriteBlocks(store storage.Storage, blocks []block.Block) {
+	for _, b := range blocks {
+		for _, prop := range []string{"url", "poster"} {
+			if url, ok := b.Props[prop].(string); ok && b.Type != "embed" {
+				b.Props[prop] = s.localURL(store, url)
+			}
+		}
+		if images, ok := b.Props["images"].([]interface{}); ok {
+			for _, raw := range images {
+				if image, ok := raw.(map[string]interface{}); ok {
+					if url, ok := image["url"].(string); ok {
+						image["url"] = s.localURL(store, url)
+					}
+				}
+			}
+		}
+		s.rewriteBlocks(store, b.Children)
+	}
+}
+
+// localURL returns the relative URL of the site's copy of a stored file.
+// URLs outside of storage, e.g. embedded videos, are kept.
+func (s *StaticSite) localURL(store storage.Storage, url string) string {
+	key, ok := store.KeyFromURL(url)
+	if !ok {
+		return url
+	}
+	name := path.Join("media", key)
+	s.Media[name] = key
+	return name
+}
+
+// pageName derives a unique file name for a project page from its title
+func pageName(project portfolio.Project, taken map[string]bool) string {
+	base := slugify(project.Title)
+	if base == "" {
+		base = "project"
+	}
+
+	name := base
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	taken[name] = true
+	return name
+}
+
+// sitemapURLSet is the root element of a sitemap
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapURL is a page listed in a sitemap
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// buildSitemap lists the HTML pages of a site under baseURL
+func buildSitemap(baseURL string, files map[string][]byte) ([]byte, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	set.URLs = append(set.URLs, sitemapURL{Loc: baseURL + "/"})
+	for _, name := range sortedKeys(files) {
+		if strings.HasSuffix(name, ".html") && name != "index.html" {
+			set.URLs = append(set.URLs, sitemapURL{Loc: baseURL + "/" + name})
+		}
+	}
+
+	out, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// sortedKeys returns the keys of a map in order, so archives are reproducible
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
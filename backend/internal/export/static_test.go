@@ -0,0 +1,107 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/site"
+	"github.com/musefolio/backend/internal/storage"
+	"github.com/musefolio/backend/internal/theme"
+)
+
+func TestStaticSiteZIP(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocal(t.TempDir(), "/media")
+	coverURL, err := store.Save(ctx, "p/cover.png", strings.NewReader("cover"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	shotURL, err := store.Save(ctx, "p/shot.png", strings.NewReader("shot"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	renderer, err := site.NewRenderer()
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+
+	p := &portfolio.Portfolio{
+		Title: "Work",
+		Type:  "portfolio",
+		Projects: []portfolio.Project{
+			{
+				ID:    primitive.NewObjectID(),
+				Title: "Logo Design",
+				Media: []portfolio.Media{{Type: "image", URL: coverURL}, {Type: "video", URL: "https://video.example.com/1"}},
+			},
+			{
+				ID:    primitive.NewObjectID(),
+				Title: "Logo design",
+				Blocks: []block.Block{{Type: "gallery", Props: block.Props{
+					"images": []interface{}{map[string]interface{}{"url": shotURL}},
+				}}},
+			},
+			{ID: primitive.NewObjectID(), Title: "Draft", Hidden: true},
+		},
+	}
+	tokens := theme.Tokens{Palette: theme.Palette{Primary: "#000000", Secondary: "#111111", Background: "#ffffff", Text: "#222222"}}
+
+	static, err := BuildStaticSite(renderer, store, site.Page{Portfolio: p}, &theme.Theme{Tokens: tokens}, "https://jane.example.com/")
+	if err != nil {
+		t.Fatalf("BuildStaticSite: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := static.WriteZIP(ctx, &buf, store); err != nil {
+		t.Fatalf("WriteZIP: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	for _, name := range []string{"index.html", "logo-design.html", "logo-design-2.html", "theme.css", "sitemap.xml", "media/p/cover.png", "media/p/shot.png"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("archive is missing %s (has %d files)", name, len(files))
+		}
+	}
+	if _, ok := files["draft.html"]; ok {
+		t.Error("hidden project got a page")
+	}
+
+	index := files["index.html"]
+	for _, want := range []string{`href="theme.css"`, `src="media/p/cover.png"`, `src="https://video.example.com/1"`, `href="logo-design-2.html"`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html lacks %s", want)
+		}
+	}
+	if strings.Contains(index, "/media/p/") {
+		t.Error("index.html still links to hosted media")
+	}
+	if page := files["logo-design-2.html"]; !strings.Contains(page, `src="media/p/shot.png"`) || !strings.Contains(page, `href="index.html"`) {
+		t.Error("project page lacks its gallery image or the link home")
+	}
+	if !strings.Contains(files["sitemap.xml"], "<loc>https://jane.example.com/logo-design.html</loc>") {
+		t.Errorf("sitemap lacks project page:\n%s", files["sitemap.xml"])
+	}
+}
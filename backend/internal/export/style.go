@@ -0,0 +1,119 @@
+package export
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/musefolio/backend/internal/theme"
+)
+
+// maxPrintScale caps the type scale of a theme on paper, where large
+// screen headings waste space
+const maxPrintScale = 1.3
+
+// rgb is a color with 0-255 components
+type rgb struct{ r, g, b int }
+
+// style holds the theme tokens translated for print
+type style struct {
+	heading, body        string
+	primary, text, muted rgb
+	scale                float64
+}
+
+// defaultStyle is used for anything the theme leaves out or that cannot be
+// translated
+var defaultStyle = style{
+	heading: "Helvetica",
+	body:    "Helvetica",
+	primary: rgb{37, 99, 235},
+	text:    rgb{17, 24, 39},
+	muted:   rgb{107, 114, 128},
+	scale:   1.25,
+}
+
+// styleOf translates the tokens of a theme. Colors are printed on white, so
+// the theme's light palette is used.
+func styleOf(t *theme.Theme) style {
+	s := defaultStyle
+	if t == nil {
+		return s
+	}
+
+	tokens := t.Tokens
+	s.heading = fontFamily(tokens.Typography.HeadingFont, s.heading)
+	s.body = fontFamily(tokens.Typography.BodyFont, s.body)
+	if c, ok := parseColor(tokens.Palette.Primary); ok {
+		s.primary = c
+	}
+	if c, ok := parseColor(tokens.Palette.Text); ok {
+		s.text = c
+	}
+	if c, ok := parseColor(tokens.Palette.Muted); ok {
+		s.muted = c
+	}
+	if scale := tokens.Typography.Scale; scale >= 1 {
+		s.scale = min(scale, maxPrintScale)
+	}
+	return s
+}
+
+// fontFamily maps a CSS font stack to the closest standard PDF font: serif
+// stacks to Times, monospace ones to Courier and everything else to
+// Helvetica
+func fontFamily(stack, fallback string) string {
+	families := strings.Split(strings.ToLower(stack), ",")
+	generic := strings.Trim(strings.TrimSpace(families[len(families)-1]), `'"`)
+	switch generic {
+	case "serif":
+		return "Times"
+	case "monospace":
+		return "Courier"
+	case "sans-serif", "system-ui":
+		return "Helvetica"
+	default:
+		return fallback
+	}
+}
+
+// parseColor parses a HEX or rgb()/rgba() color as accepted by themes.
+// Transparency is ignored.
+func parseColor(value string) (rgb, bool) {
+	value = strings.TrimSpace(strings.ToLower(value))
+
+	if hex, ok := strings.CutPrefix(value, "#"); ok {
+		if len(hex) == 3 || len(hex) == 4 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 && len(hex) != 8 {
+			return rgb{}, false
+		}
+		n, err := strconv.ParseUint(hex[:6], 16, 32)
+		if err != nil {
+			return rgb{}, false
+		}
+		return rgb{int(n >> 16 & 0xff), int(n >> 8 & 0xff), int(n & 0xff)}, true
+	}
+
+	for _, prefix := range []string{"rgba(", "rgb("} {
+		args, ok := strings.CutPrefix(value, prefix)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(strings.TrimSuffix(args, ")"), ",")
+		if len(parts) < 3 {
+			return rgb{}, false
+		}
+		var c [3]int
+		for i := range c {
+			n, err := strconv.Atoi(strings.TrimSpace(parts[i]))
+			if err != nil || n < 0 || n > 255 {
+				return rgb{}, false
+			}
+			c[i] = n
+		}
+		return rgb{c[0], c[1], c[2]}, true
+	}
+
+	return rgb{}, false
+}
@@ -0,0 +1,167 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAccountNotFound = errors.New("GitHub account not found")
+	ErrInvalidToken    = errors.New("invalid GitHub token")
+	ErrRateLimited     = errors.New("GitHub rate limit exceeded, try again later")
+
+	// errNotFound is returned for missing resources
+	errNotFound = errors.New("not found")
+)
+
+// perPage is the page size of repository listings, GitHub's maximum
+const perPage = 100
+
+// maxPages bounds how many pages of repositories are listed
+const maxPages = 10
+
+// maxReadmeSize bounds the size of a README imported as project content
+const maxReadmeSize = 512 << 10
+
+// maxErrorBody bounds how much of an error response is kept as the message
+const maxErrorBody = 1 << 10
+
+// Repo is a GitHub repository
+type Repo struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	FullName    string    `json:"full_name"`
+	Description string    `json:"description"`
+	HTMLURL     string    `json:"html_url"`
+	Homepage    string    `json:"homepage"`
+	Language    string    `json:"language"`
+	Topics      []string  `json:"topics"`
+	Stars       int       `json:"stargazers_count"`
+	Fork        bool      `json:"fork"`
+	Archived    bool      `json:"archived"`
+	Private     bool      `json:"private"`
+	PushedAt    time.Time `json:"pushed_at"`
+}
+
+// APIError is returned when GitHub rejects a request
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the GitHub REST API. Its base URL is configurable so tests
+// can point it at a local server.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a new GitHub API client. A nil httpClient uses a client
+// with a timeout.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    httpClient,
+	}
+}
+
+// Login returns the login of the account a token belongs to
+func (c *Client) Login(ctx context.Context, token string) (string, error) {
+	var account struct {
+		Login string `json:"login"`
+	}
+	if _, err := c.get(ctx, "/user", token, "application/vnd.github+json", &account); err != nil {
+		return "", err
+	}
+	return account.Login, nil
+}
+
+// Repos lists the repositories owned by an account, most recently pushed
+// first. With a token, the private repositories of the token's account are
+// included and username is ignored.
+func (c *Client) Repos(ctx context.Context, username, token string) ([]Repo, error) {
+	path := "/users/" + url.PathEscape(username) + "/repos?type=owner"
+	if token != "" {
+		path = "/user/repos?affiliation=owner"
+	}
+
+	repos := []Repo{}
+	for page := 1; page <= maxPages; page++ {
+		var batch []Repo
+		p := fmt.Sprintf("%s&sort=pushed&per_page=%d&page=%d", path, perPage, page)
+		_, err := c.get(ctx, p, token, "application/vnd.github+json", &batch)
+		if errors.Is(err, errNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, batch...)
+		if len(batch) < perPage {
+			break
+		}
+	}
+	return repos, nil
+}
+
+// Readme returns the README of a repository as Markdown, or an empty string
+// if it has none
+func (c *Client) Readme(ctx context.Context, fullName, token string) (string, error) {
+	body, err := c.get(ctx, "/repos/"+fullName+"/readme", token, "application/vnd.github.raw+json", nil)
+	if errors.Is(err, errNotFound) {
+		return "", nil
+	}
+	return string(body), err
+}
+
+// get requests path and decodes the JSON response into out, or returns the
+// raw body if out is nil
+func (c *Client) get(ctx context.Context, path, token, accept string, out interface{}) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errNotFound
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		return nil, ErrRateLimited
+	case resp.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+
+	if out != nil {
+		return nil, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxReadmeSize))
+}
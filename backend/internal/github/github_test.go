@@ -0,0 +1,154 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+// fakeGitHub serves the endpoints of the GitHub API the importer uses for
+// the account "ada", with 150 repositories and a token "secret"
+func fakeGitHub(t *testing.T) *httptest.Server {
+	repos := make([]Repo, 150)
+	for i := range repos {
+		repos[i] = Repo{
+			ID:       int64(i + 1),
+			Name:     fmt.Sprintf("repo-%d", i),
+			FullName: fmt.Sprintf("ada/repo-%d", i),
+			HTMLURL:  fmt.Sprintf("https://github.com/ada/repo-%d", i),
+			Stars:    i,
+			Fork:     i%2 == 1,
+		}
+	}
+
+	mux := http.NewServeMux()
+	page := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("per_page") != "100" || r.URL.Query().Get("sort") != "pushed" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		start := 0
+		if r.URL.Query().Get("page") == "2" {
+			start = 100
+		}
+		json.NewEncoder(w).Encode(repos[start:min(start+100, len(repos))])
+	}
+	mux.HandleFunc("GET /users/ada/repos", page)
+	mux.HandleFunc("GET /user/repos", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		page(w, r)
+	})
+	mux.HandleFunc("GET /user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"login": "ada"}`)
+	})
+	mux.HandleFunc("GET /users/limited/repos", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusForbidden)
+	})
+	mux.HandleFunc("GET /repos/ada/repo-0/readme", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), "raw") {
+			t.Errorf("README requested as %s", r.Header.Get("Accept"))
+		}
+		fmt.Fprint(w, "# Repo 0\n\nThe first one.")
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	client := NewClient(fakeGitHub(t).URL, nil)
+	ctx := context.Background()
+
+	repos, err := client.Repos(ctx, "ada", "")
+	if err != nil || len(repos) != 150 {
+		t.Fatalf("Repos = %d repos, %v", len(repos), err)
+	}
+
+	login, err := client.Login(ctx, "secret")
+	if err != nil || login != "ada" {
+		t.Errorf("Login = %q, %v", login, err)
+	}
+	if _, err := client.Repos(ctx, "", "wrong"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Repos with a wrong token: %v", err)
+	}
+	if _, err := client.Repos(ctx, "nobody", ""); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("Repos of a missing account: %v", err)
+	}
+	if _, err := client.Repos(ctx, "limited", ""); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Repos when rate limited: %v", err)
+	}
+
+	readme, err := client.Readme(ctx, "ada/repo-0", "")
+	if err != nil || readme != "# Repo 0\n\nThe first one." {
+		t.Errorf("Readme = %q, %v", readme, err)
+	}
+	if readme, err := client.Readme(ctx, "ada/repo-1", ""); err != nil || readme != "" {
+		t.Errorf("missing Readme = %q, %v", readme, err)
+	}
+}
+
+func TestSelectRepos(t *testing.T) {
+	repos := []Repo{{Name: "app"}, {Name: "fork", Fork: true}, {Name: "Lib"}}
+
+	if got := selectRepos(repos, nil, false); len(got) != 2 || got[1].Name != "Lib" {
+		t.Errorf("without forks = %+v", got)
+	}
+	if got := selectRepos(repos, nil, true); len(got) != 3 {
+		t.Errorf("with forks = %+v", got)
+	}
+	if got := selectRepos(repos, []string{"lib", "fork"}, false); len(got) != 2 || got[0].Name != "fork" {
+		t.Errorf("named = %+v", got)
+	}
+}
+
+func TestProjectInput(t *testing.T) {
+	now := time.Now()
+	repo := Repo{
+		ID:       42,
+		Name:     "engine",
+		HTMLURL:  "https://github.com/ada/engine",
+		Language: "Go",
+		Topics:   []string{"math", "engines"},
+		Stars:    7,
+	}
+
+	input := projectInput(repo, "", now)
+	if input.Title != "engine" || input.Description != "engine" || input.Content != "engine\n\nhttps://github.com/ada/engine" {
+		t.Errorf("input = %+v", input)
+	}
+	if strings.Join(input.Tags, ",") != "math,engines" {
+		t.Errorf("tags = %v", input.Tags)
+	}
+	if *input.Source != (portfolio.ProjectSource{Provider: "github", ExternalID: "42", URL: repo.HTMLURL, Stars: 7, Language: "Go", SyncedAt: now}) {
+		t.Errorf("source = %+v", input.Source)
+	}
+
+	if input := projectInput(repo, " # Engine \n", now); input.Content != "# Engine" {
+		t.Errorf("content = %q", input.Content)
+	}
+
+	synced := *input.Source
+	if sourceChanged(*input.Source, synced) {
+		t.Error("unchanged source reported as changed")
+	}
+	synced.Stars++
+	if !sourceChanged(*input.Source, synced) {
+		t.Error("new star not reported")
+	}
+}
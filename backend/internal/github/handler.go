@@ -0,0 +1,140 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+// Handler handles HTTP requests for GitHub imports
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new GitHub import handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the GitHub import routes
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/users/me/github", func(r chi.Router) {
+		r.Get("/", h.GetConnection)
+		r.Delete("/", h.DeleteConnection)
+		r.Post("/import", h.Import)
+		r.Post("/sync", h.Sync)
+	})
+}
+
+// GetConnection handles getting the current user's GitHub account
+func (h *Handler) GetConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	connection, err := h.service.GetConnection(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(connection)
+}
+
+// DeleteConnection handles disconnecting the current user's GitHub account
+func (h *Handler) DeleteConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.DeleteConnection(r.Context(), userID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Import handles importing the repositories of a GitHub account into a
+// portfolio of the current user
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	var input ImportInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.service.Import(r.Context(), userID, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Sync handles refreshing the stats of the current user's imported
+// repositories
+func (h *Handler) Sync(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.service.Sync(r.Context(), userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// writeError writes the response for a service error
+func writeError(w http.ResponseWriter, err error) {
+	var validationErrors validator.ValidationErrors
+	var apiError *APIError
+	switch {
+	case errors.Is(err, portfolio.ErrPortfolioNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, portfolio.ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrConnectionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidToken):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrRateLimited):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.As(err, &apiError):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	case errors.As(err, &validationErrors):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,60 @@
+// Package github imports the repositories of a GitHub account as projects
+// and keeps their stats in sync.
+package github
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+// Provider is the source provider of projects imported from GitHub
+const Provider = "github"
+
+// Connection is the GitHub account of a user whose repositories are kept in
+// sync with their imported projects
+type Connection struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID   primitive.ObjectID `bson:"userId" json:"userId"`
+	Username string             `bson:"username" json:"username"`
+	// Token is the encrypted personal access token, if one was given
+	Token []byte `bson:"token,omitempty" json:"-"`
+	// TokenHint is the end of the token, to tell tokens apart
+	TokenHint string `bson:"tokenHint,omitempty" json:"tokenHint,omitempty"`
+	// PortfolioID is the portfolio repositories were imported into
+	PortfolioID  primitive.ObjectID `bson:"portfolioId" json:"portfolioId"`
+	LastSyncedAt *time.Time         `bson:"lastSyncedAt,omitempty" json:"lastSyncedAt,omitempty"`
+	// LastError is why the last sync failed; empty if it succeeded
+	LastError string    `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ImportInput represents the input for importing repositories
+type ImportInput struct {
+	// Username is the account whose public repositories are imported
+	Username string `json:"username" validate:"required_without=Token,max=39"`
+	// Token imports the repositories of its account, including private ones
+	Token       string             `json:"token" validate:"max=1024"`
+	PortfolioID primitive.ObjectID `json:"portfolioId" validate:"required"`
+	// Repositories limits the import to repositories with these names
+	Repositories []string `json:"repositories,omitempty" validate:"max=500"`
+	IncludeForks bool     `json:"includeForks"`
+}
+
+// ImportResult describes the outcome of an import
+type ImportResult struct {
+	Imported []portfolio.Project `json:"imported"`
+	// Skipped lists the repositories that were already imported
+	Skipped    []string    `json:"skipped"`
+	Connection *Connection `json:"connection"`
+}
+
+// SyncResult describes the outcome of a sync
+type SyncResult struct {
+	// Updated is the number of projects whose stats changed
+	Updated  int       `json:"updated"`
+	SyncedAt time.Time `json:"syncedAt"`
+}
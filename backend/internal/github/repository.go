@@ -0,0 +1,107 @@
+package github
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/musefolio/backend/internal/database"
+)
+
+// Repository handles GitHub connection data operations
+type Repository struct {
+	db          *database.DB
+	connections *mongo.Collection
+}
+
+// NewRepository creates a new GitHub connection repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{
+		db:          db,
+		connections: db.Collection(database.GitHubConnectionsCollection),
+	}
+}
+
+// SaveConnection stores the GitHub account of a user, replacing any previous
+// one
+func (r *Repository) SaveConnection(ctx context.Context, connection *Connection) (*Connection, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"username":    connection.Username,
+			"token":       connection.Token,
+			"tokenHint":   connection.TokenHint,
+			"portfolioId": connection.PortfolioID,
+			"updatedAt":   now,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved Connection
+	if err := r.connections.FindOneAndUpdate(ctx, bson.M{"userId": connection.UserID}, update, opts).Decode(&saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// FindConnection finds the GitHub account of a user
+func (r *Repository) FindConnection(ctx context.Context, userID primitive.ObjectID) (*Connection, error) {
+	var connection Connection
+	err := r.connections.FindOne(ctx, bson.M{"userId": userID}).Decode(&connection)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &connection, nil
+}
+
+// FindConnectionsSyncedBefore finds the connections not synced since a time,
+// least recently synced first
+func (r *Repository) FindConnectionsSyncedBefore(ctx context.Context, before time.Time) ([]*Connection, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"lastSyncedAt": bson.M{"$exists": false}},
+			{"lastSyncedAt": bson.M{"$lt": before}},
+		},
+	}
+	opts := options.Find().SetSort(bson.M{"lastSyncedAt": 1})
+	cursor, err := r.connections.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	connections := []*Connection{}
+	if err := cursor.All(ctx, &connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
+// RecordSync records the outcome of a sync
+func (r *Repository) RecordSync(ctx context.Context, id primitive.ObjectID, at time.Time, message string) error {
+	update := bson.M{
+		"$set": bson.M{"lastSyncedAt": at, "lastError": message},
+	}
+	_, err := r.connections.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// DeleteConnection deletes the GitHub account of a user. It reports whether
+// there was one.
+func (r *Repository) DeleteConnection(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	result, err := r.connections.DeleteOne(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
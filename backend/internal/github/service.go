@@ -0,0 +1,304 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/deploy"
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+var ErrConnectionNotFound = errors.New("no GitHub account connected")
+
+// tokenHintLength is the number of trailing token characters kept readable
+const tokenHintLength = 4
+
+// Service handles importing GitHub repositories
+type Service struct {
+	repo         *Repository
+	portfolios   *portfolio.Service
+	client       *Client
+	cipher       *deploy.Cipher
+	syncInterval time.Duration
+	validate     *validator.Validate
+}
+
+// NewService creates a new GitHub import service. Tokens are encrypted with
+// cipher; connections are synced every syncInterval.
+func NewService(repo *Repository, portfolios *portfolio.Service, client *Client, cipher *deploy.Cipher, syncInterval time.Duration) *Service {
+	return &Service{
+		repo:         repo,
+		portfolios:   portfolios,
+		client:       client,
+		cipher:       cipher,
+		syncInterval: syncInterval,
+		validate:     validator.New(),
+	}
+}
+
+// Import adds the repositories of a GitHub account to a portfolio of the
+// user as projects. Repositories imported before are skipped. The account
+// is remembered so the stats of the projects are kept in sync.
+func (s *Service) Import(ctx context.Context, userID primitive.ObjectID, input ImportInput) (*ImportResult, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+
+	p, err := s.portfolios.GetByID(ctx, input.PortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != userID {
+		return nil, portfolio.ErrUnauthorized
+	}
+
+	username := input.Username
+	if input.Token != "" {
+		if username, err = s.client.Login(ctx, input.Token); err != nil {
+			return nil, err
+		}
+	}
+	repos, err := s.client.Repos(ctx, username, input.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.portfolios.ListProjectsBySource(ctx, userID, Provider)
+	if err != nil {
+		return nil, err
+	}
+	imported := make(map[string]bool, len(existing))
+	for _, project := range existing {
+		imported[project.Source.ExternalID] = true
+	}
+
+	result := &ImportResult{Imported: []portfolio.Project{}, Skipped: []string{}}
+	order := len(p.Projects)
+	now := time.Now()
+	for _, repo := range selectRepos(repos, input.Repositories, input.IncludeForks) {
+		if imported[externalID(repo)] {
+			result.Skipped = append(result.Skipped, repo.FullName)
+			continue
+		}
+
+		readme, err := s.client.Readme(ctx, repo.FullName, input.Token)
+		if err != nil {
+			return nil, err
+		}
+		create := projectInput(repo, readme, now)
+		create.Order = order
+
+		project, _, err := s.portfolios.AddProject(ctx, p.ID, userID, portfolio.AnyVersion, create)
+		if errors.Is(err, portfolio.ErrProjectImported) {
+			// Imported by a concurrent request
+			result.Skipped = append(result.Skipped, repo.FullName)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Imported = append(result.Imported, *project)
+		order++
+	}
+
+	connection := &Connection{UserID: userID, Username: username, PortfolioID: p.ID}
+	if input.Token != "" {
+		if connection.Token, err = s.cipher.Encrypt(input.Token); err != nil {
+			return nil, err
+		}
+		connection.TokenHint = input.Token
+		if len(input.Token) > tokenHintLength {
+			connection.TokenHint = input.Token[len(input.Token)-tokenHintLength:]
+		}
+	}
+	if result.Connection, err = s.repo.SaveConnection(ctx, connection); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetConnection gets the GitHub account of the user
+func (s *Service) GetConnection(ctx context.Context, userID primitive.ObjectID) (*Connection, error) {
+	connection, err := s.repo.FindConnection(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if connection == nil {
+		return nil, ErrConnectionNotFound
+	}
+	return connection, nil
+}
+
+// DeleteConnection disconnects the GitHub account of the user. Imported
+// projects are kept but no longer synced.
+func (s *Service) DeleteConnection(ctx context.Context, userID primitive.ObjectID) error {
+	deleted, err := s.repo.DeleteConnection(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrConnectionNotFound
+	}
+	return nil
+}
+
+// Sync refreshes the stats of the projects the user imported from GitHub
+func (s *Service) Sync(ctx context.Context, userID primitive.ObjectID) (*SyncResult, error) {
+	connection, err := s.GetConnection(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.sync(ctx, connection, time.Now())
+}
+
+// SyncDue syncs the connections that weren't synced within the sync
+// interval. A failing connection doesn't stop the others; its error is
+// recorded on it.
+func (s *Service) SyncDue(ctx context.Context, now time.Time) error {
+	connections, err := s.repo.FindConnectionsSyncedBefore(ctx, now.Add(-s.syncInterval))
+	if err != nil {
+		return err
+	}
+	for _, connection := range connections {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.sync(ctx, connection, now); err != nil {
+			slog.Warn("GitHub sync failed", "userId", connection.UserID.Hex(), "error", err)
+		}
+	}
+	return nil
+}
+
+// sync refreshes the projects of a connection and records the outcome
+func (s *Service) sync(ctx context.Context, connection *Connection, now time.Time) (*SyncResult, error) {
+	updated, err := s.refresh(ctx, connection, now)
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+	if recordErr := s.repo.RecordSync(ctx, connection.ID, now, message); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SyncResult{Updated: updated, SyncedAt: now}, nil
+}
+
+// refresh updates the stats of the projects imported from the repositories
+// of a connection. It returns the number of projects that changed.
+func (s *Service) refresh(ctx context.Context, connection *Connection, now time.Time) (int, error) {
+	token := ""
+	if len(connection.Token) > 0 {
+		var err error
+		if token, err = s.cipher.Decrypt(connection.Token); err != nil {
+			return 0, fmt.Errorf("stored GitHub token can't be read, import again: %w", err)
+		}
+	}
+
+	repos, err := s.client.Repos(ctx, connection.Username, token)
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[string]Repo, len(repos))
+	for _, repo := range repos {
+		byID[externalID(repo)] = repo
+	}
+
+	projects, err := s.portfolios.ListProjectsBySource(ctx, connection.UserID, Provider)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, project := range projects {
+		// Repositories that were deleted or made private keep their last
+		// known stats
+		repo, ok := byID[project.Source.ExternalID]
+		if !ok {
+			continue
+		}
+		source := sourceOf(repo, now)
+		if !sourceChanged(*project.Source, *source) {
+			continue
+		}
+		if err := s.portfolios.SyncProjectSource(ctx, project.ID, connection.UserID, *source); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// selectRepos picks the repositories to import. Forks are left out unless
+// includeForks is set or they are named.
+func selectRepos(repos []Repo, names []string, includeForks bool) []Repo {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[strings.ToLower(name)] = true
+	}
+
+	selected := []Repo{}
+	for _, repo := range repos {
+		switch {
+		case len(wanted) > 0 && !wanted[strings.ToLower(repo.Name)]:
+		case len(wanted) == 0 && repo.Fork && !includeForks:
+		default:
+			selected = append(selected, repo)
+		}
+	}
+	return selected
+}
+
+// projectInput builds the project of a repository. Its README is the
+// content; repositories without one link to GitHub instead.
+func projectInput(repo Repo, readme string, now time.Time) portfolio.CreateProjectInput {
+	description := strings.TrimSpace(repo.Description)
+	if description == "" {
+		description = repo.Name
+	}
+	content := strings.TrimSpace(readme)
+	if content == "" {
+		content = description + "\n\n" + repo.HTMLURL
+	}
+
+	return portfolio.CreateProjectInput{
+		Title:       repo.Name,
+		Description: description,
+		Content:     content,
+		Tags:        append([]string{}, repo.Topics...),
+		Source:      sourceOf(repo, now),
+	}
+}
+
+// sourceOf describes a repository as the source of a project
+func sourceOf(repo Repo, now time.Time) *portfolio.ProjectSource {
+	return &portfolio.ProjectSource{
+		Provider:   Provider,
+		ExternalID: externalID(repo),
+		URL:        repo.HTMLURL,
+		Stars:      repo.Stars,
+		Language:   repo.Language,
+		SyncedAt:   now,
+	}
+}
+
+// sourceChanged reports whether a sync changes the source of a project
+func sourceChanged(current, synced portfolio.ProjectSource) bool {
+	return current.URL != synced.URL || current.Stars != synced.Stars || current.Language != synced.Language
+}
+
+// externalID identifies a repository; unlike its name, the ID survives
+// renames
+func externalID(repo Repo) string {
+	return strconv.FormatInt(repo.ID, 10)
+}
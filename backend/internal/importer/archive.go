@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown import provider")
+	ErrInvalidArchive  = errors.New("file is not a valid export archive")
+	ErrNoProjects      = errors.New("archive contains no projects")
+)
+
+const (
+	// maxJSONSize bounds the JSON files read from an archive
+	maxJSONSize = 32 << 20
+	// maxMediaSize bounds a single media file imported from an archive
+	maxMediaSize = 100 << 20
+)
+
+// Parser reads the projects of an export archive, in the order the platform
+// shows them
+type Parser func(archive *zip.Reader) ([]Item, error)
+
+// parsers maps providers to the parsers of their exports
+var parsers = map[string]Parser{
+	ProviderBehance:  ParseBehance,
+	ProviderDribbble: ParseDribbble,
+}
+
+// jsonFile is a JSON document of an archive
+type jsonFile struct {
+	name string
+	data []byte
+}
+
+// readJSONFiles reads the JSON files of an archive, sorted by path
+func readJSONFiles(archive *zip.Reader) ([]jsonFile, error) {
+	files := []jsonFile{}
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || isMetadata(file.Name) || !strings.EqualFold(path.Ext(file.Name), ".json") {
+			continue
+		}
+		if file.UncompressedSize64 > maxJSONSize {
+			return nil, ErrInvalidArchive
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, ErrInvalidArchive
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxJSONSize))
+		rc.Close()
+		if err != nil {
+			return nil, ErrInvalidArchive
+		}
+		files = append(files, jsonFile{name: file.Name, data: data})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// isMetadata reports whether a file was added to the archive by the
+// operating system rather than the export
+func isMetadata(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._")
+}
+
+// archiveFiles indexes the files of an archive to find the media items
+// refer to
+type archiveFiles struct {
+	byPath map[string]*zip.File
+	byName map[string][]*zip.File
+}
+
+// indexFiles indexes the files of an archive by path and by name
+func indexFiles(archive *zip.Reader) archiveFiles {
+	files := archiveFiles{byPath: map[string]*zip.File{}, byName: map[string][]*zip.File{}}
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || isMetadata(file.Name) {
+			continue
+		}
+		files.byPath[path.Clean(file.Name)] = file
+		name := strings.ToLower(path.Base(file.Name))
+		files.byName[name] = append(files.byName[name], file)
+	}
+	return files
+}
+
+// find finds the file a media reference points to. References are paths
+// inside the archive or the URLs files were published under, whose names
+// are matched instead. When several files share the name, the one in a
+// directory named after the item is preferred.
+func (f archiveFiles) find(ref, externalID string) *zip.File {
+	name := ref
+	if u, err := url.Parse(ref); err == nil && u.Scheme != "" {
+		name = u.Path
+	}
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if file, ok := f.byPath[name]; ok {
+		return file
+	}
+
+	candidates := f.byName[strings.ToLower(path.Base(name))]
+	if len(candidates) == 0 {
+		return nil
+	}
+	if externalID != "" {
+		for _, file := range candidates {
+			for _, dir := range strings.Split(path.Dir(file.Name), "/") {
+				if dir == externalID {
+					return file
+				}
+			}
+		}
+	}
+	return candidates[0]
+}
+
+// mediaTypeOf returns the media type of a file by its extension; empty if
+// the file can't be imported
+func mediaTypeOf(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		return "image"
+	case ".mp4", ".webm", ".mov":
+		return "video"
+	}
+	return ""
+}
+
+// externalID is an ID exports write either as a number or as a string
+type externalID string
+
+// UnmarshalJSON accepts JSON numbers and strings
+func (id *externalID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = externalID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = externalID(n.String())
+	return nil
+}
+
+// names is a list exports write either as strings or as objects with a
+// name
+type names []string
+
+// UnmarshalJSON accepts arrays of strings and of objects with a name
+func (n *names) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*n = names{}
+	for _, item := range raw {
+		var name string
+		if err := json.Unmarshal(item, &name); err != nil {
+			var object struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(item, &object); err != nil {
+				return err
+			}
+			name = object.Name
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			*n = append(*n, name)
+		}
+	}
+	return nil
+}
+
+var (
+	breakTag = regexp.MustCompile(`(?i)<br\s*/?>|</li>`)
+	blockEnd = regexp.MustCompile(`(?i)</(p|div|h[1-6]|ul|ol)>`)
+	anyTag   = regexp.MustCompile(`<[^>]*>`)
+	blankRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// plainText turns the HTML descriptions of exports into plain text,
+// keeping paragraphs apart
+func plainText(s string) string {
+	s = breakTag.ReplaceAllString(s, "\n")
+	s = blockEnd.ReplaceAllString(s, "\n\n")
+	s = anyTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankRun.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// mergeTags joins tag lists, dropping duplicates regardless of case
+func mergeTags(lists ...[]string) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, list := range lists {
+		for _, tag := range list {
+			key := strings.ToLower(tag)
+			if tag == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// behanceProject is a project as the Behance API and data export describe
+// it
+type behanceProject struct {
+	ID          externalID      `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	URL         string          `json:"url"`
+	PublishedOn int64           `json:"published_on"`
+	CreatedOn   int64           `json:"created_on"`
+	Fields      names           `json:"fields"`
+	Tags        names           `json:"tags"`
+	Modules     []behanceModule `json:"modules"`
+}
+
+// behanceModule is a block of a Behance project: an image, a video, a text
+// or a collection of images
+type behanceModule struct {
+	Type         string            `json:"type"`
+	Src          string            `json:"src"`
+	Sizes        map[string]string `json:"sizes"`
+	Caption      string            `json:"caption"`
+	CaptionPlain string            `json:"caption_plain"`
+	Text         string            `json:"text"`
+	TextPlain    string            `json:"text_plain"`
+	Components   []behanceModule   `json:"components"`
+}
+
+// behanceDocument is a JSON file of a Behance export: a single project,
+// possibly wrapped, or a list of projects
+type behanceDocument struct {
+	Project  *behanceProject  `json:"project"`
+	Projects []behanceProject `json:"projects"`
+}
+
+// ParseBehance reads the projects of a Behance export: JSON files holding
+// a project, {"project": ...}, or a list of them, {"projects": [...]} or a
+// bare array, next to the project images. Lists keep their order; projects
+// exported one per file are ordered newest first, as on a Behance profile.
+func ParseBehance(archive *zip.Reader) ([]Item, error) {
+	files, err := readJSONFiles(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Item{}
+	listed := false
+	for _, file := range files {
+		projects, list := behanceProjects(file.data)
+		listed = listed || list
+		for _, project := range projects {
+			items = append(items, project.item())
+		}
+	}
+	if len(items) == 0 {
+		return nil, ErrNoProjects
+	}
+
+	if !listed {
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].PublishedAt.After(items[j].PublishedAt)
+		})
+	}
+	return items, nil
+}
+
+// behanceProjects reads the projects of a JSON file. Files that aren't
+// projects, like the profile, have none. It reports whether the file lists
+// projects.
+func behanceProjects(data []byte) ([]behanceProject, bool) {
+	var list []behanceProject
+	if err := json.Unmarshal(data, &list); err == nil {
+		return withID(list), true
+	}
+
+	var doc behanceDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false
+	}
+	switch {
+	case doc.Projects != nil:
+		return withID(doc.Projects), true
+	case doc.Project != nil:
+		return withID([]behanceProject{*doc.Project}), false
+	}
+
+	var project behanceProject
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, false
+	}
+	return withID([]behanceProject{project}), false
+}
+
+// withID drops the entries that aren't projects
+func withID(projects []behanceProject) []behanceProject {
+	kept := []behanceProject{}
+	for _, project := range projects {
+		if project.ID != "" && project.Name != "" {
+			kept = append(kept, project)
+		}
+	}
+	return kept
+}
+
+// item converts a Behance project; its text modules make up the content
+func (p behanceProject) item() Item {
+	item := Item{
+		ExternalID:  string(p.ID),
+		Title:       strings.TrimSpace(p.Name),
+		Description: plainText(p.Description),
+		URL:         p.URL,
+		Tags:        mergeTags(p.Tags, p.Fields),
+		Media:       []MediaRef{},
+	}
+	switch {
+	case p.PublishedOn > 0:
+		item.PublishedAt = time.Unix(p.PublishedOn, 0).UTC()
+	case p.CreatedOn > 0:
+		item.PublishedAt = time.Unix(p.CreatedOn, 0).UTC()
+	}
+
+	texts := []string{}
+	for _, module := range p.Modules {
+		switch module.Type {
+		case "text":
+			text := module.TextPlain
+			if text == "" {
+				text = plainText(module.Text)
+			}
+			if text = strings.TrimSpace(text); text != "" {
+				texts = append(texts, text)
+			}
+		case "media_collection":
+			for _, component := range module.Components {
+				if ref := component.ref(); ref.Ref != "" {
+					item.Media = append(item.Media, ref)
+				}
+			}
+		default:
+			if ref := module.ref(); ref.Ref != "" {
+				item.Media = append(item.Media, ref)
+			}
+		}
+	}
+	item.Content = strings.Join(texts, "\n\n")
+	return item
+}
+
+// ref references the file of an image or video module, preferring its
+// original size
+func (m behanceModule) ref() MediaRef {
+	ref := MediaRef{Ref: m.Src, Caption: m.CaptionPlain}
+	if original := m.Sizes["original"]; original != "" {
+		ref.Ref = original
+	}
+	if ref.Caption == "" {
+		ref.Caption = plainText(m.Caption)
+	}
+	return ref
+}
@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// dribbbleShot is a shot as the Dribbble API and data export describe it
+type dribbbleShot struct {
+	ID          externalID           `json:"id"`
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	HTMLURL     string               `json:"html_url"`
+	PublishedAt *time.Time           `json:"published_at"`
+	CreatedAt   *time.Time           `json:"created_at"`
+	Tags        names                `json:"tags"`
+	Images      map[string]string    `json:"images"`
+	Video       *dribbbleVideo       `json:"video"`
+	Attachments []dribbbleAttachment `json:"attachments"`
+}
+
+// dribbbleVideo is the video of an animated shot
+type dribbbleVideo struct {
+	URL string `json:"url"`
+}
+
+// dribbbleAttachment is an additional file of a shot
+type dribbbleAttachment struct {
+	URL string `json:"url"`
+}
+
+// dribbbleImageSizes are the image sizes of a shot, largest first
+var dribbbleImageSizes = []string{"four_x", "hidpi", "two_x", "normal", "one_x", "teaser"}
+
+// ParseDribbble reads the shots of a Dribbble export: a JSON file listing
+// them, as a bare array or {"shots": [...]}, next to the shot images.
+// Shots keep the order of the list, newest first.
+func ParseDribbble(archive *zip.Reader) ([]Item, error) {
+	files, err := readJSONFiles(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Item{}
+	for _, file := range files {
+		for _, shot := range dribbbleShots(file.data) {
+			items = append(items, shot.item())
+		}
+	}
+	if len(items) == 0 {
+		return nil, ErrNoProjects
+	}
+	return items, nil
+}
+
+// dribbbleShots reads the shots of a JSON file. Files that aren't shot
+// lists, like the profile, have none.
+func dribbbleShots(data []byte) []dribbbleShot {
+	var shots []dribbbleShot
+	if err := json.Unmarshal(data, &shots); err != nil {
+		var doc struct {
+			Shots []dribbbleShot `json:"shots"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil
+		}
+		shots = doc.Shots
+	}
+
+	kept := []dribbbleShot{}
+	for _, shot := range shots {
+		if shot.ID != "" && shot.Title != "" {
+			kept = append(kept, shot)
+		}
+	}
+	return kept
+}
+
+// item converts a Dribbble shot. Its video replaces the still image of an
+// animated shot; attachments follow.
+func (s dribbbleShot) item() Item {
+	item := Item{
+		ExternalID:  string(s.ID),
+		Title:       strings.TrimSpace(s.Title),
+		Description: plainText(s.Description),
+		URL:         s.HTMLURL,
+		Tags:        mergeTags(s.Tags),
+		Media:       []MediaRef{},
+	}
+	switch {
+	case s.PublishedAt != nil:
+		item.PublishedAt = s.PublishedAt.UTC()
+	case s.CreatedAt != nil:
+		item.PublishedAt = s.CreatedAt.UTC()
+	}
+
+	if s.Video != nil && s.Video.URL != "" {
+		item.Media = append(item.Media, MediaRef{Ref: s.Video.URL})
+	} else {
+		for _, size := range dribbbleImageSizes {
+			if image := s.Images[size]; image != "" {
+				item.Media = append(item.Media, MediaRef{Ref: image})
+				break
+			}
+		}
+	}
+	for _, attachment := range s.Attachments {
+		if attachment.URL != "" {
+			item.Media = append(item.Media, MediaRef{Ref: attachment.URL})
+		}
+	}
+	return item
+}
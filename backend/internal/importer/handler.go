@@ -0,0 +1,154 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+// Handler handles HTTP requests for Behance and Dribbble imports
+type Handler struct {
+	service        *Service
+	maxArchiveSize int64
+}
+
+// NewHandler creates a new import handler accepting archives of up to
+// maxArchiveSize bytes
+func NewHandler(service *Service, maxArchiveSize int64) *Handler {
+	return &Handler{
+		service:        service,
+		maxArchiveSize: maxArchiveSize,
+	}
+}
+
+// RegisterRoutes registers the import routes
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/portfolios/{id}/imports", h.ListJobs)
+	r.Post("/portfolios/{id}/imports", h.Import)
+	r.Get("/portfolios/{id}/imports/{jobID}", h.GetJob)
+}
+
+// Import handles starting the import of an export archive, uploaded as the
+// "file" form field, from the platform named by the "provider" form field
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxArchiveSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Archive is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := h.service.Start(r.Context(), portfolioID, userID, r.FormValue("provider"), file)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// ListJobs handles listing the recent imports into a portfolio
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jobs, err := h.service.ListJobs(r.Context(), portfolioID, userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetJob handles getting the progress of an import
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+	jobID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "jobID"))
+	if err != nil {
+		http.Error(w, "Invalid import job ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := h.service.GetJob(r.Context(), portfolioID, jobID, userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// writeError writes the response for a service error
+func writeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, portfolio.ErrPortfolioNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, portfolio.ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrUnknownProvider):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrInvalidArchive):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNoProjects):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.As(err, &tooLarge):
+		http.Error(w, "Archive is too large", http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newArchive builds a ZIP archive holding files with the given contents
+func newArchive(t *testing.T, files map[string]string) *zip.Reader {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func TestParseBehance(t *testing.T) {
+	archive := newArchive(t, map[string]string{
+		"profile.json": `{"user": {"id": 1, "username": "ada"}}`,
+		"projects/100.json": `{"project": {
+			"id": 100, "name": "Old Poster", "published_on": 1400000000,
+			"description": "<p>A poster.</p><p>Printed.</p>",
+			"fields": [{"id": 3, "name": "Print"}], "tags": ["poster", "print"],
+			"modules": [
+				{"type": "image", "src": "https://mir-s3-cdn-cf.behance.net/project_modules/disp/a.jpg",
+				 "sizes": {"original": "https://mir-s3-cdn-cf.behance.net/project_modules/source/a.jpg"},
+				 "caption_plain": "Front"},
+				{"type": "text", "text": "<b>Made</b> by hand"},
+				{"type": "media_collection", "components": [{"src": "images/b.png"}, {"src": "images/c.png"}]},
+				{"type": "embed", "src": ""}
+			]}}`,
+		"projects/200.json":  `{"id": "200", "name": "New Logo", "published_on": 1600000000}`,
+		"images/a.jpg":       "a",
+		"__MACOSX/._a.json":  "junk",
+		"projects/bad.json":  `not json`,
+		"images/readme.json": `{"note": "no projects here"}`,
+	})
+
+	items, err := ParseBehance(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Title != "New Logo" || items[1].Title != "Old Poster" {
+		t.Fatalf("items = %+v", items)
+	}
+
+	poster := items[1]
+	if poster.ExternalID != "100" || !poster.PublishedAt.Equal(time.Unix(1400000000, 0)) {
+		t.Errorf("poster = %+v", poster)
+	}
+	if poster.Description != "A poster.\n\nPrinted." || poster.Content != "Made by hand" {
+		t.Errorf("text = %q, %q", poster.Description, poster.Content)
+	}
+	// The "Print" field duplicates a tag
+	if strings.Join(poster.Tags, ",") != "poster,print" {
+		t.Errorf("tags = %v", poster.Tags)
+	}
+	if len(poster.Media) != 3 || poster.Media[0].Caption != "Front" || !strings.HasSuffix(poster.Media[0].Ref, "source/a.jpg") || poster.Media[2].Ref != "images/c.png" {
+		t.Errorf("media = %+v", poster.Media)
+	}
+
+	listed := newArchive(t, map[string]string{
+		"projects.json": `{"projects": [{"id": 1, "name": "First", "published_on": 1}, {"id": 2, "name": "Second", "published_on": 2}]}`,
+	})
+	if items, err := ParseBehance(listed); err != nil || items[0].Title != "First" {
+		t.Errorf("listed items = %+v, %v", items, err)
+	}
+
+	if _, err := ParseBehance(newArchive(t, map[string]string{"a.txt": "hi"})); !errors.Is(err, ErrNoProjects) {
+		t.Errorf("empty archive: %v", err)
+	}
+}
+
+func TestParseDribbble(t *testing.T) {
+	archive := newArchive(t, map[string]string{
+		"shots.json": `[
+			{"id": 2, "title": "Loader", "published_at": "2021-05-01T10:00:00Z",
+			 "description": "<p>Spinning &amp; bouncing</p>", "tags": ["motion"],
+			 "images": {"normal": "https://cdn.dribbble.com/2/shot.gif", "hidpi": "https://cdn.dribbble.com/2/shot@2x.gif"},
+			 "video": {"url": "https://cdn.dribbble.com/2/loader.mp4"}},
+			{"id": 1, "title": "Icons", "html_url": "https://dribbble.com/shots/1-Icons",
+			 "images": {"normal": "https://cdn.dribbble.com/1/shot.png", "teaser": "https://cdn.dribbble.com/1/teaser.png"},
+			 "attachments": [{"url": "https://cdn.dribbble.com/1/sheet.png"}]}
+		]`,
+	})
+
+	items, err := ParseDribbble(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Title != "Loader" || items[1].Title != "Icons" {
+		t.Fatalf("items = %+v", items)
+	}
+	if items[0].Description != "Spinning & bouncing" || items[0].PublishedAt.Year() != 2021 {
+		t.Errorf("loader = %+v", items[0])
+	}
+	if len(items[0].Media) != 1 || !strings.HasSuffix(items[0].Media[0].Ref, "loader.mp4") {
+		t.Errorf("loader media = %+v", items[0].Media)
+	}
+	if len(items[1].Media) != 2 || !strings.HasSuffix(items[1].Media[0].Ref, "1/shot.png") || items[1].URL != "https://dribbble.com/shots/1-Icons" {
+		t.Errorf("icons = %+v", items[1])
+	}
+}
+
+func TestFind(t *testing.T) {
+	files := indexFiles(newArchive(t, map[string]string{
+		"export/shots/1/shot.png": "1",
+		"export/shots/2/shot.png": "2",
+		"export/images/a.jpg":     "a",
+	}))
+
+	tests := []struct {
+		ref, id, want string
+	}{
+		{"export/images/a.jpg", "", "export/images/a.jpg"},
+		{"https://cdn.example.com/x/A.JPG", "", "export/images/a.jpg"},
+		{"https://cdn.example.com/users/2/shot.png", "2", "export/shots/2/shot.png"},
+		{"shot.png", "1", "export/shots/1/shot.png"},
+	}
+	for _, tt := range tests {
+		file := files.find(tt.ref, tt.id)
+		if file == nil || file.Name != tt.want {
+			t.Errorf("find(%q, %q) = %v, want %s", tt.ref, tt.id, file, tt.want)
+		}
+	}
+	if file := files.find("missing.png", ""); file != nil {
+		t.Errorf("find(missing) = %s", file.Name)
+	}
+}
+
+func TestProjectInput(t *testing.T) {
+	now := time.Now()
+	published := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	item := Item{
+		ExternalID:  "7",
+		Title:       "Poster",
+		Description: "Short.\n\nLonger story.",
+		URL:         "https://www.behance.net/gallery/7/Poster",
+		Tags:        []string{"print"},
+		PublishedAt: published,
+	}
+
+	input := projectInput(ProviderBehance, item, 3, now)
+	if input.Description != "Short." || input.Content != item.Description || input.Order != 3 {
+		t.Errorf("input = %+v", input)
+	}
+	if input.CreatedAt == nil || !input.CreatedAt.Equal(published) {
+		t.Errorf("created at = %v", input.CreatedAt)
+	}
+	if input.Source.Provider != ProviderBehance || input.Source.ExternalID != "7" || input.Source.URL != item.URL {
+		t.Errorf("source = %+v", input.Source)
+	}
+
+	bare := projectInput(ProviderDribbble, Item{ExternalID: "1", Title: "Icons"}, 0, now)
+	if bare.Description != "Icons" || bare.Content != "Icons" || bare.CreatedAt != nil {
+		t.Errorf("bare input = %+v", bare)
+	}
+}
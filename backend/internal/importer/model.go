@@ -0,0 +1,69 @@
+// Package importer imports the projects of Behance and Dribbble data
+// exports into portfolios. Imports run in the background and report their
+// progress as they go.
+package importer
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Platforms exports can be imported from. They are also the source
+// providers of the imported projects.
+const (
+	ProviderBehance  = "behance"
+	ProviderDribbble = "dribbble"
+)
+
+// Import job statuses
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Job is the import of an export archive into a portfolio
+type Job struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PortfolioID primitive.ObjectID `bson:"portfolioId" json:"portfolioId"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Provider    string             `bson:"provider" json:"provider"`
+	Status      string             `bson:"status" json:"status"`
+	// Total is the number of projects found in the archive
+	Total int `bson:"total" json:"total"`
+	// Processed is the number of projects handled so far
+	Processed int `bson:"processed" json:"processed"`
+	// Imported lists the IDs of the projects created
+	Imported []primitive.ObjectID `bson:"imported" json:"imported"`
+	// Skipped lists the titles of projects that were imported before
+	Skipped []string `bson:"skipped" json:"skipped"`
+	// Warnings lists media files that couldn't be imported
+	Warnings   []string   `bson:"warnings" json:"warnings"`
+	Error      string     `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time  `bson:"updatedAt" json:"updatedAt"`
+	FinishedAt *time.Time `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+}
+
+// Item is a project read from an export
+type Item struct {
+	ExternalID  string
+	Title       string
+	Description string
+	// Content is the long-form text of the project, if it has any
+	Content string
+	URL     string
+	Tags    []string
+	// PublishedAt is when the project was published; zero if unknown
+	PublishedAt time.Time
+	// Media are the project's images and videos, in the order shown
+	Media []MediaRef
+}
+
+// MediaRef is a media file of an item. Ref is a path inside the archive or
+// the URL the file was published under.
+type MediaRef struct {
+	Ref     string
+	Caption string
+}
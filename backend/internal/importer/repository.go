@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/musefolio/backend/internal/database"
+)
+
+// maxListedJobs is the number of recent import jobs listed per portfolio
+const maxListedJobs = 20
+
+// Repository handles import job data operations
+type Repository struct {
+	db   *database.DB
+	jobs *mongo.Collection
+}
+
+// NewRepository creates a new import job repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{
+		db:   db,
+		jobs: db.Collection(database.ImportJobsCollection),
+	}
+}
+
+// InsertJob records an import that is starting
+func (r *Repository) InsertJob(ctx context.Context, job *Job) error {
+	now := time.Now()
+	job.ID = primitive.NewObjectID()
+	job.Status = StatusRunning
+	job.Imported = []primitive.ObjectID{}
+	job.Skipped = []string{}
+	job.Warnings = []string{}
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	_, err := r.jobs.InsertOne(ctx, job)
+	return err
+}
+
+// UpdateProgress records the progress of a running import
+func (r *Repository) UpdateProgress(ctx context.Context, job *Job) error {
+	job.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"processed": job.Processed,
+		"imported":  job.Imported,
+		"skipped":   job.Skipped,
+		"warnings":  job.Warnings,
+		"updatedAt": job.UpdatedAt,
+	}}
+	_, err := r.jobs.UpdateOne(ctx, bson.M{"_id": job.ID, "status": StatusRunning}, update)
+	return err
+}
+
+// FinishJob records the outcome of a running import
+func (r *Repository) FinishJob(ctx context.Context, id primitive.ObjectID, status, message string) error {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":     status,
+		"error":      message,
+		"updatedAt":  now,
+		"finishedAt": now,
+	}}
+	_, err := r.jobs.UpdateOne(ctx, bson.M{"_id": id, "status": StatusRunning}, update)
+	return err
+}
+
+// FailRunningJobs marks imports started before a point in time that are
+// still recorded as running as failed
+func (r *Repository) FailRunningJobs(ctx context.Context, before time.Time, message string) (int64, error) {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":     StatusFailed,
+		"error":      message,
+		"updatedAt":  now,
+		"finishedAt": now,
+	}}
+	filter := bson.M{"status": StatusRunning, "createdAt": bson.M{"$lt": before}}
+	result, err := r.jobs.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// FindJob finds an import job of a portfolio
+func (r *Repository) FindJob(ctx context.Context, portfolioID, id primitive.ObjectID) (*Job, error) {
+	var job Job
+	err := r.jobs.FindOne(ctx, bson.M{"_id": id, "portfolioId": portfolioID}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindJobsByPortfolioID finds the most recent import jobs of a portfolio,
+// newest first
+func (r *Repository) FindJobsByPortfolioID(ctx context.Context, portfolioID primitive.ObjectID) ([]*Job, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(maxListedJobs)
+	cursor, err := r.jobs.Find(ctx, bson.M{"portfolioId": portfolioID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []*Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
@@ -0,0 +1,303 @@
+package importer
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+var ErrJobNotFound = errors.New("import job not found")
+
+// maxWarnings bounds the warnings recorded on a job
+const maxWarnings = 100
+
+// Service handles importing Behance and Dribbble exports
+type Service struct {
+	repo       *Repository
+	portfolios *portfolio.Service
+	timeout    time.Duration
+}
+
+// NewService creates a new import service. timeout bounds a single import.
+func NewService(repo *Repository, portfolios *portfolio.Service, timeout time.Duration) *Service {
+	return &Service{
+		repo:       repo,
+		portfolios: portfolios,
+		timeout:    timeout,
+	}
+}
+
+// Start reads the projects of an export archive from a provider and imports
+// them into a portfolio of the user in the background. The archive is kept
+// in a temporary file until the import is done. The returned job reports
+// the import's progress.
+func (s *Service) Start(ctx context.Context, portfolioID, userID primitive.ObjectID, provider string, archive io.Reader) (*Job, error) {
+	parse, ok := parsers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "musefolio-import-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	name := file.Name()
+	_, err = io.Copy(file, archive)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name)
+		return nil, err
+	}
+
+	reader, err := zip.OpenReader(name)
+	if err != nil {
+		os.Remove(name)
+		return nil, ErrInvalidArchive
+	}
+	discard := func() {
+		reader.Close()
+		os.Remove(name)
+	}
+
+	items, err := parse(&reader.Reader)
+	if err != nil {
+		discard()
+		return nil, err
+	}
+
+	job := &Job{
+		PortfolioID: portfolioID,
+		UserID:      userID,
+		Provider:    provider,
+		Total:       len(items),
+	}
+	if err := s.repo.InsertJob(ctx, job); err != nil {
+		discard()
+		return nil, err
+	}
+
+	started := *job
+	go func() {
+		defer discard()
+		s.run(job, &reader.Reader, items)
+	}()
+
+	return &started, nil
+}
+
+// ListJobs lists the recent imports into a portfolio
+func (s *Service) ListJobs(ctx context.Context, portfolioID, userID primitive.ObjectID) ([]*Job, error) {
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindJobsByPortfolioID(ctx, portfolioID)
+}
+
+// GetJob gets an import into a portfolio
+func (s *Service) GetJob(ctx context.Context, portfolioID, jobID, userID primitive.ObjectID) (*Job, error) {
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+
+	job, err := s.repo.FindJob(ctx, portfolioID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// FailStale marks imports that outlived the import timeout as failed. They
+// were left running by a process that stopped before they finished.
+func (s *Service) FailStale(ctx context.Context, now time.Time) error {
+	failed, err := s.repo.FailRunningJobs(ctx, now.Add(-s.timeout), "import was interrupted")
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		slog.Info("failed interrupted imports", "count", failed)
+	}
+	return nil
+}
+
+// run performs an import and records its outcome
+func (s *Service) run(job *Job, archive *zip.Reader, items []Item) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	status, message := StatusSucceeded, ""
+	if err := s.importItems(ctx, job, archive, items); err != nil {
+		status, message = StatusFailed, err.Error()
+		slog.Warn("import failed",
+			"jobId", job.ID.Hex(),
+			"portfolioId", job.PortfolioID.Hex(),
+			"provider", job.Provider,
+			"error", err,
+		)
+	}
+
+	// The import context may have run out; recording the outcome still has
+	// to happen
+	if err := s.repo.FinishJob(context.Background(), job.ID, status, message); err != nil {
+		slog.Error("failed to record import outcome", "jobId", job.ID.Hex(), "error", err)
+	}
+}
+
+// importItems adds the items to the portfolio as projects after its
+// existing ones, in order, recording progress after each. Items imported
+// before are skipped.
+func (s *Service) importItems(ctx context.Context, job *Job, archive *zip.Reader, items []Item) error {
+	p, err := s.portfolios.GetByID(ctx, job.PortfolioID)
+	if err != nil {
+		return err
+	}
+	existing, err := s.portfolios.ListProjectsBySource(ctx, job.UserID, job.Provider)
+	if err != nil {
+		return err
+	}
+	imported := make(map[string]bool, len(existing))
+	for _, project := range existing {
+		imported[project.Source.ExternalID] = true
+	}
+
+	files := indexFiles(archive)
+	order := len(p.Projects)
+	now := time.Now()
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if imported[item.ExternalID] {
+			job.Skipped = append(job.Skipped, item.Title)
+		} else {
+			project, _, err := s.portfolios.AddProject(ctx, job.PortfolioID, job.UserID, portfolio.AnyVersion, projectInput(job.Provider, item, order, now))
+			switch {
+			case errors.Is(err, portfolio.ErrProjectImported):
+				// Imported by a concurrent job
+				job.Skipped = append(job.Skipped, item.Title)
+			case err != nil:
+				return err
+			default:
+				order++
+				job.Imported = append(job.Imported, project.ID)
+				for _, ref := range item.Media {
+					if err := s.addMedia(ctx, job, project.ID, files, item, ref); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		job.Processed++
+		if err := s.repo.UpdateProgress(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addMedia stores a media file of an item with its project. Files that are
+// missing from the archive or can't be used are recorded as warnings
+// rather than failing the import.
+func (s *Service) addMedia(ctx context.Context, job *Job, projectID primitive.ObjectID, files archiveFiles, item Item, ref MediaRef) error {
+	file := files.find(ref.Ref, item.ExternalID)
+	switch {
+	case file == nil:
+		job.warn("%s: %s is not in the archive", item.Title, ref.Ref)
+		return nil
+	case mediaTypeOf(file.Name) == "":
+		job.warn("%s: %s is not an image or video", item.Title, file.Name)
+		return nil
+	case file.UncompressedSize64 > maxMediaSize:
+		job.warn("%s: %s is too large", item.Title, file.Name)
+		return nil
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		job.warn("%s: %s can't be read", item.Title, file.Name)
+		return nil
+	}
+	defer rc.Close()
+
+	input := portfolio.UploadMediaInput{Type: mediaTypeOf(file.Name), Caption: ref.Caption}
+	_, err = s.portfolios.AddMedia(ctx, job.PortfolioID, projectID, job.UserID, portfolio.AnyVersion, input, path.Base(file.Name), io.LimitReader(rc, maxMediaSize))
+	if errors.Is(err, portfolio.ErrInvalidMediaType) {
+		job.warn("%s: %s is not an image or video", item.Title, file.Name)
+		return nil
+	}
+	return err
+}
+
+// warn records a warning on the job, up to maxWarnings
+func (j *Job) warn(format string, args ...any) {
+	if len(j.Warnings) < maxWarnings {
+		j.Warnings = append(j.Warnings, fmt.Sprintf(format, args...))
+	}
+}
+
+// projectInput builds the project of an item. Its description is the first
+// paragraph of the item's; the rest falls back to what the item has.
+func projectInput(provider string, item Item, order int, now time.Time) portfolio.CreateProjectInput {
+	description, _, _ := strings.Cut(item.Description, "\n\n")
+	if description == "" {
+		description = item.Title
+	}
+	content := item.Content
+	if content == "" {
+		content = item.Description
+	}
+	if content == "" {
+		content = description
+	}
+
+	input := portfolio.CreateProjectInput{
+		Title:       item.Title,
+		Description: description,
+		Content:     content,
+		Tags:        append([]string{}, item.Tags...),
+		Order:       order,
+		Source: &portfolio.ProjectSource{
+			Provider:   provider,
+			ExternalID: item.ExternalID,
+			URL:        item.URL,
+			SyncedAt:   now,
+		},
+	}
+	if !item.PublishedAt.IsZero() {
+		publishedAt := item.PublishedAt
+		input.CreatedAt = &publishedAt
+	}
+	return input
+}
+
+// getOwned loads a portfolio and checks that it belongs to the user
+func (s *Service) getOwned(ctx context.Context, portfolioID, userID primitive.ObjectID) (*portfolio.Portfolio, error) {
+	p, err := s.portfolios.GetByID(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserID != userID {
+		return nil, portfolio.ErrUnauthorized
+	}
+	return p, nil
+}
@@ -0,0 +1,128 @@
+// Package markdown defines the format of long-form text: CommonMark with
+// GitHub Flavored Markdown tables, strikethrough, autolinks and task lists.
+// Raw HTML is limited to an allowlist, both when text is written and when
+// it is rendered.
+package markdown
+
+import (
+	"bytes"
+	stdhtml "html"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// converter parses and renders Markdown. Raw HTML is passed through to the
+// output, which is sanitized with policy afterwards.
+var converter = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+	),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// policy is the allowlist of HTML elements and attributes: the usual user
+// content ones, plus what tables, code blocks and task lists render to
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Sanitize removes the HTML the allowlist doesn't allow from Markdown
+// source. Everything else is kept as written, so the source stays
+// editable.
+func Sanitize(source string) string {
+	src := []byte(source)
+	doc := converter.Parser().Parse(text.NewReader(src))
+
+	spans := []text.Segment{}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.HTMLBlock:
+			spans = append(spans, htmlBlockSpans(n)...)
+		case *ast.RawHTML:
+			if n.Segments.Len() > 0 {
+				first, last := n.Segments.At(0), n.Segments.At(n.Segments.Len()-1)
+				spans = append(spans, text.NewSegment(first.Start, last.Stop))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(spans) == 0 {
+		return source
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span.Start < last {
+			continue
+		}
+		b.Write(src[last:span.Start])
+		b.WriteString(policy.Sanitize(string(span.Value(src))))
+		last = span.Stop
+	}
+	b.Write(src[last:])
+	return b.String()
+}
+
+// htmlBlockSpans returns the source of an HTML block to sanitize. A block
+// at the top level is sanitized as a whole; inside lists and quotes its
+// lines are interleaved with their markers, so they are sanitized one by
+// one.
+func htmlBlockSpans(n *ast.HTMLBlock) []text.Segment {
+	lines := n.Lines()
+	spans := make([]text.Segment, 0, lines.Len()+1)
+	for i := 0; i < lines.Len(); i++ {
+		spans = append(spans, lines.At(i))
+	}
+	if n.HasClosure() {
+		spans = append(spans, n.ClosureLine)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	if _, ok := n.Parent().(*ast.Document); ok {
+		return []text.Segment{text.NewSegment(spans[0].Start, spans[len(spans)-1].Stop)}
+	}
+	return spans
+}
+
+// Render converts Markdown to HTML that is safe to embed in a page
+func Render(source string) template.HTML {
+	if strings.TrimSpace(source) == "" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := converter.Convert([]byte(source), &buf); err != nil {
+		return template.HTML("<p>" + template.HTMLEscapeString(source) + "</p>")
+	}
+	return template.HTML(policy.SanitizeBytes(buf.Bytes()))
+}
+
+// stripAll removes every element, keeping the text
+var stripAll = bluemonday.StrictPolicy()
+
+// PlainText returns the text of Markdown without its markup
+func PlainText(source string) string {
+	return strings.TrimSpace(stdhtml.UnescapeString(stripAll.Sanitize(string(Render(source)))))
+}
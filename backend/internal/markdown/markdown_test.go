@@ -0,0 +1,50 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, source, want string
+	}{
+		{"plain markdown", "# Title\n\n*a* < b & `<c>`", "# Title\n\n*a* < b & `<c>`"},
+		{"script block", "Intro\n\n<script>alert(1)</script>\n\nOutro", "Intro\n\n\n\nOutro"},
+		{"inline attributes", "Some <b onclick=\"steal()\">bold</b> text", "Some <b>bold</b> text"},
+		{"quoted block", "> <div onclick=\"x\">\n> hi\n> </div>", "> <div>\n> hi\n> </div>"},
+		{"code is left alone", "```html\n<script>alert(1)</script>\n```", "```html\n<script>alert(1)</script>\n```"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.source); got != tt.want {
+			t.Errorf("%s: Sanitize() = %q, want %q", tt.name, got, tt.want)
+		}
+		if got := Sanitize(Sanitize(tt.source)); got != tt.want {
+			t.Errorf("%s: Sanitize() is not idempotent: %q", tt.name, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	html := string(Render("| a | b |\n|:--|--:|\n| 1 | 2 |\n\n```go\nx := \"<y>\"\n```\n\n- [x] done\n\n[link](javascript:alert(1)) <img src=x onerror=alert(1)>"))
+
+	for _, want := range []string{
+		`<th align="left">a</th>`,
+		`<code class="language-go">x := &#34;&lt;y&gt;&#34;`,
+		`<input checked="" disabled="" type="checkbox"> done`,
+		`<img src="x">`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered HTML lacks %s:\n%s", want, html)
+		}
+	}
+	for _, unwanted := range []string{"javascript:", "onerror"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("rendered HTML contains %s:\n%s", unwanted, html)
+		}
+	}
+
+	if Render("  \n") != "" {
+		t.Error("blank text rendered")
+	}
+}
@@ -0,0 +1,264 @@
+// Package pagination pages list endpoints with opaque cursors. A cursor
+// holds the sort value and _id of the last item of a page, and the next
+// page starts after it, which stays fast on large collections where
+// skipping doesn't.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// Query parameters
+const (
+	LimitParam  = "limit"
+	SortParam   = "sort"
+	CursorParam = "cursor"
+)
+
+// Filter types
+const (
+	FilterString   = "string"
+	FilterBool     = "bool"
+	FilterObjectID = "objectId"
+)
+
+// Sort is an order a list can be requested in. Ties are broken by _id.
+type Sort struct {
+	// Name is the value of the sort parameter selecting the order
+	Name  string
+	Field string
+	Desc  bool
+}
+
+// Filter is a query parameter filtering a list on a field by equality
+type Filter struct {
+	Param string
+	Field string
+	Type  string
+}
+
+// Options describe the pages of a list endpoint
+type Options struct {
+	DefaultLimit int
+	MaxLimit     int
+	// Sorts are the orders the list can be requested in. The first one is
+	// the default.
+	Sorts   []Sort
+	Filters []Filter
+}
+
+// Params are the page a list request asks for
+type Params struct {
+	Limit int
+	Sort  Sort
+	// After is the position the page starts after, nil on the first page
+	After *Cursor
+	// Filters are the equality filters of the request, by field
+	Filters bson.M
+}
+
+// Cursor is the position of an item in an order
+type Cursor struct {
+	Sort  string             `bson:"s"`
+	Value bson.RawValue      `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// cursorTypes are the types of sort values a cursor may hold
+var cursorTypes = map[bsontype.Type]bool{
+	bsontype.DateTime: true,
+	bsontype.Int32:    true,
+	bsontype.Int64:    true,
+	bsontype.Double:   true,
+	bsontype.String:   true,
+	bsontype.ObjectID: true,
+}
+
+// Parse reads the limit, sort, cursor and filter query parameters of a
+// list request. Limits above the maximum are capped.
+func (o Options) Parse(values url.Values) (Params, error) {
+	p := Params{Limit: o.DefaultLimit, Sort: o.Sorts[0], Filters: bson.M{}}
+
+	if value := values.Get(LimitParam); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return p, ErrInvalidLimit
+		}
+		p.Limit = min(limit, o.MaxLimit)
+	}
+
+	if name := values.Get(SortParam); name != "" {
+		found := false
+		for _, sort := range o.Sorts {
+			if sort.Name == name {
+				p.Sort, found = sort, true
+				break
+			}
+		}
+		if !found {
+			return p, ErrInvalidSort
+		}
+	}
+
+	if value := values.Get(CursorParam); value != "" {
+		c, err := decodeCursor(value)
+		// A cursor only continues the order it was made for
+		if err != nil || c.Sort != p.Sort.Name || !cursorTypes[c.Value.Type] {
+			return p, ErrInvalidCursor
+		}
+		p.After = &c
+	}
+
+	for _, f := range o.Filters {
+		value := values.Get(f.Param)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseFilter(f.Type, value)
+		if err != nil {
+			return p, fmt.Errorf("%w: %s", ErrInvalidFilter, f.Param)
+		}
+		p.Filters[f.Field] = parsed
+	}
+
+	return p, nil
+}
+
+// parseFilter converts a filter value to its type
+func parseFilter(typ, value string) (interface{}, error) {
+	switch typ {
+	case FilterBool:
+		return strconv.ParseBool(value)
+	case FilterObjectID:
+		return primitive.ObjectIDFromHex(value)
+	default:
+		return value, nil
+	}
+}
+
+// Query returns a copy of filter with the request's filters and the
+// position of its cursor added
+func (p Params) Query(filter bson.M) bson.M {
+	query := bson.M{}
+	for key, value := range filter {
+		query[key] = value
+	}
+	for field, value := range p.Filters {
+		query[field] = value
+	}
+	if p.After == nil {
+		return query
+	}
+
+	op := "$gt"
+	if p.Sort.Desc {
+		op = "$lt"
+	}
+	var after bson.M
+	if p.Sort.Field == "_id" {
+		after = bson.M{"_id": bson.M{op: p.After.ID}}
+	} else {
+		// $eq keeps a crafted value from being read as an operator
+		after = bson.M{"$or": bson.A{
+			bson.M{p.Sort.Field: bson.M{op: p.After.Value}},
+			bson.M{p.Sort.Field: bson.M{"$eq": p.After.Value}, "_id": bson.M{op: p.After.ID}},
+		}}
+	}
+	and, _ := query["$and"].(bson.A)
+	query["$and"] = append(append(bson.A{}, and...), after)
+	return query
+}
+
+// FindOptions returns the order of the page and a limit of one more item
+// than it holds, which tells whether there is a next page
+func (p Params) FindOptions() *options.FindOptions {
+	dir := 1
+	if p.Sort.Desc {
+		dir = -1
+	}
+	sort := bson.D{{Key: p.Sort.Field, Value: dir}}
+	if p.Sort.Field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: dir})
+	}
+	return options.Find().SetSort(sort).SetLimit(int64(p.Limit) + 1)
+}
+
+// Trim cuts the items found with FindOptions down to the page and returns
+// the cursor of the next page, or "" on the last page. Items are documents
+// with an _id and the sort field.
+func Trim[T any](items []T, p Params) ([]T, string, error) {
+	if len(items) <= p.Limit {
+		return items, "", nil
+	}
+	items = items[:p.Limit]
+
+	doc, err := bson.Marshal(items[p.Limit-1])
+	if err != nil {
+		return nil, "", err
+	}
+	id, ok := bson.Raw(doc).Lookup("_id").ObjectIDOK()
+	if !ok {
+		return nil, "", errors.New("pagination: item has no _id")
+	}
+	value, err := bson.Raw(doc).LookupErr(strings.Split(p.Sort.Field, ".")...)
+	if err != nil {
+		return nil, "", fmt.Errorf("pagination: item has no %s: %w", p.Sort.Field, err)
+	}
+
+	next, err := encodeCursor(Cursor{Sort: p.Sort.Name, Value: value, ID: id})
+	if err != nil {
+		return nil, "", err
+	}
+	return items, next, nil
+}
+
+// SetNext points the response at the next page with a Link header and the
+// X-Next-Cursor header. It does nothing on the last page.
+func SetNext(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	query := r.URL.Query()
+	query.Set(CursorParam, next)
+	link := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, link.String()))
+	w.Header().Set("X-Next-Cursor", next)
+}
+
+// encodeCursor encodes a cursor as an opaque string
+func encodeCursor(c Cursor) (string, error) {
+	data, err := bson.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes a cursor encoded by encodeCursor
+func decodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = bson.Unmarshal(data, &c)
+	return c, err
+}
@@ -0,0 +1,126 @@
+package pagination
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testOptions = Options{
+	DefaultLimit: 10,
+	MaxLimit:     50,
+	Sorts: []Sort{
+		{Name: "-createdAt", Field: "createdAt", Desc: true},
+		{Name: "title", Field: "title"},
+	},
+	Filters: []Filter{
+		{Param: "published", Field: "isPublished", Type: FilterBool},
+		{Param: "owner", Field: "userId", Type: FilterObjectID},
+	},
+}
+
+type item struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Title     string             `bson:"title"`
+	CreatedAt time.Time          `bson:"createdAt"`
+}
+
+func TestParse(t *testing.T) {
+	p, err := testOptions.Parse(url.Values{})
+	if err != nil || p.Limit != 10 || p.Sort.Name != "-createdAt" || p.After != nil || len(p.Filters) != 0 {
+		t.Fatalf("defaults = %+v, %v", p, err)
+	}
+
+	owner := primitive.NewObjectID()
+	p, err = testOptions.Parse(url.Values{"limit": {"500"}, "sort": {"title"}, "published": {"true"}, "owner": {owner.Hex()}, "other": {"x"}})
+	if err != nil || p.Limit != 50 || p.Sort.Field != "title" {
+		t.Fatalf("params = %+v, %v", p, err)
+	}
+	if p.Filters["isPublished"] != true || p.Filters["userId"] != owner || len(p.Filters) != 2 {
+		t.Errorf("filters = %v", p.Filters)
+	}
+
+	invalid := []struct {
+		values url.Values
+		want   error
+	}{
+		{url.Values{"limit": {"0"}}, ErrInvalidLimit},
+		{url.Values{"limit": {"ten"}}, ErrInvalidLimit},
+		{url.Values{"sort": {"password"}}, ErrInvalidSort},
+		{url.Values{"cursor": {"garbage!"}}, ErrInvalidCursor},
+		{url.Values{"published": {"maybe"}}, ErrInvalidFilter},
+		{url.Values{"owner": {"nope"}}, ErrInvalidFilter},
+	}
+	for _, tt := range invalid {
+		if _, err := testOptions.Parse(tt.values); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%v) = %v, want %v", tt.values, err, tt.want)
+		}
+	}
+}
+
+func TestTrimContinuesAfterLastItem(t *testing.T) {
+	p, _ := testOptions.Parse(url.Values{"limit": {"2"}})
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	items := []item{
+		{ID: primitive.NewObjectID(), CreatedAt: created.Add(time.Hour)},
+		{ID: primitive.NewObjectID(), CreatedAt: created},
+		{ID: primitive.NewObjectID(), CreatedAt: created.Add(-time.Hour)},
+	}
+
+	page, next, err := Trim(items, p)
+	if err != nil || len(page) != 2 || next == "" {
+		t.Fatalf("Trim = %v, %q, %v", page, next, err)
+	}
+	if _, last, _ := Trim(items[:2], p); last != "" {
+		t.Errorf("last page cursor = %q", last)
+	}
+
+	p, err = testOptions.Parse(url.Values{"limit": {"2"}, "cursor": {next}})
+	if err != nil {
+		t.Fatalf("Parse(cursor) = %v", err)
+	}
+	if p.After.ID != items[1].ID || !p.After.Value.Time().Equal(created) {
+		t.Errorf("cursor = %+v", p.After)
+	}
+
+	query := p.Query(bson.M{"userId": "u"})
+	and := query["$and"].(bson.A)
+	after := and[0].(bson.M)["$or"].(bson.A)
+	if query["userId"] != "u" || len(after) != 2 {
+		t.Errorf("query = %v", query)
+	}
+	if _, ok := after[0].(bson.M)["createdAt"].(bson.M)["$lt"]; !ok {
+		t.Errorf("descending order continues with $lt: %v", after[0])
+	}
+
+	// A cursor doesn't carry over to another order
+	if _, err := testOptions.Parse(url.Values{"sort": {"title"}, "cursor": {next}}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor of another sort = %v", err)
+	}
+}
+
+func TestSetNext(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/portfolios?limit=5&cursor=old", nil)
+	w := httptest.NewRecorder()
+	SetNext(w, r, "abc")
+
+	link := w.Header().Get("Link")
+	if !strings.HasPrefix(link, "</api/v1/portfolios?") || !strings.Contains(link, "cursor=abc") || !strings.Contains(link, "limit=5") || !strings.HasSuffix(link, `>; rel="next"`) {
+		t.Errorf("Link = %s", link)
+	}
+	if w.Header().Get("X-Next-Cursor") != "abc" {
+		t.Errorf("X-Next-Cursor = %s", w.Header().Get("X-Next-Cursor"))
+	}
+
+	w = httptest.NewRecorder()
+	SetNext(w, r, "")
+	if w.Header().Get("Link") != "" {
+		t.Errorf("Link on last page = %s", w.Header().Get("Link"))
+	}
+}
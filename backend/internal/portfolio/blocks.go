@@ -0,0 +1,146 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/block"
+)
+
+// blockEditAttempts is how often a canvas edit without a version is retried
+// on top of concurrent changes before giving up
+const blockEditAttempts = 3
+
+// AddBlock adds a block, with any children, to a project canvas
+func (s *Service) AddBlock(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64, input CreateBlockInput) (*block.Block, int64, error) {
+	added := input.Block
+	block.AssignIDs(&added)
+
+	index := -1
+	if input.Index != nil {
+		index = *input.Index
+	}
+
+	updated, err := s.editBlocks(ctx, portfolioID, projectID, userID, version, "block.add", func(blocks []block.Block) ([]block.Block, error) {
+		return block.Insert(blocks, input.ParentID, index, added)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return block.Find(updated.findProject(projectID).Blocks, added.ID), updated.Version, nil
+}
+
+// UpdateBlock updates the props or layout of a block
+func (s *Service) UpdateBlock(ctx context.Context, portfolioID, projectID, blockID primitive.ObjectID, userID primitive.ObjectID, version int64, input UpdateBlockInput) (*block.Block, int64, error) {
+	updated, err := s.editBlocks(ctx, portfolioID, projectID, userID, version, "block.update", func(blocks []block.Block) ([]block.Block, error) {
+		b := block.Find(blocks, blockID)
+		if b == nil {
+			return nil, fmt.Errorf("%w: %s", block.ErrNotFound, blockID.Hex())
+		}
+		if input.Props != nil {
+			b.Props = *input.Props
+		}
+		if input.Layout != nil {
+			b.Layout = input.Layout
+		}
+		return blocks, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return block.Find(updated.findProject(projectID).Blocks, blockID), updated.Version, nil
+}
+
+// DeleteBlock removes a block, with its children, from a project canvas
+func (s *Service) DeleteBlock(ctx context.Context, portfolioID, projectID, blockID primitive.ObjectID, userID primitive.ObjectID, version int64) (int64, error) {
+	updated, err := s.editBlocks(ctx, portfolioID, projectID, userID, version, "block.delete", func(blocks []block.Block) ([]block.Block, error) {
+		blocks, _, err := block.Remove(blocks, blockID)
+		return blocks, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated.Version, nil
+}
+
+// MoveBlock moves a block to another position or container of the canvas
+func (s *Service) MoveBlock(ctx context.Context, portfolioID, projectID, blockID primitive.ObjectID, userID primitive.ObjectID, version int64, input MoveBlockInput) ([]block.Block, int64, error) {
+	updated, err := s.editBlocks(ctx, portfolioID, projectID, userID, version, "block.move", func(blocks []block.Block) ([]block.Block, error) {
+		return block.Move(blocks, blockID, input.ParentID, input.Index)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return updated.findProject(projectID).Blocks, updated.Version, nil
+}
+
+// ReplaceBlocks saves a whole canvas at once, as drag-and-drop editors do.
+// Blocks without an ID get a fresh one.
+func (s *Service) ReplaceBlocks(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64, input ReplaceBlocksInput) ([]block.Block, int64, error) {
+	updated, err := s.editBlocks(ctx, portfolioID, projectID, userID, version, "block.replace", func([]block.Block) ([]block.Block, error) {
+		blocks, err := block.Clone(input.Blocks)
+		if err != nil {
+			return nil, err
+		}
+		assignMissingIDs(blocks)
+		return blocks, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return updated.findProject(projectID).Blocks, updated.Version, nil
+}
+
+// editBlocks applies edit to a copy of a project canvas, validates the
+// result and stores it. Nested blocks can't be targeted by positional
+// updates, so the whole canvas is written back guarded by the portfolio
+// version and the project update time it was read at.
+func (s *Service) editBlocks(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64, action string, edit func([]block.Block) ([]block.Block, error)) (*Portfolio, error) {
+	for attempt := 1; ; attempt++ {
+		portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+		if err != nil {
+			return nil, err
+		}
+		project := portfolio.findProject(projectID)
+		if project == nil {
+			return nil, ErrProjectNotFound
+		}
+
+		blocks, err := block.Clone(project.Blocks)
+		if err != nil {
+			return nil, err
+		}
+		blocks, err = edit(blocks)
+		if err != nil {
+			return nil, err
+		}
+		if err := block.Validate(blocks); err != nil {
+			return nil, err
+		}
+
+		updated, err := s.repo.SetBlocks(ctx, portfolioID, projectID, portfolio.Version, project.UpdatedAt, blocks, action)
+		if err != nil {
+			return nil, err
+		}
+		if updated != nil {
+			return updated, nil
+		}
+
+		// A caller that didn't name a version doesn't mind which one its edit
+		// applies to, so it is replayed on top of the concurrent change
+		if version != AnyVersion || attempt == blockEditAttempts {
+			return nil, ErrVersionMismatch
+		}
+	}
+}
+
+func assignMissingIDs(blocks []block.Block) {
+	for i := range blocks {
+		if blocks[i].ID.IsZero() {
+			blocks[i].ID = primitive.NewObjectID()
+		}
+		assignMissingIDs(blocks[i].Children)
+	}
+}
@@ -0,0 +1,234 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/markdown"
+)
+
+// AddStage adds a stage to the case study of a project
+func (s *Service) AddStage(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64, input CreateStageInput) (*Stage, int64, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidStage, err)
+	}
+	if err := checkMetrics(input.Type, input.Metrics); err != nil {
+		return nil, 0, err
+	}
+
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return nil, 0, err
+	}
+	project, err := caseStudyProject(portfolio, projectID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Append after the existing stages unless an order was given
+	order := len(project.CaseStudy.Stages)
+	if input.Order != nil {
+		order = *input.Order
+	}
+
+	now := time.Now()
+	stage := Stage{
+		ID:        primitive.NewObjectID(),
+		Type:      input.Type,
+		Title:     input.Title,
+		Body:      markdown.Sanitize(input.Body),
+		Metrics:   input.Metrics,
+		Media:     []Media{},
+		Order:     order,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	updated, err := s.repo.AddStage(ctx, portfolioID, projectID, version, stage)
+	if err != nil {
+		return nil, 0, err
+	}
+	if updated == nil {
+		return nil, 0, ErrVersionMismatch
+	}
+	return updated.findProject(projectID).findStage(stage.ID), updated.Version, nil
+}
+
+// UpdateStage updates a stage of a project's case study
+func (s *Service) UpdateStage(ctx context.Context, portfolioID, projectID, stageID primitive.ObjectID, userID primitive.ObjectID, version int64, input UpdateStageInput) (*Stage, int64, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidStage, err)
+	}
+
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return nil, 0, err
+	}
+	project, err := caseStudyProject(portfolio, projectID)
+	if err != nil {
+		return nil, 0, err
+	}
+	stage := project.findStage(stageID)
+	if stage == nil {
+		return nil, 0, ErrStageNotFound
+	}
+
+	// Metrics are checked against the type the stage ends up with
+	stageType, metrics := stage.Type, stage.Metrics
+	if input.Type != nil {
+		stageType = *input.Type
+	}
+	if input.Metrics != nil {
+		metrics = *input.Metrics
+	}
+	if err := checkMetrics(stageType, metrics); err != nil {
+		return nil, 0, err
+	}
+	if input.Body != nil {
+		body := markdown.Sanitize(*input.Body)
+		input.Body = &body
+	}
+
+	updated, err := s.repo.UpdateStage(ctx, portfolioID, projectID, stageID, version, input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if updated == nil {
+		return nil, 0, ErrVersionMismatch
+	}
+	return updated.findProject(projectID).findStage(stageID), updated.Version, nil
+}
+
+// DeleteStage deletes a stage from a project's case study. Stored media files
+// are kept since earlier revisions may still reference them.
+func (s *Service) DeleteStage(ctx context.Context, portfolioID, projectID, stageID primitive.ObjectID, userID primitive.ObjectID, version int64) (int64, error) {
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return 0, err
+	}
+	project, err := caseStudyProject(portfolio, projectID)
+	if err != nil {
+		return 0, err
+	}
+	if project.findStage(stageID) == nil {
+		return 0, ErrStageNotFound
+	}
+
+	return versionOf(s.repo.DeleteStage(ctx, portfolioID, projectID, stageID, version))
+}
+
+// ReorderStages sets the order of a case study's stages to the order of ids
+func (s *Service) ReorderStages(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64, input ReorderInput) (*Portfolio, error) {
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return nil, err
+	}
+	project, err := caseStudyProject(portfolio, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make([]primitive.ObjectID, len(project.CaseStudy.Stages))
+	for i, stage := range project.CaseStudy.Stages {
+		existing[i] = stage.ID
+	}
+	if err := checkPermutation(existing, input.IDs); err != nil {
+		return nil, err
+	}
+
+	return portfolioOf(s.repo.ReorderStages(ctx, portfolioID, projectID, portfolio.Version, input.IDs))
+}
+
+// AddStageMedia uploads media to a case-study stage
+func (s *Service) AddStageMedia(ctx context.Context, portfolioID, projectID, stageID primitive.ObjectID, userID primitive.ObjectID, version int64, input UploadMediaInput, filename string, file io.Reader) (int64, error) {
+	if err := s.validate.Struct(input); err != nil {
+		return 0, err
+	}
+
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return 0, err
+	}
+	project, err := caseStudyProject(portfolio, projectID)
+	if err != nil {
+		return 0, err
+	}
+	stage := project.findStage(stageID)
+	if stage == nil {
+		return 0, ErrStageNotFound
+	}
+
+	order := len(stage.Media)
+	if input.Order != nil {
+		order = *input.Order
+	}
+
+	media, key, err := s.storeMedia(ctx, portfolioID, projectID, input, order, filename, file)
+	if err != nil {
+		return 0, err
+	}
+
+	newVersion, err := versionOf(s.repo.AddStageMedia(ctx, portfolioID, projectID, stageID, version, media))
+	if err != nil {
+		s.discardMedia(ctx, key)
+		return 0, err
+	}
+	return newVersion, nil
+}
+
+// DeleteStageMedia deletes media from a case-study stage. The stored file is
+// kept since earlier revisions may still reference it.
+func (s *Service) DeleteStageMedia(ctx context.Context, portfolioID, projectID, stageID, mediaID primitive.ObjectID, userID primitive.ObjectID, version int64) (int64, error) {
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return 0, err
+	}
+	project, err := caseStudyProject(portfolio, projectID)
+	if err != nil {
+		return 0, err
+	}
+	stage := project.findStage(stageID)
+	if stage == nil {
+		return 0, ErrStageNotFound
+	}
+
+	mediaExists := false
+	for _, media := range stage.Media {
+		if media.ID == mediaID {
+			mediaExists = true
+			break
+		}
+	}
+	if !mediaExists {
+		return 0, ErrMediaNotFound
+	}
+
+	return versionOf(s.repo.DeleteStageMedia(ctx, portfolioID, projectID, stageID, mediaID, version))
+}
+
+// caseStudyProject returns the project with the given ID if it is a case study
+func caseStudyProject(portfolio *Portfolio, projectID primitive.ObjectID) (*Project, error) {
+	project := portfolio.findProject(projectID)
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if !project.IsCaseStudy() {
+		return nil, ErrNotCaseStudy
+	}
+	if project.CaseStudy == nil {
+		project.CaseStudy = &CaseStudy{}
+	}
+	return project, nil
+}
+
+// checkMetrics rejects metrics on stages other than impact stages
+func checkMetrics(stageType string, metrics []Metric) error {
+	if len(metrics) > 0 && stageType != StageImpact {
+		return fmt.Errorf("%w: metrics are only allowed on impact stages", ErrInvalidStage)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package portfolio
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ignoredDiffKeys are bookkeeping fields that change on every mutation and
+// would otherwise drown out the meaningful changes, and renderings derived
+// from other fields
+var ignoredDiffKeys = map[string]bool{
+	"version":     true,
+	"updatedAt":   true,
+	"contentHtml": true,
+}
+
+// diffPortfolios compares two portfolio snapshots and returns the changed fields.
+// Arrays of objects carrying an "id" are matched by ID so that reordering or
+// inserting an element doesn't show up as a change to every following element.
+func diffPortfolios(from, to *Portfolio) ([]RevisionChange, error) {
+	a, err := toGeneric(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toGeneric(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []RevisionChange{}
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+// toGeneric converts a value to its JSON representation as maps and slices
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func diffValues(path string, a, b interface{}, changes *[]RevisionChange) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			diffObjects(path, av, bv, changes)
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			diffArrays(path, av, bv, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, changeFor(path, a, b))
+	}
+}
+
+func diffObjects(path string, a, b map[string]interface{}, changes *[]RevisionChange) {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		if !ignoredDiffKeys[k] {
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		diffValues(joinPath(path, k), a[k], b[k], changes)
+	}
+}
+
+func diffArrays(path string, a, b []interface{}, changes *[]RevisionChange) {
+	aByID, aOK := indexByID(a)
+	bByID, bOK := indexByID(b)
+	if !aOK || !bOK {
+		// Plain arrays are compared positionally
+		n := len(a)
+		if len(b) > n {
+			n = len(b)
+		}
+		for i := 0; i < n; i++ {
+			var av, bv interface{}
+			if i < len(a) {
+				av = a[i]
+			}
+			if i < len(b) {
+				bv = b[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), av, bv, changes)
+		}
+		return
+	}
+
+	for _, item := range a {
+		id := item.(map[string]interface{})["id"].(string)
+		diffValues(fmt.Sprintf("%s[%s]", path, id), item, bByID[id], changes)
+	}
+	for _, item := range b {
+		id := item.(map[string]interface{})["id"].(string)
+		if _, ok := aByID[id]; !ok {
+			diffValues(fmt.Sprintf("%s[%s]", path, id), nil, item, changes)
+		}
+	}
+}
+
+// indexByID indexes an array of objects by their "id" field. It reports false
+// if any element is not an object with a string ID.
+func indexByID(items []interface{}) (map[string]interface{}, bool) {
+	index := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		id, ok := obj["id"].(string)
+		if !ok {
+			return nil, false
+		}
+		index[id] = obj
+	}
+	return index, true
+}
+
+func changeFor(path string, from, to interface{}) RevisionChange {
+	switch {
+	case from == nil:
+		return RevisionChange{Path: path, Op: "added", To: to}
+	case to == nil:
+		return RevisionChange{Path: path, Op: "removed", From: from}
+	default:
+		return RevisionChange{Path: path, Op: "changed", From: from, To: to}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,61 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/section"
+)
+
+func TestDiffPortfoliosMatchesSectionsByID(t *testing.T) {
+	kept := Section{ID: primitive.NewObjectID(), Title: "About", Type: "text", Content: section.TextContent("Hello")}
+	removed := Section{ID: primitive.NewObjectID(), Title: "Skills", Type: "skills", Content: section.TextContent("Go")}
+
+	from := &Portfolio{Title: "Old", Sections: []Section{removed, kept}}
+	edited := kept
+	edited.Content = section.TextContent("Hello there")
+	to := &Portfolio{Title: "New", Sections: []Section{edited}}
+
+	changes, err := diffPortfolios(from, to)
+	if err != nil {
+		t.Fatalf("diffPortfolios: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, c := range changes {
+		got[c.Path] = c.Op
+	}
+
+	want := map[string]string{
+		"title": "changed",
+		"sections[" + kept.ID.Hex() + "].content.text": "changed",
+		"sections[" + removed.ID.Hex() + "]":           "removed",
+	}
+	for path, op := range want {
+		if got[path] != op {
+			t.Errorf("change at %s = %q, want %q (all changes: %v)", path, got[path], op, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d changes, want %d: %v", len(got), len(want), got)
+	}
+}
+
+func TestDiffPortfoliosOfOneEditHasOneChange(t *testing.T) {
+	now := time.Now()
+	from := &Portfolio{ID: primitive.NewObjectID(), Title: "Work", Description: "Old", Version: 3, UpdatedAt: now}
+	to := *from
+	to.Description = "New"
+	to.Version++
+	to.UpdatedAt = now.Add(time.Minute)
+
+	changes, err := diffPortfolios(from, &to)
+	if err != nil {
+		t.Fatalf("diffPortfolios: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "description" {
+		t.Errorf("changes = %+v, want only the description", changes)
+	}
+}
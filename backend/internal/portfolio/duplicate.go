@@ -0,0 +1,344 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/section"
+	"github.com/musefolio/backend/internal/storage"
+)
+
+// maxSubdomainAttempts bounds the search for a free subdomain for a copy
+const maxSubdomainAttempts = 50
+
+// sectionTypeMappings holds the default section type conversions applied when
+// a portfolio is duplicated into a different type. An empty target drops
+// sections of that type from the copy.
+var sectionTypeMappings = map[string]map[string]string{
+	"cv": {
+		"about":        "text",
+		"services":     "",
+		"testimonials": "",
+	},
+	"about": {
+		"text":       "about",
+		"experience": "",
+		"education":  "",
+	},
+}
+
+// mediaKey returns the storage key of a project media file
+func mediaKey(portfolioID, projectID, mediaID primitive.ObjectID, ext string) string {
+	return fmt.Sprintf("%s/%s/%s%s", portfolioID.Hex(), projectID.Hex(), mediaID.Hex(), ext)
+}
+
+// copyPortfolio copies a portfolio for userID, regenerating every ID. The
+// copy gets its own projects, so editing them leaves the source untouched;
+// their media still point at the files of the source until copyFiles is
+// called. Templates get empty projects instead.
+func copyPortfolio(source *Portfolio, userID primitive.ObjectID, input DuplicatePortfolioInput) (*Portfolio, error) {
+	now := time.Now()
+	copied := &Portfolio{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Title:       source.Title + " (copy)",
+		Description: source.Description,
+		Theme:       source.Theme,
+		Layout:      source.Layout,
+		Type:        source.Type,
+		Projects:    []Project{},
+		Sections:    []Section{},
+		IsPublished: false,
+		Version:     1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if input.Title != nil {
+		copied.Title = *input.Title
+	}
+	if input.Type != nil {
+		copied.Type = *input.Type
+	}
+
+	// Sections are retyped when the copy targets a different portfolio type.
+	// Explicit mappings from the request take precedence over the defaults.
+	mapping := map[string]string{}
+	if copied.Type != source.Type {
+		for from, to := range sectionTypeMappings[copied.Type] {
+			mapping[from] = to
+		}
+	}
+	for from, to := range input.SectionTypes {
+		mapping[from] = to
+	}
+
+	for _, src := range source.Sections {
+		sectionType := src.Type
+		if to, ok := mapping[sectionType]; ok {
+			if to == "" {
+				continue
+			}
+			sectionType = to
+		}
+
+		content := src.Content
+		if input.AsTemplate {
+			content = nil
+		}
+
+		// Retyped content is fitted to the schema of its new type. Sections
+		// that can't be converted are left out.
+		if sectionType != src.Type {
+			if _, ok := section.Lookup(sectionType); !ok {
+				continue
+			}
+			if content != nil {
+				converted, err := section.Convert(sectionType, content)
+				if err != nil {
+					continue
+				}
+				content = converted
+			}
+		}
+
+		copied.Sections = append(copied.Sections, Section{
+			ID:        primitive.NewObjectID(),
+			Title:     src.Title,
+			Type:      sectionType,
+			Content:   content,
+			Order:     src.Order,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	for _, project := range source.Projects {
+		projectCopy, err := copyProject(&project, userID, input.AsTemplate, now)
+		if err != nil {
+			return nil, err
+		}
+		copied.Projects = append(copied.Projects, projectCopy)
+	}
+
+	return copied, nil
+}
+
+// copyProject copies a project with fresh IDs for it, its blocks, stages and
+// media, but not where it was imported from. Templates keep the structure of
+// a project but none of its content.
+func copyProject(source *Project, userID primitive.ObjectID, asTemplate bool, now time.Time) (Project, error) {
+	copied := Project{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Kind:        source.Kind,
+		Title:       source.Title,
+		Description: source.Description,
+		Content:     source.Content,
+		CaseStudy:   copyCaseStudy(source.CaseStudy, asTemplate, now),
+		Media:       copyMedia(source.Media),
+		Tags:        append([]string{}, source.Tags...),
+		Order:       source.Order,
+		Hidden:      source.Hidden,
+		Version:     1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if asTemplate {
+		copied.Description = ""
+		copied.Content = ""
+		copied.Media = []Media{}
+		return copied, nil
+	}
+
+	blocks, err := block.Clone(source.Blocks)
+	if err != nil {
+		return Project{}, err
+	}
+	copied.Blocks = blocks
+	for i := range copied.Blocks {
+		block.AssignIDs(&copied.Blocks[i])
+	}
+	// Source stays with the original: a project is imported once per user,
+	// and syncs keep updating only the original
+	return copied, nil
+}
+
+// copyMedia copies media with fresh IDs
+func copyMedia(source []Media) []Media {
+	copied := make([]Media, len(source))
+	for i, media := range source {
+		copied[i] = media
+		copied[i].ID = primitive.NewObjectID()
+	}
+	return copied
+}
+
+// copyCaseStudy copies a case study with fresh stage IDs. Templates keep the
+// stages but none of their content.
+func copyCaseStudy(source *CaseStudy, asTemplate bool, now time.Time) *CaseStudy {
+	if source == nil {
+		return nil
+	}
+
+	copied := &CaseStudy{
+		Role:     source.Role,
+		Team:     append([]string{}, source.Team...),
+		Duration: source.Duration,
+		Client:   source.Client,
+		Stages:   []Stage{},
+	}
+	if asTemplate {
+		copied.Role, copied.Team, copied.Duration, copied.Client = "", []string{}, "", ""
+	}
+
+	for _, stage := range source.Stages {
+		stageCopy := Stage{
+			ID:        primitive.NewObjectID(),
+			Type:      stage.Type,
+			Title:     stage.Title,
+			Body:      stage.Body,
+			Metrics:   append([]Metric(nil), stage.Metrics...),
+			Media:     copyMedia(stage.Media),
+			Order:     stage.Order,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if asTemplate {
+			stageCopy.Body = ""
+			stageCopy.Metrics = nil
+			stageCopy.Media = []Media{}
+		}
+		copied.Stages = append(copied.Stages, stageCopy)
+	}
+	return copied
+}
+
+// mediaCopier copies the stored files used by the projects of a duplicated
+// portfolio, so deleting the files of the source doesn't break the copy
+type mediaCopier struct {
+	storage     storage.Storage
+	portfolioID primitive.ObjectID
+	// urls maps the URLs of copied files to the URLs of their copies
+	urls map[string]string
+	// keys holds the keys of the copies, to clean up if the copy fails
+	keys []string
+}
+
+// copyFiles copies the files of the media, blocks and stages of the projects
+// of a portfolio and points them at the copies. Media pointing outside of
+// storage, e.g. embedded videos, is kept as is. The keys of the copies are
+// returned so they can be removed if the portfolio isn't saved.
+func (s *Service) copyFiles(ctx context.Context, portfolio *Portfolio) ([]string, error) {
+	c := &mediaCopier{storage: s.storage, portfolioID: portfolio.ID, urls: map[string]string{}}
+	for i := range portfolio.Projects {
+		project := &portfolio.Projects[i]
+		if err := c.copyAll(ctx, project.ID, project.Media); err != nil {
+			return c.keys, err
+		}
+		if cs := project.CaseStudy; cs != nil {
+			for j := range cs.Stages {
+				if err := c.copyAll(ctx, project.ID, cs.Stages[j].Media); err != nil {
+					return c.keys, err
+				}
+			}
+		}
+		if err := c.copyBlocks(ctx, project.ID, project.Blocks); err != nil {
+			return c.keys, err
+		}
+	}
+	return c.keys, nil
+}
+
+// copyAll copies the files of media in place
+func (c *mediaCopier) copyAll(ctx context.Context, projectID primitive.ObjectID, media []Media) error {
+	for i := range media {
+		url, err := c.copy(ctx, projectID, media[i].ID, media[i].URL)
+		if err != nil {
+			return fmt.Errorf("copy media %s: %w", media[i].ID.Hex(), err)
+		}
+		media[i].URL = url
+	}
+	return nil
+}
+
+// copyBlocks copies the files referenced by the media properties of blocks
+func (c *mediaCopier) copyBlocks(ctx context.Context, projectID primitive.ObjectID, blocks []block.Block) error {
+	for _, b := range blocks {
+		if b.Type != "embed" {
+			for _, prop := range []string{"url", "poster"} {
+				if url, ok := b.Props[prop].(string); ok {
+					copied, err := c.copy(ctx, projectID, primitive.NewObjectID(), url)
+					if err != nil {
+						return fmt.Errorf("copy block %s media: %w", b.ID.Hex(), err)
+					}
+					b.Props[prop] = copied
+				}
+			}
+		}
+		if images, ok := b.Props["images"].([]interface{}); ok {
+			for _, raw := range images {
+				if image, ok := raw.(map[string]interface{}); ok {
+					if url, ok := image["url"].(string); ok {
+						copied, err := c.copy(ctx, projectID, primitive.NewObjectID(), url)
+						if err != nil {
+							return fmt.Errorf("copy block %s media: %w", b.ID.Hex(), err)
+						}
+						image["url"] = copied
+					}
+				}
+			}
+		}
+		if err := c.copyBlocks(ctx, projectID, b.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copy copies a stored file once and returns the URL of its copy. URLs
+// outside of storage are returned unchanged.
+func (c *mediaCopier) copy(ctx context.Context, projectID, mediaID primitive.ObjectID, url string) (string, error) {
+	if copied, ok := c.urls[url]; ok {
+		return copied, nil
+	}
+	srcKey, ok := c.storage.KeyFromURL(url)
+	if !ok {
+		return url, nil
+	}
+
+	dstKey := mediaKey(c.portfolioID, projectID, mediaID, path.Ext(srcKey))
+	copied, err := c.storage.Copy(ctx, srcKey, dstKey)
+	if err != nil {
+		return "", err
+	}
+	c.keys = append(c.keys, dstKey)
+	c.urls[url] = copied
+	return copied, nil
+}
+
+// availableSubdomain finds a free subdomain derived from base by appending
+// "copy" and, if needed, a counter
+func (s *Service) availableSubdomain(ctx context.Context, base string) (string, error) {
+	base = strings.ToLower(base)
+	for i := 1; i <= maxSubdomainAttempts; i++ {
+		candidate := base + "copy"
+		if i > 1 {
+			candidate = fmt.Sprintf("%scopy%d", base, i)
+		}
+
+		existing, err := s.repo.FindBySubdomain(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return candidate, nil
+		}
+	}
+	return "", ErrSubdomainTaken
+}
@@ -0,0 +1,404 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/audit"
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/database/databasetest"
+	"github.com/musefolio/backend/internal/section"
+	"github.com/musefolio/backend/internal/storage"
+)
+
+// duplicateSource returns a portfolio whose poster and sketch are stored
+// under src/ and whose video is hosted elsewhere
+func duplicateSource() *Portfolio {
+	return &Portfolio{
+		ID:          primitive.NewObjectID(),
+		UserID:      primitive.NewObjectID(),
+		Title:       "Work",
+		Type:        "portfolio",
+		Subdomain:   "work",
+		IsPublished: true,
+		Version:     7,
+		Sections: []Section{
+			{ID: primitive.NewObjectID(), Title: "Me", Type: "about", Content: section.TextContent("Hi")},
+			{ID: primitive.NewObjectID(), Title: "Posters", Type: "gallery", Content: section.TextContent("Ink")},
+			{ID: primitive.NewObjectID(), Title: "Jobs", Type: "experience", Content: section.TextContent("Acme")},
+		},
+		Projects: []Project{{
+			ID:      primitive.NewObjectID(),
+			Kind:    ProjectKindCaseStudy,
+			Title:   "Poster",
+			Content: "Printed in **red**",
+			Blocks: []block.Block{{
+				ID:    primitive.NewObjectID(),
+				Type:  "image",
+				Props: block.Props{"url": "/media/src/poster.png", "alt": "Poster"},
+			}},
+			CaseStudy: &CaseStudy{
+				Role: "Designer",
+				Stages: []Stage{{
+					ID:    primitive.NewObjectID(),
+					Type:  StageProblem,
+					Body:  "Nobody came",
+					Media: []Media{{ID: primitive.NewObjectID(), Type: "image", URL: "/media/src/sketch.png"}},
+				}},
+			},
+			Media: []Media{
+				{ID: primitive.NewObjectID(), Type: "image", URL: "/media/src/poster.png", Caption: "Front"},
+				{ID: primitive.NewObjectID(), Type: "video", URL: "https://vimeo.com/1"},
+			},
+			Tags: []string{"print"},
+		}},
+	}
+}
+
+// mustCopyPortfolio copies source, failing the test if it can't be copied
+func mustCopyPortfolio(t *testing.T, source *Portfolio, userID primitive.ObjectID, input DuplicatePortfolioInput) *Portfolio {
+	t.Helper()
+	copied, err := copyPortfolio(source, userID, input)
+	if err != nil {
+		t.Fatalf("copyPortfolio: %v", err)
+	}
+	return copied
+}
+
+func TestCopyPortfolioDoesNotShareProjects(t *testing.T) {
+	source := duplicateSource()
+	original := source.Projects[0]
+	userID := primitive.NewObjectID()
+
+	copied := mustCopyPortfolio(t, source, userID, DuplicatePortfolioInput{})
+	project := &copied.Projects[0]
+	if project.ID == original.ID || project.UserID != userID {
+		t.Fatalf("copied project = %s of %s, want a new project of the user", project.ID.Hex(), project.UserID.Hex())
+	}
+	if project.Blocks[0].ID == original.Blocks[0].ID || project.Media[0].ID == original.Media[0].ID ||
+		project.CaseStudy.Stages[0].ID == original.CaseStudy.Stages[0].ID {
+		t.Errorf("copied project keeps IDs of the source")
+	}
+
+	// Edits of the copy don't show in the source
+	project.Title = "Flyer"
+	project.Tags[0] = "web"
+	project.Blocks[0].Props["alt"] = "Flyer"
+	project.Media[0].Caption = "Back"
+	project.CaseStudy.Stages[0].Body = "Everybody came"
+	project.CaseStudy.Stages[0].Media[0].URL = "/media/other.png"
+
+	got := source.Projects[0]
+	if got.Title != "Poster" || got.Tags[0] != "print" || got.Blocks[0].Props["alt"] != "Poster" {
+		t.Errorf("source project changed: %+v", got)
+	}
+	if got.Media[0].Caption != "Front" || got.CaseStudy.Stages[0].Body != "Nobody came" ||
+		got.CaseStudy.Stages[0].Media[0].URL != "/media/src/sketch.png" {
+		t.Errorf("source media or stages changed: %+v", got)
+	}
+
+	template := mustCopyPortfolio(t, source, userID, DuplicatePortfolioInput{AsTemplate: true})
+	if p := template.Projects[0]; p.Content != "" || len(p.Media) != 0 || p.Blocks != nil || p.CaseStudy.Stages[0].Body != "" {
+		t.Errorf("template project keeps content: %+v", p)
+	}
+}
+
+func TestCopyPortfolioRegeneratesIDs(t *testing.T) {
+	source := duplicateSource()
+	userID := primitive.NewObjectID()
+
+	copied := mustCopyPortfolio(t, source, userID, DuplicatePortfolioInput{})
+	if copied.ID == source.ID || copied.UserID != userID || copied.Title != "Work (copy)" {
+		t.Errorf("copy = %s of %s titled %q", copied.ID.Hex(), copied.UserID.Hex(), copied.Title)
+	}
+	if copied.IsPublished || copied.Version != 1 {
+		t.Errorf("copy = published %v, version %d; want an unpublished first version", copied.IsPublished, copied.Version)
+	}
+	for i, sec := range copied.Sections {
+		if sec.ID == source.Sections[i].ID || sec.Content.Text() != source.Sections[i].Content.Text() {
+			t.Errorf("section %d = %+v", i, sec)
+		}
+	}
+}
+
+func TestCopyPortfolioMapsSectionTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		typ   string
+		types map[string]string
+		want  string
+	}{
+		{"same type", "portfolio", nil, "about,gallery,experience"},
+		{"default mapping", "cv", nil, "text,gallery,experience"},
+		{"explicit mapping wins", "cv", map[string]string{"about": "", "experience": "text"}, "gallery,text"},
+		{"explicit mapping without retyping", "", map[string]string{"gallery": "text"}, "about,text,experience"},
+	}
+	for _, tt := range tests {
+		input := DuplicatePortfolioInput{SectionTypes: tt.types}
+		if tt.typ != "" {
+			input.Type = &tt.typ
+		}
+		copied := mustCopyPortfolio(t, duplicateSource(), primitive.NewObjectID(), input)
+		var types []string
+		for _, sec := range copied.Sections {
+			types = append(types, sec.Type)
+		}
+		if got := strings.Join(types, ","); got != tt.want {
+			t.Errorf("%s: section types = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCopyPortfolioReportsUncopyableBlocks(t *testing.T) {
+	source := duplicateSource()
+	source.Projects[0].Blocks[0].Props["width"] = math.Inf(1)
+
+	if _, err := copyPortfolio(source, primitive.NewObjectID(), DuplicatePortfolioInput{}); err == nil {
+		t.Error("copying a block that can't be encoded succeeded, want an error")
+	}
+}
+
+func TestCopyPortfolioAsTemplate(t *testing.T) {
+	source := duplicateSource()
+	copied := mustCopyPortfolio(t, source, primitive.NewObjectID(), DuplicatePortfolioInput{AsTemplate: true})
+	if len(copied.Sections) != 3 || copied.Sections[0].Title != "Me" || copied.Sections[0].Content != nil {
+		t.Errorf("template sections = %+v, want their titles without content", copied.Sections)
+	}
+	if p := copied.Projects[0]; p.ID == source.Projects[0].ID || p.Title != "Poster" || p.Description != "" || p.Content != "" || len(p.Media) != 0 {
+		t.Errorf("template project = %+v, want a new project with its title but no content", p)
+	}
+}
+
+func TestCopyFilesGivesTheCopyItsOwnMedia(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocal(t.TempDir(), "/media")
+	for _, key := range []string{"src/poster.png", "src/sketch.png"} {
+		if _, err := store.Save(ctx, key, strings.NewReader(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &Service{storage: store}
+	copied := mustCopyPortfolio(t, duplicateSource(), primitive.NewObjectID(), DuplicatePortfolioInput{})
+	keys, err := s.copyFiles(ctx, copied)
+	if err != nil {
+		t.Fatalf("copyFiles: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("copied keys = %v, want the poster and the sketch once each", keys)
+	}
+
+	project := copied.Projects[0]
+	prefix := "/media/" + copied.ID.Hex() + "/" + project.ID.Hex() + "/"
+	poster := project.Media[0].URL
+	if !strings.HasPrefix(poster, prefix) || project.Blocks[0].Props["url"] != poster {
+		t.Errorf("poster = %s, block = %v; want one copy under %s", poster, project.Blocks[0].Props["url"], prefix)
+	}
+	if project.Media[1].URL != "https://vimeo.com/1" {
+		t.Errorf("external media = %s, want it kept", project.Media[1].URL)
+	}
+
+	sketch := project.CaseStudy.Stages[0].Media[0].URL
+	key, _ := store.KeyFromURL(sketch)
+	file, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("open copy of the sketch: %v", err)
+	}
+	defer file.Close()
+	if data, _ := io.ReadAll(file); string(data) != "src/sketch.png" {
+		t.Errorf("copy of the sketch = %q", data)
+	}
+}
+
+func TestDuplicateRemovesCopiedMediaOnFailure(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	root := t.TempDir()
+	s.storage = storage.NewLocal(root, "/media")
+	if _, err := s.storage.Save(ctx, "src/poster.png", strings.NewReader("poster")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sketch was never stored, so copying it fails after the poster
+	source := duplicateSource()
+	if err := repo.Insert(ctx, source, "portfolio.create"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Duplicate(ctx, source.ID, source.UserID, DuplicatePortfolioInput{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("Duplicate = %v, want ErrNotFound", err)
+	}
+
+	var files []string
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, p)
+		}
+		return err
+	})
+	if len(files) != 1 {
+		t.Errorf("stored files = %v, want only the source poster", files)
+	}
+	if copies, err := repo.FindByUserID(ctx, source.UserID); err != nil || len(copies) != 1 {
+		t.Errorf("portfolios = %d, %v; want no copy", len(copies), err)
+	}
+}
+
+func TestAvailableSubdomain(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	userID := primitive.NewObjectID()
+
+	create := func(subdomain string) {
+		t.Helper()
+		if _, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: subdomain, Subdomain: subdomain}, PortfolioSeed{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	create("workcopy")
+	if got, err := s.availableSubdomain(ctx, "Work"); err != nil || got != "workcopy2" {
+		t.Errorf("availableSubdomain = %q, %v; want workcopy2", got, err)
+	}
+
+	for i := 2; i <= maxSubdomainAttempts; i++ {
+		create(fmt.Sprintf("workcopy%d", i))
+	}
+	if got, err := s.availableSubdomain(ctx, "work"); !errors.Is(err, ErrSubdomainTaken) {
+		t.Errorf("availableSubdomain with every candidate taken = %q, %v; want ErrSubdomainTaken", got, err)
+	}
+}
+
+func TestCopyPortfolioRetypesSectionsToRegisteredTypes(t *testing.T) {
+	items := func(item map[string]interface{}) section.Content {
+		return section.Content{"items": []interface{}{item}}
+	}
+	source := &Portfolio{
+		Type: "portfolio",
+		Sections: []Section{
+			{Type: "text", Title: "Hello", Content: section.TextContent("I draw <script>x</script>things")},
+			{Type: "experience", Title: "Jobs", Content: items(map[string]interface{}{"role": "Designer", "organization": "Acme"})},
+			{Type: "skills", Title: "Skills", Content: items(map[string]interface{}{"name": "Ink"})},
+			{Type: "awards", Title: "Awards", Content: items(map[string]interface{}{"title": "Best poster"})},
+			{Type: "about", Title: "Me", Content: section.Content{"headline": "Hi", "text": "Me"}},
+		},
+	}
+	about := "about"
+	copied := mustCopyPortfolio(t, source, primitive.NewObjectID(), DuplicatePortfolioInput{
+		Type: &about,
+		// Skills don't fit testimonials and there are no galleries
+		SectionTypes: map[string]string{"skills": "testimonials", "awards": "gallery"},
+	})
+
+	var types []string
+	for _, sec := range copied.Sections {
+		types = append(types, sec.Type)
+		if err := section.Validate(sec.Type, sec.Content); err != nil {
+			t.Errorf("%s section is invalid: %v", sec.Type, err)
+		}
+	}
+	if got := strings.Join(types, ","); got != "about,about" {
+		t.Fatalf("section types = %s, want the text as about and the about kept", got)
+	}
+	if text := copied.Sections[0].Content.Text(); strings.Contains(text, "script") {
+		t.Errorf("retyped content not sanitized: %q", text)
+	}
+
+	cv := "cv"
+	copied = mustCopyPortfolio(t, source, primitive.NewObjectID(), DuplicatePortfolioInput{Type: &cv})
+	last := copied.Sections[len(copied.Sections)-1]
+	if last.Type != "text" || last.Content.Text() != "Me" || last.Content["headline"] != nil {
+		t.Errorf("about in a CV = %+v, want text without the headline", last)
+	}
+}
+
+func TestDuplicateThenUpdateRetypedSection(t *testing.T) {
+	ctx := context.Background()
+	db := databasetest.New(t)
+	repo := NewRepository(db, RevisionRetention{})
+	s := NewService(repo, audit.NewRepository(db), storage.NewLocal(t.TempDir(), "/media"), nil, nil)
+	userID := primitive.NewObjectID()
+
+	source, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Work", Type: "portfolio", Subdomain: "work"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := repo.AddSection(ctx, source.ID, source.Version, CreateSectionInput{Title: "Hello", Type: "text", Content: section.TextContent("Hi")}); err != nil {
+		t.Fatal(err)
+	}
+
+	about := "about"
+	copied, err := s.Duplicate(ctx, source.ID, userID, DuplicatePortfolioInput{Type: &about})
+	if err != nil {
+		t.Fatalf("Duplicate: %v", err)
+	}
+	if len(copied.Sections) != 1 || copied.Sections[0].Type != "about" {
+		t.Fatalf("copied sections = %+v", copied.Sections)
+	}
+
+	content := section.Content{"headline": "Hello", "text": "Updated"}
+	updated, _, err := s.UpdateSection(ctx, copied.ID, copied.Sections[0].ID, userID, copied.Version, UpdateSectionInput{Content: &content})
+	if err != nil {
+		t.Fatalf("UpdateSection of the retyped section: %v", err)
+	}
+	if updated.Content.Text() != "Updated" {
+		t.Errorf("updated content = %v", updated.Content)
+	}
+}
+
+func TestCopyPortfolioLeavesImportSourceWithTheOriginal(t *testing.T) {
+	source := duplicateSource()
+	source.Projects[0].Source = &ProjectSource{Provider: "github", ExternalID: "42", URL: "https://github.com/ink/poster"}
+
+	copied := mustCopyPortfolio(t, source, source.UserID, DuplicatePortfolioInput{})
+	if copied.Projects[0].Source != nil {
+		t.Errorf("copied project source = %+v, want none", copied.Projects[0].Source)
+	}
+	if source.Projects[0].Source == nil {
+		t.Error("source project lost its source")
+	}
+}
+
+func TestDuplicatePortfolioWithImportedProject(t *testing.T) {
+	ctx := context.Background()
+	db := databasetest.New(t)
+	if err := db.EnsureIndexes(ctx); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository(db, RevisionRetention{})
+	s := NewService(repo, audit.NewRepository(db), storage.NewLocal(t.TempDir(), "/media"), nil, nil)
+
+	source := &Portfolio{
+		ID:        primitive.NewObjectID(),
+		UserID:    primitive.NewObjectID(),
+		Title:     "Code",
+		Type:      "portfolio",
+		Subdomain: "code",
+		Version:   1,
+		Projects: []Project{{
+			ID:     primitive.NewObjectID(),
+			Title:  "musefolio",
+			Source: &ProjectSource{Provider: "github", ExternalID: "42", URL: "https://github.com/ink/musefolio"},
+		}},
+	}
+	if err := repo.Insert(ctx, source, "portfolio.create"); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := s.Duplicate(ctx, source.ID, source.UserID, DuplicatePortfolioInput{})
+	if err != nil {
+		t.Fatalf("Duplicate: %v", err)
+	}
+	if len(copied.Projects) != 1 || copied.Projects[0].Source != nil {
+		t.Errorf("copied projects = %+v, want one without a source", copied.Projects)
+	}
+}
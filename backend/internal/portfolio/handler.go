@@ -4,9 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
 	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/pagination"
+	"github.com/musefolio/backend/internal/template"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -31,21 +37,58 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 		r.Get("/subdomain/{subdomain}", h.GetBySubdomain)
 		r.Put("/{id}", h.Update)
 		r.Delete("/{id}", h.Delete)
+		r.Put("/{id}/schedule", h.Schedule)
+		r.Post("/{id}/duplicate", h.Duplicate)
 
 		// Project routes
 		r.Post("/{id}/projects", h.AddProject)
+		r.Post("/{id}/projects/link", h.LinkProject)
+		r.Put("/{id}/projects/order", h.ReorderProjects)
 		r.Put("/{id}/projects/{projectID}", h.UpdateProject)
 		r.Delete("/{id}/projects/{projectID}", h.DeleteProject)
 
 		// Section routes
 		r.Post("/{id}/sections", h.AddSection)
+		r.Put("/{id}/sections/order", h.ReorderSections)
 		r.Put("/{id}/sections/{sectionID}", h.UpdateSection)
 		r.Delete("/{id}/sections/{sectionID}", h.DeleteSection)
 
 		// Media routes
 		r.Post("/{id}/projects/{projectID}/media", h.AddMedia)
+		r.Put("/{id}/projects/{projectID}/media/order", h.ReorderMedia)
 		r.Delete("/{id}/projects/{projectID}/media/{mediaID}", h.DeleteMedia)
+
+		// Block routes
+		r.Post("/{id}/projects/{projectID}/blocks", h.AddBlock)
+		r.Put("/{id}/projects/{projectID}/blocks", h.ReplaceBlocks)
+		r.Put("/{id}/projects/{projectID}/blocks/{blockID}", h.UpdateBlock)
+		r.Delete("/{id}/projects/{projectID}/blocks/{blockID}", h.DeleteBlock)
+		r.Post("/{id}/projects/{projectID}/blocks/{blockID}/move", h.MoveBlock)
+
+		// Case-study stage routes
+		r.Post("/{id}/projects/{projectID}/stages", h.AddStage)
+		r.Put("/{id}/projects/{projectID}/stages/order", h.ReorderStages)
+		r.Put("/{id}/projects/{projectID}/stages/{stageID}", h.UpdateStage)
+		r.Delete("/{id}/projects/{projectID}/stages/{stageID}", h.DeleteStage)
+		r.Post("/{id}/projects/{projectID}/stages/{stageID}/media", h.AddStageMedia)
+		r.Delete("/{id}/projects/{projectID}/stages/{stageID}/media/{mediaID}", h.DeleteStageMedia)
+
+		// Revision routes
+		r.Get("/{id}/revisions", h.ListRevisions)
+		r.Get("/{id}/revisions/{revisionID}", h.GetRevision)
+		r.Get("/{id}/revisions/{revisionID}/diff", h.DiffRevision)
+		r.Post("/{id}/revisions/{revisionID}/restore", h.RestoreRevision)
+	})
+
+	// Project library routes
+	r.Route("/projects", func(r chi.Router) {
+		r.Get("/", h.ListProjects)
+		r.Get("/{projectID}", h.GetProject)
+		r.Put("/{projectID}", h.UpdateLibraryProject)
+		r.Delete("/{projectID}", h.DeleteLibraryProject)
 	})
+
+	r.Get("/search", h.Search)
 }
 
 // Create handles portfolio creation
@@ -65,15 +108,68 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 	portfolio, err := h.service.Create(r.Context(), userID, input)
 	if err != nil {
-		if errors.Is(err, ErrSubdomainTaken) {
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, ErrSubdomainTaken):
 			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, template.ErrTemplateNotFound), errors.Is(err, ErrInvalidTheme):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.As(err, &validationErrors):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, portfolio.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
+
+// Duplicate handles copying a portfolio
+func (h *Handler) Duplicate(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	var input DuplicatePortfolioInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	portfolio, err := h.service.Duplicate(r.Context(), id, userID, input)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrSubdomainTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.As(err, &validationErrors):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
 		return
 	}
 
+	setETag(w, portfolio.Version)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(portfolio)
 }
 
@@ -95,24 +191,49 @@ func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, portfolio.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(portfolio)
 }
 
-// GetByUserID handles getting all portfolios for a user
+// listOptions describe the pages of a user's portfolio list
+var listOptions = pagination.Options{
+	DefaultLimit: 50,
+	MaxLimit:     100,
+	Sorts: []pagination.Sort{
+		{Name: "createdAt", Field: "createdAt"},
+		{Name: "-createdAt", Field: "createdAt", Desc: true},
+		{Name: "-updatedAt", Field: "updatedAt", Desc: true},
+		{Name: "title", Field: "title"},
+	},
+	Filters: []pagination.Filter{
+		{Param: "published", Field: "isPublished", Type: pagination.FilterBool},
+		{Param: "type", Field: "type", Type: pagination.FilterString},
+	},
+}
+
+// GetByUserID handles listing a page of the portfolios of the current user.
+// The next page is linked from the Link header.
 func (h *Handler) GetByUserID(w http.ResponseWriter, r *http.Request) {
+	p, err := listOptions.Parse(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	portfolios, err := h.service.GetByUserID(r.Context(), userID)
+	portfolios, next, err := h.service.ListByUserID(r.Context(), userID, p)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	pagination.SetNext(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(portfolios)
 }
@@ -159,7 +280,12 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	portfolio, err := h.service.Update(r.Context(), id, userID, input)
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	portfolio, err := h.service.Update(r.Context(), id, userID, version, input)
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrPortfolioNotFound):
@@ -168,12 +294,17 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 		case errors.Is(err, ErrSubdomainTaken):
 			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, ErrInvalidTheme):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		default:
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	setETag(w, portfolio.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(portfolio)
 }
@@ -192,12 +323,19 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.Delete(r.Context(), id, userID); err != nil {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id, userID, version); err != nil {
 		switch {
 		case errors.Is(err, ErrPortfolioNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound)
 		case errors.Is(err, ErrUnauthorized):
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		default:
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
@@ -207,6 +345,53 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Schedule handles setting a portfolio's publish and unpublish times
+func (h *Handler) Schedule(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	var input ScheduleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	portfolio, err := h.service.Schedule(r.Context(), id, userID, version, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrInvalidSchedule):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, portfolio.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
+
 // AddProject handles adding a project to a portfolio
 func (h *Handler) AddProject(w http.ResponseWriter, r *http.Request) {
 	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
@@ -227,19 +412,29 @@ func (h *Handler) AddProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := h.service.AddProject(r.Context(), portfolioID, userID, input)
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	project, newVersion, err := h.service.AddProject(r.Context(), portfolioID, userID, version, input)
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrPortfolioNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound)
 		case errors.Is(err, ErrUnauthorized):
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrNotCaseStudy):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		default:
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	setETag(w, newVersion)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(project)
 }
@@ -270,7 +465,12 @@ func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	project, err := h.service.UpdateProject(r.Context(), portfolioID, projectID, userID, input)
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	project, newVersion, err := h.service.UpdateProject(r.Context(), portfolioID, projectID, userID, version, input)
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrPortfolioNotFound):
@@ -279,12 +479,17 @@ func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 		case errors.Is(err, ErrUnauthorized):
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrNotCaseStudy):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		default:
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	setETag(w, newVersion)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(project)
 }
@@ -309,7 +514,13 @@ func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteProject(r.Context(), portfolioID, projectID, userID); err != nil {
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.service.DeleteProject(r.Context(), portfolioID, projectID, userID, version)
+	if err != nil {
 		switch {
 		case errors.Is(err, ErrPortfolioNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound)
@@ -317,24 +528,27 @@ func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 		case errors.Is(err, ErrUnauthorized):
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		default:
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	setETag(w, newVersion)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// AddSection handles adding a section to a portfolio
-func (h *Handler) AddSection(w http.ResponseWriter, r *http.Request) {
+// LinkProject handles showing an existing project in a portfolio
+func (h *Handler) LinkProject(w http.ResponseWriter, r *http.Request) {
 	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
 	if err != nil {
 		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
 		return
 	}
 
-	var input CreateSectionInput
+	var input LinkProjectInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
@@ -346,42 +560,74 @@ func (h *Handler) AddSection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	section, err := h.service.AddSection(r.Context(), portfolioID, userID, input)
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	project, newVersion, err := h.service.LinkProject(r.Context(), portfolioID, userID, version, input)
 	if err != nil {
+		var validationErrors validator.ValidationErrors
 		switch {
 		case errors.Is(err, ErrPortfolioNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrProjectNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
 		case errors.Is(err, ErrUnauthorized):
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrProjectLinked):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.As(err, &validationErrors):
+			http.Error(w, err.Error(), http.StatusBadRequest)
 		default:
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	setETag(w, newVersion)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(section)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(project)
 }
 
-// UpdateSection handles updating a section in a portfolio
-func (h *Handler) UpdateSection(w http.ResponseWriter, r *http.Request) {
-	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
-	if err != nil {
-		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+// ListProjects handles listing the projects of the current user
+func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	sectionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "sectionID"))
+	projects, err := h.service.ListProjects(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var input UpdateSectionInput
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projects)
+}
+
+// Search handles searching the portfolios, projects and sections of the
+// current user for the "q" query parameter
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		http.Error(w, "Missing search query", http.StatusBadRequest)
 		return
 	}
+	limit := DefaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
 
 	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
 	if !ok {
@@ -389,36 +635,25 @@ func (h *Handler) UpdateSection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	section, err := h.service.UpdateSection(r.Context(), portfolioID, sectionID, userID, input)
+	result, err := h.service.Search(r.Context(), userID, query, limit)
 	if err != nil {
-		switch {
-		case errors.Is(err, ErrPortfolioNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, ErrSectionNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, ErrUnauthorized):
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if errors.Is(err, ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(section)
+	json.NewEncoder(w).Encode(result)
 }
 
-// DeleteSection handles deleting a section from a portfolio
-func (h *Handler) DeleteSection(w http.ResponseWriter, r *http.Request) {
-	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
-	if err != nil {
-		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
-		return
-	}
-
-	sectionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "sectionID"))
+// GetProject handles getting a project of the current user
+func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "projectID"))
 	if err != nil {
-		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
 		return
 	}
 
@@ -428,56 +663,31 @@ func (h *Handler) DeleteSection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteSection(r.Context(), portfolioID, sectionID, userID); err != nil {
-		switch {
-		case errors.Is(err, ErrPortfolioNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, ErrSectionNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, ErrUnauthorized):
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+	project, err := h.service.GetProject(r.Context(), projectID, userID)
+	if err != nil {
+		writeLibraryError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	setETag(w, project.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(project)
 }
 
-// AddMedia handles adding media to a project
-func (h *Handler) AddMedia(w http.ResponseWriter, r *http.Request) {
-	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
-	if err != nil {
-		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
-		return
-	}
-
+// UpdateLibraryProject handles updating a project in every portfolio
+// showing it
+func (h *Handler) UpdateLibraryProject(w http.ResponseWriter, r *http.Request) {
 	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "projectID"))
 	if err != nil {
 		http.Error(w, "Invalid project ID", http.StatusBadRequest)
 		return
 	}
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(32 << 20); err != nil { // 32MB max
-		http.Error(w, "Invalid form data", http.StatusBadRequest)
-		return
-	}
-
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "Invalid file", http.StatusBadRequest)
+	var input UpdateProjectInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
-
-	// Get other form values
-	input := UploadMediaInput{
-		Type:    r.FormValue("type"),
-		Caption: r.FormValue("caption"),
-		Order:   0, // Default order, can be updated later
-	}
 
 	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
 	if !ok {
@@ -485,69 +695,1189 @@ func (h *Handler) AddMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Use original filename
-	filename := header.Filename
-
-	if err := h.service.AddMedia(r.Context(), portfolioID, projectID, userID, input, filename); err != nil {
-		switch {
-		case errors.Is(err, ErrPortfolioNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, ErrProjectNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, ErrUnauthorized):
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-		case errors.Is(err, ErrInvalidMediaType):
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+	version, ok := requireVersion(w, r)
+	if !ok {
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-}
-
-// DeleteMedia handles deleting media from a project
-func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
-	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	project, err := h.service.UpdateLibraryProject(r.Context(), projectID, userID, version, input)
 	if err != nil {
-		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		writeLibraryError(w, err)
 		return
 	}
 
+	setETag(w, project.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(project)
+}
+
+// DeleteLibraryProject handles deleting a project from every portfolio
+func (h *Handler) DeleteLibraryProject(w http.ResponseWriter, r *http.Request) {
 	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "projectID"))
 	if err != nil {
 		http.Error(w, "Invalid project ID", http.StatusBadRequest)
 		return
 	}
 
-	mediaID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "mediaID"))
-	if err != nil {
-		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	version, ok := requireVersion(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	if err := h.service.DeleteMedia(r.Context(), portfolioID, projectID, mediaID, userID); err != nil {
-		switch {
-		case errors.Is(err, ErrPortfolioNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, ErrProjectNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
-		case errors.Is(err, ErrMediaNotFound):
-			http.Error(w, err.Error(), http.StatusNotFound)
+	if err := h.service.DeleteLibraryProject(r.Context(), projectID, userID, version); err != nil {
+		writeLibraryError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeLibraryError maps project library errors to responses
+func writeLibraryError(w http.ResponseWriter, err error) {
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.Is(err, ErrProjectNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrNotCaseStudy):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrVersionMismatch):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.As(err, &validationErrors):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// AddSection handles adding a section to a portfolio
+func (h *Handler) AddSection(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	var input CreateSectionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	section, newVersion, err := h.service.AddSection(r.Context(), portfolioID, userID, version, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrInvalidSection):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, newVersion)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(section)
+}
+
+// UpdateSection handles updating a section in a portfolio
+func (h *Handler) UpdateSection(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	sectionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "sectionID"))
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	var input UpdateSectionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	section, newVersion, err := h.service.UpdateSection(r.Context(), portfolioID, sectionID, userID, version, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrSectionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrInvalidSection):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, newVersion)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(section)
+}
+
+// DeleteSection handles deleting a section from a portfolio
+func (h *Handler) DeleteSection(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	sectionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "sectionID"))
+	if err != nil {
+		http.Error(w, "Invalid section ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.service.DeleteSection(r.Context(), portfolioID, sectionID, userID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrSectionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, newVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMedia handles adding media to a project
+func (h *Handler) AddMedia(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "projectID"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse multipart form
+	if err := r.ParseMultipartForm(32 << 20); err != nil { // 32MB max
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Invalid file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// Get other form values
+	input := UploadMediaInput{
+		Type:    r.FormValue("type"),
+		Caption: r.FormValue("caption"),
+	}
+	if value := r.FormValue("order"); value != "" {
+		order, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid order", http.StatusBadRequest)
+			return
+		}
+		input.Order = &order
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Use original filename
+	filename := header.Filename
+
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.service.AddMedia(r.Context(), portfolioID, projectID, userID, version, input, filename, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrProjectNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrInvalidMediaType):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, newVersion)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// DeleteMedia handles deleting media from a project
+func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "projectID"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	mediaID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.service.DeleteMedia(r.Context(), portfolioID, projectID, mediaID, userID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrProjectNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrMediaNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
 		case errors.Is(err, ErrUnauthorized):
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		default:
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	setETag(w, newVersion)
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// AddBlock handles adding a block to a project canvas
+func (h *Handler) AddBlock(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	var input CreateBlockInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	added, newVersion, err := h.service.AddBlock(r.Context(), portfolioID, projectID, userID, version, input)
+	if err != nil {
+		writeBlockError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+// ReplaceBlocks handles saving a whole project canvas
+func (h *Handler) ReplaceBlocks(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	var input ReplaceBlocksInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	blocks, newVersion, err := h.service.ReplaceBlocks(r.Context(), portfolioID, projectID, userID, version, input)
+	if err != nil {
+		writeBlockError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocks)
+}
+
+// UpdateBlock handles updating the props or layout of a block
+func (h *Handler) UpdateBlock(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	blockID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "blockID"))
+	if err != nil {
+		http.Error(w, "Invalid block ID", http.StatusBadRequest)
+		return
+	}
+
+	var input UpdateBlockInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	updated, newVersion, err := h.service.UpdateBlock(r.Context(), portfolioID, projectID, blockID, userID, version, input)
+	if err != nil {
+		writeBlockError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteBlock handles removing a block from a project canvas
+func (h *Handler) DeleteBlock(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	blockID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "blockID"))
+	if err != nil {
+		http.Error(w, "Invalid block ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.service.DeleteBlock(r.Context(), portfolioID, projectID, blockID, userID, version)
+	if err != nil {
+		writeBlockError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveBlock handles moving a block within a project canvas
+func (h *Handler) MoveBlock(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	blockID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "blockID"))
+	if err != nil {
+		http.Error(w, "Invalid block ID", http.StatusBadRequest)
+		return
+	}
+
+	var input MoveBlockInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	blocks, newVersion, err := h.service.MoveBlock(r.Context(), portfolioID, projectID, blockID, userID, version, input)
+	if err != nil {
+		writeBlockError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocks)
+}
+
+// AddStage handles adding a stage to a case study
+func (h *Handler) AddStage(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	var input CreateStageInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	stage, newVersion, err := h.service.AddStage(r.Context(), portfolioID, projectID, userID, version, input)
+	if err != nil {
+		writeStageError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stage)
+}
+
+// UpdateStage handles updating a case-study stage
+func (h *Handler) UpdateStage(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	stageID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "stageID"))
+	if err != nil {
+		http.Error(w, "Invalid stage ID", http.StatusBadRequest)
+		return
+	}
+
+	var input UpdateStageInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	stage, newVersion, err := h.service.UpdateStage(r.Context(), portfolioID, projectID, stageID, userID, version, input)
+	if err != nil {
+		writeStageError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stage)
+}
+
+// DeleteStage handles deleting a case-study stage
+func (h *Handler) DeleteStage(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	stageID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "stageID"))
+	if err != nil {
+		http.Error(w, "Invalid stage ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.service.DeleteStage(r.Context(), portfolioID, projectID, stageID, userID, version)
+	if err != nil {
+		writeStageError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderStages handles reordering the stages of a case study
+func (h *Handler) ReorderStages(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	var input ReorderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	portfolio, err := h.service.ReorderStages(r.Context(), portfolioID, projectID, userID, version, input)
+	if err != nil {
+		writeStageError(w, err)
+		return
+	}
+
+	setETag(w, portfolio.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
+
+// AddStageMedia handles uploading media to a case-study stage
+func (h *Handler) AddStageMedia(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	stageID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "stageID"))
+	if err != nil {
+		http.Error(w, "Invalid stage ID", http.StatusBadRequest)
+		return
+	}
+
+	// Parse multipart form
+	if err := r.ParseMultipartForm(32 << 20); err != nil { // 32MB max
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Invalid file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	input := UploadMediaInput{
+		Type:    r.FormValue("type"),
+		Caption: r.FormValue("caption"),
+	}
+	if value := r.FormValue("order"); value != "" {
+		order, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid order", http.StatusBadRequest)
+			return
+		}
+		input.Order = &order
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.service.AddStageMedia(r.Context(), portfolioID, projectID, stageID, userID, version, input, header.Filename, file)
+	if err != nil {
+		writeStageError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// DeleteStageMedia handles deleting media from a case-study stage
+func (h *Handler) DeleteStageMedia(w http.ResponseWriter, r *http.Request) {
+	portfolioID, projectID, ok := projectParams(w, r)
+	if !ok {
+		return
+	}
+
+	stageID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "stageID"))
+	if err != nil {
+		http.Error(w, "Invalid stage ID", http.StatusBadRequest)
+		return
+	}
+
+	mediaID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	newVersion, err := h.service.DeleteStageMedia(r.Context(), portfolioID, projectID, stageID, mediaID, userID, version)
+	if err != nil {
+		writeStageError(w, err)
+		return
+	}
+
+	setETag(w, newVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStageError maps errors of case-study stage edits to responses
+func writeStageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPortfolioNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrProjectNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrStageNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrMediaNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrNotCaseStudy):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrInvalidStage):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrInvalidMediaType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrInvalidOrder):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrVersionMismatch):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// projectParams parses the portfolio and project IDs of a project route
+func projectParams(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, primitive.ObjectID, bool) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "projectID"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return portfolioID, projectID, true
+}
+
+// writeBlockError maps errors of canvas edits to responses
+func writeBlockError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPortfolioNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrProjectNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, block.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, block.ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, ErrVersionMismatch):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ReorderProjects handles reordering the projects of a portfolio
+func (h *Handler) ReorderProjects(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	var input ReorderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	portfolio, err := h.service.ReorderProjects(r.Context(), portfolioID, userID, version, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrInvalidOrder):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, portfolio.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
+
+// ReorderSections handles reordering the sections of a portfolio
+func (h *Handler) ReorderSections(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	var input ReorderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	portfolio, err := h.service.ReorderSections(r.Context(), portfolioID, userID, version, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrInvalidOrder):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, portfolio.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
+
+// ReorderMedia handles reordering the media of a project
+func (h *Handler) ReorderMedia(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "projectID"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var input ReorderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := requireVersion(w, r)
+	if !ok {
+		return
+	}
+
+	portfolio, err := h.service.ReorderMedia(r.Context(), portfolioID, projectID, userID, version, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrProjectNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrInvalidOrder):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, portfolio.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
+
+// ListRevisions handles listing the revision history of a portfolio
+func (h *Handler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revisions, err := h.service.ListRevisions(r.Context(), portfolioID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// GetRevision handles getting a single revision of a portfolio
+func (h *Handler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	revisionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "revisionID"))
+	if err != nil {
+		http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revision, err := h.service.GetRevision(r.Context(), portfolioID, revisionID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrRevisionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revision)
+}
+
+// DiffRevision handles comparing a revision with another revision or the live portfolio
+func (h *Handler) DiffRevision(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	revisionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "revisionID"))
+	if err != nil {
+		http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	diff, err := h.service.DiffRevision(r.Context(), portfolioID, revisionID, userID, r.URL.Query().Get("against"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrRevisionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrInvalidRevision):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// RestoreRevision handles restoring a portfolio to a previous revision
+func (h *Handler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	portfolioID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+		return
+	}
+
+	revisionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "revisionID"))
+	if err != nil {
+		http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	version, ok := optionalVersion(w, r)
+	if !ok {
+		return
+	}
+
+	portfolio, err := h.service.RestoreRevision(r.Context(), portfolioID, revisionID, userID, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPortfolioNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrRevisionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrUnauthorized):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrSubdomainTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, ErrVersionMismatch):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, portfolio.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
+
+// setETag sets the ETag header to a portfolio version
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// requireVersion reads the portfolio or project version a write is based on
+// from the If-Match header. It responds with 428 if the header is missing and 412 if
+// it can't match any version, reporting false in both cases.
+func requireVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if r.Header.Get("If-Match") == "" {
+		http.Error(w, "If-Match header required", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	return optionalVersion(w, r)
+}
+
+// optionalVersion is like requireVersion but allows the If-Match header to be
+// omitted, in which case the write applies to any version
+func optionalVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return AnyVersion, true
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		unquoted = value
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 0 {
+		http.Error(w, ErrVersionMismatch.Error(), http.StatusPreconditionFailed)
+		return 0, false
+	}
+	return version, true
+}
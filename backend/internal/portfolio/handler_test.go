@@ -0,0 +1,343 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/audit"
+	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/database/databasetest"
+	"github.com/musefolio/backend/internal/storage"
+)
+
+// newTestService creates a service backed by a fresh test database
+func newTestService(t *testing.T) (*Service, *Repository) {
+	t.Helper()
+	db := databasetest.New(t)
+	repo := NewRepository(db, RevisionRetention{})
+	return NewService(repo, audit.NewRepository(db), storage.NewLocal(t.TempDir(), "/media"), nil, nil), repo
+}
+
+func TestVersionFromIfMatch(t *testing.T) {
+	tests := []struct {
+		ifMatch     string
+		required    bool
+		wantVersion int64
+		wantStatus  int
+	}{
+		{"", true, 0, http.StatusPreconditionRequired},
+		{"", false, AnyVersion, 0},
+		{"*", true, AnyVersion, 0},
+		{`"3"`, true, 3, 0},
+		{`W/"3"`, true, 3, 0},
+		{"3", false, 3, 0},
+		{`"-2"`, true, 0, http.StatusPreconditionFailed},
+		{`"abc"`, false, 0, http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/portfolios/1", nil)
+		if tt.ifMatch != "" {
+			r.Header.Set("If-Match", tt.ifMatch)
+		}
+		w := httptest.NewRecorder()
+
+		parse := optionalVersion
+		if tt.required {
+			parse = requireVersion
+		}
+		version, ok := parse(w, r)
+		if ok != (tt.wantStatus == 0) || (ok && version != tt.wantVersion) || (!ok && w.Code != tt.wantStatus) {
+			t.Errorf("If-Match %q (required %v) = %d, %v, status %d; want %d, status %d",
+				tt.ifMatch, tt.required, version, ok, w.Code, tt.wantVersion, tt.wantStatus)
+		}
+	}
+}
+
+func TestUpdateChecksVersion(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	userID := primitive.NewObjectID()
+	router := chi.NewRouter()
+	NewHandler(s).RegisterRoutes(router)
+
+	p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Work", Subdomain: "work"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, "/portfolios/"+p.ID.Hex()+path, strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send(http.MethodPut, "", "", `{"title":"A"}`); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("update without If-Match = %d, want 428", w.Code)
+	}
+	w := send(http.MethodPut, "", `"1"`, `{"title":"B"}`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("update at the current version = %d, ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+	if w := send(http.MethodPut, "", `"1"`, `{"title":"C"}`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("update at a stale version = %d, want 412", w.Code)
+	}
+	if w := send(http.MethodPut, "", "*", `{"title":"D"}`); w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Errorf("update at any version = %d, ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+
+	// Adding a project doesn't need If-Match
+	w = send(http.MethodPost, "/projects", "", `{"title":"Poster","description":"Print","content":"Ink"}`)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"4"` {
+		t.Errorf("add project without If-Match = %d, ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestLibraryProjectRoutesCheckVersion(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	userID := primitive.NewObjectID()
+	router := chi.NewRouter()
+	NewHandler(s).RegisterRoutes(router)
+
+	p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Work", Subdomain: "work"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, _, err := repo.AddProject(ctx, p.ID, userID, p.Version, CreateProjectInput{Title: "Poster"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method, ifMatch, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, "/projects/"+project.ID.Hex(), strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := send(http.MethodGet, "", ""); w.Code != http.StatusOK || w.Header().Get("ETag") != `"1"` {
+		t.Fatalf("get project = %d, ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+	if w := send(http.MethodPut, "", `{"title":"A"}`); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("update without If-Match = %d, want 428", w.Code)
+	}
+	if w := send(http.MethodPut, `"1"`, `{"title":"B"}`); w.Code != http.StatusOK || w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("update at the current version = %d, ETag %s", w.Code, w.Header().Get("ETag"))
+	}
+
+	// Edits through a portfolio change the project version too
+	title := "C"
+	if _, _, err := repo.UpdateProject(ctx, p.ID, project.ID, AnyVersion, UpdateProjectInput{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+	if w := send(http.MethodPut, `"2"`, `{"title":"D"}`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("update at a stale version = %d, want 412", w.Code)
+	}
+
+	if w := send(http.MethodDelete, "", ""); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("delete without If-Match = %d, want 428", w.Code)
+	}
+	if w := send(http.MethodDelete, `"2"`, ""); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("delete at a stale version = %d, want 412", w.Code)
+	}
+	if w := send(http.MethodDelete, `"3"`, ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete at the current version = %d, want 204", w.Code)
+	}
+}
+
+func TestLegacyPortfoliosMatchVersionZero(t *testing.T) {
+	ctx := context.Background()
+	_, repo := newTestService(t)
+
+	// Portfolios stored before versioning have no version field
+	id := primitive.NewObjectID()
+	legacy := bson.M{"_id": id, "userId": primitive.NewObjectID(), "title": "Old", "subdomain": "old", "sections": bson.A{}}
+	if _, err := repo.collection.InsertOne(ctx, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	title := "Stale"
+	if p, err := repo.Update(ctx, id, 1, UpdatePortfolioInput{Title: &title}); err != nil || p != nil {
+		t.Errorf("Update at version 1 = %v, %v; want no match", p, err)
+	}
+	title = "New"
+	p, err := repo.Update(ctx, id, 0, UpdatePortfolioInput{Title: &title})
+	if err != nil || p == nil {
+		t.Fatalf("Update at version 0 = %v, %v", p, err)
+	}
+	if p.Version != 1 || p.Title != "New" {
+		t.Errorf("updated legacy portfolio = version %d, title %q", p.Version, p.Title)
+	}
+}
+
+func TestDiffRevisionRejectsMalformedAgainst(t *testing.T) {
+	// The comparison target is checked before anything is loaded
+	router := chi.NewRouter()
+	NewHandler(NewService(nil, nil, nil, nil, nil)).RegisterRoutes(router)
+
+	path := "/portfolios/" + primitive.NewObjectID().Hex() + "/revisions/" + primitive.NewObjectID().Hex() + "/diff?against=yesterday"
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, primitive.NewObjectID()))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("diff against a malformed ID = %d, want 400", w.Code)
+	}
+}
+
+func TestDiffRevisionAgainstUnknownRevision(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	userID := primitive.NewObjectID()
+
+	p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Work", Subdomain: "work"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := repo.FindRevisions(ctx, p.ID)
+	if err != nil || len(revisions) == 0 {
+		t.Fatalf("revisions = %v, %v", revisions, err)
+	}
+
+	_, err = s.DiffRevision(ctx, p.ID, revisions[0].ID, userID, primitive.NewObjectID().Hex())
+	if !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("diff against an unknown revision = %v, want ErrRevisionNotFound", err)
+	}
+	if _, err := s.DiffRevision(ctx, p.ID, revisions[0].ID, userID, "current"); err != nil {
+		t.Errorf("diff against current = %v", err)
+	}
+}
+
+func TestReorderRoutesCheckRequest(t *testing.T) {
+	// Malformed requests are rejected before anything is loaded
+	router := chi.NewRouter()
+	NewHandler(NewService(nil, nil, nil, nil, nil)).RegisterRoutes(router)
+
+	base := "/portfolios/" + primitive.NewObjectID().Hex()
+	for _, path := range []string{"/projects/order", "/sections/order", "/projects/" + primitive.NewObjectID().Hex() + "/media/order"} {
+		send := func(body, ifMatch string) int {
+			r := httptest.NewRequest(http.MethodPut, base+path, strings.NewReader(body))
+			r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, primitive.NewObjectID()))
+			if ifMatch != "" {
+				r.Header.Set("If-Match", ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			return w.Code
+		}
+		if code := send(`{"ids":`, `"1"`); code != http.StatusBadRequest {
+			t.Errorf("%s with a malformed body = %d, want 400", path, code)
+		}
+		if code := send(`{"ids":[]}`, ""); code != http.StatusPreconditionRequired {
+			t.Errorf("%s without If-Match = %d, want 428", path, code)
+		}
+	}
+}
+
+func TestReorderRoutes(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	userID := primitive.NewObjectID()
+	router := chi.NewRouter()
+	NewHandler(s).RegisterRoutes(router)
+
+	p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Work", Subdomain: "work"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var projects []primitive.ObjectID
+	for i, title := range []string{"Poster", "Flyer"} {
+		project, _, err := repo.AddProject(ctx, p.ID, userID, AnyVersion, CreateProjectInput{Title: title, Order: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		projects = append(projects, project.ID)
+	}
+	var sections, media []primitive.ObjectID
+	for i, title := range []string{"About", "Contact"} {
+		section, _, err := repo.AddSection(ctx, p.ID, AnyVersion, CreateSectionInput{Title: title, Type: "about", Order: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sections = append(sections, section.ID)
+
+		m := Media{ID: primitive.NewObjectID(), Type: "image", URL: "/media/" + title + ".png", Order: i, CreatedAt: primitive.NewDateTimeFromTime(time.Now())}
+		if _, err := repo.AddMedia(ctx, p.ID, projects[0], AnyVersion, m); err != nil {
+			t.Fatal(err)
+		}
+		media = append(media, m.ID)
+	}
+
+	send := func(path, ifMatch string, ids ...primitive.ObjectID) *httptest.ResponseRecorder {
+		t.Helper()
+		hexes := make([]string, len(ids))
+		for i, id := range ids {
+			hexes[i] = `"` + id.Hex() + `"`
+		}
+		body := `{"ids":[` + strings.Join(hexes, ",") + `]}`
+		r := httptest.NewRequest(http.MethodPut, "/portfolios/"+p.ID.Hex()+path, strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	current := func() string {
+		t.Helper()
+		p, err := repo.FindByID(ctx, p.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf(`"%d"`, p.Version)
+	}
+
+	routes := []struct {
+		path string
+		ids  []primitive.ObjectID
+	}{
+		{"/projects/order", projects},
+		{"/sections/order", sections},
+		{"/projects/" + projects[0].Hex() + "/media/order", media},
+	}
+	for _, route := range routes {
+		stale := current()
+		if w := send(route.path, "", route.ids[1], route.ids[0]); w.Code != http.StatusPreconditionRequired {
+			t.Errorf("%s without If-Match = %d, want 428", route.path, w.Code)
+		}
+		if w := send(route.path, stale, route.ids[1]); w.Code != http.StatusBadRequest {
+			t.Errorf("%s missing an ID = %d, want 400", route.path, w.Code)
+		}
+		if w := send(route.path, stale, route.ids[1], route.ids[1]); w.Code != http.StatusBadRequest {
+			t.Errorf("%s with a duplicate ID = %d, want 400", route.path, w.Code)
+		}
+		if w := send(route.path, stale, route.ids[1], route.ids[0]); w.Code != http.StatusOK {
+			t.Errorf("%s = %d, want 200", route.path, w.Code)
+		}
+		if w := send(route.path, stale, route.ids[0], route.ids[1]); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("%s at a stale version = %d, want 412", route.path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ListProjects lists the projects of a user across all their portfolios
+func (s *Service) ListProjects(ctx context.Context, userID primitive.ObjectID) ([]Project, error) {
+	return s.repo.FindProjectsByUserID(ctx, userID)
+}
+
+// GetProject gets a project of the user
+func (s *Service) GetProject(ctx context.Context, projectID, userID primitive.ObjectID) (*Project, error) {
+	project, err := s.repo.FindProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+	if project.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return project, nil
+}
+
+// ListProjectsBySource lists the projects of a user imported from a provider
+func (s *Service) ListProjectsBySource(ctx context.Context, userID primitive.ObjectID, provider string) ([]Project, error) {
+	return s.repo.FindProjectsBySource(ctx, userID, provider)
+}
+
+// SyncProjectSource updates what is known about the origin of an imported
+// project of the user, such as its stars
+func (s *Service) SyncProjectSource(ctx context.Context, projectID, userID primitive.ObjectID, source ProjectSource) error {
+	if _, err := s.GetProject(ctx, projectID, userID); err != nil {
+		return err
+	}
+	return s.repo.SetProjectSource(ctx, projectID, source)
+}
+
+// UpdateLibraryProject updates a project of the user in every portfolio
+// showing it, if the project is still at version. Order and Hidden are set per
+// portfolio and ignored here.
+func (s *Service) UpdateLibraryProject(ctx context.Context, projectID, userID primitive.ObjectID, version int64, input UpdateProjectInput) (*Project, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.getProjectAtVersion(ctx, projectID, userID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Engagement details only apply to case studies
+	kind := existing.Kind
+	if input.Kind != nil {
+		kind = *input.Kind
+	}
+	if input.CaseStudy != nil && kind != ProjectKindCaseStudy {
+		return nil, ErrNotCaseStudy
+	}
+	sanitizeProjectUpdate(&input)
+
+	project, err := s.repo.UpdateLibraryProject(ctx, projectID, version, input)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		// The project changed or was deleted after it was checked
+		return nil, ErrVersionMismatch
+	}
+	return project, nil
+}
+
+// DeleteLibraryProject deletes a project of the user if it is still at
+// version, removing it from every portfolio showing it
+func (s *Service) DeleteLibraryProject(ctx context.Context, projectID, userID primitive.ObjectID, version int64) error {
+	if _, err := s.getProjectAtVersion(ctx, projectID, userID, version); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteLibraryProject(ctx, projectID, version); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrVersionMismatch
+		}
+		return err
+	}
+	return nil
+}
+
+// getProjectAtVersion gets a project of the user, reporting
+// ErrVersionMismatch unless it is at version
+func (s *Service) getProjectAtVersion(ctx context.Context, projectID, userID primitive.ObjectID, version int64) (*Project, error) {
+	project, err := s.GetProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if version != AnyVersion && project.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return project, nil
+}
+
+// LinkProject shows an existing project of the user in a portfolio
+func (s *Service) LinkProject(ctx context.Context, portfolioID, userID primitive.ObjectID, version int64, input LinkProjectInput) (*Project, int64, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return nil, 0, err
+	}
+
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Projects of other users are reported as missing
+	project, err := s.repo.FindProject(ctx, input.ProjectID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if project == nil || project.UserID != userID {
+		return nil, 0, ErrProjectNotFound
+	}
+	if portfolio.hasProject(project.ID) {
+		return nil, 0, ErrProjectLinked
+	}
+
+	ref := ProjectRef{ProjectID: project.ID, Order: len(portfolio.ProjectRefs), Hidden: input.Hidden}
+	if input.Order != nil {
+		ref.Order = *input.Order
+	}
+
+	updated, err := s.repo.LinkProject(ctx, portfolioID, version, ref)
+	if err != nil {
+		return nil, 0, err
+	}
+	if updated == nil {
+		return nil, 0, ErrVersionMismatch
+	}
+	return updated.findProject(project.ID), updated.Version, nil
+}
@@ -0,0 +1,69 @@
+package portfolio
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// embeddedPortfolio is a portfolio stored before projects had their own
+// collection
+type embeddedPortfolio struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	UserID   primitive.ObjectID `bson:"userId"`
+	Projects []embeddedProject  `bson:"projects"`
+}
+
+// embeddedProject is a project stored inside its portfolio, with its order
+type embeddedProject struct {
+	Project `bson:",inline"`
+	Order   int `bson:"order"`
+}
+
+// MigrateEmbeddedProjects moves projects still embedded in portfolio
+// documents into the projects collection and replaces them with references.
+// It is safe to run repeatedly and returns the number of portfolios migrated.
+func (r *Repository) MigrateEmbeddedProjects(ctx context.Context) (int, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"projects": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var legacy embeddedPortfolio
+		if err := cursor.Decode(&legacy); err != nil {
+			return migrated, err
+		}
+
+		refs := make([]ProjectRef, 0, len(legacy.Projects))
+		for _, embedded := range legacy.Projects {
+			project := embedded.Project
+			project.UserID = legacy.UserID
+			if project.Media == nil {
+				project.Media = []Media{}
+			}
+
+			// A previous, interrupted run may already have moved the project
+			opts := options.Replace().SetUpsert(true)
+			if _, err := r.projects.ReplaceOne(ctx, bson.M{"_id": project.ID}, project, opts); err != nil {
+				return migrated, err
+			}
+			refs = append(refs, ProjectRef{ProjectID: project.ID, Order: embedded.Order})
+		}
+
+		update := bson.M{
+			"$set":   bson.M{"projectRefs": refs},
+			"$unset": bson.M{"projects": ""},
+		}
+		if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": legacy.ID}, update); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
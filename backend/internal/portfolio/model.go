@@ -1,53 +1,292 @@
 package portfolio
 
 import (
+	"context"
+	"encoding/json"
+	"sort"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/markdown"
+	"github.com/musefolio/backend/internal/section"
 )
 
-// Portfolio represents a user's portfolio
+// Portfolio represents a user's portfolio. Projects live in their own
+// collection and are shared between the portfolios of their owner: a
+// portfolio stores references to them in ProjectRefs, and Projects is filled
+// in from those when the portfolio is loaded. Only revision snapshots store
+// Projects.
 type Portfolio struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID       primitive.ObjectID `bson:"userId" json:"userId"`
-	Title        string             `bson:"title" json:"title"`
-	Description  string             `bson:"description" json:"description"`
-	Theme        string             `bson:"theme" json:"theme"`
-	Layout       string             `bson:"layout" json:"layout"`
-	Type         string             `bson:"type" json:"type"`
-	Projects     []Project          `bson:"projects" json:"projects"`
-	Sections     []Section          `bson:"sections" json:"sections"`
-	Subdomain    string             `bson:"subdomain" json:"subdomain"`
-	CustomDomain *string            `bson:"customDomain,omitempty" json:"customDomain,omitempty"`
-	IsPublished  bool               `bson:"isPublished" json:"isPublished"`
-	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
-}
-
-// Project represents a portfolio project
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID  `bson:"userId" json:"userId"`
+	Title        string              `bson:"title" json:"title"`
+	Description  string              `bson:"description" json:"description"`
+	Theme        string              `bson:"theme" json:"theme"`
+	Layout       string              `bson:"layout" json:"layout"`
+	Type         string              `bson:"type" json:"type"`
+	Projects     []Project           `bson:"projects,omitempty" json:"projects"`
+	ProjectRefs  []ProjectRef        `bson:"projectRefs" json:"-"`
+	Sections     []Section           `bson:"sections" json:"sections"`
+	Subdomain    string              `bson:"subdomain" json:"subdomain"`
+	CustomDomain *string             `bson:"customDomain,omitempty" json:"customDomain,omitempty"`
+	IsPublished  bool                `bson:"isPublished" json:"isPublished"`
+	PublishAt    *time.Time          `bson:"publishAt,omitempty" json:"publishAt,omitempty"`
+	UnpublishAt  *time.Time          `bson:"unpublishAt,omitempty" json:"unpublishAt,omitempty"`
+	TemplateID   *primitive.ObjectID `bson:"templateId,omitempty" json:"templateId,omitempty"`
+	Version      int64               `bson:"version" json:"version"`
+	CreatedAt    time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+
+// sortByOrder sorts projects, sections, case-study stages and media by their
+// Order field
+func (p *Portfolio) sortByOrder() {
+	sort.SliceStable(p.Projects, func(i, j int) bool {
+		return p.Projects[i].Order < p.Projects[j].Order
+	})
+	sort.SliceStable(p.Sections, func(i, j int) bool {
+		return p.Sections[i].Order < p.Sections[j].Order
+	})
+	for i := range p.Projects {
+		p.Projects[i].sortByOrder()
+	}
+}
+
+// sortByOrder sorts the media and case-study stages of a project by their
+// Order field
+func (p *Project) sortByOrder() {
+	sortMedia(p.Media)
+	if cs := p.CaseStudy; cs != nil {
+		sort.SliceStable(cs.Stages, func(i, j int) bool {
+			return cs.Stages[i].Order < cs.Stages[j].Order
+		})
+		for j := range cs.Stages {
+			sortMedia(cs.Stages[j].Media)
+		}
+	}
+}
+
+// sortMedia sorts media by their Order field
+func sortMedia(media []Media) {
+	sort.SliceStable(media, func(i, j int) bool {
+		return media[i].Order < media[j].Order
+	})
+}
+
+// applyRefs copies the per-portfolio order and visibility of each project
+// from the portfolio's references. Snapshots recorded before projects had
+// their own collection have no references; their projects are already sorted.
+func (p *Portfolio) applyRefs() {
+	if len(p.ProjectRefs) == 0 {
+		for i := range p.Projects {
+			p.Projects[i].Order = i
+		}
+		return
+	}
+	refs := make(map[primitive.ObjectID]ProjectRef, len(p.ProjectRefs))
+	for _, ref := range p.ProjectRefs {
+		refs[ref.ProjectID] = ref
+	}
+	for i := range p.Projects {
+		if ref, ok := refs[p.Projects[i].ID]; ok {
+			p.Projects[i].Order = ref.Order
+			p.Projects[i].Hidden = ref.Hidden
+		}
+	}
+}
+
+// VisibleProjects returns the projects shown on the public site
+func (p *Portfolio) VisibleProjects() []Project {
+	visible := make([]Project, 0, len(p.Projects))
+	for _, project := range p.Projects {
+		if !project.Hidden {
+			visible = append(visible, project)
+		}
+	}
+	return visible
+}
+
+// hasProject reports whether the portfolio references the project
+func (p *Portfolio) hasProject(id primitive.ObjectID) bool {
+	for _, ref := range p.ProjectRefs {
+		if ref.ProjectID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// refsOf builds the references placing projects in a portfolio
+func refsOf(projects []Project) []ProjectRef {
+	refs := make([]ProjectRef, len(projects))
+	for i, project := range projects {
+		refs[i] = ProjectRef{ProjectID: project.ID, Order: project.Order, Hidden: project.Hidden}
+	}
+	return refs
+}
+
+// findProject returns the project with the given ID, or nil
+func (p *Portfolio) findProject(id primitive.ObjectID) *Project {
+	for i := range p.Projects {
+		if p.Projects[i].ID == id {
+			return &p.Projects[i]
+		}
+	}
+	return nil
+}
+
+// findSection returns the section with the given ID, or nil
+func (p *Portfolio) findSection(id primitive.ObjectID) *Section {
+	for i := range p.Sections {
+		if p.Sections[i].ID == id {
+			return &p.Sections[i]
+		}
+	}
+	return nil
+}
+
+// Project kinds
+const (
+	ProjectKindStandard  = "standard"
+	ProjectKindCaseStudy = "case-study"
+)
+
+// ProjectRef places a project in a portfolio. Order and visibility are set
+// per portfolio, everything else is shared.
+type ProjectRef struct {
+	ProjectID primitive.ObjectID `bson:"_id"`
+	Order     int                `bson:"order"`
+	Hidden    bool               `bson:"hidden,omitempty"`
+}
+
+// Project represents a project of a user, shown in any number of their
+// portfolios. Blocks is the canvas of the project; projects without blocks
+// are rendered from Content. Case-study projects are told through the stages
+// of CaseStudy instead. Order and Hidden are taken from the reference of the
+// portfolio the project was loaded through. Version counts the changes of the
+// project itself, whichever portfolio they were made through.
 type Project struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Kind        string             `bson:"kind,omitempty" json:"kind,omitempty"`
 	Title       string             `bson:"title" json:"title"`
 	Description string             `bson:"description" json:"description"`
 	Content     string             `bson:"content" json:"content"`
+	Blocks      []block.Block      `bson:"blocks,omitempty" json:"blocks,omitempty"`
+	CaseStudy   *CaseStudy         `bson:"caseStudy,omitempty" json:"caseStudy,omitempty"`
 	Media       []Media            `bson:"media" json:"media"`
 	Tags        []string           `bson:"tags" json:"tags"`
-	Order       int                `bson:"order" json:"order"`
+	Source      *ProjectSource     `bson:"source,omitempty" json:"source,omitempty"`
+	Order       int                `bson:"-" json:"order"`
+	Hidden      bool               `bson:"-" json:"hidden"`
+	Version     int64              `bson:"version" json:"version"`
 	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
 
+// MarshalJSON adds the HTML rendering of the project's Markdown content as
+// contentHtml
+func (p Project) MarshalJSON() ([]byte, error) {
+	type project Project
+	return json.Marshal(struct {
+		project
+		ContentHTML string `json:"contentHtml,omitempty"`
+	}{project(p), string(markdown.Render(p.Content))})
+}
+
+// ProjectSource links an imported project to where it came from, with the
+// statistics last synced from there
+type ProjectSource struct {
+	Provider string `bson:"provider" json:"provider"`
+	// ExternalID identifies the project at the provider
+	ExternalID string    `bson:"externalId" json:"externalId"`
+	URL        string    `bson:"url" json:"url"`
+	Stars      int       `bson:"stars,omitempty" json:"stars,omitempty"`
+	Language   string    `bson:"language,omitempty" json:"language,omitempty"`
+	SyncedAt   time.Time `bson:"syncedAt" json:"syncedAt"`
+}
+
+// IsCaseStudy reports whether the project is a case study
+func (p *Project) IsCaseStudy() bool {
+	return p.Kind == ProjectKindCaseStudy
+}
+
+// findStage returns the case-study stage with the given ID, or nil
+func (p *Project) findStage(id primitive.ObjectID) *Stage {
+	if p.CaseStudy == nil {
+		return nil
+	}
+	for i := range p.CaseStudy.Stages {
+		if p.CaseStudy.Stages[i].ID == id {
+			return &p.CaseStudy.Stages[i]
+		}
+	}
+	return nil
+}
+
+// CaseStudy holds the engagement details and the story of a case-study project
+type CaseStudy struct {
+	Role     string   `bson:"role" json:"role"`
+	Team     []string `bson:"team" json:"team"`
+	Duration string   `bson:"duration" json:"duration"`
+	Client   string   `bson:"client" json:"client"`
+	Stages   []Stage  `bson:"stages" json:"stages"`
+}
+
+// Stage types of a case study, in the order they are usually told
+const (
+	StageProblem   = "problem"
+	StageResearch  = "research"
+	StageProcess   = "process"
+	StageSolution  = "solution"
+	StageImpact    = "impact"
+	StageLearnings = "learnings"
+)
+
+// Stage is one chapter of a case study. Body holds rich text; metrics are
+// only used by impact stages.
+type Stage struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Type      string             `bson:"type" json:"type"`
+	Title     string             `bson:"title" json:"title"`
+	Body      string             `bson:"body" json:"body"`
+	Metrics   []Metric           `bson:"metrics,omitempty" json:"metrics,omitempty"`
+	Media     []Media            `bson:"media" json:"media"`
+	Order     int                `bson:"order" json:"order"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Metric is a measured outcome of a case study, such as "+32%" conversion
+type Metric struct {
+	Label       string `bson:"label" json:"label" validate:"required,max=100"`
+	Value       string `bson:"value" json:"value" validate:"required,max=50"`
+	Description string `bson:"description,omitempty" json:"description,omitempty" validate:"max=300"`
+}
+
 // Section represents a custom portfolio section
 type Section struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Title     string             `bson:"title" json:"title"`
 	Type      string             `bson:"type" json:"type"`
-	Content   string             `bson:"content" json:"content"`
+	Content   section.Content    `bson:"content" json:"content"`
 	Order     int                `bson:"order" json:"order"`
 	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
 
+// MarshalJSON adds the HTML rendering of the Markdown fields of the
+// section's content as contentHtml
+func (s Section) MarshalJSON() ([]byte, error) {
+	type sectionJSON Section
+	return json.Marshal(struct {
+		sectionJSON
+		ContentHTML section.Content `json:"contentHtml,omitempty"`
+	}{sectionJSON(s), section.Render(s.Type, s.Content)})
+}
+
 // Media represents a media file in a project
 type Media struct {
 	ID        primitive.ObjectID `bson:"_id" json:"id"`
@@ -62,10 +301,18 @@ type Media struct {
 type CreatePortfolioInput struct {
 	Title       string `json:"title" validate:"required"`
 	Description string `json:"description" validate:"required"`
-	Theme       string `json:"theme" validate:"required"`
-	Layout      string `json:"layout" validate:"required"`
+	Theme       string `json:"theme" validate:"required_without=TemplateID"`
+	Layout      string `json:"layout" validate:"required_without=TemplateID"`
 	Subdomain   string `json:"subdomain" validate:"required,min=3,alphanum"`
 	Type        string `json:"type" validate:"omitempty,oneof=about cv portfolio"`
+	TemplateID  string `json:"templateId,omitempty" validate:"omitempty,mongodb"`
+}
+
+// PortfolioSeed holds the initial content of a new portfolio
+type PortfolioSeed struct {
+	TemplateID *primitive.ObjectID
+	Sections   []Section
+	Projects   []Project
 }
 
 // UpdatePortfolioInput represents the input for updating a portfolio
@@ -80,43 +327,221 @@ type UpdatePortfolioInput struct {
 	Type         *string `json:"type,omitempty" validate:"omitempty,oneof=about cv portfolio"`
 }
 
+// DuplicatePortfolioInput represents the input for duplicating a portfolio
+type DuplicatePortfolioInput struct {
+	Title     *string `json:"title,omitempty"`
+	Subdomain *string `json:"subdomain,omitempty" validate:"omitempty,min=3,alphanum"`
+	Type      *string `json:"type,omitempty" validate:"omitempty,oneof=about cv portfolio"`
+	// SectionTypes maps section types of the source to the types used in the
+	// copy; an empty value drops sections of that type
+	SectionTypes map[string]string `json:"sectionTypes,omitempty"`
+	// AsTemplate copies the structure of the portfolio without its content
+	AsTemplate bool `json:"asTemplate"`
+}
+
+// ScheduleInput represents the input for scheduling a portfolio to be
+// published or unpublished. A nil time clears that part of the schedule.
+type ScheduleInput struct {
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
+}
+
+// PublishEvent describes a change of a portfolio's published state
+type PublishEvent struct {
+	Portfolio *Portfolio
+	Published bool
+	Scheduled bool
+	At        time.Time
+}
+
+// PublishHook is notified whenever a portfolio is published or unpublished
+type PublishHook func(ctx context.Context, event PublishEvent)
+
 // CreateProjectInput represents the input for creating a new project
 type CreateProjectInput struct {
+	Kind        string   `json:"kind,omitempty" validate:"omitempty,oneof=standard case-study"`
 	Title       string   `json:"title" validate:"required"`
 	Description string   `json:"description" validate:"required"`
-	Content     string   `json:"content" validate:"required"`
+	Content     string   `json:"content" validate:"required_unless=Kind case-study"`
 	Tags        []string `json:"tags"`
 	Order       int      `json:"order"`
+	// CaseStudy holds the engagement details of a case-study project
+	CaseStudy *CaseStudyInput `json:"caseStudy,omitempty"`
+	// Source is set by importers and can't be set through the API
+	Source *ProjectSource `json:"-"`
+	// CreatedAt keeps the original date of an imported project; it can't be
+	// set through the API
+	CreatedAt *time.Time `json:"-"`
 }
 
-// UpdateProjectInput represents the input for updating a project
+// UpdateProjectInput represents the input for updating a project. Order and
+// Hidden only apply to the portfolio the project is updated through.
 type UpdateProjectInput struct {
+	Kind        *string   `json:"kind,omitempty" validate:"omitempty,oneof=standard case-study"`
 	Title       *string   `json:"title,omitempty"`
 	Description *string   `json:"description,omitempty"`
 	Content     *string   `json:"content,omitempty"`
 	Tags        *[]string `json:"tags,omitempty"`
 	Order       *int      `json:"order,omitempty"`
+	Hidden      *bool     `json:"hidden,omitempty"`
+	// CaseStudy replaces the engagement details; stages are left untouched
+	CaseStudy *CaseStudyInput `json:"caseStudy,omitempty"`
+}
+
+// LinkProjectInput represents the input for showing an existing project of
+// the user in a portfolio
+type LinkProjectInput struct {
+	ProjectID primitive.ObjectID `json:"projectId" validate:"required"`
+	// Order defaults to placing the project after the existing projects
+	Order  *int `json:"order,omitempty"`
+	Hidden bool `json:"hidden"`
+}
+
+// CaseStudyInput represents the engagement details of a case study
+type CaseStudyInput struct {
+	Role     string   `json:"role" validate:"max=200"`
+	Team     []string `json:"team" validate:"max=50,dive,max=200"`
+	Duration string   `json:"duration" validate:"max=100"`
+	Client   string   `json:"client" validate:"max=200"`
+}
+
+// CreateStageInput represents the input for adding a stage to a case study
+type CreateStageInput struct {
+	Type    string   `json:"type" validate:"required,oneof=problem research process solution impact learnings"`
+	Title   string   `json:"title" validate:"max=200"`
+	Body    string   `json:"body" validate:"max=50000"`
+	Metrics []Metric `json:"metrics,omitempty" validate:"max=20,dive"`
+	// Order defaults to placing the stage after the existing stages
+	Order *int `json:"order,omitempty"`
+}
+
+// UpdateStageInput represents the input for updating a case-study stage
+type UpdateStageInput struct {
+	Type    *string   `json:"type,omitempty" validate:"omitempty,oneof=problem research process solution impact learnings"`
+	Title   *string   `json:"title,omitempty" validate:"omitempty,max=200"`
+	Body    *string   `json:"body,omitempty" validate:"omitempty,max=50000"`
+	Metrics *[]Metric `json:"metrics,omitempty" validate:"omitempty,max=20,dive"`
+	Order   *int      `json:"order,omitempty"`
 }
 
 // CreateSectionInput represents the input for creating a new section
 type CreateSectionInput struct {
-	Title   string `json:"title" validate:"required"`
-	Type    string `json:"type" validate:"required"`
-	Content string `json:"content" validate:"required"`
-	Order   int    `json:"order"`
+	Title   string          `json:"title" validate:"required"`
+	Type    string          `json:"type" validate:"required"`
+	Content section.Content `json:"content"`
+	Order   int             `json:"order"`
 }
 
 // UpdateSectionInput represents the input for updating a section
 type UpdateSectionInput struct {
-	Title   *string `json:"title,omitempty"`
-	Type    *string `json:"type,omitempty"`
-	Content *string `json:"content,omitempty"`
-	Order   *int    `json:"order,omitempty"`
+	Title   *string          `json:"title,omitempty"`
+	Type    *string          `json:"type,omitempty"`
+	Content *section.Content `json:"content,omitempty"`
+	Order   *int             `json:"order,omitempty"`
+}
+
+// CreateBlockInput represents the input for adding a block to a project canvas
+type CreateBlockInput struct {
+	Block block.Block `json:"block"`
+	// ParentID is the container block to add to, the canvas root if nil
+	ParentID *primitive.ObjectID `json:"parentId,omitempty"`
+	// Index is the position among the parent's children, appended if nil
+	Index *int `json:"index,omitempty"`
+}
+
+// UpdateBlockInput represents the input for updating a block
+type UpdateBlockInput struct {
+	Props  *block.Props  `json:"props,omitempty"`
+	Layout *block.Layout `json:"layout,omitempty"`
+}
+
+// MoveBlockInput represents the input for moving a block within a canvas
+type MoveBlockInput struct {
+	ParentID *primitive.ObjectID `json:"parentId"`
+	Index    int                 `json:"index"`
+}
+
+// ReplaceBlocksInput represents the input for saving a whole canvas
+type ReplaceBlocksInput struct {
+	Blocks []block.Block `json:"blocks"`
 }
 
 // UploadMediaInput represents the input for uploading media
 type UploadMediaInput struct {
 	Type    string `json:"type" validate:"required,oneof=image video document"`
 	Caption string `json:"caption"`
-	Order   int    `json:"order"`
+	// Order defaults to placing the media after the project's existing media
+	Order *int `json:"order,omitempty"`
+}
+
+// ReorderInput represents the complete new order of a collection as a list of IDs
+type ReorderInput struct {
+	IDs []primitive.ObjectID `json:"ids"`
+}
+
+// Revision is a snapshot of a portfolio recorded after a mutation
+type Revision struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PortfolioID primitive.ObjectID  `bson:"portfolioId" json:"portfolioId"`
+	AuthorID    *primitive.ObjectID `bson:"authorId,omitempty" json:"authorId,omitempty"`
+	Action      string              `bson:"action" json:"action"`
+	Snapshot    *Portfolio          `bson:"snapshot,omitempty" json:"snapshot,omitempty"`
+	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
+}
+
+// RevisionRetention controls how many revisions are kept per portfolio
+type RevisionRetention struct {
+	MaxRevisions int
+	MaxAge       time.Duration
+}
+
+// RevisionChange describes a single field difference between two snapshots
+type RevisionChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// RevisionDiff is the result of comparing two portfolio snapshots
+type RevisionDiff struct {
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Changes []RevisionChange `json:"changes"`
+}
+
+// Kinds of search hits
+const (
+	SearchHitPortfolio = "portfolio"
+	SearchHitProject   = "project"
+	SearchHitSection   = "section"
+)
+
+// SearchHit is a portfolio, project or section matching a search
+type SearchHit struct {
+	Kind string `json:"kind"`
+	// ID is the ID of the portfolio, project or section
+	ID    primitive.ObjectID `json:"id"`
+	Title string             `json:"title"`
+	// Field names the field the snippet was taken from
+	Field string `json:"field,omitempty"`
+	// Snippet is an HTML-escaped excerpt with the matched words in <mark>
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+	// Portfolios are where the hit is found: the portfolio itself, the
+	// portfolio of a section or the portfolios showing a project
+	Portfolios []SearchPortfolio `json:"portfolios"`
+}
+
+// SearchPortfolio identifies the portfolio of a search hit
+type SearchPortfolio struct {
+	ID        primitive.ObjectID `json:"id"`
+	Title     string             `json:"title"`
+	Subdomain string             `json:"subdomain"`
+}
+
+// SearchResult lists the hits of a search, best first
+type SearchResult struct {
+	Query string      `json:"query"`
+	Hits  []SearchHit `json:"hits"`
 }
@@ -0,0 +1,75 @@
+package portfolio
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/section"
+)
+
+func TestApplyRefsUsesPerPortfolioPlacement(t *testing.T) {
+	first := Project{ID: primitive.NewObjectID(), Title: "First"}
+	second := Project{ID: primitive.NewObjectID(), Title: "Second"}
+
+	p := &Portfolio{
+		Projects: []Project{first, second},
+		ProjectRefs: []ProjectRef{
+			{ProjectID: second.ID, Order: 0},
+			{ProjectID: first.ID, Order: 1, Hidden: true},
+		},
+	}
+	p.applyRefs()
+	p.sortByOrder()
+
+	if p.Projects[0].ID != second.ID || p.Projects[1].ID != first.ID {
+		t.Fatalf("projects = %s, %s; want Second, First", p.Projects[0].Title, p.Projects[1].Title)
+	}
+	visible := p.VisibleProjects()
+	if len(visible) != 1 || visible[0].ID != second.ID {
+		t.Errorf("visible projects = %v, want only Second", visible)
+	}
+
+	refs := refsOf(p.Projects)
+	if refs[1].ProjectID != first.ID || refs[1].Order != 1 || !refs[1].Hidden {
+		t.Errorf("refsOf = %+v, want First hidden at order 1", refs[1])
+	}
+}
+
+func TestApplyRefsKeepsLegacySnapshotOrder(t *testing.T) {
+	p := &Portfolio{Projects: []Project{{Title: "A"}, {Title: "B"}}}
+	p.applyRefs()
+
+	for i, project := range p.Projects {
+		if project.Order != i {
+			t.Errorf("project %s order = %d, want %d", project.Title, project.Order, i)
+		}
+	}
+}
+
+func TestJSONIncludesRenderedContent(t *testing.T) {
+	data, err := json.Marshal(Portfolio{
+		Projects: []Project{{Title: "Poster", Content: "**Bold** move"}},
+		Sections: []Section{{Type: "about", Content: section.Content{"headline": "Hi", "text": "About *me*"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Projects []map[string]interface{} `json:"projects"`
+		Sections []map[string]interface{} `json:"sections"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	project := out.Projects[0]
+	if project["content"] != "**Bold** move" || project["contentHtml"] != "<p><strong>Bold</strong> move</p>\n" || project["title"] != "Poster" {
+		t.Errorf("project = %v", project)
+	}
+	rendered, _ := out.Sections[0]["contentHtml"].(map[string]interface{})
+	if rendered["text"] != "<p>About <em>me</em></p>\n" || rendered["headline"] != nil {
+		t.Errorf("section contentHtml = %v", out.Sections[0]["contentHtml"])
+	}
+}
@@ -2,6 +2,9 @@ package portfolio
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -9,25 +12,40 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/block"
 	"github.com/musefolio/backend/internal/database"
+	"github.com/musefolio/backend/internal/pagination"
 )
 
+// AnyVersion disables the optimistic concurrency check on a mutation
+const AnyVersion int64 = -1
+
 // Repository handles portfolio data operations
 type Repository struct {
 	db         *database.DB
 	collection *mongo.Collection
+	projects   *mongo.Collection
+	revisions  *mongo.Collection
+	retention  RevisionRetention
+	// afterClaim, if set, runs between claiming a portfolio version and
+	// updating the project in updateProjectIf, so tests can race it
+	afterClaim func()
 }
 
 // NewRepository creates a new portfolio repository
-func NewRepository(db *database.DB) *Repository {
+func NewRepository(db *database.DB, retention RevisionRetention) *Repository {
 	return &Repository{
 		db:         db,
 		collection: db.Collection(database.PortfoliosCollection),
+		projects:   db.Collection(database.ProjectsCollection),
+		revisions:  db.Collection(database.RevisionsCollection),
+		retention:  retention,
 	}
 }
 
 // Create creates a new portfolio
-func (r *Repository) Create(ctx context.Context, userID primitive.ObjectID, input CreatePortfolioInput) (*Portfolio, error) {
+func (r *Repository) Create(ctx context.Context, userID primitive.ObjectID, input CreatePortfolioInput, seed PortfolioSeed) (*Portfolio, error) {
 	now := time.Now()
 	portfolio := &Portfolio{
 		ID:          primitive.NewObjectID(),
@@ -37,22 +55,67 @@ func (r *Repository) Create(ctx context.Context, userID primitive.ObjectID, inpu
 		Theme:       input.Theme,
 		Layout:      input.Layout,
 		Type:        input.Type,
-		Projects:    []Project{},
-		Sections:    []Section{},
+		Projects:    seed.Projects,
+		Sections:    seed.Sections,
 		Subdomain:   input.Subdomain,
 		IsPublished: false,
+		TemplateID:  seed.TemplateID,
+		Version:     1,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
+	if portfolio.Projects == nil {
+		portfolio.Projects = []Project{}
+	}
+	if portfolio.Sections == nil {
+		portfolio.Sections = []Section{}
+	}
 
-	_, err := r.collection.InsertOne(ctx, portfolio)
-	if err != nil {
+	if err := r.Insert(ctx, portfolio, "portfolio.create"); err != nil {
 		return nil, err
 	}
-
 	return portfolio, nil
 }
 
+// Insert stores a fully built portfolio, e.g. a copy of another one. Projects
+// that don't exist yet are stored as well; existing ones are shared.
+func (r *Repository) Insert(ctx context.Context, portfolio *Portfolio, action string) error {
+	for i := range portfolio.Projects {
+		portfolio.Projects[i].UserID = portfolio.UserID
+		if err := r.insertProject(ctx, &portfolio.Projects[i]); err != nil {
+			return err
+		}
+	}
+	portfolio.ProjectRefs = refsOf(portfolio.Projects)
+
+	doc := *portfolio
+	doc.Projects = nil
+	if _, err := r.collection.InsertOne(ctx, doc); err != nil {
+		return err
+	}
+
+	r.recordRevision(ctx, portfolio, action)
+
+	return nil
+}
+
+// insertProject stores a project unless a project with its ID exists
+func (r *Repository) insertProject(ctx context.Context, project *Project) error {
+	data, err := bson.Marshal(project)
+	if err != nil {
+		return err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	delete(fields, "_id")
+
+	opts := options.Update().SetUpsert(true)
+	_, err = r.projects.UpdateOne(ctx, bson.M{"_id": project.ID}, bson.M{"$setOnInsert": fields}, opts)
+	return err
+}
+
 // FindByID finds a portfolio by ID
 func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*Portfolio, error) {
 	var portfolio Portfolio
@@ -63,6 +126,9 @@ func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*Port
 		}
 		return nil, err
 	}
+	if err := r.hydrate(ctx, &portfolio); err != nil {
+		return nil, err
+	}
 	return &portfolio, nil
 }
 
@@ -78,10 +144,64 @@ func (r *Repository) FindByUserID(ctx context.Context, userID primitive.ObjectID
 	if err := cursor.All(ctx, &portfolios); err != nil {
 		return nil, err
 	}
+	if err := r.hydrate(ctx, portfolios...); err != nil {
+		return nil, err
+	}
+
+	return portfolios, nil
+}
+
+// ListByUserID lists a page of the portfolios of a user
+func (r *Repository) ListByUserID(ctx context.Context, userID primitive.ObjectID, p pagination.Params) ([]*Portfolio, error) {
+	cursor, err := r.collection.Find(ctx, p.Query(bson.M{"userId": userID}), p.FindOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
+	portfolios := []*Portfolio{}
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return nil, err
+	}
+	if err := r.hydrate(ctx, portfolios...); err != nil {
+		return nil, err
+	}
 	return portfolios, nil
 }
 
+// FindPublisherIDs finds the users with at least one published portfolio
+func (r *Repository) FindPublisherIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	return r.distinctIDs(ctx, "userId", bson.M{"isPublished": true})
+}
+
+// FindOwnerIDsUpdatedSince finds the users with a portfolio updated at or
+// after a point in time
+func (r *Repository) FindOwnerIDsUpdatedSince(ctx context.Context, since time.Time) ([]primitive.ObjectID, error) {
+	return r.distinctIDs(ctx, "userId", bson.M{"updatedAt": bson.M{"$gte": since}})
+}
+
+// FindPublishedIDs finds the IDs of the published portfolios
+func (r *Repository) FindPublishedIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	return r.distinctIDs(ctx, "_id", bson.M{"isPublished": true})
+}
+
+// distinctIDs finds the distinct IDs in a field of the portfolios matching
+// filter
+func (r *Repository) distinctIDs(ctx context.Context, field string, filter bson.M) ([]primitive.ObjectID, error) {
+	values, err := r.collection.Distinct(ctx, field, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 // FindBySubdomain finds a portfolio by subdomain
 func (r *Repository) FindBySubdomain(ctx context.Context, subdomain string) (*Portfolio, error) {
 	var portfolio Portfolio
@@ -92,186 +212,377 @@ func (r *Repository) FindBySubdomain(ctx context.Context, subdomain string) (*Po
 		}
 		return nil, err
 	}
+	if err := r.hydrate(ctx, &portfolio); err != nil {
+		return nil, err
+	}
 	return &portfolio, nil
 }
 
 // Update updates a portfolio
-func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, input UpdatePortfolioInput) (*Portfolio, error) {
-	update := bson.M{
-		"$set": bson.M{
-			"updatedAt": time.Now(),
-		},
-	}
+func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, version int64, input UpdatePortfolioInput) (*Portfolio, error) {
+	set := bson.M{}
 
 	if input.Title != nil {
-		update["$set"].(bson.M)["title"] = *input.Title
+		set["title"] = *input.Title
 	}
 	if input.Description != nil {
-		update["$set"].(bson.M)["description"] = *input.Description
+		set["description"] = *input.Description
 	}
 	if input.Theme != nil {
-		update["$set"].(bson.M)["theme"] = *input.Theme
+		set["theme"] = *input.Theme
 	}
 	if input.Layout != nil {
-		update["$set"].(bson.M)["layout"] = *input.Layout
+		set["layout"] = *input.Layout
 	}
 	if input.Subdomain != nil {
-		update["$set"].(bson.M)["subdomain"] = *input.Subdomain
+		set["subdomain"] = *input.Subdomain
 	}
 	if input.CustomDomain != nil {
-		update["$set"].(bson.M)["customDomain"] = *input.CustomDomain
+		set["customDomain"] = *input.CustomDomain
 	}
 	if input.IsPublished != nil {
-		update["$set"].(bson.M)["isPublished"] = *input.IsPublished
+		set["isPublished"] = *input.IsPublished
 	}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var portfolio Portfolio
-	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&portfolio)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &portfolio, nil
+	return r.update(ctx, bson.M{"_id": id}, version, bson.M{"$set": set}, "portfolio.update")
 }
 
-// Delete deletes a portfolio
-func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+// Delete deletes a portfolio and its revision history
+func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID, version int64) error {
+	filter := bson.M{"_id": id}
+	addVersionFilter(filter, version)
+
+	result, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return err
 	}
 	if result.DeletedCount == 0 {
 		return mongo.ErrNoDocuments
 	}
+
+	if _, err := r.revisions.DeleteMany(ctx, bson.M{"portfolioId": id}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// AddProject adds a project to a portfolio
-func (r *Repository) AddProject(ctx context.Context, portfolioID primitive.ObjectID, input CreateProjectInput) (*Project, error) {
+// AddProject creates a project of userID and adds it to a portfolio
+func (r *Repository) AddProject(ctx context.Context, portfolioID, userID primitive.ObjectID, version int64, input CreateProjectInput) (*Project, *Portfolio, error) {
 	now := time.Now()
 	project := &Project{
 		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Kind:        input.Kind,
 		Title:       input.Title,
 		Description: input.Description,
 		Content:     input.Content,
 		Tags:        input.Tags,
+		Source:      input.Source,
 		Order:       input.Order,
 		Media:       []Media{},
+		Version:     1,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
-
-	update := bson.M{
-		"$push": bson.M{"projects": project},
-		"$set":  bson.M{"updatedAt": now},
+	if input.CreatedAt != nil {
+		project.CreatedAt = *input.CreatedAt
+	}
+	if project.IsCaseStudy() {
+		project.CaseStudy = &CaseStudy{Team: []string{}, Stages: []Stage{}}
+		if input.CaseStudy != nil {
+			project.CaseStudy.Role = input.CaseStudy.Role
+			project.CaseStudy.Duration = input.CaseStudy.Duration
+			project.CaseStudy.Client = input.CaseStudy.Client
+			if input.CaseStudy.Team != nil {
+				project.CaseStudy.Team = input.CaseStudy.Team
+			}
+		}
 	}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var portfolio Portfolio
-	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": portfolioID}, update, opts).Decode(&portfolio)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
+	if _, err := r.projects.InsertOne(ctx, project); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, nil, ErrProjectImported
 		}
-		return nil, err
+		return nil, nil, err
+	}
+
+	update := bson.M{
+		"$push": bson.M{"projectRefs": ProjectRef{ProjectID: project.ID, Order: input.Order}},
 	}
 
-	// Find and return the newly added project
-	for _, p := range portfolio.Projects {
-		if p.ID == project.ID {
-			return &p, nil
+	portfolio, err := r.update(ctx, bson.M{"_id": portfolioID}, version, update, "project.add")
+	if err != nil || portfolio == nil {
+		// The project belongs to no portfolio, and kept it would block a
+		// retried import of its source
+		if _, deleteErr := r.projects.DeleteOne(ctx, bson.M{"_id": project.ID}); deleteErr != nil {
+			return nil, nil, errors.Join(err, deleteErr)
 		}
+		return nil, nil, err
 	}
 
-	return nil, nil
+	return portfolio.findProject(project.ID), portfolio, nil
 }
 
-// UpdateProject updates a project in a portfolio
-func (r *Repository) UpdateProject(ctx context.Context, portfolioID, projectID primitive.ObjectID, input UpdateProjectInput) (*Project, error) {
+// LinkProject adds an existing project to a portfolio
+func (r *Repository) LinkProject(ctx context.Context, portfolioID primitive.ObjectID, version int64, ref ProjectRef) (*Portfolio, error) {
 	update := bson.M{
-		"$set": bson.M{
-			"updatedAt": time.Now(),
-		},
+		"$push": bson.M{"projectRefs": ref},
+	}
+
+	filter := bson.M{
+		"_id":             portfolioID,
+		"projectRefs._id": bson.M{"$ne": ref.ProjectID},
+	}
+	return r.update(ctx, filter, version, update, "project.link")
+}
+
+// UpdateProject updates a project through one of its portfolios. Order and
+// visibility are changed for that portfolio only.
+func (r *Repository) UpdateProject(ctx context.Context, portfolioID, projectID primitive.ObjectID, version int64, input UpdateProjectInput) (*Project, *Portfolio, error) {
+	refSet := bson.M{}
+	if input.Order != nil {
+		refSet["projectRefs.$.order"] = *input.Order
 	}
+	if input.Hidden != nil {
+		refSet["projectRefs.$.hidden"] = *input.Hidden
+	}
+
+	var update bson.M
+	if set := projectSet(input); len(set) > 0 {
+		update = bson.M{"$set": set}
+	}
+
+	portfolio, err := r.updateProject(ctx, portfolioID, projectID, version, refSet, update, "project.update")
+	if err != nil || portfolio == nil {
+		return nil, nil, err
+	}
+
+	return portfolio.findProject(projectID), portfolio, nil
+}
+
+// projectSet builds the $set of the shared fields changed by input
+func projectSet(input UpdateProjectInput) bson.M {
+	set := bson.M{}
 
 	if input.Title != nil {
-		update["$set"].(bson.M)["projects.$.title"] = *input.Title
+		set["title"] = *input.Title
 	}
 	if input.Description != nil {
-		update["$set"].(bson.M)["projects.$.description"] = *input.Description
+		set["description"] = *input.Description
 	}
 	if input.Content != nil {
-		update["$set"].(bson.M)["projects.$.content"] = *input.Content
+		set["content"] = *input.Content
 	}
 	if input.Tags != nil {
-		update["$set"].(bson.M)["projects.$.tags"] = *input.Tags
+		set["tags"] = *input.Tags
 	}
-	if input.Order != nil {
-		update["$set"].(bson.M)["projects.$.order"] = *input.Order
+	if input.Kind != nil {
+		set["kind"] = *input.Kind
+	}
+	if input.CaseStudy != nil {
+		team := input.CaseStudy.Team
+		if team == nil {
+			team = []string{}
+		}
+		set["caseStudy.role"] = input.CaseStudy.Role
+		set["caseStudy.team"] = team
+		set["caseStudy.duration"] = input.CaseStudy.Duration
+		set["caseStudy.client"] = input.CaseStudy.Client
 	}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var portfolio Portfolio
-	err := r.collection.FindOneAndUpdate(
-		ctx,
-		bson.M{
-			"_id":          portfolioID,
-			"projects._id": projectID,
+	return set
+}
+
+// DeleteProject removes a project from a portfolio. The project itself stays
+// in the user's library and in their other portfolios.
+func (r *Repository) DeleteProject(ctx context.Context, portfolioID, projectID primitive.ObjectID, version int64) (*Portfolio, error) {
+	update := bson.M{
+		"$pull": bson.M{
+			"projectRefs": bson.M{"_id": projectID},
 		},
-		update,
-		opts,
-	).Decode(&portfolio)
+	}
+
+	filter := bson.M{
+		"_id":             portfolioID,
+		"projectRefs._id": projectID,
+	}
+	return r.update(ctx, filter, version, update, "project.delete")
+}
+
+// FindProjectsByUserID finds the projects of a user, newest first
+func (r *Repository) FindProjectsByUserID(ctx context.Context, userID primitive.ObjectID) ([]Project, error) {
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := r.projects.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+	for i := range projects {
+		projects[i].sortByOrder()
+	}
+	return projects, nil
+}
+
+// FindProjectsBySource finds the projects of a user imported from a
+// provider
+func (r *Repository) FindProjectsBySource(ctx context.Context, userID primitive.ObjectID, provider string) ([]Project, error) {
+	cursor, err := r.projects.Find(ctx, bson.M{"userId": userID, "source.provider": provider})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// SearchProjects finds the projects of a user matching a text search,
+// best matches first
+func (r *Repository) SearchProjects(ctx context.Context, userID primitive.ObjectID, query string, limit int64) ([]Project, error) {
+	filter := bson.M{"userId": userID, "$text": bson.M{"$search": query}}
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	cursor, err := r.projects.Find(ctx, filter, options.Find().SetProjection(score).SetSort(score).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// SearchPortfolios finds the portfolios of a user whose title, description
+// or sections match a text search, best matches first. Their projects are
+// not loaded.
+func (r *Repository) SearchPortfolios(ctx context.Context, userID primitive.ObjectID, query string, limit int64) ([]*Portfolio, error) {
+	filter := bson.M{"userId": userID, "$text": bson.M{"$search": query}}
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(score).SetSort(score).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	portfolios := []*Portfolio{}
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return nil, err
+	}
+	return portfolios, nil
+}
+
+// FindPortfoliosShowing finds the portfolios of a user showing any of the
+// projects. Only their titles, subdomains and project references are
+// loaded.
+func (r *Repository) FindPortfoliosShowing(ctx context.Context, userID primitive.ObjectID, projectIDs []primitive.ObjectID) ([]*Portfolio, error) {
+	filter := bson.M{"userId": userID, "projectRefs._id": bson.M{"$in": projectIDs}}
+	projection := bson.M{"title": 1, "subdomain": 1, "projectRefs": 1}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	portfolios := []*Portfolio{}
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return nil, err
+	}
+	return portfolios, nil
+}
+
+// SetProjectSource replaces the source of a project. Every portfolio
+// showing it gets a new version.
+func (r *Repository) SetProjectSource(ctx context.Context, id primitive.ObjectID, source ProjectSource) error {
+	update := bson.M{
+		"$set": bson.M{"source": source, "updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+	if _, err := r.projects.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return err
+	}
+	return r.touchProject(ctx, id, primitive.NilObjectID)
+}
 
+// FindProject finds a project by ID
+func (r *Repository) FindProject(ctx context.Context, id primitive.ObjectID) (*Project, error) {
+	var project Project
+	err := r.projects.FindOne(ctx, bson.M{"_id": id}).Decode(&project)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
 		return nil, err
 	}
+	project.sortByOrder()
+	return &project, nil
+}
 
-	// Find and return the updated project
-	for _, project := range portfolio.Projects {
-		if project.ID == projectID {
-			return &project, nil
-		}
+// UpdateLibraryProject updates a project directly, outside of any portfolio,
+// if it is still at version. Every portfolio showing it gets a new version,
+// but as the change wasn't made through any of them, none records it.
+func (r *Repository) UpdateLibraryProject(ctx context.Context, id primitive.ObjectID, version int64, input UpdateProjectInput) (*Project, error) {
+	set := projectSet(input)
+	set["updatedAt"] = time.Now()
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
 	}
 
-	return nil, nil
-}
+	filter := bson.M{"_id": id}
+	addVersionFilter(filter, version)
 
-// DeleteProject deletes a project from a portfolio
-func (r *Repository) DeleteProject(ctx context.Context, portfolioID, projectID primitive.ObjectID) error {
-	update := bson.M{
-		"$pull": bson.M{
-			"projects": bson.M{"_id": projectID},
-		},
-		"$set": bson.M{
-			"updatedAt": time.Now(),
-		},
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var project Project
+	err := r.projects.FindOneAndUpdate(ctx, filter, update, opts).Decode(&project)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
 	}
+	if err := r.touchProject(ctx, id, primitive.NilObjectID); err != nil {
+		return nil, err
+	}
+
+	project.sortByOrder()
+	return &project, nil
+}
 
-	result, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": portfolioID},
-		update,
-	)
+// DeleteLibraryProject deletes a project if it is still at version and
+// removes it from every portfolio showing it
+func (r *Repository) DeleteLibraryProject(ctx context.Context, id primitive.ObjectID, version int64) error {
+	filter := bson.M{"_id": id}
+	addVersionFilter(filter, version)
 
+	result, err := r.projects.DeleteOne(ctx, filter)
 	if err != nil {
 		return err
 	}
-
-	if result.ModifiedCount == 0 {
+	if result.DeletedCount == 0 {
 		return mongo.ErrNoDocuments
 	}
 
-	return nil
+	update := bson.M{
+		"$pull": bson.M{"projectRefs": bson.M{"_id": id}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+		"$inc":  bson.M{"version": 1},
+	}
+	return r.updateAll(ctx, bson.M{"projectRefs._id": id}, update, "project.delete")
 }
 
 // AddSection adds a section to a portfolio
-func (r *Repository) AddSection(ctx context.Context, portfolioID primitive.ObjectID, input CreateSectionInput) (*Section, error) {
+func (r *Repository) AddSection(ctx context.Context, portfolioID primitive.ObjectID, version int64, input CreateSectionInput) (*Section, *Portfolio, error) {
 	now := time.Now()
 	section := &Section{
 		ID:        primitive.NewObjectID(),
@@ -285,165 +596,829 @@ func (r *Repository) AddSection(ctx context.Context, portfolioID primitive.Objec
 
 	update := bson.M{
 		"$push": bson.M{"sections": section},
-		"$set":  bson.M{"updatedAt": now},
-	}
-
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var portfolio Portfolio
-	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": portfolioID}, update, opts).Decode(&portfolio)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
 	}
 
-	// Find and return the newly added section
-	for _, s := range portfolio.Sections {
-		if s.ID == section.ID {
-			return &s, nil
-		}
+	portfolio, err := r.update(ctx, bson.M{"_id": portfolioID}, version, update, "section.add")
+	if err != nil || portfolio == nil {
+		return nil, nil, err
 	}
 
-	return nil, nil
+	return portfolio.findSection(section.ID), portfolio, nil
 }
 
 // UpdateSection updates a section in a portfolio
-func (r *Repository) UpdateSection(ctx context.Context, portfolioID, sectionID primitive.ObjectID, input UpdateSectionInput) (*Section, error) {
-	update := bson.M{
-		"$set": bson.M{
-			"updatedAt": time.Now(),
-		},
+func (r *Repository) UpdateSection(ctx context.Context, portfolioID, sectionID primitive.ObjectID, version int64, input UpdateSectionInput) (*Section, *Portfolio, error) {
+	set := bson.M{
+		"sections.$.updatedAt": time.Now(),
 	}
 
 	if input.Title != nil {
-		update["$set"].(bson.M)["sections.$.title"] = *input.Title
+		set["sections.$.title"] = *input.Title
 	}
 	if input.Type != nil {
-		update["$set"].(bson.M)["sections.$.type"] = *input.Type
+		set["sections.$.type"] = *input.Type
 	}
 	if input.Content != nil {
-		update["$set"].(bson.M)["sections.$.content"] = *input.Content
+		set["sections.$.content"] = *input.Content
 	}
 	if input.Order != nil {
-		update["$set"].(bson.M)["sections.$.order"] = *input.Order
+		set["sections.$.order"] = *input.Order
 	}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	var portfolio Portfolio
-	err := r.collection.FindOneAndUpdate(
-		ctx,
-		bson.M{
-			"_id":          portfolioID,
-			"sections._id": sectionID,
-		},
-		update,
-		opts,
-	).Decode(&portfolio)
-
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, err
+	filter := bson.M{
+		"_id":          portfolioID,
+		"sections._id": sectionID,
 	}
 
-	// Find and return the updated section
-	for _, section := range portfolio.Sections {
-		if section.ID == sectionID {
-			return &section, nil
-		}
+	portfolio, err := r.update(ctx, filter, version, bson.M{"$set": set}, "section.update")
+	if err != nil || portfolio == nil {
+		return nil, nil, err
 	}
 
-	return nil, nil
+	return portfolio.findSection(sectionID), portfolio, nil
 }
 
 // DeleteSection deletes a section from a portfolio
-func (r *Repository) DeleteSection(ctx context.Context, portfolioID, sectionID primitive.ObjectID) error {
+func (r *Repository) DeleteSection(ctx context.Context, portfolioID, sectionID primitive.ObjectID, version int64) (*Portfolio, error) {
 	update := bson.M{
 		"$pull": bson.M{
 			"sections": bson.M{"_id": sectionID},
 		},
-		"$set": bson.M{
-			"updatedAt": time.Now(),
-		},
-	}
-
-	result, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": portfolioID},
-		update,
-	)
-
-	if err != nil {
-		return err
 	}
 
-	if result.ModifiedCount == 0 {
-		return mongo.ErrNoDocuments
+	filter := bson.M{
+		"_id":          portfolioID,
+		"sections._id": sectionID,
 	}
-
-	return nil
+	return r.update(ctx, filter, version, update, "section.delete")
 }
 
 // AddMedia adds media to a project
-func (r *Repository) AddMedia(ctx context.Context, portfolioID, projectID primitive.ObjectID, media Media) error {
+func (r *Repository) AddMedia(ctx context.Context, portfolioID, projectID primitive.ObjectID, version int64, media Media) (*Portfolio, error) {
 	update := bson.M{
 		"$push": bson.M{
-			"projects.$.media": media,
+			"media": media,
 		},
-		"$set": bson.M{
-			"updatedAt": time.Now(),
+	}
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, update, "media.add")
+}
+
+// DeleteMedia deletes media from a project
+func (r *Repository) DeleteMedia(ctx context.Context, portfolioID, projectID, mediaID primitive.ObjectID, version int64) (*Portfolio, error) {
+	update := bson.M{
+		"$pull": bson.M{
+			"media": bson.M{"_id": mediaID},
 		},
 	}
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, update, "media.delete")
+}
 
-	result, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{
-			"_id":          portfolioID,
-			"projects._id": projectID,
+// AddStage adds a stage to the case study of a project
+func (r *Repository) AddStage(ctx context.Context, portfolioID, projectID primitive.ObjectID, version int64, stage Stage) (*Portfolio, error) {
+	update := bson.M{
+		"$push": bson.M{
+			"caseStudy.stages": stage,
 		},
-		update,
-	)
+	}
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, update, "stage.add")
+}
 
-	if err != nil {
-		return err
+// UpdateStage updates a stage of a project's case study
+func (r *Repository) UpdateStage(ctx context.Context, portfolioID, projectID, stageID primitive.ObjectID, version int64, input UpdateStageInput) (*Portfolio, error) {
+	const path = "caseStudy.stages.$[stage]."
+	set := bson.M{
+		path + "updatedAt": time.Now(),
 	}
 
-	if result.ModifiedCount == 0 {
-		return mongo.ErrNoDocuments
+	if input.Type != nil {
+		set[path+"type"] = *input.Type
+	}
+	if input.Title != nil {
+		set[path+"title"] = *input.Title
+	}
+	if input.Body != nil {
+		set[path+"body"] = *input.Body
+	}
+	if input.Metrics != nil {
+		set[path+"metrics"] = *input.Metrics
+	}
+	if input.Order != nil {
+		set[path+"order"] = *input.Order
 	}
 
-	return nil
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, bson.M{"$set": set}, "stage.update",
+		bson.M{"stage._id": stageID})
 }
 
-// DeleteMedia deletes media from a project
-func (r *Repository) DeleteMedia(ctx context.Context, portfolioID, projectID, mediaID primitive.ObjectID) error {
+// DeleteStage deletes a stage from a project's case study
+func (r *Repository) DeleteStage(ctx context.Context, portfolioID, projectID, stageID primitive.ObjectID, version int64) (*Portfolio, error) {
 	update := bson.M{
 		"$pull": bson.M{
-			"projects.$.media": bson.M{"_id": mediaID},
-		},
-		"$set": bson.M{
-			"updatedAt": time.Now(),
+			"caseStudy.stages": bson.M{"_id": stageID},
 		},
 	}
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, update, "stage.delete")
+}
+
+// ReorderStages sets the order of a case study's stages to their position in ids
+func (r *Repository) ReorderStages(ctx context.Context, portfolioID, projectID primitive.ObjectID, version int64, ids []primitive.ObjectID) (*Portfolio, error) {
+	set, filters := reorderUpdate("caseStudy.stages", ids)
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, bson.M{"$set": set}, "stage.reorder", filters...)
+}
+
+// AddStageMedia adds media to a case-study stage
+func (r *Repository) AddStageMedia(ctx context.Context, portfolioID, projectID, stageID primitive.ObjectID, version int64, media Media) (*Portfolio, error) {
+	update := bson.M{
+		"$push": bson.M{
+			"caseStudy.stages.$[stage].media": media,
+		},
+	}
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, update, "stage.media.add",
+		bson.M{"stage._id": stageID})
+}
 
-	result, err := r.collection.UpdateOne(
-		ctx,
-		bson.M{
-			"_id":          portfolioID,
-			"projects._id": projectID,
+// DeleteStageMedia deletes media from a case-study stage
+func (r *Repository) DeleteStageMedia(ctx context.Context, portfolioID, projectID, stageID, mediaID primitive.ObjectID, version int64) (*Portfolio, error) {
+	update := bson.M{
+		"$pull": bson.M{
+			"caseStudy.stages.$[stage].media": bson.M{"_id": mediaID},
 		},
-		update,
-	)
+	}
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, update, "stage.media.delete",
+		bson.M{"stage._id": stageID})
+}
+
+// SetBlocks replaces the canvas of a project, provided the project is still
+// as last updated at updatedAt. Another portfolio showing the project may
+// have changed the canvas without changing this portfolio's version yet.
+func (r *Repository) SetBlocks(ctx context.Context, portfolioID, projectID primitive.ObjectID, version int64, updatedAt time.Time, blocks []block.Block, action string) (*Portfolio, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"blocks": blocks,
+		},
+	}
+	// Projects stored without a timestamp are treated as the zero time
+	match := bson.M{"updatedAt": updatedAt}
+	if updatedAt.IsZero() {
+		match["updatedAt"] = bson.M{"$in": bson.A{updatedAt, nil}}
+	}
+	return r.updateProjectIf(ctx, portfolioID, projectID, match, version, nil, update, action)
+}
+
+// ReorderProjects sets the order of a portfolio's projects to their position in ids
+func (r *Repository) ReorderProjects(ctx context.Context, portfolioID primitive.ObjectID, version int64, ids []primitive.ObjectID) (*Portfolio, error) {
+	set, filters := reorderUpdate("projectRefs", ids)
+	return r.update(ctx, bson.M{"_id": portfolioID}, version, bson.M{"$set": set}, "project.reorder", filters...)
+}
+
+// ReorderSections sets the order of a portfolio's sections to their position in ids
+func (r *Repository) ReorderSections(ctx context.Context, portfolioID primitive.ObjectID, version int64, ids []primitive.ObjectID) (*Portfolio, error) {
+	set, filters := reorderUpdate("sections", ids)
+	return r.update(ctx, bson.M{"_id": portfolioID}, version, bson.M{"$set": set}, "section.reorder", filters...)
+}
+
+// ReorderMedia sets the order of a project's media to their position in ids
+func (r *Repository) ReorderMedia(ctx context.Context, portfolioID, projectID primitive.ObjectID, version int64, ids []primitive.ObjectID) (*Portfolio, error) {
+	set, filters := reorderUpdate("media", ids)
+	return r.updateProject(ctx, portfolioID, projectID, version, nil, bson.M{"$set": set}, "media.reorder", filters...)
+}
+
+// SetSchedule sets or clears the publish and unpublish times of a portfolio
+func (r *Repository) SetSchedule(ctx context.Context, id primitive.ObjectID, version int64, input ScheduleInput) (*Portfolio, error) {
+	set := bson.M{}
+	unset := bson.M{}
 
+	if input.PublishAt != nil {
+		set["publishAt"] = *input.PublishAt
+	} else {
+		unset["publishAt"] = ""
+	}
+	if input.UnpublishAt != nil {
+		set["unpublishAt"] = *input.UnpublishAt
+	} else {
+		unset["unpublishAt"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	return r.update(ctx, bson.M{"_id": id}, version, update, "portfolio.schedule")
+}
+
+// FindDueSchedules finds portfolios with a publish or unpublish time at or before now
+func (r *Repository) FindDueSchedules(ctx context.Context, now time.Time) ([]*Portfolio, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"publishAt": bson.M{"$lte": now}},
+			bson.M{"unpublishAt": bson.M{"$lte": now}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var portfolios []*Portfolio
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return nil, err
+	}
+
+	return portfolios, nil
+}
+
+// ApplySchedule publishes or unpublishes a portfolio whose scheduled time has
+// passed and clears that schedule. field is either "publishAt" or
+// "unpublishAt". changed reports whether the published state changed; a
+// portfolio already in that state only has its schedule cleared. It returns
+// nil if the schedule was already applied, e.g. by another instance.
+func (r *Repository) ApplySchedule(ctx context.Context, id primitive.ObjectID, field string, published bool, now time.Time) (portfolio *Portfolio, changed bool, err error) {
+	action := "schedule.unpublish"
+	if published {
+		action = "schedule.publish"
+	}
+
+	filter := bson.M{
+		"_id":         id,
+		field:         bson.M{"$lte": now},
+		"isPublished": bson.M{"$ne": published},
+	}
+	update := bson.M{
+		"$set":   bson.M{"isPublished": published},
+		"$unset": bson.M{field: ""},
+	}
+	portfolio, err = r.update(ctx, filter, AnyVersion, update, action)
+	if err != nil || portfolio != nil {
+		return portfolio, portfolio != nil, err
+	}
+
+	filter = bson.M{
+		"_id": id,
+		field: bson.M{"$lte": now},
+	}
+	portfolio, err = r.update(ctx, filter, AnyVersion, bson.M{"$unset": bson.M{field: ""}}, action)
+	return portfolio, false, err
+}
+
+// Restore replaces a portfolio's content with a previously recorded snapshot.
+// The published state and schedule are left untouched. Projects shown only
+// by this portfolio, and projects deleted since, get their content back from
+// the snapshot. Projects other portfolios show too keep their current content
+// and only their order and visibility in this portfolio are restored.
+func (r *Repository) Restore(ctx context.Context, id primitive.ObjectID, version int64, snapshot *Portfolio) (*Portfolio, error) {
+	projects, rewritten, err := r.restorePlan(ctx, id, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	// The portfolio version is claimed before any project is written, so a
+	// stale restore leaves the projects alone
+	filter := bson.M{"_id": id}
+	addVersionFilter(filter, version)
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"title":        snapshot.Title,
+			"description":  snapshot.Description,
+			"theme":        snapshot.Theme,
+			"layout":       snapshot.Layout,
+			"type":         snapshot.Type,
+			"projectRefs":  refsOf(projects),
+			"sections":     snapshot.Sections,
+			"subdomain":    snapshot.Subdomain,
+			"customDomain": snapshot.CustomDomain,
+			"updatedAt":    now,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var portfolio Portfolio
+	err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&portfolio)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for _, project := range rewritten {
+		project.UserID = snapshot.UserID
+		project.UpdatedAt = now
+		if err := r.restoreProject(ctx, &project); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.hydrate(ctx, &portfolio); err != nil {
+		return nil, err
+	}
+
+	r.recordRevision(ctx, &portfolio, "revision.restore")
+
+	return &portfolio, nil
+}
+
+// restoreProject stores a project as recorded in a snapshot, recreating it if
+// it was deleted since. It gets the version after its current one, so writes
+// based on the current project fail.
+func (r *Repository) restoreProject(ctx context.Context, project *Project) error {
+	current, err := r.FindProject(ctx, project.ID)
 	if err != nil {
 		return err
 	}
+	project.Version = 1
+	if current != nil {
+		project.Version = current.Version + 1
+	}
 
-	if result.ModifiedCount == 0 {
-		return mongo.ErrNoDocuments
+	opts := options.Replace().SetUpsert(true)
+	_, err = r.projects.ReplaceOne(ctx, bson.M{"_id": project.ID}, project, opts)
+	return err
+}
+
+// RestorePreview returns the portfolio as restoring snapshot would leave it,
+// without changing anything
+func (r *Repository) RestorePreview(ctx context.Context, portfolio, snapshot *Portfolio) (*Portfolio, error) {
+	projects, _, err := r.restorePlan(ctx, portfolio.ID, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := *portfolio
+	preview.Title = snapshot.Title
+	preview.Description = snapshot.Description
+	preview.Theme = snapshot.Theme
+	preview.Layout = snapshot.Layout
+	preview.Type = snapshot.Type
+	preview.ProjectRefs = refsOf(projects)
+	preview.Projects = projects
+	preview.Sections = snapshot.Sections
+	preview.Subdomain = snapshot.Subdomain
+	preview.CustomDomain = snapshot.CustomDomain
+	preview.sortByOrder()
+	return &preview, nil
+}
+
+// restorePlan works out the projects the portfolio with ID id shows after
+// restoring snapshot. Projects other portfolios show too are kept as they are
+// now, placed as in the snapshot. The others are restored from the snapshot
+// and also returned as rewritten.
+func (r *Repository) restorePlan(ctx context.Context, id primitive.ObjectID, snapshot *Portfolio) (projects, rewritten []Project, err error) {
+	if len(snapshot.Projects) == 0 {
+		return []Project{}, nil, nil
+	}
+	ids := make([]primitive.ObjectID, len(snapshot.Projects))
+	for i, project := range snapshot.Projects {
+		ids[i] = project.ID
+	}
+
+	filter := bson.M{"_id": bson.M{"$ne": id}, "projectRefs._id": bson.M{"$in": ids}}
+	sharedIDs, err := r.collection.Distinct(ctx, "projectRefs._id", filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	cursor, err := r.projects.Find(ctx, bson.M{"_id": bson.M{"$in": sharedIDs}})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var found []Project
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, nil, err
+	}
+	shared := make(map[primitive.ObjectID]Project, len(found))
+	for _, project := range found {
+		shared[project.ID] = project
+	}
+
+	projects, rewritten = planRestore(snapshot.Projects, shared)
+	return projects, rewritten, nil
+}
+
+// planRestore splits the projects of a snapshot into those kept as they are
+// stored in shared and those restored from the snapshot. It returns all of
+// them as shown after the restore, and the restored ones.
+func planRestore(snapshot []Project, shared map[primitive.ObjectID]Project) (projects, rewritten []Project) {
+	projects = make([]Project, 0, len(snapshot))
+	for _, project := range snapshot {
+		current, ok := shared[project.ID]
+		if !ok {
+			projects = append(projects, project)
+			rewritten = append(rewritten, project)
+			continue
+		}
+		current.Order = project.Order
+		current.Hidden = project.Hidden
+		projects = append(projects, current)
+	}
+	return projects, rewritten
+}
+
+// update applies a mutation to the portfolio matching filter, bumping its
+// version and updatedAt and recording a revision of the result. If version is
+// not AnyVersion the portfolio must still be at that version. It returns nil
+// if no portfolio matched.
+func (r *Repository) update(ctx context.Context, filter bson.M, version int64, update bson.M, action string, arrayFilters ...interface{}) (*Portfolio, error) {
+	addVersionFilter(filter, version)
+
+	set, _ := update["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	set["updatedAt"] = time.Now()
+	update["$inc"] = bson.M{"version": 1}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if len(arrayFilters) > 0 {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+	}
+
+	var portfolio Portfolio
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&portfolio)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := r.hydrate(ctx, &portfolio); err != nil {
+		return nil, err
+	}
+
+	r.recordRevision(ctx, &portfolio, action)
+
+	return &portfolio, nil
+}
+
+// updateProject applies a mutation to a project shown in a portfolio and
+// records it as a change of that portfolio. refSet updates the portfolio's
+// reference to the project and projectUpdate, if not nil, the project
+// itself. The portfolio version is claimed first, so concurrent edits through
+// the same portfolio fail with a version mismatch instead of interleaving.
+// Other portfolios showing the project get a new version too. It returns nil
+// if the portfolio doesn't match or doesn't show the project.
+func (r *Repository) updateProject(ctx context.Context, portfolioID, projectID primitive.ObjectID, version int64, refSet bson.M, projectUpdate bson.M, action string, arrayFilters ...interface{}) (*Portfolio, error) {
+	return r.updateProjectIf(ctx, portfolioID, projectID, nil, version, refSet, projectUpdate, action, arrayFilters...)
+}
+
+// updateProjectIf is updateProject with match as further conditions on the
+// project. If the project doesn't match, nothing changes and nil is returned.
+func (r *Repository) updateProjectIf(ctx context.Context, portfolioID, projectID primitive.ObjectID, match bson.M, version int64, refSet bson.M, projectUpdate bson.M, action string, arrayFilters ...interface{}) (*Portfolio, error) {
+	filter := bson.M{
+		"_id":             portfolioID,
+		"projectRefs._id": projectID,
+	}
+	addVersionFilter(filter, version)
+
+	// The portfolio as it was before the claim, to give back if the project
+	// changes between the check below and its update
+	var previous Portfolio
+	if err := r.collection.FindOne(ctx, filter).Decode(&previous); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	projectFilter := bson.M{"_id": projectID}
+	for key, value := range match {
+		projectFilter[key] = value
+	}
+	if projectUpdate != nil {
+		count, err := r.projects.CountDocuments(ctx, projectFilter)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, nil
+		}
+	}
+
+	claimFilter := bson.M{
+		"_id":             portfolioID,
+		"projectRefs._id": projectID,
+	}
+	addVersionFilter(claimFilter, previous.Version)
+
+	set := bson.M{"updatedAt": time.Now()}
+	for key, value := range refSet {
+		set[key] = value
+	}
+	claim := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var portfolio Portfolio
+	err := r.collection.FindOneAndUpdate(ctx, claimFilter, claim, opts).Decode(&portfolio)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if r.afterClaim != nil {
+		r.afterClaim()
+	}
+
+	if projectUpdate != nil {
+		projectSet, _ := projectUpdate["$set"].(bson.M)
+		if projectSet == nil {
+			projectSet = bson.M{}
+			projectUpdate["$set"] = projectSet
+		}
+		projectSet["updatedAt"] = time.Now()
+		projectUpdate["$inc"] = bson.M{"version": 1}
+
+		updateOpts := options.Update()
+		if len(arrayFilters) > 0 {
+			updateOpts.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+		}
+		result, err := r.projects.UpdateOne(ctx, projectFilter, projectUpdate, updateOpts)
+		if err != nil {
+			return nil, errors.Join(err, r.unclaim(ctx, &previous))
+		}
+		if result.MatchedCount == 0 {
+			return nil, r.unclaim(ctx, &previous)
+		}
+
+		// The project has changed, so this only invalidates cached copies
+		// of the other portfolios and can't fail the edit any more
+		if err := r.touchProject(ctx, projectID, portfolioID); err != nil {
+			slog.Error("failed to bump versions of portfolios showing a project", "projectId", projectID.Hex(), "error", err)
+		}
+	}
+
+	if err := r.hydrate(ctx, &portfolio); err != nil {
+		return nil, err
 	}
 
+	r.recordRevision(ctx, &portfolio, action)
+
+	return &portfolio, nil
+}
+
+// unclaim gives a portfolio claimed by updateProjectIf back its references
+// from before the claim, unless it changed again since. The version moves on
+// rather than back, since the claimed one may already have been read.
+func (r *Repository) unclaim(ctx context.Context, previous *Portfolio) error {
+	filter := bson.M{
+		"_id":     previous.ID,
+		"version": previous.Version + 1,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"projectRefs": previous.ProjectRefs,
+			"updatedAt":   previous.UpdatedAt,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// touchProject gives every portfolio showing a project, except the one with
+// ID except, a new version so cached copies of them are invalidated. The
+// change is recorded only in the history of the portfolio it was made
+// through, if any, rather than once per portfolio showing the project.
+func (r *Repository) touchProject(ctx context.Context, projectID, except primitive.ObjectID) error {
+	filter := bson.M{
+		"projectRefs._id": projectID,
+		"_id":             bson.M{"$ne": except},
+	}
+	update := bson.M{
+		"$set": bson.M{"updatedAt": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// updateAll applies an update to every portfolio matching filter, regardless
+// of its version, and records a revision of each
+func (r *Repository) updateAll(ctx context.Context, filter bson.M, update bson.M, action string) error {
+	ids, err := r.collection.Distinct(ctx, "_id", filter)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	if _, err := r.collection.UpdateMany(ctx, filter, update); err != nil {
+		return err
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var portfolios []*Portfolio
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return err
+	}
+	if err := r.hydrate(ctx, portfolios...); err != nil {
+		return err
+	}
+	for _, portfolio := range portfolios {
+		r.recordRevision(ctx, portfolio, action)
+	}
 	return nil
 }
+
+// hydrate loads the projects referenced by portfolios and sorts their
+// content
+func (r *Repository) hydrate(ctx context.Context, portfolios ...*Portfolio) error {
+	var ids []primitive.ObjectID
+	for _, portfolio := range portfolios {
+		for _, ref := range portfolio.ProjectRefs {
+			ids = append(ids, ref.ProjectID)
+		}
+	}
+
+	projects := map[primitive.ObjectID]Project{}
+	if len(ids) > 0 {
+		cursor, err := r.projects.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+
+		var found []Project
+		if err := cursor.All(ctx, &found); err != nil {
+			return err
+		}
+		for _, project := range found {
+			projects[project.ID] = project
+		}
+	}
+
+	for _, portfolio := range portfolios {
+		portfolio.Projects = make([]Project, 0, len(portfolio.ProjectRefs))
+		for _, ref := range portfolio.ProjectRefs {
+			if project, ok := projects[ref.ProjectID]; ok {
+				portfolio.Projects = append(portfolio.Projects, project)
+			}
+		}
+		portfolio.applyRefs()
+		portfolio.sortByOrder()
+	}
+	return nil
+}
+
+// reorderUpdate builds the $set and array filters that assign each element of
+// the array at path its position in ids as its order
+func reorderUpdate(path string, ids []primitive.ObjectID) (bson.M, []interface{}) {
+	set := bson.M{}
+	filters := make([]interface{}, 0, len(ids))
+	for i, id := range ids {
+		identifier := fmt.Sprintf("e%d", i)
+		set[fmt.Sprintf("%s.$[%s].order", path, identifier)] = i
+		filters = append(filters, bson.M{identifier + "._id": id})
+	}
+	return set, filters
+}
+
+// addVersionFilter restricts filter to documents at the given version.
+// Portfolios created before versioning have no version field and are treated
+// as version 0.
+func addVersionFilter(filter bson.M, version int64) {
+	switch {
+	case version == AnyVersion:
+	case version == 0:
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	default:
+		filter["version"] = version
+	}
+}
+
+// recordRevision stores a snapshot of the portfolio and prunes old revisions.
+// Failures are logged rather than returned since the mutation has already
+// been applied.
+func (r *Repository) recordRevision(ctx context.Context, portfolio *Portfolio, action string) {
+	revision := &Revision{
+		ID:          primitive.NewObjectID(),
+		PortfolioID: portfolio.ID,
+		Action:      action,
+		Snapshot:    portfolio,
+		CreatedAt:   time.Now(),
+	}
+	if authorID, ok := ctx.Value(auth.UserIDKey).(primitive.ObjectID); ok {
+		revision.AuthorID = &authorID
+	}
+
+	if _, err := r.revisions.InsertOne(ctx, revision); err != nil {
+		slog.Error("failed to record portfolio revision", "portfolioId", portfolio.ID.Hex(), "action", action, "error", err)
+		return
+	}
+
+	if err := r.pruneRevisions(ctx, portfolio.ID); err != nil {
+		slog.Error("failed to prune portfolio revisions", "portfolioId", portfolio.ID.Hex(), "error", err)
+	}
+}
+
+// pruneRevisions enforces the retention policy for a portfolio
+func (r *Repository) pruneRevisions(ctx context.Context, portfolioID primitive.ObjectID) error {
+	if r.retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-r.retention.MaxAge)
+		_, err := r.revisions.DeleteMany(ctx, bson.M{
+			"portfolioId": portfolioID,
+			"createdAt":   bson.M{"$lt": cutoff},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.retention.MaxRevisions > 0 {
+		// Find the newest revision that falls outside the limit and delete it
+		// together with everything older
+		opts := options.FindOne().
+			SetSort(bson.M{"_id": -1}).
+			SetSkip(int64(r.retention.MaxRevisions)).
+			SetProjection(bson.M{"_id": 1})
+
+		var oldest Revision
+		err := r.revisions.FindOne(ctx, bson.M{"portfolioId": portfolioID}, opts).Decode(&oldest)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil
+			}
+			return err
+		}
+
+		_, err = r.revisions.DeleteMany(ctx, bson.M{
+			"portfolioId": portfolioID,
+			"_id":         bson.M{"$lte": oldest.ID},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindRevisions lists the revisions of a portfolio, newest first, without snapshots
+func (r *Repository) FindRevisions(ctx context.Context, portfolioID primitive.ObjectID) ([]*Revision, error) {
+	opts := options.Find().
+		SetSort(bson.M{"_id": -1}).
+		SetProjection(bson.M{"snapshot": 0})
+
+	cursor, err := r.revisions.Find(ctx, bson.M{"portfolioId": portfolioID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []*Revision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// FindRevision finds a single revision of a portfolio, including its snapshot
+func (r *Repository) FindRevision(ctx context.Context, portfolioID, revisionID primitive.ObjectID) (*Revision, error) {
+	var revision Revision
+	err := r.revisions.FindOne(ctx, bson.M{"_id": revisionID, "portfolioId": portfolioID}).Decode(&revision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	revision.applyRefs()
+	return &revision, nil
+}
+
+// FindPreviousRevision finds the revision recorded immediately before the given one
+func (r *Repository) FindPreviousRevision(ctx context.Context, portfolioID, revisionID primitive.ObjectID) (*Revision, error) {
+	opts := options.FindOne().SetSort(bson.M{"_id": -1})
+
+	var revision Revision
+	err := r.revisions.FindOne(ctx, bson.M{
+		"portfolioId": portfolioID,
+		"_id":         bson.M{"$lt": revisionID},
+	}, opts).Decode(&revision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	revision.applyRefs()
+	return &revision, nil
+}
+
+// applyRefs restores the order and visibility of the snapshot's projects
+func (r *Revision) applyRefs() {
+	if r.Snapshot != nil {
+		r.Snapshot.applyRefs()
+	}
+}
@@ -0,0 +1,367 @@
+package portfolio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/database/databasetest"
+)
+
+func TestPlanRestore(t *testing.T) {
+	own := Project{ID: primitive.NewObjectID(), Title: "Old flyer", Order: 0}
+	shared := Project{ID: primitive.NewObjectID(), Title: "Old poster", Order: 1, Hidden: true}
+	current := Project{ID: shared.ID, Title: "New poster", Order: 5}
+
+	projects, rewritten := planRestore([]Project{own, shared}, map[primitive.ObjectID]Project{shared.ID: current})
+	if len(rewritten) != 1 || rewritten[0].ID != own.ID || rewritten[0].Title != "Old flyer" {
+		t.Errorf("rewritten = %+v, want only the flyer from the snapshot", rewritten)
+	}
+	if len(projects) != 2 || projects[0].Title != "Old flyer" {
+		t.Fatalf("projects = %+v", projects)
+	}
+	if p := projects[1]; p.Title != "New poster" || p.Order != 1 || !p.Hidden {
+		t.Errorf("shared project = %+v, want its current content placed as in the snapshot", p)
+	}
+}
+
+func TestRestoreRewritesOnlyUnsharedProjects(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(databasetest.New(t), RevisionRetention{})
+	userID := primitive.NewObjectID()
+
+	first, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "First", Subdomain: "first"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Second", Subdomain: "second"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared, first, err := repo.AddProject(ctx, first.ID, userID, first.Version, CreateProjectInput{Title: "Poster", Content: "Old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, first, err := repo.AddProject(ctx, first.ID, userID, first.Version, CreateProjectInput{Title: "Logo", Content: "Logo", Order: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, first, err := repo.AddProject(ctx, first.ID, userID, first.Version, CreateProjectInput{Title: "Flyer", Content: "Old", Order: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.LinkProject(ctx, second.ID, second.Version, ProjectRef{ProjectID: shared.ID}); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := first
+
+	// After the snapshot, the shared project is edited through the second
+	// portfolio and hidden in the first, the project only the first shows is
+	// edited and the other project is deleted
+	title, content, hidden := "Poster v2", "New", true
+	if _, _, err := repo.UpdateProject(ctx, second.ID, shared.ID, AnyVersion, UpdateProjectInput{Title: &title, Content: &content}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := repo.UpdateProject(ctx, first.ID, shared.ID, AnyVersion, UpdateProjectInput{Hidden: &hidden}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := repo.UpdateProject(ctx, first.ID, own.ID, AnyVersion, UpdateProjectInput{Content: &content}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteLibraryProject(ctx, deleted.ID, AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := repo.Restore(ctx, first.ID, AnyVersion, snapshot)
+	if err != nil || restored == nil {
+		t.Fatalf("Restore = %v, %v", restored, err)
+	}
+	if len(restored.Projects) != 3 || restored.Projects[0].Hidden {
+		t.Fatalf("restored projects = %+v, want all three with the shared one visible", restored.Projects)
+	}
+	if p := restored.Projects[0]; p.ID != shared.ID || p.Title != "Poster v2" || p.Content != "New" {
+		t.Errorf("shared project = %+v, want the later edit kept", p)
+	}
+	if p := restored.Projects[1]; p.ID != deleted.ID || p.Title != "Logo" || p.UserID != userID {
+		t.Errorf("deleted project = %+v, want it recreated", p)
+	}
+	if p := restored.Projects[2]; p.ID != own.ID || p.Content != "Old" {
+		t.Errorf("unshared project = %+v, want its content restored", p)
+	}
+
+	second, err = repo.FindByID(ctx, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Projects) != 1 || second.Projects[0].Title != "Poster v2" {
+		t.Errorf("second portfolio projects = %+v", second.Projects)
+	}
+}
+
+func TestLibraryDeleteIsRecordedInEveryPortfolio(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(databasetest.New(t), RevisionRetention{})
+	userID := primitive.NewObjectID()
+
+	var portfolios []*Portfolio
+	for _, subdomain := range []string{"first", "second"} {
+		p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: subdomain, Subdomain: subdomain}, PortfolioSeed{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		portfolios = append(portfolios, p)
+	}
+	project, _, err := repo.AddProject(ctx, portfolios[0].ID, userID, AnyVersion, CreateProjectInput{Title: "Poster"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.LinkProject(ctx, portfolios[1].ID, AnyVersion, ProjectRef{ProjectID: project.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteLibraryProject(ctx, project.ID, AnyVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range portfolios {
+		revisions, err := repo.FindRevisions(ctx, p.ID)
+		if err != nil || len(revisions) < 2 {
+			t.Fatalf("%s: revisions = %v, %v", p.Title, revisions, err)
+		}
+		if revisions[0].Action != "project.delete" {
+			t.Errorf("%s: latest action = %s", p.Title, revisions[0].Action)
+		}
+
+		// The revision before the delete brings the project back
+		before, err := repo.FindRevision(ctx, p.ID, revisions[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, err := repo.Restore(ctx, p.ID, AnyVersion, before.Snapshot)
+		if err != nil || restored == nil {
+			t.Fatalf("%s: Restore = %v, %v", p.Title, restored, err)
+		}
+		if len(restored.Projects) != 1 || restored.Projects[0].ID != project.ID {
+			t.Errorf("%s: restored projects = %+v, want the poster", p.Title, restored.Projects)
+		}
+	}
+}
+
+func TestSharedProjectEditIsRecordedOnce(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(databasetest.New(t), RevisionRetention{})
+	userID := primitive.NewObjectID()
+
+	first, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "First", Subdomain: "first"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Second", Subdomain: "second"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, _, err := repo.AddProject(ctx, first.ID, userID, AnyVersion, CreateProjectInput{Title: "Poster"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, err = repo.LinkProject(ctx, second.ID, AnyVersion, ProjectRef{ProjectID: project.ID}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := repo.FindRevisions(ctx, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	title := "Poster v2"
+	if _, _, err := repo.UpdateProject(ctx, first.ID, project.ID, AnyVersion, UpdateProjectInput{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := repo.FindRevisions(ctx, first.ID)
+	if err != nil || len(revisions) == 0 || revisions[0].Action != "project.update" {
+		t.Errorf("first portfolio revisions = %v, %v; want the edit recorded", revisions, err)
+	}
+	after, err := repo.FindRevisions(ctx, second.ID)
+	if err != nil || len(after) != len(before) {
+		t.Errorf("second portfolio revisions = %d, %v; want %d", len(after), err, len(before))
+	}
+	current, err := repo.FindByID(ctx, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != second.Version+1 || current.Projects[0].Title != title {
+		t.Errorf("second portfolio = version %d with %q, want version %d with the edit", current.Version, current.Projects[0].Title, second.Version+1)
+	}
+}
+
+func TestProjectChangedAfterClaimMovesVersionOn(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(databasetest.New(t), RevisionRetention{})
+	userID := primitive.NewObjectID()
+
+	p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Work", Subdomain: "work"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, p, err := repo.AddProject(ctx, p.ID, userID, p.Version, CreateProjectInput{Title: "Flyer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The project is deleted elsewhere right after this edit claimed the
+	// portfolio version
+	var claimed *Portfolio
+	repo.afterClaim = func() {
+		repo.afterClaim = nil
+		if claimed, err = repo.FindByID(ctx, p.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.projects.DeleteOne(ctx, bson.M{"_id": project.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	order, title := 4, "Poster"
+	_, updated, err := repo.UpdateProject(ctx, p.ID, project.ID, p.Version, UpdateProjectInput{Order: &order, Title: &title})
+	if err != nil || updated != nil {
+		t.Fatalf("UpdateProject of a project deleted after the claim = %v, %v; want nil", updated, err)
+	}
+
+	latest, err := repo.FindByID(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed == nil || claimed.ProjectRefs[0].Order != order {
+		t.Fatalf("claimed portfolio = %+v, want the new order", claimed)
+	}
+	if latest.Version <= claimed.Version {
+		t.Errorf("version after the failed edit = %d, want past the claimed %d", latest.Version, claimed.Version)
+	}
+	if latest.ProjectRefs[0].Order != 0 {
+		t.Errorf("project order = %d, want it as before the claim", latest.ProjectRefs[0].Order)
+	}
+}
+
+func TestSetBlocksRejectsCanvasChangedThroughAnotherPortfolio(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(databasetest.New(t), RevisionRetention{})
+	userID := primitive.NewObjectID()
+
+	first, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "First", Subdomain: "first"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Second", Subdomain: "second"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, _, err := repo.AddProject(ctx, first.ID, userID, AnyVersion, CreateProjectInput{Title: "Poster"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.LinkProject(ctx, second.ID, AnyVersion, ProjectRef{ProjectID: project.ID}); err != nil {
+		t.Fatal(err)
+	}
+	read, err := repo.FindByID(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAt := read.findProject(project.ID).UpdatedAt
+
+	// The other portfolio saves its canvas after this one was read
+	time.Sleep(5 * time.Millisecond)
+	theirs := []block.Block{{ID: primitive.NewObjectID(), Type: "text", Props: block.Props{"text": "Theirs"}}}
+	if p, err := repo.SetBlocks(ctx, second.ID, project.ID, AnyVersion, readAt, theirs, "block.replace"); err != nil || p == nil {
+		t.Fatalf("SetBlocks through the other portfolio = %v, %v", p, err)
+	}
+
+	// Even at this portfolio's current version the stale canvas is refused
+	current, err := repo.FindByID(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ours := []block.Block{{ID: primitive.NewObjectID(), Type: "text", Props: block.Props{"text": "Ours"}}}
+	if p, err := repo.SetBlocks(ctx, first.ID, project.ID, current.Version, readAt, ours, "block.replace"); err != nil || p != nil {
+		t.Errorf("SetBlocks with a stale canvas = %v, %v; want no match", p, err)
+	}
+
+	saved, err := repo.FindProject(ctx, project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Blocks) != 1 || saved.Blocks[0].Props["text"] != "Theirs" {
+		t.Errorf("canvas = %+v, want the other portfolio's", saved.Blocks)
+	}
+}
+
+func TestStaleRestoreLeavesProjectsAlone(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(databasetest.New(t), RevisionRetention{})
+	userID := primitive.NewObjectID()
+
+	p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Work", Subdomain: "work"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, snapshot, err := repo.AddProject(ctx, p.ID, userID, p.Version, CreateProjectInput{Title: "Flyer", Content: "Old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "New"
+	_, current, err := repo.UpdateProject(ctx, p.ID, project.ID, snapshot.Version, UpdateProjectInput{Content: &content})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := repo.Restore(ctx, p.ID, snapshot.Version, snapshot)
+	if err != nil || restored != nil {
+		t.Fatalf("Restore at a stale version = %v, %v; want nil", restored, err)
+	}
+	got, err := repo.FindProject(ctx, project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Content != "New" {
+		t.Errorf("project content = %q, want the edit kept", got.Content)
+	}
+	if latest, err := repo.FindByID(ctx, p.ID); err != nil || latest.Version != current.Version {
+		t.Errorf("portfolio = %v, %v; want it still at version %d", latest, err, current.Version)
+	}
+}
+
+func TestUpdateOfChangedProjectKeepsPortfolioVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository(databasetest.New(t), RevisionRetention{})
+	userID := primitive.NewObjectID()
+
+	p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Work", Subdomain: "work"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, p, err := repo.AddProject(ctx, p.ID, userID, p.Version, CreateProjectInput{Title: "Flyer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The canvas was last read before the project changed elsewhere
+	stale := project.UpdatedAt.Add(-time.Minute)
+	updated, err := repo.SetBlocks(ctx, p.ID, project.ID, p.Version, stale, []block.Block{}, "block.add")
+	if err != nil || updated != nil {
+		t.Fatalf("SetBlocks on a changed project = %v, %v; want nil", updated, err)
+	}
+	latest, err := repo.FindByID(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Version != p.Version {
+		t.Errorf("portfolio version = %d, want %d unchanged", latest.Version, p.Version)
+	}
+
+	order := 3
+	if _, updated, err := repo.UpdateProject(ctx, p.ID, project.ID, p.Version, UpdateProjectInput{Order: &order}); err != nil || updated == nil {
+		t.Errorf("UpdateProject at the unchanged version = %v, %v", updated, err)
+	}
+}
@@ -0,0 +1,335 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/markdown"
+)
+
+var ErrInvalidQuery = errors.New("search query has no words to search for")
+
+const (
+	// DefaultSearchLimit and MaxSearchLimit bound the hits of a search
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+	// searchCandidates is the number of portfolios and of projects a search
+	// ranks hits from
+	searchCandidates = 100
+	// snippetWords is the length of a snippet, of which snippetLead words
+	// come before the first match
+	snippetWords = 30
+	snippetLead  = 8
+	// minMatchScore ranks documents the text index matched through words
+	// the snippet matching doesn't recognize
+	minMatchScore = 0.1
+)
+
+// Weights of the fields hits are ranked by, in line with the weights of the
+// text indexes
+const (
+	titleWeight        = 10
+	tagsWeight         = 5
+	sectionTitleWeight = 5
+	descriptionWeight  = 3
+	contentWeight      = 1
+)
+
+// searchTokens splits a query into words, "quoted phrases" and -excluded
+// words or phrases
+var searchTokens = regexp.MustCompile(`-?"[^"]*"?|\S+`)
+
+// stopWords are words the text index ignores, which aren't highlighted
+// either
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"with": true,
+}
+
+// Search finds the portfolios, projects and sections of the user matching a
+// query in MongoDB text search syntax: words, "quoted phrases" and -excluded
+// words. Hits are ranked by how often and where the words occur, titles
+// first, and come with a highlighted snippet of the best matching field.
+func (s *Service) Search(ctx context.Context, userID primitive.ObjectID, query string, limit int) (*SearchResult, error) {
+	query = strings.TrimSpace(query)
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrInvalidQuery
+	}
+	if limit < 1 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	portfolios, err := s.repo.SearchPortfolios(ctx, userID, query, searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.repo.SearchProjects(ctx, userID, query, searchCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []SearchHit{}
+	for _, p := range portfolios {
+		hits = append(hits, portfolioHits(p, terms)...)
+	}
+
+	if len(projects) > 0 {
+		ids := make([]primitive.ObjectID, len(projects))
+		for i, project := range projects {
+			ids[i] = project.ID
+		}
+		showing, err := s.repo.FindPortfoliosShowing(ctx, userID, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			hits = append(hits, projectHit(project, showing, terms))
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return &SearchResult{Query: query, Hits: hits}, nil
+}
+
+// portfolioHits ranks a portfolio the text index matched: the portfolio
+// itself if its title or description match, and each matching section
+func portfolioHits(p *Portfolio, terms map[string]bool) []SearchHit {
+	ref := []SearchPortfolio{{ID: p.ID, Title: p.Title, Subdomain: p.Subdomain}}
+
+	hits := []SearchHit{}
+	score, field, snippet := matchFields(terms, []searchField{
+		{"title", p.Title, titleWeight},
+		{"description", p.Description, descriptionWeight},
+	})
+	if score > 0 {
+		hits = append(hits, SearchHit{Kind: SearchHitPortfolio, ID: p.ID, Title: p.Title, Field: field, Snippet: snippet, Score: score, Portfolios: ref})
+	}
+
+	for _, sec := range p.Sections {
+		fields := []searchField{{"title", sec.Title, sectionTitleWeight}}
+		fields = append(fields, contentFields("content", map[string]interface{}(sec.Content))...)
+		score, field, snippet := matchFields(terms, fields)
+		if score > 0 {
+			hits = append(hits, SearchHit{Kind: SearchHitSection, ID: sec.ID, Title: sec.Title, Field: field, Snippet: snippet, Score: score, Portfolios: ref})
+		}
+	}
+
+	// The index matched words the snippets don't recognize
+	if len(hits) == 0 {
+		_, field, snippet := matchFields(terms, []searchField{{"description", p.Description, descriptionWeight}})
+		hits = append(hits, SearchHit{Kind: SearchHitPortfolio, ID: p.ID, Title: p.Title, Field: field, Snippet: snippet, Score: minMatchScore, Portfolios: ref})
+	}
+	return hits
+}
+
+// projectHit ranks a project the text index matched
+func projectHit(project Project, showing []*Portfolio, terms map[string]bool) SearchHit {
+	fields := []searchField{
+		{"title", project.Title, titleWeight},
+		{"tags", strings.Join(project.Tags, " "), tagsWeight},
+		{"description", project.Description, descriptionWeight},
+		{"content", markdown.PlainText(project.Content), contentWeight},
+	}
+	if project.CaseStudy != nil {
+		for _, stage := range project.CaseStudy.Stages {
+			fields = append(fields,
+				searchField{"caseStudy.stages.title", stage.Title, contentWeight},
+				searchField{"caseStudy.stages.body", stage.Body, contentWeight},
+			)
+		}
+	}
+	fields = append(fields, blockFields(project.Blocks)...)
+
+	score, field, snippet := matchFields(terms, fields)
+	hit := SearchHit{
+		Kind:       SearchHitProject,
+		ID:         project.ID,
+		Title:      project.Title,
+		Field:      field,
+		Snippet:    snippet,
+		Score:      max(score, minMatchScore),
+		Portfolios: []SearchPortfolio{},
+	}
+	for _, p := range showing {
+		for _, ref := range p.ProjectRefs {
+			if ref.ProjectID == project.ID {
+				hit.Portfolios = append(hit.Portfolios, SearchPortfolio{ID: p.ID, Title: p.Title, Subdomain: p.Subdomain})
+				break
+			}
+		}
+	}
+	return hit
+}
+
+// searchField is a piece of text a search ranks and takes snippets from
+type searchField struct {
+	name   string
+	text   string
+	weight float64
+}
+
+// contentFields lists the text of structured content, such as section
+// content, as fields named by their path
+func contentFields(path string, v interface{}) []searchField {
+	switch v := v.(type) {
+	case string:
+		return []searchField{{path, markdown.PlainText(v), contentWeight}}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := []searchField{}
+		for _, key := range keys {
+			fields = append(fields, contentFields(path+"."+key, v[key])...)
+		}
+		return fields
+	case []interface{}:
+		fields := []searchField{}
+		for _, item := range v {
+			fields = append(fields, contentFields(path, item)...)
+		}
+		return fields
+	}
+	return nil
+}
+
+// blockFields lists the text of text blocks, including nested ones
+func blockFields(blocks []block.Block) []searchField {
+	fields := []searchField{}
+	for _, b := range blocks {
+		if text := b.Props.String("text"); text != "" {
+			fields = append(fields, searchField{"blocks.text", text, contentWeight})
+		}
+		fields = append(fields, blockFields(b.Children)...)
+	}
+	return fields
+}
+
+// matchFields scores fields against the search terms. Each occurrence of a
+// term counts, up to three per term and field, times the field's weight.
+// The snippet is taken from the best matching field other than the title,
+// or the first one with text.
+func matchFields(terms map[string]bool, fields []searchField) (score float64, field, snippet string) {
+	best, bestScore := -1, 0.0
+	for i, f := range fields {
+		counts := map[string]int{}
+		for _, word := range strings.Fields(f.text) {
+			if term := normalizeWord(word); terms[term] && counts[term] < 3 {
+				counts[term]++
+			}
+		}
+		fieldScore := 0.0
+		for _, count := range counts {
+			fieldScore += float64(count) * f.weight
+		}
+		score += fieldScore
+
+		if f.name == "title" || strings.TrimSpace(f.text) == "" {
+			continue
+		}
+		if best < 0 || fieldScore > bestScore {
+			best, bestScore = i, fieldScore
+		}
+	}
+	if best < 0 {
+		return score, "", ""
+	}
+	return score, fields[best].name, highlight(fields[best].text, terms)
+}
+
+// highlight builds the snippet of a text around the first match of the
+// search terms, with matched words in <mark> and the rest escaped
+func highlight(text string, terms map[string]bool) string {
+	words := strings.Fields(text)
+	start := 0
+	for i, word := range words {
+		if terms[normalizeWord(word)] {
+			start = max(i-snippetLead, 0)
+			break
+		}
+	}
+	end := min(start+snippetWords, len(words))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("… ")
+	}
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteByte(' ')
+		}
+		word := words[i]
+		if !terms[normalizeWord(word)] {
+			b.WriteString(html.EscapeString(word))
+			continue
+		}
+		// Punctuation around the word stays outside the mark
+		first := strings.IndexFunc(word, isWordRune)
+		last := strings.LastIndexFunc(word, isWordRune)
+		_, size := utf8.DecodeRuneInString(word[last:])
+		last += size
+		b.WriteString(html.EscapeString(word[:first]))
+		b.WriteString("<mark>" + html.EscapeString(word[first:last]) + "</mark>")
+		b.WriteString(html.EscapeString(word[last:]))
+	}
+	if end < len(words) {
+		b.WriteString(" …")
+	}
+	return b.String()
+}
+
+// searchTerms returns the normalized words of a query to rank and
+// highlight with. Excluded words and stop words are left out.
+func searchTerms(query string) map[string]bool {
+	terms := map[string]bool{}
+	for _, token := range searchTokens.FindAllString(query, -1) {
+		if strings.HasPrefix(token, "-") {
+			continue
+		}
+		for _, word := range strings.Fields(strings.ReplaceAll(token, `"`, " ")) {
+			if term := normalizeWord(word); term != "" {
+				terms[term] = true
+			}
+		}
+	}
+	return terms
+}
+
+// normalizeWord lowercases a word, strips surrounding punctuation and
+// reduces common English suffixes, approximating the stemming of the text
+// index. Stop words normalize to "".
+func normalizeWord(word string) string {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !isWordRune(r) }))
+	if stopWords[word] {
+		return ""
+	}
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if stem, ok := strings.CutSuffix(word, suffix); ok && len(stem) >= 3 {
+			return stem
+		}
+	}
+	return word
+}
+
+// isWordRune reports whether r is part of a word rather than punctuation
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
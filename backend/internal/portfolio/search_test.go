@@ -0,0 +1,62 @@
+package portfolio
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/section"
+)
+
+func TestSearchTerms(t *testing.T) {
+	terms := searchTerms(`Designing "the brand refresh" -logo -"old work"`)
+	for _, want := range []string{"design", "brand", "refresh"} {
+		if !terms[want] {
+			t.Errorf("missing term %q in %v", want, terms)
+		}
+	}
+	if len(terms) != 3 {
+		t.Errorf("terms = %v", terms)
+	}
+
+	if terms := searchTerms(`the -poster`); len(terms) != 0 {
+		t.Errorf("terms = %v, want none", terms)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	terms := searchTerms("posters")
+
+	got := highlight(`Big & bold <Poster>, printed: "poster."`, terms)
+	want := `Big &amp; bold &lt;<mark>Poster</mark>&gt;, printed: &#34;<mark>poster</mark>.&#34;`
+	if got != want {
+		t.Errorf("highlight = %s, want %s", got, want)
+	}
+
+	long := strings.Repeat("word ", 20) + "poster" + strings.Repeat(" word", 40)
+	got = highlight(long, terms)
+	if !strings.HasPrefix(got, "… word") || !strings.HasSuffix(got, "word …") || len(strings.Fields(got)) != snippetWords+2 {
+		t.Errorf("long snippet = %s", got)
+	}
+}
+
+func TestPortfolioHitsRanksMatchingSections(t *testing.T) {
+	about := Section{ID: primitive.NewObjectID(), Title: "About", Type: "text", Content: section.TextContent("I design **posters** and book covers.")}
+	skills := Section{ID: primitive.NewObjectID(), Title: "Skills", Type: "text", Content: section.TextContent("Go")}
+	p := &Portfolio{ID: primitive.NewObjectID(), Title: "Prints", Description: "Poster work", Sections: []Section{about, skills}}
+
+	hits := portfolioHits(p, searchTerms("poster"))
+	if len(hits) != 2 {
+		t.Fatalf("hits = %+v", hits)
+	}
+	if hits[0].Kind != SearchHitPortfolio || hits[0].Field != "description" || hits[0].Score != descriptionWeight {
+		t.Errorf("portfolio hit = %+v", hits[0])
+	}
+	if hits[1].Kind != SearchHitSection || hits[1].ID != about.ID || hits[1].Field != "content.text" {
+		t.Errorf("section hit = %+v", hits[1])
+	}
+	if hits[1].Snippet != "I design <mark>posters</mark> and book covers." {
+		t.Errorf("snippet = %s", hits[1].Snippet)
+	}
+}
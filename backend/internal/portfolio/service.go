@@ -4,12 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/musefolio/backend/internal/audit"
+	"github.com/musefolio/backend/internal/markdown"
+	"github.com/musefolio/backend/internal/pagination"
+	"github.com/musefolio/backend/internal/section"
+	"github.com/musefolio/backend/internal/storage"
+	"github.com/musefolio/backend/internal/template"
+	"github.com/musefolio/backend/internal/theme"
 )
 
 var (
@@ -17,27 +28,52 @@ var (
 	ErrProjectNotFound   = errors.New("project not found")
 	ErrSectionNotFound   = errors.New("section not found")
 	ErrMediaNotFound     = errors.New("media not found")
+	ErrRevisionNotFound  = errors.New("revision not found")
+	ErrInvalidRevision   = errors.New("invalid revision")
 	ErrSubdomainTaken    = errors.New("subdomain already taken")
 	ErrInvalidMediaType  = errors.New("invalid media type")
 	ErrUnauthorized      = errors.New("unauthorized")
+	ErrInvalidSchedule   = errors.New("invalid schedule")
+	ErrVersionMismatch   = errors.New("portfolio has been modified")
+	ErrInvalidOrder      = errors.New("invalid order")
+	ErrInvalidTheme      = errors.New("invalid theme")
+	ErrInvalidSection    = errors.New("invalid section")
+	ErrNotCaseStudy      = errors.New("project is not a case study")
+	ErrStageNotFound     = errors.New("stage not found")
+	ErrInvalidStage      = errors.New("invalid stage")
+	ErrProjectLinked     = errors.New("project already in portfolio")
+	ErrProjectImported   = errors.New("project already imported")
 )
 
 // Service handles portfolio business logic
 type Service struct {
 	repo      *Repository
+	audit     *audit.Repository
+	storage   storage.Storage
+	templates *template.Service
+	themes    *theme.Service
 	validate  *validator.Validate
-	mediaPath string
+	hooks     []PublishHook
 }
 
 // NewService creates a new portfolio service
-func NewService(repo *Repository, mediaPath string) *Service {
+func NewService(repo *Repository, auditRepo *audit.Repository, store storage.Storage, templates *template.Service, themes *theme.Service) *Service {
 	return &Service{
 		repo:      repo,
+		audit:     auditRepo,
+		storage:   store,
+		templates: templates,
+		themes:    themes,
 		validate:  validator.New(),
-		mediaPath: mediaPath,
 	}
 }
 
+// OnPublishStateChange registers a hook that is called whenever a portfolio is
+// published or unpublished, whether by its owner or by the scheduler
+func (s *Service) OnPublishStateChange(hook PublishHook) {
+	s.hooks = append(s.hooks, hook)
+}
+
 // Create creates a new portfolio
 func (s *Service) Create(ctx context.Context, userID primitive.ObjectID, input CreatePortfolioInput) (*Portfolio, error) {
 	// Validate input
@@ -54,7 +90,167 @@ func (s *Service) Create(ctx context.Context, userID primitive.ObjectID, input C
 		return nil, ErrSubdomainTaken
 	}
 
-	return s.repo.Create(ctx, userID, input)
+	var seed PortfolioSeed
+	if input.TemplateID != "" {
+		templateID, err := primitive.ObjectIDFromHex(input.TemplateID)
+		if err != nil {
+			return nil, template.ErrTemplateNotFound
+		}
+		tpl, err := s.templates.GetPublished(ctx, templateID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Explicit choices win over the template's defaults
+		if input.Theme == "" {
+			input.Theme = tpl.Theme
+		}
+		if input.Theme == "" {
+			input.Theme = theme.DefaultPreset
+		}
+		if input.Layout == "" {
+			input.Layout = tpl.Layout
+		}
+		if input.Type == "" {
+			input.Type = tpl.Type
+		}
+		seed = seedFromTemplate(tpl, time.Now())
+	}
+
+	if err := s.checkTheme(ctx, input.Theme, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(ctx, userID, input, seed)
+}
+
+// validateSectionContent checks content against the schema of the section type
+func validateSectionContent(sectionType string, content section.Content) error {
+	err := section.Validate(sectionType, content)
+	if errors.Is(err, section.ErrUnknownType) {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidSection, sectionType)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSection, err)
+	}
+	return nil
+}
+
+// sanitizeProjectUpdate removes the HTML Markdown doesn't allow from the
+// content of a project update
+func sanitizeProjectUpdate(input *UpdateProjectInput) {
+	if input.Content != nil {
+		content := markdown.Sanitize(*input.Content)
+		input.Content = &content
+	}
+}
+
+// checkTheme checks that the theme reference names a preset or one of the
+// user's custom themes
+func (s *Service) checkTheme(ctx context.Context, ref string, userID primitive.ObjectID) error {
+	if err := s.themes.CanUse(ctx, ref, userID); err != nil {
+		if errors.Is(err, theme.ErrThemeNotFound) {
+			return ErrInvalidTheme
+		}
+		return err
+	}
+	return nil
+}
+
+// seedFromTemplate builds the initial sections and placeholder projects of a
+// portfolio created from a template
+func seedFromTemplate(tpl *template.Template, now time.Time) PortfolioSeed {
+	seed := PortfolioSeed{
+		TemplateID: &tpl.ID,
+		Sections:   make([]Section, 0, len(tpl.Sections)),
+		Projects:   make([]Project, 0, len(tpl.Projects)),
+	}
+
+	for _, section := range tpl.Sections {
+		seed.Sections = append(seed.Sections, Section{
+			ID:        primitive.NewObjectID(),
+			Title:     section.Label,
+			Type:      section.Type,
+			Content:   section.Content,
+			Order:     section.Order,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	for _, project := range tpl.Projects {
+		tags := project.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		seed.Projects = append(seed.Projects, Project{
+			ID:          primitive.NewObjectID(),
+			Title:       project.Title,
+			Description: project.Description,
+			Content:     project.Content,
+			Media:       []Media{},
+			Tags:        tags,
+			Order:       project.Order,
+			Version:     1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+
+	return seed
+}
+
+// Duplicate creates a copy of a portfolio owned by the user under a fresh
+// subdomain. The copy gets its own copies of the projects and their media.
+func (s *Service) Duplicate(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, input DuplicatePortfolioInput) (*Portfolio, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+
+	source, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var subdomain string
+	if input.Subdomain != nil {
+		existingPortfolio, err := s.repo.FindBySubdomain(ctx, *input.Subdomain)
+		if err != nil {
+			return nil, err
+		}
+		if existingPortfolio != nil {
+			return nil, ErrSubdomainTaken
+		}
+		subdomain = *input.Subdomain
+	} else {
+		subdomain, err = s.availableSubdomain(ctx, source.Subdomain)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	copied, err := copyPortfolio(source, userID, input)
+	if err != nil {
+		return nil, err
+	}
+	copied.Subdomain = subdomain
+	keys, err := s.copyFiles(ctx, copied)
+	if err == nil {
+		err = s.repo.Insert(ctx, copied, "portfolio.duplicate")
+	}
+	if err != nil {
+		for _, key := range keys {
+			s.discardMedia(ctx, key)
+		}
+		return nil, err
+	}
+
+	s.recordAudit(ctx, copied.ID, "portfolio.duplicate", map[string]interface{}{
+		"sourceId": source.ID.Hex(),
+	})
+
+	return copied, nil
 }
 
 // GetByID gets a portfolio by ID
@@ -74,6 +270,32 @@ func (s *Service) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([
 	return s.repo.FindByUserID(ctx, userID)
 }
 
+// ListByUserID lists a page of the portfolios of a user. It returns the
+// cursor of the next page, or "" on the last page.
+func (s *Service) ListByUserID(ctx context.Context, userID primitive.ObjectID, p pagination.Params) ([]*Portfolio, string, error) {
+	portfolios, err := s.repo.ListByUserID(ctx, userID, p)
+	if err != nil {
+		return nil, "", err
+	}
+	return pagination.Trim(portfolios, p)
+}
+
+// ListPublishers lists the users with at least one published portfolio
+func (s *Service) ListPublishers(ctx context.Context) ([]primitive.ObjectID, error) {
+	return s.repo.FindPublisherIDs(ctx)
+}
+
+// ListOwnersUpdatedSince lists the users with a portfolio updated at or
+// after a point in time
+func (s *Service) ListOwnersUpdatedSince(ctx context.Context, since time.Time) ([]primitive.ObjectID, error) {
+	return s.repo.FindOwnerIDsUpdatedSince(ctx, since)
+}
+
+// ListPublishedIDs lists the IDs of the published portfolios
+func (s *Service) ListPublishedIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	return s.repo.FindPublishedIDs(ctx)
+}
+
 // GetBySubdomain gets a portfolio by subdomain
 func (s *Service) GetBySubdomain(ctx context.Context, subdomain string) (*Portfolio, error) {
 	portfolio, err := s.repo.FindBySubdomain(ctx, subdomain)
@@ -87,7 +309,7 @@ func (s *Service) GetBySubdomain(ctx context.Context, subdomain string) (*Portfo
 }
 
 // Update updates a portfolio
-func (s *Service) Update(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, input UpdatePortfolioInput) (*Portfolio, error) {
+func (s *Service) Update(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, version int64, input UpdatePortfolioInput) (*Portfolio, error) {
 	// Validate input
 	if err := s.validate.Struct(input); err != nil {
 		return nil, err
@@ -104,6 +326,15 @@ func (s *Service) Update(ctx context.Context, id primitive.ObjectID, userID prim
 	if portfolio.UserID != userID {
 		return nil, ErrUnauthorized
 	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return nil, err
+	}
+
+	if input.Theme != nil && *input.Theme != portfolio.Theme {
+		if err := s.checkTheme(ctx, *input.Theme, userID); err != nil {
+			return nil, err
+		}
+	}
 
 	// Check if new subdomain is taken
 	if input.Subdomain != nil && *input.Subdomain != portfolio.Subdomain {
@@ -116,11 +347,163 @@ func (s *Service) Update(ctx context.Context, id primitive.ObjectID, userID prim
 		}
 	}
 
-	return s.repo.Update(ctx, id, input)
+	updated, err := s.repo.Update(ctx, id, version, input)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrVersionMismatch
+	}
+
+	if updated.IsPublished != portfolio.IsPublished {
+		s.publishStateChanged(ctx, updated, false)
+	}
+
+	return updated, nil
+}
+
+// Schedule sets when a portfolio is automatically published and unpublished
+func (s *Service) Schedule(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, version int64, input ScheduleInput) (*Portfolio, error) {
+	portfolio, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if input.PublishAt != nil && input.PublishAt.Before(now) {
+		return nil, fmt.Errorf("%w: publishAt must be in the future", ErrInvalidSchedule)
+	}
+	if input.UnpublishAt != nil && input.UnpublishAt.Before(now) {
+		return nil, fmt.Errorf("%w: unpublishAt must be in the future", ErrInvalidSchedule)
+	}
+	if input.PublishAt != nil && input.UnpublishAt != nil && !input.UnpublishAt.After(*input.PublishAt) {
+		return nil, fmt.Errorf("%w: unpublishAt must be after publishAt", ErrInvalidSchedule)
+	}
+
+	portfolio, err = s.repo.SetSchedule(ctx, id, version, input)
+	if err != nil {
+		return nil, err
+	}
+	if portfolio == nil {
+		return nil, ErrVersionMismatch
+	}
+
+	metadata := map[string]interface{}{}
+	if input.PublishAt != nil {
+		metadata["publishAt"] = *input.PublishAt
+	}
+	if input.UnpublishAt != nil {
+		metadata["unpublishAt"] = *input.UnpublishAt
+	}
+	s.recordAudit(ctx, portfolio.ID, "portfolio.schedule", metadata)
+
+	return portfolio, nil
+}
+
+// ApplyDueSchedules publishes and unpublishes every portfolio whose scheduled
+// time has passed. It is run periodically by the background scheduler. A
+// portfolio whose schedule can't be applied doesn't hold up the others; the
+// errors of all of them are returned together.
+func (s *Service) ApplyDueSchedules(ctx context.Context, now time.Time) error {
+	portfolios, err := s.repo.FindDueSchedules(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, portfolio := range portfolios {
+		for _, step := range dueSteps(portfolio, now) {
+			updated, changed, err := s.repo.ApplySchedule(ctx, portfolio.ID, step.field, step.published, now)
+			if err != nil {
+				// Later steps depend on this one, so they wait for the
+				// next run too
+				errs = append(errs, fmt.Errorf("portfolio %s: %w", portfolio.ID.Hex(), err))
+				break
+			}
+			// The schedule may have been applied elsewhere already, or the
+			// portfolio was in the scheduled state anyway
+			if updated == nil || !changed {
+				continue
+			}
+
+			s.publishStateChanged(ctx, updated, true)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// scheduleStep is a due transition of a portfolio's schedule
+type scheduleStep struct {
+	field     string
+	published bool
+}
+
+// dueSteps returns the scheduled transitions of a portfolio that are due at
+// now. Both are applied in the order they were due so that a portfolio
+// scheduled to go live and expire while the scheduler was down ends up in the
+// right state.
+func dueSteps(portfolio *Portfolio, now time.Time) []scheduleStep {
+	type candidate struct {
+		scheduleStep
+		at *time.Time
+	}
+	candidates := []candidate{
+		{scheduleStep{"publishAt", true}, portfolio.PublishAt},
+		{scheduleStep{"unpublishAt", false}, portfolio.UnpublishAt},
+	}
+	if portfolio.PublishAt != nil && portfolio.UnpublishAt != nil && portfolio.UnpublishAt.Before(*portfolio.PublishAt) {
+		candidates[0], candidates[1] = candidates[1], candidates[0]
+	}
+
+	var steps []scheduleStep
+	for _, c := range candidates {
+		if c.at != nil && !c.at.After(now) {
+			steps = append(steps, c.scheduleStep)
+		}
+	}
+	return steps
+}
+
+// publishStateChanged records an audit entry and notifies hooks about a
+// portfolio being published or unpublished
+func (s *Service) publishStateChanged(ctx context.Context, portfolio *Portfolio, scheduled bool) {
+	action := "portfolio.unpublish"
+	if portfolio.IsPublished {
+		action = "portfolio.publish"
+	}
+	s.recordAudit(ctx, portfolio.ID, action, map[string]interface{}{"scheduled": scheduled})
+
+	event := PublishEvent{
+		Portfolio: portfolio,
+		Published: portfolio.IsPublished,
+		Scheduled: scheduled,
+		At:        time.Now(),
+	}
+	for _, hook := range s.hooks {
+		hook(ctx, event)
+	}
+}
+
+// recordAudit stores an audit entry for a portfolio. Failures are logged since
+// the change being audited has already been applied.
+func (s *Service) recordAudit(ctx context.Context, portfolioID primitive.ObjectID, action string, metadata map[string]interface{}) {
+	err := s.audit.Record(ctx, audit.Entry{
+		Action:       action,
+		ResourceType: "portfolio",
+		ResourceID:   portfolioID,
+		Metadata:     metadata,
+	})
+	if err != nil {
+		slog.Error("failed to record audit entry", "portfolioId", portfolioID.Hex(), "action", action, "error", err)
+	}
 }
 
 // Delete deletes a portfolio
-func (s *Service) Delete(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) error {
+func (s *Service) Delete(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, version int64) error {
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, id)
 	if err != nil {
@@ -132,78 +515,119 @@ func (s *Service) Delete(ctx context.Context, id primitive.ObjectID, userID prim
 	if portfolio.UserID != userID {
 		return ErrUnauthorized
 	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return err
+	}
 
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id, version); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrVersionMismatch
+		}
+		return err
+	}
+	return nil
 }
 
 // AddProject adds a project to a portfolio
-func (s *Service) AddProject(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID, input CreateProjectInput) (*Project, error) {
+func (s *Service) AddProject(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID, version int64, input CreateProjectInput) (*Project, int64, error) {
 	// Validate input
 	if err := s.validate.Struct(input); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, portfolioID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if portfolio == nil {
-		return nil, ErrPortfolioNotFound
+		return nil, 0, ErrPortfolioNotFound
 	}
 	if portfolio.UserID != userID {
-		return nil, ErrUnauthorized
+		return nil, 0, ErrUnauthorized
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return nil, 0, err
 	}
 
-	return s.repo.AddProject(ctx, portfolioID, input)
+	if input.CaseStudy != nil && input.Kind != ProjectKindCaseStudy {
+		return nil, 0, ErrNotCaseStudy
+	}
+	input.Content = markdown.Sanitize(input.Content)
+
+	project, updated, err := s.repo.AddProject(ctx, portfolioID, userID, version, input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if updated == nil {
+		return nil, 0, ErrVersionMismatch
+	}
+	return project, updated.Version, nil
 }
 
 // UpdateProject updates a project in a portfolio
-func (s *Service) UpdateProject(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, input UpdateProjectInput) (*Project, error) {
+func (s *Service) UpdateProject(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64, input UpdateProjectInput) (*Project, int64, error) {
 	// Validate input
 	if err := s.validate.Struct(input); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, portfolioID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if portfolio == nil {
-		return nil, ErrPortfolioNotFound
+		return nil, 0, ErrPortfolioNotFound
 	}
 	if portfolio.UserID != userID {
-		return nil, ErrUnauthorized
+		return nil, 0, ErrUnauthorized
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return nil, 0, err
 	}
 
 	// Check if project exists
-	projectExists := false
-	for _, project := range portfolio.Projects {
-		if project.ID == projectID {
-			projectExists = true
-			break
-		}
+	existing := portfolio.findProject(projectID)
+	if existing == nil {
+		return nil, 0, ErrProjectNotFound
 	}
-	if !projectExists {
-		return nil, ErrProjectNotFound
+
+	// Engagement details only apply to case studies
+	kind := existing.Kind
+	if input.Kind != nil {
+		kind = *input.Kind
 	}
+	if input.CaseStudy != nil && kind != ProjectKindCaseStudy {
+		return nil, 0, ErrNotCaseStudy
+	}
+	sanitizeProjectUpdate(&input)
 
-	return s.repo.UpdateProject(ctx, portfolioID, projectID, input)
+	project, updated, err := s.repo.UpdateProject(ctx, portfolioID, projectID, version, input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if updated == nil {
+		return nil, 0, ErrVersionMismatch
+	}
+	return project, updated.Version, nil
 }
 
 // DeleteProject deletes a project from a portfolio
-func (s *Service) DeleteProject(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID) error {
+func (s *Service) DeleteProject(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64) (int64, error) {
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, portfolioID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if portfolio == nil {
-		return ErrPortfolioNotFound
+		return 0, ErrPortfolioNotFound
 	}
 	if portfolio.UserID != userID {
-		return ErrUnauthorized
+		return 0, ErrUnauthorized
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return 0, err
 	}
 
 	// Check if project exists
@@ -215,80 +639,121 @@ func (s *Service) DeleteProject(ctx context.Context, portfolioID, projectID prim
 		}
 	}
 	if !projectExists {
-		return ErrProjectNotFound
+		return 0, ErrProjectNotFound
 	}
 
-	return s.repo.DeleteProject(ctx, portfolioID, projectID)
+	return versionOf(s.repo.DeleteProject(ctx, portfolioID, projectID, version))
 }
 
 // AddSection adds a section to a portfolio
-func (s *Service) AddSection(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID, input CreateSectionInput) (*Section, error) {
+func (s *Service) AddSection(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID, version int64, input CreateSectionInput) (*Section, int64, error) {
 	// Validate input
 	if err := s.validate.Struct(input); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, portfolioID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if portfolio == nil {
-		return nil, ErrPortfolioNotFound
+		return nil, 0, ErrPortfolioNotFound
 	}
 	if portfolio.UserID != userID {
-		return nil, ErrUnauthorized
+		return nil, 0, ErrUnauthorized
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return nil, 0, err
+	}
+
+	if err := validateSectionContent(input.Type, input.Content); err != nil {
+		return nil, 0, err
 	}
+	input.Content = section.Sanitize(input.Type, input.Content)
 
-	return s.repo.AddSection(ctx, portfolioID, input)
+	added, updated, err := s.repo.AddSection(ctx, portfolioID, version, input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if updated == nil {
+		return nil, 0, ErrVersionMismatch
+	}
+	return added, updated.Version, nil
 }
 
 // UpdateSection updates a section in a portfolio
-func (s *Service) UpdateSection(ctx context.Context, portfolioID, sectionID primitive.ObjectID, userID primitive.ObjectID, input UpdateSectionInput) (*Section, error) {
+func (s *Service) UpdateSection(ctx context.Context, portfolioID, sectionID primitive.ObjectID, userID primitive.ObjectID, version int64, input UpdateSectionInput) (*Section, int64, error) {
 	// Validate input
 	if err := s.validate.Struct(input); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, portfolioID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if portfolio == nil {
-		return nil, ErrPortfolioNotFound
+		return nil, 0, ErrPortfolioNotFound
 	}
 	if portfolio.UserID != userID {
-		return nil, ErrUnauthorized
+		return nil, 0, ErrUnauthorized
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return nil, 0, err
 	}
 
 	// Check if section exists
-	sectionExists := false
-	for _, section := range portfolio.Sections {
-		if section.ID == sectionID {
-			sectionExists = true
-			break
-		}
+	existing := portfolio.findSection(sectionID)
+	if existing == nil {
+		return nil, 0, ErrSectionNotFound
 	}
-	if !sectionExists {
-		return nil, ErrSectionNotFound
+
+	// Content is checked against the resulting type whenever either changes,
+	// so sections of types that predate the registry stay editable otherwise
+	if input.Type != nil || input.Content != nil {
+		sectionType, content := existing.Type, existing.Content
+		if input.Type != nil {
+			sectionType = *input.Type
+		}
+		if input.Content != nil {
+			content = *input.Content
+		}
+		if err := validateSectionContent(sectionType, content); err != nil {
+			return nil, 0, err
+		}
+		if input.Content != nil {
+			content = section.Sanitize(sectionType, content)
+			input.Content = &content
+		}
 	}
 
-	return s.repo.UpdateSection(ctx, portfolioID, sectionID, input)
+	updatedSection, updated, err := s.repo.UpdateSection(ctx, portfolioID, sectionID, version, input)
+	if err != nil {
+		return nil, 0, err
+	}
+	if updated == nil {
+		return nil, 0, ErrVersionMismatch
+	}
+	return updatedSection, updated.Version, nil
 }
 
 // DeleteSection deletes a section from a portfolio
-func (s *Service) DeleteSection(ctx context.Context, portfolioID, sectionID primitive.ObjectID, userID primitive.ObjectID) error {
+func (s *Service) DeleteSection(ctx context.Context, portfolioID, sectionID primitive.ObjectID, userID primitive.ObjectID, version int64) (int64, error) {
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, portfolioID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if portfolio == nil {
-		return ErrPortfolioNotFound
+		return 0, ErrPortfolioNotFound
 	}
 	if portfolio.UserID != userID {
-		return ErrUnauthorized
+		return 0, ErrUnauthorized
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return 0, err
 	}
 
 	// Check if section exists
@@ -300,87 +765,124 @@ func (s *Service) DeleteSection(ctx context.Context, portfolioID, sectionID prim
 		}
 	}
 	if !sectionExists {
-		return ErrSectionNotFound
+		return 0, ErrSectionNotFound
 	}
 
-	return s.repo.DeleteSection(ctx, portfolioID, sectionID)
+	return versionOf(s.repo.DeleteSection(ctx, portfolioID, sectionID, version))
 }
 
 // AddMedia adds media to a project
-func (s *Service) AddMedia(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, input UploadMediaInput, filename string) error {
+func (s *Service) AddMedia(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64, input UploadMediaInput, filename string, file io.Reader) (int64, error) {
 	// Validate input
 	if err := s.validate.Struct(input); err != nil {
-		return err
+		return 0, err
 	}
 
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, portfolioID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if portfolio == nil {
-		return ErrPortfolioNotFound
+		return 0, ErrPortfolioNotFound
 	}
 	if portfolio.UserID != userID {
-		return ErrUnauthorized
+		return 0, ErrUnauthorized
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return 0, err
 	}
 
 	// Check if project exists
-	projectExists := false
-	for _, project := range portfolio.Projects {
-		if project.ID == projectID {
-			projectExists = true
-			break
-		}
+	project := portfolio.findProject(projectID)
+	if project == nil {
+		return 0, ErrProjectNotFound
 	}
-	if !projectExists {
-		return ErrProjectNotFound
+
+	// Append after the existing media unless an order was given
+	order := len(project.Media)
+	if input.Order != nil {
+		order = *input.Order
+	}
+
+	media, key, err := s.storeMedia(ctx, portfolioID, projectID, input, order, filename, file)
+	if err != nil {
+		return 0, err
 	}
 
+	newVersion, err := versionOf(s.repo.AddMedia(ctx, portfolioID, projectID, version, media))
+	if err != nil {
+		s.discardMedia(ctx, key)
+		return 0, err
+	}
+	return newVersion, nil
+}
+
+// storeMedia checks the file extension against the media type and stores
+// the file. It returns the media to record and the key of the stored file.
+func (s *Service) storeMedia(ctx context.Context, portfolioID, projectID primitive.ObjectID, input UploadMediaInput, order int, filename string, file io.Reader) (Media, string, error) {
 	// Validate media type based on file extension
 	ext := strings.ToLower(filepath.Ext(filename))
 	switch input.Type {
 	case "image":
 		if !isValidImageExt(ext) {
-			return ErrInvalidMediaType
+			return Media{}, "", ErrInvalidMediaType
 		}
 	case "video":
 		if !isValidVideoExt(ext) {
-			return ErrInvalidMediaType
+			return Media{}, "", ErrInvalidMediaType
 		}
 	case "document":
 		if !isValidDocumentExt(ext) {
-			return ErrInvalidMediaType
+			return Media{}, "", ErrInvalidMediaType
 		}
 	default:
-		return ErrInvalidMediaType
+		return Media{}, "", ErrInvalidMediaType
+	}
+
+	// Store the file under a generated name so uploads can't collide or
+	// escape the portfolio's directory
+	mediaID := primitive.NewObjectID()
+	key := mediaKey(portfolioID, projectID, mediaID, ext)
+	url, err := s.storage.Save(ctx, key, file)
+	if err != nil {
+		return Media{}, "", err
 	}
 
-	// Create media object
 	media := Media{
-		ID:        primitive.NewObjectID(),
+		ID:        mediaID,
 		Type:      input.Type,
-		URL:       fmt.Sprintf("/media/%s/%s/%s", portfolioID.Hex(), projectID.Hex(), filename),
+		URL:       url,
 		Caption:   input.Caption,
-		Order:     input.Order,
+		Order:     order,
 		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
 	}
+	return media, key, nil
+}
 
-	return s.repo.AddMedia(ctx, portfolioID, projectID, media)
+// discardMedia removes a stored file whose media could not be recorded
+func (s *Service) discardMedia(ctx context.Context, key string) {
+	if err := s.storage.Delete(ctx, key); err != nil {
+		slog.Error("failed to remove orphaned media file", "key", key, "error", err)
+	}
 }
 
-// DeleteMedia deletes media from a project
-func (s *Service) DeleteMedia(ctx context.Context, portfolioID, projectID, mediaID primitive.ObjectID, userID primitive.ObjectID) error {
+// DeleteMedia deletes media from a project. The stored file is kept since
+// earlier revisions may still reference it.
+func (s *Service) DeleteMedia(ctx context.Context, portfolioID, projectID, mediaID primitive.ObjectID, userID primitive.ObjectID, version int64) (int64, error) {
 	// Check if portfolio exists and belongs to user
 	portfolio, err := s.repo.FindByID(ctx, portfolioID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if portfolio == nil {
-		return ErrPortfolioNotFound
+		return 0, ErrPortfolioNotFound
 	}
 	if portfolio.UserID != userID {
-		return ErrUnauthorized
+		return 0, ErrUnauthorized
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return 0, err
 	}
 
 	// Check if project exists and contains media
@@ -399,13 +901,290 @@ func (s *Service) DeleteMedia(ctx context.Context, portfolioID, projectID, media
 		}
 	}
 	if !projectExists {
-		return ErrProjectNotFound
+		return 0, ErrProjectNotFound
 	}
 	if !mediaExists {
-		return ErrMediaNotFound
+		return 0, ErrMediaNotFound
 	}
 
-	return s.repo.DeleteMedia(ctx, portfolioID, projectID, mediaID)
+	return versionOf(s.repo.DeleteMedia(ctx, portfolioID, projectID, mediaID, version))
+}
+
+// ReorderProjects sets the order of a portfolio's projects to the order of ids
+func (s *Service) ReorderProjects(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID, version int64, input ReorderInput) (*Portfolio, error) {
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make([]primitive.ObjectID, len(portfolio.Projects))
+	for i, project := range portfolio.Projects {
+		existing[i] = project.ID
+	}
+	if err := checkPermutation(existing, input.IDs); err != nil {
+		return nil, err
+	}
+
+	return portfolioOf(s.repo.ReorderProjects(ctx, portfolioID, portfolio.Version, input.IDs))
+}
+
+// ReorderSections sets the order of a portfolio's sections to the order of ids
+func (s *Service) ReorderSections(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID, version int64, input ReorderInput) (*Portfolio, error) {
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make([]primitive.ObjectID, len(portfolio.Sections))
+	for i, section := range portfolio.Sections {
+		existing[i] = section.ID
+	}
+	if err := checkPermutation(existing, input.IDs); err != nil {
+		return nil, err
+	}
+
+	return portfolioOf(s.repo.ReorderSections(ctx, portfolioID, portfolio.Version, input.IDs))
+}
+
+// ReorderMedia sets the order of a project's media to the order of ids
+func (s *Service) ReorderMedia(ctx context.Context, portfolioID, projectID primitive.ObjectID, userID primitive.ObjectID, version int64, input ReorderInput) (*Portfolio, error) {
+	portfolio, err := s.getOwnedAtVersion(ctx, portfolioID, userID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	project := portfolio.findProject(projectID)
+	if project == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	existing := make([]primitive.ObjectID, len(project.Media))
+	for i, media := range project.Media {
+		existing[i] = media.ID
+	}
+	if err := checkPermutation(existing, input.IDs); err != nil {
+		return nil, err
+	}
+
+	return portfolioOf(s.repo.ReorderMedia(ctx, portfolioID, projectID, portfolio.Version, input.IDs))
+}
+
+// ListRevisions lists the revision history of a portfolio
+func (s *Service) ListRevisions(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID) ([]*Revision, error) {
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.FindRevisions(ctx, portfolioID)
+}
+
+// GetRevision gets a single revision of a portfolio, including its snapshot
+func (s *Service) GetRevision(ctx context.Context, portfolioID, revisionID primitive.ObjectID, userID primitive.ObjectID) (*Revision, error) {
+	if _, err := s.getOwned(ctx, portfolioID, userID); err != nil {
+		return nil, err
+	}
+
+	revision, err := s.repo.FindRevision(ctx, portfolioID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, ErrRevisionNotFound
+	}
+	return revision, nil
+}
+
+// DiffRevision compares a revision with another one. against may be the hex ID
+// of another revision, "current" for the live portfolio, or empty to compare
+// with the revision recorded immediately before it.
+func (s *Service) DiffRevision(ctx context.Context, portfolioID, revisionID primitive.ObjectID, userID primitive.ObjectID, against string) (*RevisionDiff, error) {
+	var otherID primitive.ObjectID
+	if against != "" && against != "current" {
+		id, err := primitive.ObjectIDFromHex(against)
+		if err != nil {
+			return nil, fmt.Errorf("%w: against must be \"current\" or a revision ID", ErrInvalidRevision)
+		}
+		otherID = id
+	}
+
+	portfolio, err := s.getOwned(ctx, portfolioID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := s.repo.FindRevision(ctx, portfolioID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	diff := &RevisionDiff{To: revision.ID.Hex()}
+	from := &Portfolio{}
+	to := revision.Snapshot
+
+	switch against {
+	case "":
+		previous, err := s.repo.FindPreviousRevision(ctx, portfolioID, revisionID)
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil {
+			diff.From = previous.ID.Hex()
+			from = previous.Snapshot
+		}
+	case "current":
+		// Show what restoring the revision would change, which leaves the
+		// content of shared projects and the published state alone
+		diff.From = "current"
+		from = portfolio
+		if to != nil {
+			to, err = s.repo.RestorePreview(ctx, portfolio, to)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		other, err := s.repo.FindRevision(ctx, portfolioID, otherID)
+		if err != nil {
+			return nil, err
+		}
+		if other == nil {
+			return nil, ErrRevisionNotFound
+		}
+		diff.From = other.ID.Hex()
+		from = other.Snapshot
+	}
+
+	changes, err := diffPortfolios(from, to)
+	if err != nil {
+		return nil, err
+	}
+	diff.Changes = changes
+
+	return diff, nil
+}
+
+// RestoreRevision restores a portfolio to the state captured by a revision
+func (s *Service) RestoreRevision(ctx context.Context, portfolioID, revisionID primitive.ObjectID, userID primitive.ObjectID, version int64) (*Portfolio, error) {
+	portfolio, err := s.getOwned(ctx, portfolioID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return nil, err
+	}
+
+	revision, err := s.repo.FindRevision(ctx, portfolioID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil || revision.Snapshot == nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	// The subdomain may have been claimed by another portfolio since
+	if revision.Snapshot.Subdomain != portfolio.Subdomain {
+		existingPortfolio, err := s.repo.FindBySubdomain(ctx, revision.Snapshot.Subdomain)
+		if err != nil {
+			return nil, err
+		}
+		if existingPortfolio != nil && existingPortfolio.ID != portfolioID {
+			return nil, ErrSubdomainTaken
+		}
+	}
+
+	restored, err := s.repo.Restore(ctx, portfolioID, version, revision.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if restored == nil {
+		return nil, ErrVersionMismatch
+	}
+	return restored, nil
+}
+
+// getOwned loads a portfolio and checks that it belongs to the user
+func (s *Service) getOwned(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID) (*Portfolio, error) {
+	portfolio, err := s.repo.FindByID(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if portfolio == nil {
+		return nil, ErrPortfolioNotFound
+	}
+	if portfolio.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return portfolio, nil
+}
+
+// getOwnedAtVersion loads a portfolio owned by the user and checks that it is
+// still at the version the caller based its change on
+func (s *Service) getOwnedAtVersion(ctx context.Context, portfolioID primitive.ObjectID, userID primitive.ObjectID, version int64) (*Portfolio, error) {
+	portfolio, err := s.getOwned(ctx, portfolioID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(portfolio, version); err != nil {
+		return nil, err
+	}
+	return portfolio, nil
+}
+
+// checkPermutation reports ErrInvalidOrder unless ids contains every ID of
+// existing exactly once
+func checkPermutation(existing, ids []primitive.ObjectID) error {
+	if len(ids) != len(existing) {
+		return fmt.Errorf("%w: expected %d IDs, got %d", ErrInvalidOrder, len(existing), len(ids))
+	}
+
+	remaining := make(map[primitive.ObjectID]bool, len(existing))
+	for _, id := range existing {
+		remaining[id] = true
+	}
+	for _, id := range ids {
+		if !remaining[id] {
+			return fmt.Errorf("%w: unknown or duplicate ID %s", ErrInvalidOrder, id.Hex())
+		}
+		delete(remaining, id)
+	}
+	return nil
+}
+
+// portfolioOf converts the result of a versioned repository mutation, where a
+// nil portfolio means the portfolio changed after it was checked
+func portfolioOf(portfolio *Portfolio, err error) (*Portfolio, error) {
+	if err != nil {
+		return nil, err
+	}
+	if portfolio == nil {
+		return nil, ErrVersionMismatch
+	}
+	return portfolio, nil
+}
+
+// checkVersion reports ErrVersionMismatch if the portfolio is no longer at the
+// version the caller based its change on
+func checkVersion(portfolio *Portfolio, version int64) error {
+	if version != AnyVersion && portfolio.Version != version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// versionOf returns the version of a portfolio after a repository mutation.
+// A nil portfolio means the versioned update matched nothing, i.e. the
+// portfolio changed after it was checked.
+func versionOf(portfolio *Portfolio, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	if portfolio == nil {
+		return 0, ErrVersionMismatch
+	}
+	return portfolio.Version, nil
 }
 
 // Helper functions for media type validation
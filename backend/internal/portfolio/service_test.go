@@ -0,0 +1,328 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/audit"
+	"github.com/musefolio/backend/internal/database/databasetest"
+	"github.com/musefolio/backend/internal/section"
+	"github.com/musefolio/backend/internal/storage"
+	"github.com/musefolio/backend/internal/template"
+	"github.com/musefolio/backend/internal/theme"
+)
+
+func TestDueSteps(t *testing.T) {
+	now := time.Now()
+	hourAgo, minuteAgo, later := now.Add(-time.Hour), now.Add(-time.Minute), now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		publish   *time.Time
+		unpublish *time.Time
+		want      []scheduleStep
+	}{
+		{"nothing scheduled", nil, nil, nil},
+		{"not due yet", &later, &later, nil},
+		{"publish due", &minuteAgo, &later, []scheduleStep{{"publishAt", true}}},
+		{"live and expired", &hourAgo, &minuteAgo, []scheduleStep{{"publishAt", true}, {"unpublishAt", false}}},
+		{"expired and relaunched", &minuteAgo, &hourAgo, []scheduleStep{{"unpublishAt", false}, {"publishAt", true}}},
+	}
+	for _, tt := range tests {
+		got := dueSteps(&Portfolio{PublishAt: tt.publish, UnpublishAt: tt.unpublish}, now)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: steps = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: steps = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestCheckPermutation(t *testing.T) {
+	a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	existing := []primitive.ObjectID{a, b, c}
+
+	tests := []struct {
+		name     string
+		existing []primitive.ObjectID
+		ids      []primitive.ObjectID
+		ok       bool
+	}{
+		{"exact permutation", existing, []primitive.ObjectID{c, a, b}, true},
+		{"missing ID", existing, []primitive.ObjectID{c, a}, false},
+		{"extra ID", existing, []primitive.ObjectID{c, a, b, primitive.NewObjectID()}, false},
+		{"unknown ID", existing, []primitive.ObjectID{c, a, primitive.NewObjectID()}, false},
+		{"duplicate ID", existing, []primitive.ObjectID{c, a, a}, false},
+		{"empty list", existing, nil, false},
+		{"nothing to order", nil, nil, true},
+	}
+	for _, tt := range tests {
+		err := checkPermutation(tt.existing, tt.ids)
+		if tt.ok && err != nil {
+			t.Errorf("%s: err = %v, want nil", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("%s: err = %v, want ErrInvalidOrder", tt.name, err)
+		}
+	}
+}
+
+func TestApplyDueSchedulesNotifiesOnlyChanges(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	userID := primitive.NewObjectID()
+
+	var events []PublishEvent
+	s.OnPublishStateChange(func(ctx context.Context, event PublishEvent) {
+		events = append(events, event)
+	})
+
+	due := time.Now().Add(-time.Minute)
+	schedule := func(subdomain string, published bool, input ScheduleInput) *Portfolio {
+		t.Helper()
+		p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: subdomain, Subdomain: subdomain}, PortfolioSeed{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if published {
+			if p, err = repo.Update(ctx, p.ID, p.Version, UpdatePortfolioInput{IsPublished: &published}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if p, err = repo.SetSchedule(ctx, p.ID, p.Version, input); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	launch := schedule("launch", false, ScheduleInput{PublishAt: &due})
+	live := schedule("live", true, ScheduleInput{PublishAt: &due})
+
+	if err := s.ApplyDueSchedules(ctx, time.Now()); err != nil {
+		t.Fatalf("ApplyDueSchedules: %v", err)
+	}
+	if len(events) != 1 || events[0].Portfolio.ID != launch.ID || !events[0].Published || !events[0].Scheduled {
+		t.Fatalf("events = %+v, want only the launch published", events)
+	}
+
+	// The schedule of the portfolio that was live already is cleared anyway
+	live, err := repo.FindByID(ctx, live.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live.PublishAt != nil || !live.IsPublished {
+		t.Errorf("live portfolio = published %v, publishAt %v", live.IsPublished, live.PublishAt)
+	}
+
+	if err := s.ApplyDueSchedules(ctx, time.Now()); err != nil {
+		t.Fatalf("ApplyDueSchedules again: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("events after second run = %d, want no more", len(events))
+	}
+}
+
+func TestApplyDueSchedulesContinuesPastFailures(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	userID := primitive.NewObjectID()
+
+	var events []PublishEvent
+	s.OnPublishStateChange(func(ctx context.Context, event PublishEvent) {
+		events = append(events, event)
+	})
+
+	due := time.Now().Add(-time.Minute)
+	schedule := func(subdomain string) *Portfolio {
+		t.Helper()
+		p, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: subdomain, Subdomain: subdomain}, PortfolioSeed{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p, err = repo.SetSchedule(ctx, p.ID, p.Version, ScheduleInput{PublishAt: &due}); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	// The first portfolio shows a project that can't be loaded
+	broken := schedule("broken")
+	projectID := primitive.NewObjectID()
+	if _, err := repo.projects.InsertOne(ctx, bson.M{"_id": projectID, "userId": userID, "title": 42}); err != nil {
+		t.Fatal(err)
+	}
+	ref := bson.M{"$push": bson.M{"projectRefs": ProjectRef{ProjectID: projectID}}}
+	if _, err := repo.collection.UpdateOne(ctx, bson.M{"_id": broken.ID}, ref); err != nil {
+		t.Fatal(err)
+	}
+	launch := schedule("launch")
+
+	if err := s.ApplyDueSchedules(ctx, time.Now()); err == nil {
+		t.Error("ApplyDueSchedules with a broken portfolio succeeded, want its error")
+	}
+	if len(events) != 1 || events[0].Portfolio.ID != launch.ID {
+		t.Errorf("events = %+v, want the launch published", events)
+	}
+}
+
+func TestSeedFromTemplate(t *testing.T) {
+	now := time.Now()
+	tpl := &template.Template{
+		ID:       primitive.NewObjectID(),
+		Sections: []template.Section{{Type: "about", Label: "About me", Content: section.TextContent("Hi"), Order: 1}},
+		Projects: []template.Project{{Title: "Sample project", Content: "Describe it", Order: 2}},
+	}
+
+	seed := seedFromTemplate(tpl, now)
+	if seed.TemplateID == nil || *seed.TemplateID != tpl.ID {
+		t.Errorf("template ID = %v, want %s", seed.TemplateID, tpl.ID.Hex())
+	}
+	if len(seed.Sections) != 1 || seed.Sections[0].Title != "About me" || seed.Sections[0].Type != "about" ||
+		seed.Sections[0].Content.Text() != "Hi" || seed.Sections[0].ID.IsZero() {
+		t.Errorf("sections = %+v", seed.Sections)
+	}
+	if len(seed.Projects) != 1 || seed.Projects[0].Title != "Sample project" || seed.Projects[0].Tags == nil ||
+		seed.Projects[0].Media == nil || seed.Projects[0].ID.IsZero() {
+		t.Errorf("projects = %+v", seed.Projects)
+	}
+}
+
+func TestCreateFromTemplate(t *testing.T) {
+	ctx := context.Background()
+	db := databasetest.New(t)
+	themes := theme.NewService(theme.NewRepository(db))
+	templates := template.NewService(template.NewRepository(db))
+	repo := NewRepository(db, RevisionRetention{})
+	s := NewService(repo, audit.NewRepository(db), storage.NewLocal(t.TempDir(), "/media"), templates, themes)
+	userID := primitive.NewObjectID()
+
+	preset := theme.CreateThemeInput{Name: "Modern", Tokens: theme.Tokens{
+		Palette:    theme.Palette{Primary: "#2563eb", Secondary: "#7c3aed", Background: "#fff", Text: "#000"},
+		Typography: theme.Typography{HeadingFont: "'Inter', sans-serif", BodyFont: "'Inter', sans-serif", BaseSize: "16px", Scale: 1.25, LineHeight: 1.6},
+		Spacing:    theme.Spacing{Unit: "8px"},
+		Radius:     theme.Radius{Small: "4px", Medium: "8px", Large: "16px"},
+	}}
+	if _, err := themes.SeedPreset(ctx, theme.DefaultPreset, 1, preset); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := templates.Create(ctx, template.CreateTemplateInput{
+		Slug:        "developer-cv",
+		Name:        "Developer CV",
+		Profession:  "developer",
+		Layout:      "single-column",
+		Type:        "cv",
+		Sections:    []template.Section{{Type: "about", Label: "About", Content: section.TextContent("Hi")}},
+		Projects:    []template.Project{{Title: "Side project"}},
+		IsPublished: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := s.Create(ctx, userID, CreatePortfolioInput{
+		Title:       "My CV",
+		Description: "Work",
+		Subdomain:   "mycv",
+		TemplateID:  tpl.ID.Hex(),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Layout != "single-column" || created.Type != "cv" || created.Theme != theme.DefaultPreset {
+		t.Errorf("portfolio = layout %q, type %q, theme %q; want the template's defaults", created.Layout, created.Type, created.Theme)
+	}
+	if created.TemplateID == nil || *created.TemplateID != tpl.ID {
+		t.Errorf("template ID = %v", created.TemplateID)
+	}
+
+	loaded, err := repo.FindByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Sections) != 1 || loaded.Sections[0].Title != "About" || len(loaded.Projects) != 1 || loaded.Projects[0].Title != "Side project" {
+		t.Errorf("seeded content = %+v, %+v", loaded.Sections, loaded.Projects)
+	}
+
+	// Drafts and unknown templates can't be applied
+	draft, err := templates.Create(ctx, template.CreateTemplateInput{Slug: "draft", Name: "Draft", Profession: "x", Layout: "grid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{draft.ID.Hex(), primitive.NewObjectID().Hex()} {
+		_, err := s.Create(ctx, userID, CreatePortfolioInput{Title: "T", Description: "D", Subdomain: "other", TemplateID: id})
+		if !errors.Is(err, template.ErrTemplateNotFound) {
+			t.Errorf("Create from template %s = %v, want ErrTemplateNotFound", id, err)
+		}
+	}
+}
+
+func TestDiffAgainstCurrentShowsWhatRestoreApplies(t *testing.T) {
+	ctx := context.Background()
+	s, repo := newTestService(t)
+	userID := primitive.NewObjectID()
+
+	first, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "First", Subdomain: "first"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Create(ctx, userID, CreatePortfolioInput{Title: "Second", Subdomain: "second"}, PortfolioSeed{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, _, err := repo.AddProject(ctx, first.ID, userID, AnyVersion, CreateProjectInput{Title: "Poster"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, _, err := repo.AddProject(ctx, first.ID, userID, AnyVersion, CreateProjectInput{Title: "Flyer", Content: "Old", Order: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.LinkProject(ctx, second.ID, AnyVersion, ProjectRef{ProjectID: shared.ID}); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := repo.FindRevisions(ctx, first.ID)
+	if err != nil || len(revisions) == 0 {
+		t.Fatalf("revisions = %v, %v", revisions, err)
+	}
+	revisionID := revisions[0].ID
+
+	// Restoring neither touches the shared project nor unpublishes
+	title, content, published := "Poster v2", "New", true
+	if _, _, err := repo.UpdateProject(ctx, second.ID, shared.ID, AnyVersion, UpdateProjectInput{Title: &title}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := repo.UpdateProject(ctx, first.ID, own.ID, AnyVersion, UpdateProjectInput{Content: &content}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Update(ctx, first.ID, AnyVersion, UpdatePortfolioInput{IsPublished: &published}); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := s.DiffRevision(ctx, first.ID, revisionID, userID, "current")
+	if err != nil {
+		t.Fatalf("DiffRevision: %v", err)
+	}
+	want := "projects[" + own.ID.Hex() + "].content"
+	if len(diff.Changes) != 1 || diff.Changes[0].Path != want || diff.Changes[0].To != "Old" {
+		t.Errorf("changes = %+v, want only %s back to Old", diff.Changes, want)
+	}
+
+	restored, err := s.RestoreRevision(ctx, first.ID, revisionID, userID, AnyVersion)
+	if err != nil {
+		t.Fatalf("RestoreRevision: %v", err)
+	}
+	if p := restored.findProject(shared.ID); p == nil || p.Title != title || !restored.IsPublished {
+		t.Errorf("restored = %+v, want the shared project and published state kept", restored)
+	}
+	if diff, err := s.DiffRevision(ctx, first.ID, revisionID, userID, "current"); err != nil || len(diff.Changes) != 0 {
+		t.Errorf("diff after restoring = %+v, %v; want no changes", diff, err)
+	}
+}
@@ -0,0 +1,254 @@
+package resume
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/user"
+)
+
+// SchemaURL is the JSON Schema exported résumés declare
+const SchemaURL = "https://raw.githubusercontent.com/jsonresume/resume-schema/v1.0.0/schema.json"
+
+// skillLevels names the proficiencies of CV skills, by level
+var skillLevels = []string{"", "Beginner", "Elementary", "Intermediate", "Advanced", "Expert"}
+
+// levelAliases maps other common JSON Resume skill levels to proficiencies
+var levelAliases = map[string]int{
+	"novice":     1,
+	"basic":      2,
+	"proficient": 4,
+	"master":     5,
+}
+
+// fluencies maps words found in JSON Resume fluencies to CV language
+// proficiencies, checked in order
+var fluencies = []struct{ word, proficiency string }{
+	{"native", "native"},
+	{"bilingual", "native"},
+	{"fluent", "fluent"},
+	{"full professional", "fluent"},
+	{"professional", "professional"},
+	{"limited", "limited"},
+	{"elementary", "elementary"},
+	{"basic", "elementary"},
+	{"beginner", "elementary"},
+}
+
+// socialNetworks maps JSON Resume profile networks to social links
+var socialNetworks = []struct {
+	network string
+	link    func(*user.SocialLinks) *string
+}{
+	{"LinkedIn", func(l *user.SocialLinks) *string { return &l.LinkedIn }},
+	{"GitHub", func(l *user.SocialLinks) *string { return &l.GitHub }},
+	{"Twitter", func(l *user.SocialLinks) *string { return &l.Twitter }},
+	{"Instagram", func(l *user.SocialLinks) *string { return &l.Instagram }},
+}
+
+// FromProfile builds a résumé from the profile of a user, their CV and the
+// projects of a portfolio, as exported by viewerID. Hidden projects are left
+// out; c may be nil. The account email is only included for the owner.
+func FromProfile(owner *user.User, c *cv.CV, projects []portfolio.Project, viewerID primitive.ObjectID) *Resume {
+	r := &Resume{Schema: SchemaURL}
+
+	if owner != nil {
+		r.Basics = Basics{
+			Name:    owner.Name,
+			Label:   owner.Profession,
+			Summary: owner.Bio,
+		}
+		if owner.ID == viewerID {
+			r.Basics.Email = owner.Email
+		}
+		if isAbsoluteURL(owner.Avatar) {
+			r.Basics.Image = owner.Avatar
+		}
+		if links := owner.SocialLinks; links != nil {
+			r.Basics.URL = links.Website
+			for _, n := range socialNetworks {
+				if link := *n.link(links); link != "" {
+					r.Basics.Profiles = append(r.Basics.Profiles, Profile{Network: n.network, URL: link})
+				}
+			}
+		}
+	}
+
+	if c != nil {
+		if c.Headline != "" {
+			r.Basics.Label = c.Headline
+		}
+		if c.Summary != "" {
+			r.Basics.Summary = c.Summary
+		}
+		exportCV(r, c)
+	}
+
+	for _, p := range projects {
+		if p.Hidden {
+			continue
+		}
+		r.Projects = append(r.Projects, Project{
+			Name:        p.Title,
+			Description: p.Description,
+			Keywords:    p.Tags,
+		})
+	}
+
+	return r
+}
+
+// exportCV adds the entries of a CV to a résumé
+func exportCV(r *Resume, c *cv.CV) {
+	for _, e := range c.Experience {
+		work := Work{
+			Name:       e.Organization,
+			Position:   e.Role,
+			Location:   e.Location,
+			StartDate:  string(e.StartDate),
+			Summary:    e.Description,
+			Highlights: e.Highlights,
+		}
+		if !e.Current {
+			work.EndDate = string(e.EndDate)
+		}
+		r.Work = append(r.Work, work)
+	}
+
+	for _, e := range c.Education {
+		education := Education{
+			Institution: e.Institution,
+			Area:        e.Field,
+			StudyType:   e.Degree,
+			StartDate:   string(e.StartDate),
+			Score:       e.Grade,
+		}
+		if !e.Current {
+			education.EndDate = string(e.EndDate)
+		}
+		r.Education = append(r.Education, education)
+	}
+
+	// Categorized skills become keywords of a skill named after the
+	// category
+	groups := map[string]int{}
+	for _, s := range c.Skills {
+		if s.Category == "" {
+			r.Skills = append(r.Skills, Skill{Name: s.Name, Level: skillLevel(s.Proficiency)})
+			continue
+		}
+		i, ok := groups[s.Category]
+		if !ok {
+			i = len(r.Skills)
+			groups[s.Category] = i
+			r.Skills = append(r.Skills, Skill{Name: s.Category})
+		}
+		r.Skills[i].Keywords = append(r.Skills[i].Keywords, s.Name)
+	}
+
+	for _, e := range c.Certifications {
+		r.Certificates = append(r.Certificates, Certificate{
+			Name:   e.Name,
+			Date:   string(e.IssueDate),
+			Issuer: e.Issuer,
+			URL:    e.URL,
+		})
+	}
+
+	for _, e := range c.Languages {
+		fluency := e.Proficiency
+		if fluency != "" {
+			fluency = strings.ToUpper(fluency[:1]) + fluency[1:]
+		}
+		r.Languages = append(r.Languages, Language{Language: e.Name, Fluency: fluency})
+	}
+
+	for _, e := range c.Awards {
+		r.Awards = append(r.Awards, Award{
+			Title:   e.Title,
+			Date:    string(e.Date),
+			Awarder: e.Issuer,
+			Summary: e.Description,
+		})
+	}
+}
+
+// skillLevel names a skill proficiency
+func skillLevel(proficiency int) string {
+	if proficiency < 0 || proficiency >= len(skillLevels) {
+		return ""
+	}
+	return skillLevels[proficiency]
+}
+
+// proficiency maps a JSON Resume skill level to a proficiency. Unknown
+// levels map to 0, no proficiency.
+func proficiency(level string) int {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" {
+		return 0
+	}
+	for i, name := range skillLevels {
+		if strings.ToLower(name) == level {
+			return i
+		}
+	}
+	return levelAliases[level]
+}
+
+// fluency maps a free-form JSON Resume fluency to a language proficiency
+func fluency(text string) string {
+	text = strings.ToLower(text)
+	for _, f := range fluencies {
+		if strings.Contains(text, f.word) {
+			return f.proficiency
+		}
+	}
+	return ""
+}
+
+// toDate converts a JSON Resume date, reporting whether it is valid
+func toDate(value string) (cv.Date, bool) {
+	d := cv.Date(strings.TrimSpace(value))
+	if d == "" {
+		return d, true
+	}
+	_, ok := d.Time()
+	return d, ok
+}
+
+// dateLayouts are the date formats found in imported documents and the
+// precision they convert to
+var dateLayouts = []struct{ layout, format string }{
+	{"Jan 2006", "2006-01"},
+	{"January 2006", "2006-01"},
+	{"01/2006", "2006-01"},
+	{"Jan 2, 2006", "2006-01-02"},
+	{"1/2/06", "2006-01-02"},
+	{"2006-01-02", "2006-01-02"},
+	{"2006-01", "2006-01"},
+	{"2006", "2006"},
+}
+
+// looseDate converts a date as people write it to a JSON Resume date. Dates
+// in an unknown format are kept, so the import reports them.
+func looseDate(value string) string {
+	value = strings.TrimSpace(value)
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l.layout, value); err == nil {
+			return t.Format(l.format)
+		}
+	}
+	return value
+}
+
+// isAbsoluteURL reports whether raw is an absolute http(s) URL
+func isAbsoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
@@ -0,0 +1,164 @@
+package resume
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ErrUnsupportedDocument is returned for documents that aren't a PDF or
+// DOCX file, or whose text can't be extracted
+var ErrUnsupportedDocument = errors.New("unsupported document: upload a PDF or DOCX file")
+
+// maxDocumentXMLSize bounds the size of the text part of a DOCX file
+const maxDocumentXMLSize = 20 << 20
+
+// ExtractText returns the text of a PDF or DOCX document, one line per
+// line of the document
+func ExtractText(r io.ReaderAt, size int64) (string, error) {
+	magic := make([]byte, 5)
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return "", ErrUnsupportedDocument
+	}
+
+	var text string
+	var err error
+	switch {
+	case bytes.Equal(magic, []byte("%PDF-")):
+		text, err = extractPDF(r, size)
+	case bytes.HasPrefix(magic, []byte("PK")):
+		text, err = extractDOCX(r, size)
+	default:
+		return "", ErrUnsupportedDocument
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedDocument, err)
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%w: the document has no text", ErrUnsupportedDocument)
+	}
+	return text, nil
+}
+
+// extractPDF returns the text of a PDF file. Characters are grouped into
+// lines and words by their position on the page, as the order of a PDF's
+// content doesn't follow the reading order.
+func extractPDF(r io.ReaderAt, size int64) (text string, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("malformed PDF: %v", recovered)
+		}
+	}()
+
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		chars := page.Content().Text
+		sort.SliceStable(chars, func(i, j int) bool {
+			if math.Abs(chars[i].Y-chars[j].Y) > lineTolerance(chars[i], chars[j]) {
+				return chars[i].Y > chars[j].Y
+			}
+			return chars[i].X < chars[j].X
+		})
+
+		for j, c := range chars {
+			if j > 0 {
+				prev := chars[j-1]
+				switch {
+				case math.Abs(c.Y-prev.Y) > lineTolerance(c, prev):
+					b.WriteString("\n")
+				case c.X-(prev.X+prev.W) > c.FontSize*0.2 && c.S != " " && prev.S != " ":
+					b.WriteString(" ")
+				}
+			}
+			b.WriteString(c.S)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// lineTolerance is how far apart characters on the same line can be
+// vertically
+func lineTolerance(a, b pdf.Text) float64 {
+	return math.Max(a.FontSize, b.FontSize) / 2
+}
+
+// extractDOCX returns the text of a DOCX file, a line per paragraph
+func extractDOCX(r io.ReaderAt, size int64) (string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+
+	var document *zip.File
+	for _, f := range archive.File {
+		if f.Name == "word/document.xml" {
+			document = f
+			break
+		}
+	}
+	if document == nil {
+		return "", errors.New("not a DOCX file")
+	}
+
+	rc, err := document.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	var b strings.Builder
+	decoder := xml.NewDecoder(io.LimitReader(rc, maxDocumentXMLSize))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteString("\t")
+			case "br", "cr":
+				b.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
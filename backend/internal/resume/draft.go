@@ -0,0 +1,568 @@
+package resume
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Sections of a résumé document
+const (
+	sectionHeader     = "header"
+	sectionSummary    = "summary"
+	sectionContact    = "contact"
+	sectionExperience = "experience"
+	sectionEducation  = "education"
+	sectionSkills     = "skills"
+	sectionLanguages  = "languages"
+	sectionOther      = "other"
+)
+
+// sectionHeadings maps the usual headings of a résumé to their section
+var sectionHeadings = map[string]string{
+	"summary":                 sectionSummary,
+	"professional summary":    sectionSummary,
+	"profile":                 sectionSummary,
+	"professional profile":    sectionSummary,
+	"about":                   sectionSummary,
+	"about me":                sectionSummary,
+	"objective":               sectionSummary,
+	"career objective":        sectionSummary,
+	"personal statement":      sectionSummary,
+	"contact":                 sectionContact,
+	"contact details":         sectionContact,
+	"contact information":     sectionContact,
+	"experience":              sectionExperience,
+	"work experience":         sectionExperience,
+	"professional experience": sectionExperience,
+	"relevant experience":     sectionExperience,
+	"employment":              sectionExperience,
+	"employment history":      sectionExperience,
+	"work history":            sectionExperience,
+	"career history":          sectionExperience,
+	"education":               sectionEducation,
+	"education and training":  sectionEducation,
+	"academic background":     sectionEducation,
+	"qualifications":          sectionEducation,
+	"skills":                  sectionSkills,
+	"key skills":              sectionSkills,
+	"technical skills":        sectionSkills,
+	"skills and tools":        sectionSkills,
+	"core competencies":       sectionSkills,
+	"competencies":            sectionSkills,
+	"expertise":               sectionSkills,
+	"languages":               sectionLanguages,
+	"projects":                sectionOther,
+	"certifications":          sectionOther,
+	"certificates":            sectionOther,
+	"awards":                  sectionOther,
+	"publications":            sectionOther,
+	"volunteering":            sectionOther,
+	"volunteer experience":    sectionOther,
+	"interests":               sectionOther,
+	"hobbies":                 sectionOther,
+	"references":              sectionOther,
+}
+
+const monthPattern = `(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?`
+
+const datePattern = `(?:` + monthPattern + `\s+\d{4}|\d{1,2}/\d{4}|\d{4}-\d{2}|\d{4})`
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d\s().-]{6,}\d`)
+	urlPattern   = regexp.MustCompile(`(?i)(?:https?://|www\.|\b(?:linkedin|github|behance|dribbble|twitter|x)\.com/)\S+`)
+	// dateRangePattern matches ranges such as "Jan 2020 – Present"
+	dateRangePattern = regexp.MustCompile(`(?i)(` + datePattern + `)\s*(?:-|–|—|to|until)\s*(` + datePattern + `|present|current|now|today)`)
+	yearPattern      = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+	bulletPattern    = regexp.MustCompile(`^\s*[•●▪◦‣∙·*–-]\s*`)
+	// institutionPattern and degreePattern recognize the lines of
+	// education entries
+	institutionPattern = regexp.MustCompile(`(?i)\b(?:university|college|school|institute|academy|polytechnic|universit[éä]t|école|conservatory)\b`)
+	degreePattern      = regexp.MustCompile(`(?i)\b(?:bachelor|master|doctor|ph\.?d|mba|b\.?sc|m\.?sc|b\.?a|m\.?a|b\.?eng|m\.?eng|bfa|mfa|diploma|associate|certificate|degree|a-levels?|gcse)\b`)
+	// rolePattern recognizes job titles when a position's title and
+	// employer are on separate lines
+	rolePattern = regexp.MustCompile(`(?i)\b(?:engineer|developer|designer|manager|director|intern|analyst|consultant|lead|head|officer|specialist|architect|assistant|scientist|researcher|teacher|lecturer|writer|editor|artist|illustrator|photographer|producer|founder|freelance|freelancer|owner|coordinator|administrator|programmer|strategist)\b`)
+	// headerSeparators split a position's heading into title and employer
+	headerSeparators = []string{" at ", " @ ", " | ", " — ", " – ", " - ", ", "}
+)
+
+// ParseDraft detects the sections of a résumé's text: contact details,
+// summary, experience, education, skills and languages. The detection is
+// heuristic, so the draft is meant to be reviewed before it is imported.
+func ParseDraft(text string) *Draft {
+	d := &Draft{Sections: []string{}, Unrecognized: []string{}}
+
+	sections := map[string][]string{}
+	order := []string{}
+	current := sectionHeader
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		if section, ok := sectionHeadings[headingKey(line)]; ok {
+			current = section
+			if section != sectionOther && !containsString(d.Sections, section) {
+				d.Sections = append(d.Sections, section)
+			}
+			continue
+		}
+		if _, ok := sections[current]; !ok {
+			order = append(order, current)
+		}
+		sections[current] = append(sections[current], line)
+	}
+
+	for _, section := range order {
+		lines := sections[section]
+		switch section {
+		case sectionHeader, sectionContact:
+			d.parseHeader(lines)
+		case sectionSummary:
+			d.parseSummary(lines)
+		case sectionExperience:
+			d.parseExperience(lines)
+		case sectionEducation:
+			d.parseEducation(lines)
+		case sectionSkills:
+			d.parseSkills(lines)
+		case sectionLanguages:
+			d.parseLanguages(lines)
+		default:
+			d.Unrecognized = append(d.Unrecognized, lines...)
+		}
+	}
+	if (d.Resume.Basics.Email != "" || d.Resume.Basics.Phone != "") && !containsString(d.Sections, sectionContact) {
+		d.Sections = append(d.Sections, sectionContact)
+	}
+	return d
+}
+
+// headingKey normalizes a line for looking it up in sectionHeadings.
+// Headings spelled out letter by letter ("E X P E R I E N C E") are joined.
+func headingKey(line string) string {
+	line = strings.ToLower(strings.TrimRight(line, ": "))
+	line = strings.ReplaceAll(line, "&", "and")
+	words := strings.Fields(line)
+	spelled := len(words) > 3
+	for _, word := range words {
+		if len([]rune(word)) != 1 {
+			spelled = false
+		}
+	}
+	if spelled {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words, " ")
+}
+
+// parseHeader reads the name, title and contact details at the top of a
+// résumé
+func (d *Draft) parseHeader(lines []string) {
+	basics := &d.Resume.Basics
+	for _, line := range lines {
+		if rest, ok := d.parseContact(line); ok {
+			if rest != "" {
+				d.Unrecognized = append(d.Unrecognized, rest)
+			}
+			continue
+		}
+		switch {
+		case basics.Name == "" && isName(line):
+			basics.Name = line
+		case basics.Label == "" && basics.Name != "" && len(line) <= 80:
+			basics.Label = line
+		case len(line) > 80 && basics.Summary == "":
+			basics.Summary = line
+		default:
+			d.Unrecognized = append(d.Unrecognized, line)
+		}
+	}
+}
+
+// parseContact reads the email address, phone number and links of a line.
+// It reports whether the line had contact details and returns what is left
+// of it.
+func (d *Draft) parseContact(line string) (string, bool) {
+	basics := &d.Resume.Basics
+	found := false
+
+	rest := emailPattern.ReplaceAllStringFunc(line, func(email string) string {
+		if basics.Email == "" {
+			basics.Email = email
+		}
+		found = true
+		return ""
+	})
+	rest = urlPattern.ReplaceAllStringFunc(rest, func(link string) string {
+		d.addLink(strings.TrimRight(link, ".,;)"))
+		found = true
+		return ""
+	})
+	rest = phonePattern.ReplaceAllStringFunc(rest, func(phone string) string {
+		// Date ranges aren't phone numbers
+		if len(strings.Map(keepDigits, phone)) < 7 || dateRangePattern.MatchString(phone) {
+			return phone
+		}
+		if basics.Phone == "" {
+			basics.Phone = strings.TrimSpace(phone)
+		}
+		found = true
+		return ""
+	})
+
+	rest = cleanLine(rest)
+	for _, label := range []string{"email", "e-mail", "phone", "tel", "mobile", "web", "website", "linkedin", "github"} {
+		if strings.EqualFold(strings.TrimRight(rest, ": "), label) {
+			rest = ""
+		}
+	}
+	return rest, found
+}
+
+// addLink adds a link of a résumé as a profile or its website
+func (d *Draft) addLink(link string) {
+	url := link
+	if !strings.Contains(url, "://") {
+		url = "https://" + url
+	}
+	if !isAbsoluteURL(url) {
+		return
+	}
+
+	basics := &d.Resume.Basics
+	lower := strings.ToLower(url)
+	for _, n := range socialNetworks {
+		domain := strings.ToLower(n.network) + ".com/"
+		if !strings.Contains(lower, domain) && !(n.network == "Twitter" && strings.Contains(lower, "//x.com/")) {
+			continue
+		}
+		basics.Profiles = append(basics.Profiles, Profile{Network: n.network, URL: url})
+		return
+	}
+	if basics.URL == "" {
+		basics.URL = url
+	}
+}
+
+// parseSummary reads the summary of a résumé
+func (d *Draft) parseSummary(lines []string) {
+	summary := make([]string, 0, len(lines))
+	for _, line := range lines {
+		summary = append(summary, bulletPattern.ReplaceAllString(line, ""))
+	}
+	if d.Resume.Basics.Summary != "" {
+		d.Unrecognized = append(d.Unrecognized, d.Resume.Basics.Summary)
+	}
+	d.Resume.Basics.Summary = strings.Join(summary, " ")
+}
+
+// entryLine is a line of an experience entry
+type entryLine struct {
+	text   string
+	bullet bool
+}
+
+// parseExperience reads positions. A position starts at a line with a
+// date range; its title and employer are on that line or the lines just
+// above it, and the lines below describe it.
+func (d *Draft) parseExperience(lines []string) {
+	var entries []*Work
+	var bodies [][]entryLine
+	var pending []entryLine
+
+	for _, line := range lines {
+		bullet := bulletPattern.MatchString(line)
+		text := bulletPattern.ReplaceAllString(line, "")
+
+		m := dateRangePattern.FindStringSubmatchIndex(text)
+		if bullet || m == nil {
+			pending = append(pending, entryLine{text: text, bullet: bullet})
+			continue
+		}
+
+		work := &Work{
+			StartDate: draftDate(text[m[2]:m[3]]),
+			EndDate:   draftDate(text[m[4]:m[5]]),
+		}
+		rest := cleanLine(text[:m[0]] + " " + text[m[1]:])
+
+		// The title and employer are on the lines above when the date
+		// line doesn't hold both
+		parts := []string{}
+		if rest != "" {
+			parts = append(parts, rest)
+		}
+		if rest == "" || splitHeading(rest) == nil {
+			want := 2 - len(parts)
+			for want > 0 && len(pending) > 0 {
+				last := pending[len(pending)-1]
+				if last.bullet || len(last.text) > 80 || strings.HasSuffix(last.text, ".") {
+					break
+				}
+				pending = pending[:len(pending)-1]
+				parts = append([]string{last.text}, parts...)
+				want--
+			}
+		}
+		work.Position, work.Name = positionAndEmployer(parts)
+
+		if len(entries) == 0 {
+			for _, l := range pending {
+				d.Unrecognized = append(d.Unrecognized, l.text)
+			}
+		} else {
+			bodies[len(bodies)-1] = pending
+		}
+		pending = nil
+		entries = append(entries, work)
+		bodies = append(bodies, nil)
+	}
+	if len(entries) == 0 {
+		for _, l := range pending {
+			d.Unrecognized = append(d.Unrecognized, l.text)
+		}
+		return
+	}
+	bodies[len(bodies)-1] = pending
+
+	for i, work := range entries {
+		var summary []string
+		for _, l := range bodies[i] {
+			switch {
+			case l.bullet:
+				work.Highlights = append(work.Highlights, l.text)
+			case len(work.Highlights) > 0:
+				// Bullets wrapped onto the next line
+				work.Highlights[len(work.Highlights)-1] += " " + l.text
+			default:
+				summary = append(summary, l.text)
+			}
+		}
+		work.Summary = strings.Join(summary, " ")
+		d.Resume.Work = append(d.Resume.Work, *work)
+	}
+}
+
+// splitHeading splits a position's heading such as "Designer at Acme" into
+// its parts, returning nil when it has no separator
+func splitHeading(heading string) []string {
+	for _, sep := range headerSeparators {
+		if i := strings.Index(heading, sep); i > 0 {
+			return []string{strings.TrimSpace(heading[:i]), strings.TrimSpace(heading[i+len(sep):])}
+		}
+	}
+	return nil
+}
+
+// positionAndEmployer tells the title and the employer of a position
+// apart
+func positionAndEmployer(parts []string) (string, string) {
+	switch len(parts) {
+	case 0:
+		return "", ""
+	case 1:
+		if split := splitHeading(parts[0]); split != nil {
+			parts = split
+		} else {
+			return parts[0], ""
+		}
+	}
+	position, employer := parts[0], parts[1]
+	if !rolePattern.MatchString(position) && rolePattern.MatchString(employer) {
+		position, employer = employer, position
+	}
+	return position, employer
+}
+
+// parseEducation reads education entries. An entry is made of a line
+// naming the institution, one naming the degree and the dates, in any
+// order.
+func (d *Draft) parseEducation(lines []string) {
+	var current *Education
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if current.Institution != "" {
+			d.Resume.Education = append(d.Resume.Education, *current)
+		} else if current.StudyType != "" {
+			d.Unrecognized = append(d.Unrecognized, current.StudyType)
+		}
+		current = nil
+	}
+
+	for _, line := range lines {
+		text := bulletPattern.ReplaceAllString(line, "")
+
+		var start, end string
+		if m := dateRangePattern.FindStringSubmatchIndex(text); m != nil {
+			start, end = draftDate(text[m[2]:m[3]]), draftDate(text[m[4]:m[5]])
+			text = text[:m[0]] + " " + text[m[1]:]
+		} else if years := yearPattern.FindAllStringIndex(text, -1); len(years) == 1 {
+			end = text[years[0][0]:years[0][1]]
+			text = text[:years[0][0]] + " " + text[years[0][1]:]
+		}
+		text = cleanLine(text)
+
+		parts := splitHeading(text)
+		if parts == nil {
+			parts = []string{text}
+		}
+		var institution, degree string
+		for _, part := range parts {
+			switch {
+			case institutionPattern.MatchString(part) && institution == "":
+				institution = part
+			case degreePattern.MatchString(part) && degree == "":
+				degree = part
+			}
+		}
+		// Institutions without a telling name are recognized by their
+		// place at the start of an entry
+		if institution == "" && degree == "" && text != "" {
+			if current == nil || current.Institution == "" {
+				institution = text
+			} else {
+				d.Unrecognized = append(d.Unrecognized, text)
+			}
+		}
+
+		if current != nil && ((institution != "" && current.Institution != "") || (degree != "" && current.StudyType != "")) {
+			flush()
+		}
+		if current == nil {
+			current = &Education{}
+		}
+		if institution != "" {
+			current.Institution = institution
+		}
+		if degree != "" {
+			current.StudyType, current.Area = splitDegree(degree)
+		}
+		if current.StartDate == "" && current.EndDate == "" {
+			current.StartDate, current.EndDate = start, end
+		}
+	}
+	flush()
+}
+
+// splitDegree splits a degree such as "BSc in Computer Science" into the
+// type of study and its area
+func splitDegree(degree string) (string, string) {
+	if i := strings.Index(strings.ToLower(degree), " in "); i > 0 {
+		return strings.TrimSpace(degree[:i]), strings.TrimSpace(degree[i+4:])
+	}
+	return degree, ""
+}
+
+// parseSkills reads skills. Lines such as "Design: Figma, Sketch" become
+// a group of skills named after the category.
+func (d *Draft) parseSkills(lines []string) {
+	for _, line := range lines {
+		line = bulletPattern.ReplaceAllString(line, "")
+		category := ""
+		if i := strings.Index(line, ":"); i > 0 && i < 40 {
+			category, line = strings.TrimSpace(line[:i]), line[i+1:]
+		}
+
+		var keywords []string
+		for _, item := range splitItems(line) {
+			if len(strings.Fields(item)) > 5 {
+				d.Unrecognized = append(d.Unrecognized, item)
+				continue
+			}
+			keywords = append(keywords, item)
+		}
+		if category != "" && len(keywords) > 0 {
+			d.Resume.Skills = append(d.Resume.Skills, Skill{Name: category, Keywords: keywords})
+			continue
+		}
+		for _, keyword := range keywords {
+			d.Resume.Skills = append(d.Resume.Skills, Skill{Name: keyword})
+		}
+	}
+}
+
+// languagePattern matches "French (fluent)", "French – fluent" and
+// "French: B2"
+var languagePattern = regexp.MustCompile(`^([^(:–—-]+?)\s*(?:\((.+)\)|[:–—-]\s*(.+))?$`)
+
+// parseLanguages reads spoken languages and their fluency
+func (d *Draft) parseLanguages(lines []string) {
+	for _, line := range lines {
+		for _, item := range splitItems(bulletPattern.ReplaceAllString(line, "")) {
+			m := languagePattern.FindStringSubmatch(item)
+			if m == nil {
+				d.Unrecognized = append(d.Unrecognized, item)
+				continue
+			}
+			d.Resume.Languages = append(d.Resume.Languages, Language{
+				Language: strings.TrimSpace(m[1]),
+				Fluency:  strings.TrimSpace(m[2] + m[3]),
+			})
+		}
+	}
+}
+
+// splitItems splits a line listing items
+func splitItems(line string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|' || r == '•' || r == '·'
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// draftDate converts a date of a résumé's text. Open-ended dates such as
+// "Present" are empty.
+func draftDate(value string) string {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "present", "current", "now", "today":
+		return ""
+	}
+	// "Sept. 2019" and "september 2019" are read as "Sep 2019"
+	if fields := strings.Fields(value); len(fields) == 2 && len(fields[0]) >= 3 && unicode.IsLetter(rune(fields[0][0])) {
+		value = strings.ToUpper(fields[0][:1]) + strings.ToLower(fields[0][1:3]) + " " + fields[1]
+	}
+	return looseDate(value)
+}
+
+// cleanLine removes parentheses, extra spaces and leading and trailing
+// separators from a line
+func cleanLine(line string) string {
+	line = strings.NewReplacer("(", "", ")", "").Replace(line)
+	return strings.TrimFunc(strings.Join(strings.Fields(line), " "), isSeparator)
+}
+
+// isName reports whether a line looks like a person's name
+func isName(line string) bool {
+	words := strings.Fields(line)
+	if len(words) < 2 || len(words) > 5 {
+		return false
+	}
+	for _, r := range line {
+		if !unicode.IsLetter(r) && r != ' ' && r != '-' && r != '\'' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// isSeparator reports whether r separates the parts of a line
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune("|•·,;:–—-/", r)
+}
+
+func keepDigits(r rune) rune {
+	if unicode.IsDigit(r) {
+		return r
+	}
+	return -1
+}
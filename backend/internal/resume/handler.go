@@ -0,0 +1,201 @@
+package resume
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/template"
+	"github.com/musefolio/backend/internal/user"
+)
+
+// Handler handles HTTP requests for résumé imports
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new résumé handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the résumé routes
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Post("/users/me/cv/import", h.Import)
+	r.Post("/users/me/cv/import/linkedin", h.ImportLinkedIn)
+	r.Post("/users/me/cv/parse", h.Parse)
+	r.Post("/users/me/cv/import/draft", h.AcceptDraft)
+}
+
+// Import handles importing a JSON Resume into the current user's profile
+// and CV. The "portfolioId" query parameter names the portfolio the
+// résumé's projects are added to, and "dryRun=true" returns the changes
+// without applying them.
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	var opts ImportOptions
+	if raw := r.URL.Query().Get("portfolioId"); raw != "" {
+		portfolioID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+			return
+		}
+		opts.PortfolioID = &portfolioID
+	}
+	opts.DryRun = r.URL.Query().Get("dryRun") == "true"
+
+	var input Resume
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.service.Import(r.Context(), userID, &input, opts)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ImportLinkedIn handles importing a LinkedIn data archive, uploaded as the
+// "file" form field, into the current user's profile and CV. Existing values
+// are kept unless "overwrite=true" is set; "portfolioId" and "dryRun" work
+// as for Import.
+func (h *Handler) ImportLinkedIn(w http.ResponseWriter, r *http.Request) {
+	var opts ImportOptions
+	if raw := r.URL.Query().Get("portfolioId"); raw != "" {
+		portfolioID, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			http.Error(w, "Invalid portfolio ID", http.StatusBadRequest)
+			return
+		}
+		opts.PortfolioID = &portfolioID
+	}
+	opts.DryRun = r.URL.Query().Get("dryRun") == "true"
+	opts.KeepExisting = r.URL.Query().Get("overwrite") != "true"
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		http.Error(w, ErrInvalidArchive.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.service.ImportLinkedIn(r.Context(), userID, archive, opts)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Parse handles extracting a draft résumé from a PDF or DOCX document
+// uploaded as the "file" form field. Nothing is saved; the reviewed draft
+// is accepted with AcceptDraft.
+func (h *Handler) Parse(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	text, err := ExtractText(file, header.Size)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ParseDraft(text))
+}
+
+// AcceptDraft handles importing a reviewed draft into the current user's
+// profile and CV, and creating a CV portfolio for its projects
+func (h *Handler) AcceptDraft(w http.ResponseWriter, r *http.Request) {
+	var input AcceptDraftInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.service.AcceptDraft(r.Context(), userID, input)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Portfolio != nil {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// writeImportError writes the response for an import error
+func writeImportError(w http.ResponseWriter, err error) {
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.Is(err, user.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, portfolio.ErrPortfolioNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, portfolio.ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, portfolio.ErrSubdomainTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, cv.ErrInvalidCV), errors.Is(err, ErrInvalidArchive), errors.Is(err, ErrUnsupportedDocument),
+		errors.Is(err, template.ErrTemplateNotFound), errors.Is(err, portfolio.ErrInvalidTheme):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.As(err, &validationErrors):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,289 @@
+package resume
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidArchive is returned for uploads that aren't a LinkedIn data
+// archive
+var ErrInvalidArchive = errors.New("invalid LinkedIn data archive")
+
+// maxCSVSize bounds the size of a single CSV file read from an archive
+const maxCSVSize = 10 << 20
+
+// linkedInFile describes a CSV file of a LinkedIn data archive and the
+// columns that are imported from it
+type linkedInFile struct {
+	name    string
+	columns []string
+	// read adds a row of the file to a résumé
+	read func(r *Resume, row map[string]string)
+}
+
+// linkedInFiles lists the files of a LinkedIn data archive that are
+// imported
+var linkedInFiles = []linkedInFile{
+	{
+		name:    "Profile.csv",
+		columns: []string{"First Name", "Last Name", "Headline", "Summary", "Websites", "Twitter Handles"},
+		read: func(r *Resume, row map[string]string) {
+			r.Basics.Name = strings.TrimSpace(row["First Name"] + " " + row["Last Name"])
+			r.Basics.Label = row["Headline"]
+			r.Basics.Summary = row["Summary"]
+			r.Basics.URL = firstWebsite(row["Websites"])
+			if handles := splitList(row["Twitter Handles"]); len(handles) > 0 {
+				handle := strings.TrimPrefix(handles[0], "@")
+				r.Basics.Profiles = append(r.Basics.Profiles, Profile{
+					Network:  "Twitter",
+					Username: handle,
+					URL:      "https://twitter.com/" + handle,
+				})
+			}
+		},
+	},
+	{
+		name:    "Positions.csv",
+		columns: []string{"Company Name", "Title", "Description", "Location", "Started On", "Finished On"},
+		read: func(r *Resume, row map[string]string) {
+			r.Work = append(r.Work, Work{
+				Name:      row["Company Name"],
+				Position:  row["Title"],
+				Summary:   row["Description"],
+				Location:  row["Location"],
+				StartDate: looseDate(row["Started On"]),
+				EndDate:   looseDate(row["Finished On"]),
+			})
+		},
+	},
+	{
+		name:    "Education.csv",
+		columns: []string{"School Name", "Degree Name", "Start Date", "End Date", "Notes"},
+		read: func(r *Resume, row map[string]string) {
+			education := Education{
+				Institution: row["School Name"],
+				StudyType:   row["Degree Name"],
+				StartDate:   looseDate(row["Start Date"]),
+				EndDate:     looseDate(row["End Date"]),
+			}
+			if notes := strings.TrimSpace(row["Notes"]); notes != "" {
+				education.Courses = []string{notes}
+			}
+			r.Education = append(r.Education, education)
+		},
+	},
+	{
+		name:    "Skills.csv",
+		columns: []string{"Name"},
+		read: func(r *Resume, row map[string]string) {
+			r.Skills = append(r.Skills, Skill{Name: row["Name"]})
+		},
+	},
+	{
+		name:    "Projects.csv",
+		columns: []string{"Title", "Description", "Url", "Started On", "Finished On"},
+		read: func(r *Resume, row map[string]string) {
+			r.Projects = append(r.Projects, Project{
+				Name:        row["Title"],
+				Description: row["Description"],
+				URL:         row["Url"],
+				StartDate:   looseDate(row["Started On"]),
+				EndDate:     looseDate(row["Finished On"]),
+			})
+		},
+	},
+	{
+		name:    "Languages.csv",
+		columns: []string{"Name", "Proficiency"},
+		read: func(r *Resume, row map[string]string) {
+			r.Languages = append(r.Languages, Language{Language: row["Name"], Fluency: row["Proficiency"]})
+		},
+	},
+	{
+		name:    "Certifications.csv",
+		columns: []string{"Name", "Authority", "Url", "Started On"},
+		read: func(r *Resume, row map[string]string) {
+			r.Certificates = append(r.Certificates, Certificate{
+				Name:   row["Name"],
+				Issuer: row["Authority"],
+				URL:    row["Url"],
+				Date:   looseDate(row["Started On"]),
+			})
+		},
+	},
+	{
+		name:    "Honors.csv",
+		columns: []string{"Title", "Description", "Issued On"},
+		read: func(r *Resume, row map[string]string) {
+			r.Awards = append(r.Awards, Award{
+				Title:   row["Title"],
+				Summary: row["Description"],
+				Date:    looseDate(row["Issued On"]),
+			})
+		},
+	},
+}
+
+// ParseLinkedIn converts a LinkedIn "Download your data" archive into a
+// résumé. It also returns the data of the archive that has no place in
+// Musefolio: files that aren't imported and filled columns of imported
+// files that aren't mapped.
+func ParseLinkedIn(archive *zip.Reader) (*Resume, []string, error) {
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() {
+			files[path.Base(f.Name)] = f
+		}
+	}
+
+	r := &Resume{}
+	unmapped := []string{}
+	found := false
+	for _, lf := range linkedInFiles {
+		f, ok := files[lf.name]
+		if !ok {
+			continue
+		}
+		delete(files, lf.name)
+		found = true
+
+		columns, err := readLinkedInFile(f, lf, r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, lf.name, err)
+		}
+		for _, column := range columns {
+			unmapped = append(unmapped, lf.name+": "+column)
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("%w: none of %s found", ErrInvalidArchive, linkedInFileNames())
+	}
+
+	rest := []string{}
+	for name := range files {
+		if strings.HasSuffix(name, ".csv") {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	return r, append(unmapped, rest...), nil
+}
+
+// readLinkedInFile adds the rows of a CSV file to a résumé. It returns the
+// columns that had values but aren't imported.
+func readLinkedInFile(f *zip.File, lf linkedInFile, r *Resume) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	reader := csv.NewReader(io.LimitReader(rc, maxCSVSize))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	// Some exports start with notes before the header
+	var header []string
+	for header == nil {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range record {
+			record[i] = strings.Trim(strings.TrimPrefix(record[i], "\ufeff"), "\" ")
+		}
+		if containsString(record, lf.columns[0]) {
+			header = record
+		}
+	}
+
+	filled := map[string]bool{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = strings.TrimSpace(record[i])
+				if row[column] != "" {
+					filled[column] = true
+				}
+			}
+		}
+		lf.read(r, row)
+	}
+
+	var unmapped []string
+	for _, column := range header {
+		if filled[column] && !containsString(lf.columns, column) {
+			unmapped = append(unmapped, column)
+		}
+	}
+	return unmapped, nil
+}
+
+// listItem matches the items of LinkedIn's list values, e.g.
+// "[PERSONAL:https://ada.dev,COMPANY:https://example.com]"
+var listItem = regexp.MustCompile(`^(?:[A-Z_]+:)?(.+)$`)
+
+// splitList splits a LinkedIn list value into its items, dropping item
+// types
+func splitList(value string) []string {
+	value = strings.Trim(strings.TrimSpace(value), "[]")
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if m := listItem.FindStringSubmatch(item); m != nil {
+			items = append(items, m[1])
+		}
+	}
+	return items
+}
+
+// firstWebsite returns the first URL of LinkedIn's websites value
+func firstWebsite(value string) string {
+	for _, item := range splitList(value) {
+		if !strings.Contains(item, "://") {
+			item = "https://" + item
+		}
+		if isAbsoluteURL(item) {
+			return item
+		}
+	}
+	return ""
+}
+
+// linkedInFileNames lists the imported file names for error messages
+func linkedInFileNames() string {
+	names := make([]string, len(linkedInFiles))
+	for i, lf := range linkedInFiles {
+		names[i] = lf.name
+	}
+	return strings.Join(names, ", ")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+// Package resume converts between JSON Resume documents
+// (https://jsonresume.org/schema) and the profile, CV and projects of a user.
+package resume
+
+import "github.com/musefolio/backend/internal/portfolio"
+
+// Resume is a JSON Resume document. Sections Musefolio has no place for,
+// such as volunteering or references, are ignored.
+type Resume struct {
+	Schema       string        `json:"$schema,omitempty"`
+	Basics       Basics        `json:"basics"`
+	Work         []Work        `json:"work,omitempty"`
+	Education    []Education   `json:"education,omitempty"`
+	Skills       []Skill       `json:"skills,omitempty"`
+	Projects     []Project     `json:"projects,omitempty"`
+	Certificates []Certificate `json:"certificates,omitempty"`
+	Languages    []Language    `json:"languages,omitempty"`
+	Awards       []Award       `json:"awards,omitempty"`
+}
+
+// Basics holds the personal details of a résumé
+type Basics struct {
+	Name     string    `json:"name,omitempty"`
+	Label    string    `json:"label,omitempty"`
+	Image    string    `json:"image,omitempty"`
+	Email    string    `json:"email,omitempty"`
+	Phone    string    `json:"phone,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	Summary  string    `json:"summary,omitempty"`
+	Location *Location `json:"location,omitempty"`
+	Profiles []Profile `json:"profiles,omitempty"`
+}
+
+// Location is where the person of a résumé lives
+type Location struct {
+	Address     string `json:"address,omitempty"`
+	PostalCode  string `json:"postalCode,omitempty"`
+	City        string `json:"city,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Region      string `json:"region,omitempty"`
+}
+
+// Profile is an account on a social network
+type Profile struct {
+	Network  string `json:"network,omitempty"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// Work is a position held
+type Work struct {
+	Name       string   `json:"name,omitempty"`
+	Position   string   `json:"position,omitempty"`
+	Location   string   `json:"location,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	StartDate  string   `json:"startDate,omitempty"`
+	EndDate    string   `json:"endDate,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	Highlights []string `json:"highlights,omitempty"`
+}
+
+// Education is a degree, diploma or course
+type Education struct {
+	Institution string   `json:"institution,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Area        string   `json:"area,omitempty"`
+	StudyType   string   `json:"studyType,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	Score       string   `json:"score,omitempty"`
+	Courses     []string `json:"courses,omitempty"`
+}
+
+// Skill is a skill, or a group of skills listed as keywords
+type Skill struct {
+	Name     string   `json:"name,omitempty"`
+	Level    string   `json:"level,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// Project is a project worked on
+type Project struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	URL         string   `json:"url,omitempty"`
+}
+
+// Certificate is a professional certification or license
+type Certificate struct {
+	Name   string `json:"name,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Issuer string `json:"issuer,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Language is a spoken language
+type Language struct {
+	Language string `json:"language,omitempty"`
+	Fluency  string `json:"fluency,omitempty"`
+}
+
+// Award is an award, grant or honour
+type Award struct {
+	Title   string `json:"title,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Awarder string `json:"awarder,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// Change is a single modification an import makes
+type Change struct {
+	// Resource is what is modified: "user", "cv" or "portfolio"
+	Resource string `json:"resource"`
+	// Field is the modified field or list, e.g. "name" or "experience"
+	Field string `json:"field"`
+	// Action is "set" for fields that are overwritten and "add" for list
+	// entries that are appended
+	Action string      `json:"action"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after"`
+}
+
+// Conflict is a field whose existing value differs from the imported one
+type Conflict struct {
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	Existing string `json:"existing"`
+	Imported string `json:"imported"`
+	// Kept is set when the existing value was kept
+	Kept bool `json:"kept"`
+}
+
+// ImportResult describes the outcome of an import
+type ImportResult struct {
+	// DryRun is set when the changes were only computed, not applied
+	DryRun    bool       `json:"dryRun"`
+	Changes   []Change   `json:"changes"`
+	Conflicts []Conflict `json:"conflicts"`
+	// Skipped lists the entries that could not be imported and why
+	Skipped []string `json:"skipped"`
+	// Unmapped lists the data of the source Musefolio has no place for
+	Unmapped []string `json:"unmapped,omitempty"`
+}
+
+// Draft is a résumé detected in an uploaded document, to be reviewed and
+// accepted by the user
+type Draft struct {
+	Resume Resume `json:"resume"`
+	// Sections lists the sections that were detected
+	Sections []string `json:"sections"`
+	// Unrecognized lists the lines of the document that weren't placed
+	Unrecognized []string `json:"unrecognized"`
+}
+
+// AcceptDraftInput represents the input for accepting a reviewed draft
+type AcceptDraftInput struct {
+	Resume Resume `json:"resume"`
+	// Portfolio creates a CV portfolio the résumé's projects are added to
+	Portfolio    *portfolio.CreatePortfolioInput `json:"portfolio,omitempty"`
+	KeepExisting bool                            `json:"keepExisting"`
+}
+
+// AcceptDraftResult describes the outcome of accepting a draft
+type AcceptDraftResult struct {
+	ImportResult
+	Portfolio *portfolio.Portfolio `json:"portfolio,omitempty"`
+}
@@ -0,0 +1,400 @@
+package resume
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/user"
+)
+
+// maxHighlights is the number of highlights a CV position holds
+const maxHighlights = 20
+
+// plan is what importing a résumé changes. Fields the résumé leaves empty
+// are kept, and list entries are only added when the CV or portfolio
+// doesn't have them yet, so importing the same résumé twice changes
+// nothing the second time.
+type plan struct {
+	result ImportResult
+	// keepExisting keeps fields that already have a value
+	keepExisting bool
+	// user updates the profile; nil if it is unchanged
+	user *user.UpdateUserInput
+	// cv is the whole CV after the import; nil if it is unchanged
+	cv *cv.UpdateCVInput
+	// projects are added to the portfolio
+	projects []portfolio.CreateProjectInput
+}
+
+// buildPlan computes the changes of importing r. existing holds the
+// projects of the portfolio projects are imported into; when it is nil the
+// résumé's projects are skipped.
+func buildPlan(u *user.User, c *cv.CV, existing []portfolio.Project, r *Resume, keepExisting bool) *plan {
+	p := &plan{
+		result:       ImportResult{Changes: []Change{}, Conflicts: []Conflict{}, Skipped: []string{}},
+		keepExisting: keepExisting,
+	}
+	p.planUser(u, r.Basics)
+	p.planCV(c, r)
+	if existing != nil {
+		p.planProjects(existing, r.Projects)
+	} else if len(r.Projects) > 0 {
+		p.skip("projects: no portfolio to import %d projects into", len(r.Projects))
+	}
+	return p
+}
+
+// change records a change
+func (p *plan) change(resource, field, action string, before, after interface{}) {
+	p.result.Changes = append(p.result.Changes, Change{
+		Resource: resource,
+		Field:    field,
+		Action:   action,
+		Before:   before,
+		After:    after,
+	})
+}
+
+// set reports whether a field is overwritten with value, recording the
+// change. Existing values that differ are reported as conflicts and, with
+// keepExisting, kept.
+func (p *plan) set(resource, field, current, value string) bool {
+	if value == "" || value == current {
+		return false
+	}
+	if current != "" {
+		p.result.Conflicts = append(p.result.Conflicts, Conflict{
+			Resource: resource,
+			Field:    field,
+			Existing: current,
+			Imported: value,
+			Kept:     p.keepExisting,
+		})
+		if p.keepExisting {
+			return false
+		}
+	}
+	p.change(resource, field, "set", current, value)
+	return true
+}
+
+// skip records an entry that can't be imported
+func (p *plan) skip(format string, args ...interface{}) {
+	p.result.Skipped = append(p.result.Skipped, fmt.Sprintf(format, args...))
+}
+
+// planUser updates the profile from the basics of a résumé
+func (p *plan) planUser(u *user.User, basics Basics) {
+	input := user.UpdateUserInput{}
+	changed := false
+
+	set := func(field string, current string, value string, target **string) {
+		value = strings.TrimSpace(value)
+		if p.set("user", field, current, value) {
+			*target = &value
+			changed = true
+		}
+	}
+	set("name", u.Name, basics.Name, &input.Name)
+	set("profession", u.Profession, basics.Label, &input.Profession)
+	set("bio", u.Bio, basics.Summary, &input.Bio)
+
+	links := user.SocialLinks{}
+	if u.SocialLinks != nil {
+		links = *u.SocialLinks
+	}
+	linksChanged := false
+	setLink := func(field string, link *string, value string) {
+		value = strings.TrimSpace(value)
+		if value == "" || value == *link {
+			return
+		}
+		if !isAbsoluteURL(value) {
+			p.skip("%s: %q is not a URL", field, value)
+			return
+		}
+		if p.set("user", field, *link, value) {
+			*link = value
+			linksChanged = true
+		}
+	}
+	setLink("socialLinks.website", &links.Website, basics.URL)
+	for _, profile := range basics.Profiles {
+		for _, n := range socialNetworks {
+			network := strings.ToLower(strings.TrimSpace(profile.Network))
+			if network == strings.ToLower(n.network) || (n.network == "Twitter" && network == "x") {
+				setLink("socialLinks."+strings.ToLower(n.network), n.link(&links), profile.URL)
+			}
+		}
+	}
+	if linksChanged {
+		input.SocialLinks = &links
+		changed = true
+	}
+
+	if changed {
+		p.user = &input
+	}
+}
+
+// planCV merges the sections of a résumé into a CV
+func (p *plan) planCV(c *cv.CV, r *Resume) {
+	input := cv.UpdateCVInput{
+		Headline:       c.Headline,
+		Summary:        c.Summary,
+		Experience:     c.Experience,
+		Education:      c.Education,
+		Skills:         c.Skills,
+		Certifications: c.Certifications,
+		Languages:      c.Languages,
+		Awards:         c.Awards,
+	}
+	changed := false
+
+	if label := strings.TrimSpace(r.Basics.Label); p.set("cv", "headline", c.Headline, label) {
+		input.Headline = label
+		changed = true
+	}
+	if summary := strings.TrimSpace(r.Basics.Summary); p.set("cv", "summary", c.Summary, summary) {
+		input.Summary = summary
+		changed = true
+	}
+
+	// seen tracks the entries of each list so duplicates are left out
+	seen := map[string]bool{}
+	add := func(list, key string, entry interface{}) bool {
+		key = list + "\x00" + strings.ToLower(key)
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		p.change("cv", list, "add", nil, entry)
+		changed = true
+		return true
+	}
+	for _, e := range c.Experience {
+		seen["experience\x00"+strings.ToLower(experienceKey(e))] = true
+	}
+	for _, e := range c.Education {
+		seen["education\x00"+strings.ToLower(educationKey(e))] = true
+	}
+	for _, e := range c.Skills {
+		seen["skills\x00"+strings.ToLower(e.Name)] = true
+	}
+	for _, e := range c.Certifications {
+		seen["certifications\x00"+strings.ToLower(e.Name+"\x00"+e.Issuer)] = true
+	}
+	for _, e := range c.Languages {
+		seen["languages\x00"+strings.ToLower(e.Name)] = true
+	}
+	for _, e := range c.Awards {
+		seen["awards\x00"+strings.ToLower(e.Title+"\x00"+string(e.Date))] = true
+	}
+
+	for i, w := range r.Work {
+		e, err := toExperience(w)
+		if err != nil {
+			p.skip("work[%d]: %v", i, err)
+			continue
+		}
+		if add("experience", experienceKey(e), e) {
+			input.Experience = append(input.Experience, e)
+		}
+	}
+
+	for i, ed := range r.Education {
+		e, err := toEducation(ed)
+		if err != nil {
+			p.skip("education[%d]: %v", i, err)
+			continue
+		}
+		if add("education", educationKey(e), e) {
+			input.Education = append(input.Education, e)
+		}
+	}
+
+	for _, s := range toSkills(r.Skills) {
+		if add("skills", s.Name, s) {
+			input.Skills = append(input.Skills, s)
+		}
+	}
+
+	for i, cert := range r.Certificates {
+		date, ok := toDate(cert.Date)
+		if strings.TrimSpace(cert.Name) == "" || !ok {
+			p.skip("certificates[%d]: a name and a valid date are required", i)
+			continue
+		}
+		e := cv.Certification{Name: strings.TrimSpace(cert.Name), Issuer: cert.Issuer, IssueDate: date}
+		if isAbsoluteURL(cert.URL) {
+			e.URL = cert.URL
+		}
+		if add("certifications", e.Name+"\x00"+e.Issuer, e) {
+			input.Certifications = append(input.Certifications, e)
+		}
+	}
+
+	for i, l := range r.Languages {
+		name := strings.TrimSpace(l.Language)
+		if name == "" {
+			p.skip("languages[%d]: a language is required", i)
+			continue
+		}
+		e := cv.Language{Name: name, Proficiency: fluency(l.Fluency)}
+		if add("languages", e.Name, e) {
+			input.Languages = append(input.Languages, e)
+		}
+	}
+
+	for i, a := range r.Awards {
+		date, ok := toDate(a.Date)
+		if strings.TrimSpace(a.Title) == "" || !ok {
+			p.skip("awards[%d]: a title and a valid date are required", i)
+			continue
+		}
+		e := cv.Award{Title: strings.TrimSpace(a.Title), Issuer: a.Awarder, Date: date, Description: a.Summary}
+		if add("awards", e.Title+"\x00"+string(e.Date), e) {
+			input.Awards = append(input.Awards, e)
+		}
+	}
+
+	if changed {
+		p.cv = &input
+	}
+}
+
+// planProjects adds the projects of a résumé a portfolio doesn't have yet
+func (p *plan) planProjects(existing []portfolio.Project, projects []Project) {
+	seen := map[string]bool{}
+	for _, project := range existing {
+		seen[strings.ToLower(project.Title)] = true
+	}
+
+	for i, project := range projects {
+		title := strings.TrimSpace(project.Name)
+		if title == "" {
+			p.skip("projects[%d]: a name is required", i)
+			continue
+		}
+		if seen[strings.ToLower(title)] {
+			continue
+		}
+		seen[strings.ToLower(title)] = true
+
+		input := toProject(project)
+		input.Title = title
+		input.Order = len(existing) + len(p.projects)
+		p.projects = append(p.projects, input)
+		p.change("portfolio", "projects", "add", nil, input)
+	}
+}
+
+// experienceKey identifies a position when merging
+func experienceKey(e cv.Experience) string {
+	return e.Role + "\x00" + e.Organization + "\x00" + string(e.StartDate)
+}
+
+// educationKey identifies an education entry when merging
+func educationKey(e cv.Education) string {
+	return e.Institution + "\x00" + e.Degree + "\x00" + e.Field
+}
+
+// toExperience converts a position of a résumé. Positions without an end
+// date are current.
+func toExperience(w Work) (cv.Experience, error) {
+	e := cv.Experience{
+		Role:         strings.TrimSpace(w.Position),
+		Organization: strings.TrimSpace(w.Name),
+		Location:     w.Location,
+		Description:  w.Summary,
+		Highlights:   w.Highlights,
+	}
+	if e.Role == "" || e.Organization == "" {
+		return e, fmt.Errorf("a position and a name are required")
+	}
+
+	var startOK, endOK bool
+	e.StartDate, startOK = toDate(w.StartDate)
+	e.EndDate, endOK = toDate(w.EndDate)
+	if e.StartDate == "" || !startOK || !endOK {
+		return e, fmt.Errorf("a valid start date is required and the end date must be valid")
+	}
+	e.Current = e.EndDate == ""
+
+	if len(e.Highlights) > maxHighlights {
+		e.Highlights = e.Highlights[:maxHighlights]
+	}
+	return e, nil
+}
+
+// toEducation converts an education entry of a résumé
+func toEducation(ed Education) (cv.Education, error) {
+	e := cv.Education{
+		Institution: strings.TrimSpace(ed.Institution),
+		Degree:      ed.StudyType,
+		Field:       ed.Area,
+		Grade:       ed.Score,
+	}
+	if e.Institution == "" {
+		return e, fmt.Errorf("an institution is required")
+	}
+	if len(ed.Courses) > 0 {
+		e.Description = "Courses: " + strings.Join(ed.Courses, ", ")
+	}
+
+	var startOK, endOK bool
+	e.StartDate, startOK = toDate(ed.StartDate)
+	e.EndDate, endOK = toDate(ed.EndDate)
+	if !startOK || !endOK {
+		return e, fmt.Errorf("dates must be formatted as YYYY, YYYY-MM or YYYY-MM-DD")
+	}
+	return e, nil
+}
+
+// toSkills converts the skills of a résumé. Skills listing keywords become
+// a skill per keyword, categorized by the skill's name.
+func toSkills(skills []Skill) []cv.Skill {
+	var converted []cv.Skill
+	for _, s := range skills {
+		name := strings.TrimSpace(s.Name)
+		level := proficiency(s.Level)
+		if len(s.Keywords) == 0 {
+			if name != "" {
+				converted = append(converted, cv.Skill{Name: name, Proficiency: level})
+			}
+			continue
+		}
+		for _, keyword := range s.Keywords {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				converted = append(converted, cv.Skill{Name: keyword, Category: name, Proficiency: level})
+			}
+		}
+	}
+	return converted
+}
+
+// toProject converts a project of a résumé. Highlights and the project's
+// link make up its content.
+func toProject(project Project) portfolio.CreateProjectInput {
+	description := strings.TrimSpace(project.Description)
+	if description == "" {
+		description = strings.TrimSpace(project.Name)
+	}
+
+	var content strings.Builder
+	content.WriteString(description)
+	for _, highlight := range project.Highlights {
+		content.WriteString("\n- " + highlight)
+	}
+	if isAbsoluteURL(project.URL) {
+		content.WriteString("\n\n" + project.URL)
+	}
+
+	return portfolio.CreateProjectInput{
+		Description: description,
+		Content:     content.String(),
+		Tags:        project.Keywords,
+	}
+}
@@ -0,0 +1,344 @@
+package resume
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-pdf/fpdf"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/user"
+)
+
+const sample = `{
+  "basics": {
+    "name": "Ada Lovelace",
+    "label": "Analyst",
+    "url": "https://ada.example.com",
+    "summary": "Writes programs for engines.",
+    "profiles": [
+      {"network": "GitHub", "url": "https://github.com/ada"},
+      {"network": "X", "url": "https://x.com/ada"},
+      {"network": "Mastodon", "url": "https://mastodon.social/@ada"}
+    ]
+  },
+  "work": [
+    {"name": "Analytical Society", "position": "Analyst", "startDate": "1842-10", "highlights": ["Note G"]},
+    {"name": "Babbage & Co", "position": "Engineer", "startDate": "1833", "endDate": "1840-06-01"},
+    {"name": "Nowhere", "position": "Ghost", "startDate": "sometime"}
+  ],
+  "education": [{"institution": "Home", "area": "Mathematics", "courses": ["Calculus", "Logic"]}],
+  "skills": [
+    {"name": "Mathematics", "level": "Master", "keywords": ["Calculus", "Logic"]},
+    {"name": "Poetry"}
+  ],
+  "languages": [{"language": "English", "fluency": "Native speaker"}, {"language": "French", "fluency": "Professional working proficiency"}],
+  "projects": [
+    {"name": "Note G", "description": "The first program", "highlights": ["Bernoulli numbers"], "keywords": ["algorithms"]},
+    {"name": "Existing"}
+  ]
+}`
+
+func TestBuildPlan(t *testing.T) {
+	var r Resume
+	if err := json.Unmarshal([]byte(sample), &r); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	u := &user.User{Name: "Ada", SocialLinks: &user.SocialLinks{GitHub: "https://github.com/ada"}}
+	c := &cv.CV{
+		Experience: []cv.Experience{{Role: "Engineer", Organization: "Babbage & Co", StartDate: "1833"}},
+		Skills:     []cv.Skill{{Name: "logic"}},
+	}
+	existing := []portfolio.Project{{Title: "existing"}}
+
+	p := buildPlan(u, c, existing, &r, false)
+
+	if p.user == nil || *p.user.Name != "Ada Lovelace" || *p.user.Bio != "Writes programs for engines." {
+		t.Fatalf("user update = %+v", p.user)
+	}
+	links := p.user.SocialLinks
+	if links.Twitter != "https://x.com/ada" || links.Website != "https://ada.example.com" || links.GitHub != "https://github.com/ada" {
+		t.Errorf("social links = %+v", links)
+	}
+
+	if p.cv == nil {
+		t.Fatal("cv unchanged")
+	}
+	if len(p.cv.Experience) != 2 || p.cv.Experience[1].Organization != "Analytical Society" || !p.cv.Experience[1].Current {
+		t.Errorf("experience = %+v", p.cv.Experience)
+	}
+	if len(p.cv.Education) != 1 || p.cv.Education[0].Description != "Courses: Calculus, Logic" {
+		t.Errorf("education = %+v", p.cv.Education)
+	}
+	// "Logic" is already in the CV
+	if len(p.cv.Skills) != 3 || p.cv.Skills[1] != (cv.Skill{Name: "Calculus", Category: "Mathematics", Proficiency: 5}) {
+		t.Errorf("skills = %+v", p.cv.Skills)
+	}
+	if p.cv.Languages[0].Proficiency != "native" || p.cv.Languages[1].Proficiency != "professional" {
+		t.Errorf("languages = %+v", p.cv.Languages)
+	}
+
+	if len(p.projects) != 1 || p.projects[0].Title != "Note G" || p.projects[0].Order != 1 ||
+		!strings.Contains(p.projects[0].Content, "- Bernoulli numbers") {
+		t.Errorf("projects = %+v", p.projects)
+	}
+
+	if len(p.result.Skipped) != 1 || !strings.HasPrefix(p.result.Skipped[0], "work[2]") {
+		t.Errorf("skipped = %v", p.result.Skipped)
+	}
+
+	counts := map[string]int{}
+	for _, change := range p.result.Changes {
+		counts[change.Resource+"."+change.Field]++
+	}
+	want := map[string]int{
+		"user.name": 1, "user.profession": 1, "user.bio": 1, "user.socialLinks.website": 1, "user.socialLinks.twitter": 1,
+		"cv.headline": 1, "cv.summary": 1, "cv.experience": 1, "cv.education": 1, "cv.skills": 2, "cv.languages": 2,
+		"portfolio.projects": 1,
+	}
+	for key, n := range want {
+		if counts[key] != n {
+			t.Errorf("%d changes to %s, want %d", counts[key], key, n)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	owner := &user.User{
+		ID:          primitive.NewObjectID(),
+		Name:        "Ada Lovelace",
+		Email:       "ada@example.com",
+		Profession:  "Analyst",
+		Avatar:      "/media/avatars/ada.png",
+		SocialLinks: &user.SocialLinks{LinkedIn: "https://linkedin.com/in/ada"},
+	}
+	c := &cv.CV{
+		Headline:   "Analyst",
+		Experience: []cv.Experience{{Role: "Analyst", Organization: "Analytical Society", StartDate: "1842", Current: true, EndDate: "1843"}},
+		Skills:     []cv.Skill{{Name: "Calculus", Category: "Mathematics"}, {Name: "Poetry", Proficiency: 3}, {Name: "Logic", Category: "Mathematics"}},
+		Languages:  []cv.Language{{Name: "English", Proficiency: "native"}},
+	}
+	projects := []portfolio.Project{{Title: "Note G", Description: "The first program"}, {Title: "Draft", Hidden: true}}
+
+	r := FromProfile(owner, c, projects, owner.ID)
+	if r.Basics.Label != "Analyst" || r.Basics.Email != "ada@example.com" || r.Basics.Image != "" || len(r.Basics.Profiles) != 1 {
+		t.Errorf("basics = %+v", r.Basics)
+	}
+	if r.Work[0].EndDate != "" {
+		t.Error("current position exported with an end date")
+	}
+	if len(r.Skills) != 2 || strings.Join(r.Skills[0].Keywords, ",") != "Calculus,Logic" || r.Skills[1].Level != "Intermediate" {
+		t.Errorf("skills = %+v", r.Skills)
+	}
+	if len(r.Projects) != 1 {
+		t.Errorf("projects = %+v", r.Projects)
+	}
+
+	// Importing an export of the same data changes nothing
+	p := buildPlan(owner, c, projects, r, false)
+	if len(p.result.Changes) != 0 || p.user != nil || p.cv != nil {
+		t.Errorf("round trip changes = %+v", p.result.Changes)
+	}
+}
+
+func TestFromProfileLeavesOutEmailForOthers(t *testing.T) {
+	owner := &user.User{ID: primitive.NewObjectID(), Name: "Ada Lovelace", Email: "ada@example.com"}
+
+	r := FromProfile(owner, nil, nil, primitive.NewObjectID())
+	if r.Basics.Email != "" || r.Basics.Name != "Ada Lovelace" {
+		t.Errorf("basics exported to another user = %+v, want no account email", r.Basics)
+	}
+}
+
+func TestParseLinkedIn(t *testing.T) {
+	files := map[string]string{
+		"Profile.csv": "\ufeff\"First Name\",Last Name,Headline,Summary,Websites,Twitter Handles,Birth Date\n" +
+			"Ada,Lovelace,Analyst,\"Writes programs,\nfor engines.\",[PERSONAL:ada.example.com],[@ada],Dec 10\n",
+		"Positions.csv": "Company Name,Title,Description,Location,Started On,Finished On\n" +
+			"Analytical Society,Analyst,,London,Oct 1842,\n" +
+			"Babbage & Co,Engineer,,,1833,Jun 1840\n",
+		"Education.csv":   "School Name,Start Date,End Date,Notes,Degree Name,Activities\nHome,,,,,\n",
+		"Skills.csv":      "Notes:\nSkills you listed\n\nName\nCalculus\nLogic\n",
+		"Connections.csv": "First Name,Last Name\nCharles,Babbage\n",
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create("Basic_LinkedInDataExport/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	zw.Close()
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, unmapped, err := ParseLinkedIn(archive)
+	if err != nil {
+		t.Fatalf("ParseLinkedIn: %v", err)
+	}
+
+	if r.Basics.Name != "Ada Lovelace" || r.Basics.URL != "https://ada.example.com" || r.Basics.Summary != "Writes programs,\nfor engines." {
+		t.Errorf("basics = %+v", r.Basics)
+	}
+	if len(r.Basics.Profiles) != 1 || r.Basics.Profiles[0].URL != "https://twitter.com/ada" {
+		t.Errorf("profiles = %+v", r.Basics.Profiles)
+	}
+	if len(r.Work) != 2 || r.Work[0].StartDate != "1842-10" || r.Work[1].EndDate != "1840-06" {
+		t.Errorf("work = %+v", r.Work)
+	}
+	if len(r.Education) != 1 || len(r.Skills) != 2 {
+		t.Errorf("education = %+v, skills = %+v", r.Education, r.Skills)
+	}
+	if strings.Join(unmapped, "|") != "Profile.csv: Birth Date|Connections.csv" {
+		t.Errorf("unmapped = %v", unmapped)
+	}
+
+	// Existing values are kept and reported as conflicts
+	u := &user.User{Name: "Ada King", Bio: "Writes programs,\nfor engines."}
+	p := buildPlan(u, &cv.CV{}, nil, r, true)
+	if p.user.Name != nil || len(p.result.Conflicts) != 1 || !p.result.Conflicts[0].Kept || p.result.Conflicts[0].Imported != "Ada Lovelace" {
+		t.Errorf("conflicts = %+v", p.result.Conflicts)
+	}
+	if p.cv == nil || len(p.cv.Experience) != 2 || !p.cv.Experience[0].Current {
+		t.Errorf("cv = %+v", p.cv)
+	}
+
+	empty, _ := zip.NewReader(bytes.NewReader(emptyZip()), int64(len(emptyZip())))
+	if _, _, err := ParseLinkedIn(empty); err == nil {
+		t.Error("archive without LinkedIn files accepted")
+	}
+}
+
+func emptyZip() []byte {
+	var buf bytes.Buffer
+	zip.NewWriter(&buf).Close()
+	return buf.Bytes()
+}
+
+const draftText = `Ada Lovelace
+Analyst & Programmer
+ada@example.com | +44 20 7946 0958 | linkedin.com/in/ada | https://ada.example.com
+
+PROFILE
+Writes programs for engines.
+
+E X P E R I E N C E
+Analyst at Analytical Society    Oct 1842 - Present
+• Wrote Note G
+  for the engine
+Babbage & Co
+Engineer
+Jan 1833 – Jun 1840
+Translated the memoir.
+
+Education
+University of London
+BSc in Mathematics (1830 - 1833)
+
+Skills
+Mathematics: Calculus, Logic
+Poetry; Translation
+
+Languages
+English (Native), French - Fluent
+
+Interests
+Horses
+`
+
+func TestParseDraft(t *testing.T) {
+	d := ParseDraft(draftText)
+	basics := d.Resume.Basics
+	if basics.Name != "Ada Lovelace" || basics.Label != "Analyst & Programmer" || basics.Email != "ada@example.com" ||
+		basics.Phone != "+44 20 7946 0958" || basics.URL != "https://ada.example.com" || basics.Summary != "Writes programs for engines." {
+		t.Errorf("basics = %+v", basics)
+	}
+	if len(basics.Profiles) != 1 || basics.Profiles[0].Network != "LinkedIn" {
+		t.Errorf("profiles = %+v", basics.Profiles)
+	}
+
+	work := d.Resume.Work
+	if len(work) != 2 {
+		t.Fatalf("work = %+v", work)
+	}
+	if work[0].Name != "Analytical Society" || work[0].Position != "Analyst" || work[0].StartDate != "1842-10" || work[0].EndDate != "" ||
+		strings.Join(work[0].Highlights, "|") != "Wrote Note G for the engine" {
+		t.Errorf("work[0] = %+v", work[0])
+	}
+	if work[1].Name != "Babbage & Co" || work[1].Position != "Engineer" || work[1].StartDate != "1833-01" ||
+		work[1].EndDate != "1840-06" || work[1].Summary != "Translated the memoir." {
+		t.Errorf("work[1] = %+v", work[1])
+	}
+
+	if len(d.Resume.Education) != 1 || !reflect.DeepEqual(d.Resume.Education[0], Education{
+		Institution: "University of London", StudyType: "BSc", Area: "Mathematics", StartDate: "1830", EndDate: "1833",
+	}) {
+		t.Errorf("education = %+v", d.Resume.Education)
+	}
+	if len(d.Resume.Skills) != 3 || strings.Join(d.Resume.Skills[0].Keywords, ",") != "Calculus,Logic" || d.Resume.Skills[2].Name != "Translation" {
+		t.Errorf("skills = %+v", d.Resume.Skills)
+	}
+	if len(d.Resume.Languages) != 2 || d.Resume.Languages[1] != (Language{Language: "French", Fluency: "Fluent"}) {
+		t.Errorf("languages = %+v", d.Resume.Languages)
+	}
+	if strings.Join(d.Unrecognized, "|") != "Horses" {
+		t.Errorf("unrecognized = %v", d.Unrecognized)
+	}
+	if strings.Join(d.Sections, ",") != "summary,experience,education,skills,languages,contact" {
+		t.Errorf("sections = %v", d.Sections)
+	}
+
+	// The draft imports without skipping anything
+	p := buildPlan(&user.User{}, &cv.CV{}, nil, &d.Resume, false)
+	if len(p.result.Skipped) != 0 || p.cv == nil || len(p.cv.Experience) != 2 {
+		t.Errorf("import skipped %v", p.result.Skipped)
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	var docx bytes.Buffer
+	zw := zip.NewWriter(&docx)
+	f, _ := zw.Create("word/document.xml")
+	f.Write([]byte(`<?xml version="1.0"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>Ada </w:t></w:r><w:r><w:t>Lovelace</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Experience</w:t></w:r></w:p></w:body></w:document>`))
+	zw.Close()
+
+	text, err := ExtractText(bytes.NewReader(docx.Bytes()), int64(docx.Len()))
+	if err != nil || text != "Ada Lovelace\nExperience\n" {
+		t.Errorf("DOCX text = %q, %v", text, err)
+	}
+
+	doc := fpdf.New("P", "mm", "A4", "")
+	doc.AddPage()
+	doc.SetFont("Helvetica", "", 12)
+	doc.Cell(80, 8, "Analyst at Analytical Society")
+	doc.Cell(40, 8, "Oct 1842 - Present")
+	doc.Ln(10)
+	doc.Cell(80, 8, "Skills")
+	var pdf bytes.Buffer
+	if err := doc.Output(&pdf); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err = ExtractText(bytes.NewReader(pdf.Bytes()), int64(pdf.Len()))
+	if err != nil || text != "Analyst at Analytical Society Oct 1842 - Present\nSkills\n" {
+		t.Errorf("PDF text = %q, %v", text, err)
+	}
+
+	if _, err := ExtractText(strings.NewReader("plain text"), 10); !errors.Is(err, ErrUnsupportedDocument) {
+		t.Errorf("plain text: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package resume
+
+import (
+	"archive/zip"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/user"
+)
+
+// Service handles importing résumés
+type Service struct {
+	users      *user.Service
+	cvs        *cv.Service
+	portfolios *portfolio.Service
+}
+
+// NewService creates a new résumé service
+func NewService(users *user.Service, cvs *cv.Service, portfolios *portfolio.Service) *Service {
+	return &Service{
+		users:      users,
+		cvs:        cvs,
+		portfolios: portfolios,
+	}
+}
+
+// ImportOptions controls what an import does
+type ImportOptions struct {
+	// PortfolioID is the portfolio the résumé's projects are added to. They
+	// are skipped when it is nil.
+	PortfolioID *primitive.ObjectID
+	// DryRun only computes the changes without applying them
+	DryRun bool
+	// KeepExisting keeps profile and CV fields that already have a value
+	// instead of overwriting them
+	KeepExisting bool
+}
+
+// Import imports a résumé into the profile and CV of a user. The basics
+// overwrite the profile unless opts.KeepExisting is set, while positions, education and other list entries
+// are merged into the CV.
+func (s *Service) Import(ctx context.Context, userID primitive.ObjectID, r *Resume, opts ImportOptions) (*ImportResult, error) {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.cvs.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []portfolio.Project
+	if opts.PortfolioID != nil {
+		p, err := s.portfolios.GetByID(ctx, *opts.PortfolioID)
+		if err != nil {
+			return nil, err
+		}
+		if p.UserID != userID {
+			return nil, portfolio.ErrUnauthorized
+		}
+		existing = append([]portfolio.Project{}, p.Projects...)
+	}
+
+	plan := buildPlan(u, c, existing, r, opts.KeepExisting)
+
+	// A dry run reports an invalid CV the same way the import would
+	if plan.cv != nil {
+		if err := s.cvs.Check(*plan.cv); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.DryRun {
+		plan.result.DryRun = true
+		return &plan.result, nil
+	}
+
+	if plan.user != nil {
+		if _, err := s.users.Update(ctx, userID, *plan.user); err != nil {
+			return nil, err
+		}
+	}
+	if plan.cv != nil {
+		if _, err := s.cvs.Replace(ctx, userID, *plan.cv); err != nil {
+			return nil, err
+		}
+	}
+	for _, input := range plan.projects {
+		if _, _, err := s.portfolios.AddProject(ctx, *opts.PortfolioID, userID, portfolio.AnyVersion, input); err != nil {
+			return nil, err
+		}
+	}
+
+	return &plan.result, nil
+}
+
+// ImportLinkedIn imports a LinkedIn data archive into the profile and CV of
+// a user. Projects are added to the user's first CV portfolio unless
+// opts.PortfolioID names another one.
+func (s *Service) ImportLinkedIn(ctx context.Context, userID primitive.ObjectID, archive *zip.Reader, opts ImportOptions) (*ImportResult, error) {
+	r, unmapped, err := ParseLinkedIn(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.PortfolioID == nil {
+		portfolios, err := s.portfolios.GetByUserID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range portfolios {
+			if p.Type == "cv" {
+				opts.PortfolioID = &p.ID
+				break
+			}
+		}
+	}
+
+	result, err := s.Import(ctx, userID, r, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Unmapped = unmapped
+	return result, nil
+}
+
+// AcceptDraft imports a reviewed draft into the profile and CV of a user,
+// optionally creating a CV portfolio for the draft's projects
+func (s *Service) AcceptDraft(ctx context.Context, userID primitive.ObjectID, input AcceptDraftInput) (*AcceptDraftResult, error) {
+	opts := ImportOptions{KeepExisting: input.KeepExisting}
+	if input.Portfolio == nil {
+		result, err := s.Import(ctx, userID, &input.Resume, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &AcceptDraftResult{ImportResult: *result}, nil
+	}
+
+	// An invalid draft must not leave an empty portfolio behind
+	opts.DryRun = true
+	if _, err := s.Import(ctx, userID, &input.Resume, opts); err != nil {
+		return nil, err
+	}
+
+	create := *input.Portfolio
+	create.Type = "cv"
+	p, err := s.portfolios.Create(ctx, userID, create)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.DryRun = false
+	opts.PortfolioID = &p.ID
+	result, err := s.Import(ctx, userID, &input.Resume, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Reload the portfolio to include the imported projects
+	if p, err = s.portfolios.GetByID(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return &AcceptDraftResult{ImportResult: *result, Portfolio: p}, nil
+}
@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/database"
+)
+
+// Job is a unit of periodic background work
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// leaser hands out named leases, such as *database.DB does
+type leaser interface {
+	AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+}
+
+// Scheduler runs periodic jobs. Each run is guarded by a lease in MongoDB so
+// that only one API instance executes a given job per interval.
+type Scheduler struct {
+	leases leaser
+	owner  string
+	jobs   []Job
+}
+
+// New creates a new scheduler
+func New(db *database.DB) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		leases: db,
+		owner:  fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), primitive.NewObjectID().Hex()),
+	}
+}
+
+// Add registers a job. It must be called before Start.
+func (s *Scheduler) Add(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start runs every registered job in its own goroutine until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	leaseName := "scheduler:" + job.Name
+
+	// Hold the lease for the whole interval so other instances skip this tick
+	acquired, err := s.leases.AcquireLease(ctx, leaseName, s.owner, job.Interval)
+	if err != nil {
+		slog.Error("failed to acquire scheduler lease", "job", job.Name, "error", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		slog.Error("scheduled job failed", "job", job.Name, "error", err)
+		return
+	}
+	slog.Debug("scheduled job completed", "job", job.Name, "duration", time.Since(start))
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryLeases hands out leases like the leases collection does
+type memoryLeases struct {
+	mu     sync.Mutex
+	owners map[string]string
+	expiry map[string]time.Time
+	ttls   []time.Duration
+	err    error
+}
+
+func newMemoryLeases() *memoryLeases {
+	return &memoryLeases{owners: map[string]string{}, expiry: map[string]time.Time{}}
+}
+
+func (m *memoryLeases) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ttls = append(m.ttls, ttl)
+	if m.err != nil {
+		return false, m.err
+	}
+	now := time.Now()
+	if holder, ok := m.owners[name]; ok && holder != owner && now.Before(m.expiry[name]) {
+		return false, nil
+	}
+	m.owners[name] = owner
+	m.expiry[name] = now.Add(ttl)
+	return true, nil
+}
+
+func TestRunOnceNeedsTheLease(t *testing.T) {
+	leases := newMemoryLeases()
+	first := &Scheduler{leases: leases, owner: "first"}
+	second := &Scheduler{leases: leases, owner: "second"}
+
+	var runs int
+	job := Job{Name: "count", Interval: time.Hour, Run: func(ctx context.Context) error {
+		runs++
+		return nil
+	}}
+
+	first.runOnce(context.Background(), job)
+	second.runOnce(context.Background(), job)
+	first.runOnce(context.Background(), job)
+	if runs != 2 {
+		t.Errorf("runs = %d, want 2 by the lease holder only", runs)
+	}
+	if leases.ttls[0] != time.Hour {
+		t.Errorf("lease ttl = %s, want the job interval", leases.ttls[0])
+	}
+
+	leases.err = errors.New("connection lost")
+	first.runOnce(context.Background(), job)
+	if runs != 2 {
+		t.Errorf("job ran without a lease")
+	}
+}
+
+func TestStartRunsJobsUntilCancelled(t *testing.T) {
+	s := &Scheduler{leases: newMemoryLeases(), owner: "only"}
+
+	var runs atomic.Int32
+	ran := make(chan struct{}, 10)
+	s.Add("tick", 10*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return errors.New("failures don't stop the loop")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatalf("job ran %d times, want at least 3", runs.Load())
+		}
+	}
+
+	cancel()
+	time.Sleep(30 * time.Millisecond)
+	stopped := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Errorf("job kept running after cancel")
+	}
+}
@@ -0,0 +1,278 @@
+// Package schema implements the subset of JSON Schema used to describe and
+// validate structured content such as section content and block properties.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Schema is the subset of JSON Schema used to describe structured content
+type Schema struct {
+	Type                 string             `json:"type"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// FieldError describes a value that does not match its schema
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every mismatch found in a piece of content
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		messages[i] = fe.Path + ": " + fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// datePattern matches the partial dates used in CVs, e.g. 2021, 2021-04 or 2021-04-30
+var datePattern = regexp.MustCompile(`^\d{4}(-(0[1-9]|1[0-2])(-(0[1-9]|[12]\d|3[01]))?)?$`)
+
+// Validate checks a value decoded from JSON against the schema. Paths in the
+// returned errors start with root.
+func (s *Schema) Validate(root string, v interface{}) error {
+	var errs []FieldError
+	s.validate(root, v, &errs)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *[]FieldError) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := asObject(v)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if value, ok := obj[name]; !ok || value == nil {
+				*errs = append(*errs, FieldError{Path: path + "." + name, Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, FieldError{Path: path + "." + name, Message: "is not allowed"})
+				}
+				continue
+			}
+			if obj[name] != nil {
+				prop.validate(path+"."+name, obj[name], errs)
+			}
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		length := len([]rune(str))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+		if str != "" && !validFormat(s.Format, str) {
+			fail("must be a valid %s", s.Format)
+		}
+	case "number", "integer":
+		num, ok := v.(float64)
+		if !ok {
+			fail("must be a %s", s.Type)
+			return
+		}
+		if s.Type == "integer" && num != math.Trunc(num) {
+			fail("must be an integer")
+		}
+		if s.Minimum != nil && num < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && num > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("must be a boolean")
+		}
+	}
+}
+
+// asObject accepts plain maps as well as named map types such as
+// section.Content
+func asObject(v interface{}) (map[string]interface{}, bool) {
+	if obj, ok := v.(map[string]interface{}); ok {
+		return obj, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String &&
+		rv.Type().ConvertibleTo(mapType) {
+		return rv.Convert(mapType).Interface().(map[string]interface{}), true
+	}
+	return nil, false
+}
+
+var mapType = reflect.TypeOf(map[string]interface{}{})
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "email":
+		_, err := mail.ParseAddress(s)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "mailto") && (u.Host != "" || u.Opaque != "")
+	case "uri-reference":
+		// Absolute web URLs or root-relative paths such as stored media
+		u, err := url.Parse(s)
+		if err != nil {
+			return false
+		}
+		if u.Scheme == "" {
+			return u.Host == "" && strings.HasPrefix(u.Path, "/")
+		}
+		return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+	case "date":
+		return datePattern.MatchString(s)
+	}
+	return true
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Object builds a closed object schema
+func Object(required []string, properties map[string]*Schema) *Schema {
+	closed := false
+	return &Schema{
+		Type:                 "object",
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: &closed,
+	}
+}
+
+// Array builds an array schema with at most maxItems items
+func Array(title string, maxItems int, items *Schema) *Schema {
+	return &Schema{Type: "array", Title: title, MaxItems: Int(maxItems), Items: items}
+}
+
+// String builds a string schema with at most maxLength characters
+func String(title string, maxLength int) *Schema {
+	return &Schema{Type: "string", Title: title, MaxLength: Int(maxLength)}
+}
+
+// Markdown builds a schema for Markdown text of at most maxLength
+// characters
+func Markdown(title string, maxLength int) *Schema {
+	return &Schema{Type: "string", Title: title, Format: "markdown", MaxLength: Int(maxLength)}
+}
+
+// Format builds a string schema of the given format
+func Format(title, format string) *Schema {
+	return &Schema{Type: "string", Title: title, Format: format, MaxLength: Int(2000)}
+}
+
+// DateString builds a schema for partial dates such as 2021, 2021-04 or 2021-04-30
+func DateString(title string) *Schema {
+	return &Schema{Type: "string", Title: title, Format: "date"}
+}
+
+// Enum builds a string schema restricted to values
+func Enum(title string, values ...string) *Schema {
+	return &Schema{Type: "string", Title: title, Enum: values}
+}
+
+// Integer builds an integer schema within [min, max]
+func Integer(title string, min, max float64) *Schema {
+	return &Schema{Type: "integer", Title: title, Minimum: &min, Maximum: &max}
+}
+
+// Boolean builds a boolean schema
+func Boolean(title string) *Schema {
+	return &Schema{Type: "boolean", Title: title}
+}
+
+// Int returns a pointer to n
+func Int(n int) *int {
+	return &n
+}
+
+// DecodeDocument decodes a raw BSON document to the same types JSON decoding
+// produces, so that stored content compares and validates the same regardless
+// of where it came from
+func DecodeDocument(data []byte) (map[string]interface{}, error) {
+	ext, err := bson.MarshalExtJSON(bson.Raw(data), false, false)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(ext, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
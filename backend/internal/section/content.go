@@ -0,0 +1,73 @@
+package section
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	"github.com/musefolio/backend/internal/schema"
+)
+
+// Content is the structured content of a section. Its shape is described by
+// the schema of the section's type.
+type Content map[string]interface{}
+
+// TextContent wraps plain text as content, the way sections stored before
+// typed content existed are read
+func TextContent(text string) Content {
+	if text == "" {
+		return nil
+	}
+	return Content{"text": text}
+}
+
+// Text returns the content's plain "text" field, if any
+func (c Content) Text() string {
+	text, _ := c["text"].(string)
+	return text
+}
+
+// UnmarshalJSON accepts a content object, or a plain string from clients
+// that predate typed content
+func (c *Content) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = TextContent(text)
+		return nil
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*c = m
+	return nil
+}
+
+// UnmarshalBSONValue decodes a content document, or a plain string stored
+// before typed content existed
+func (c *Content) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.String:
+		text, _, ok := bsoncore.ReadString(data)
+		if !ok {
+			return fmt.Errorf("section content: malformed string")
+		}
+		*c = TextContent(text)
+		return nil
+	case bsontype.EmbeddedDocument:
+		m, err := schema.DecodeDocument(data)
+		if err != nil {
+			return err
+		}
+		*c = m
+		return nil
+	case bsontype.Null, bsontype.Undefined:
+		*c = nil
+		return nil
+	default:
+		return fmt.Errorf("section content: cannot decode %s", t)
+	}
+}
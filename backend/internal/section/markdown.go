@@ -0,0 +1,99 @@
+package section
+
+import (
+	"github.com/musefolio/backend/internal/markdown"
+	"github.com/musefolio/backend/internal/schema"
+)
+
+// Sanitize removes the HTML Markdown doesn't allow from the Markdown fields
+// of content, as described by the schema of the named section type.
+// Content of unknown types is returned as is.
+func Sanitize(typeName string, content Content) Content {
+	t, ok := Lookup(typeName)
+	if !ok || content == nil {
+		return content
+	}
+	sanitized, _ := sanitizeValue(t.Schema, map[string]interface{}(content)).(map[string]interface{})
+	return sanitized
+}
+
+// sanitizeValue returns a copy of v with its Markdown strings sanitized
+func sanitizeValue(s *schema.Schema, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if s.Format == "markdown" {
+			return markdown.Sanitize(v)
+		}
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			if property, ok := s.Properties[key]; ok {
+				value = sanitizeValue(property, value)
+			}
+			out[key] = value
+		}
+		return out
+	case []interface{}:
+		if s.Items == nil {
+			return v
+		}
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = sanitizeValue(s.Items, item)
+		}
+		return out
+	}
+	return v
+}
+
+// Render returns the HTML of the Markdown fields of content, in the shape
+// of the content: objects only keep the fields that render, lists keep
+// every item. It returns nil if content has no Markdown fields.
+func Render(typeName string, content Content) Content {
+	t, ok := Lookup(typeName)
+	if !ok || content == nil {
+		return nil
+	}
+	rendered, ok := renderValue(t.Schema, map[string]interface{}(content))
+	if !ok {
+		return nil
+	}
+	return rendered.(map[string]interface{})
+}
+
+// renderValue renders the Markdown strings of v. It reports whether v holds
+// any.
+func renderValue(s *schema.Schema, v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		if s.Format == "markdown" && v != "" {
+			return string(markdown.Render(v)), true
+		}
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, value := range v {
+			if property, ok := s.Properties[key]; ok {
+				if rendered, ok := renderValue(property, value); ok {
+					out[key] = rendered
+				}
+			}
+		}
+		return out, len(out) > 0
+	case []interface{}:
+		if s.Items == nil {
+			return nil, false
+		}
+		out := make([]interface{}, len(v))
+		found := false
+		for i, item := range v {
+			rendered, ok := renderValue(s.Items, item)
+			if !ok {
+				rendered = map[string]interface{}{}
+			}
+			out[i] = rendered
+			found = found || ok
+		}
+		return out, found
+	}
+	return nil, false
+}
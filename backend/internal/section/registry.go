@@ -0,0 +1,197 @@
+package section
+
+import (
+	"errors"
+
+	"github.com/musefolio/backend/internal/schema"
+)
+
+// ErrUnknownType is returned for section types missing from the registry
+var ErrUnknownType = errors.New("unknown section type")
+
+// Type describes a kind of section and the shape of its content
+type Type struct {
+	Name        string         `json:"name"`
+	Label       string         `json:"label"`
+	Description string         `json:"description"`
+	Schema      *schema.Schema `json:"schema"`
+}
+
+// introText is the optional free text every structured section may start with
+var introText = schema.Markdown("Introduction", 2000)
+
+// registry lists the section types in the order editors should offer them
+var registry = []Type{
+	{
+		Name:        "text",
+		Label:       "Text",
+		Description: "Free-form text",
+		Schema: schema.Object([]string{"text"}, map[string]*schema.Schema{
+			"text": schema.Markdown("Text", 20000),
+		}),
+	},
+	{
+		Name:        "about",
+		Label:       "About",
+		Description: "Introduction with an optional headline",
+		Schema: schema.Object([]string{"text"}, map[string]*schema.Schema{
+			"headline": schema.String("Headline", 200),
+			"text":     schema.Markdown("Text", 5000),
+		}),
+	},
+	{
+		Name:        "experience",
+		Label:       "Experience",
+		Description: "Positions held, most recent first",
+		Schema: withItems(schema.Object([]string{"role", "organization"}, map[string]*schema.Schema{
+			"role":         schema.String("Role", 200),
+			"organization": schema.String("Organization", 200),
+			"location":     schema.String("Location", 200),
+			"startDate":    schema.DateString("Start date"),
+			"endDate":      schema.DateString("End date"),
+			"current":      schema.Boolean("Current position"),
+			"description":  schema.Markdown("Description", 5000),
+		})),
+	},
+	{
+		Name:        "education",
+		Label:       "Education",
+		Description: "Degrees, diplomas and courses",
+		Schema: withItems(schema.Object([]string{"institution"}, map[string]*schema.Schema{
+			"institution": schema.String("Institution", 200),
+			"degree":      schema.String("Degree", 200),
+			"field":       schema.String("Field of study", 200),
+			"startDate":   schema.DateString("Start date"),
+			"endDate":     schema.DateString("End date"),
+			"description": schema.Markdown("Description", 5000),
+		})),
+	},
+	{
+		Name:        "skills",
+		Label:       "Skills",
+		Description: "Skills with an optional proficiency level",
+		Schema: withItems(schema.Object([]string{"name"}, map[string]*schema.Schema{
+			"name":     schema.String("Skill", 100),
+			"category": schema.String("Category", 100),
+			"level":    schema.Integer("Level", 1, 5),
+		})),
+	},
+	{
+		Name:        "services",
+		Label:       "Services",
+		Description: "Services offered to clients",
+		Schema: withItems(schema.Object([]string{"title"}, map[string]*schema.Schema{
+			"title":       schema.String("Title", 200),
+			"description": schema.Markdown("Description", 2000),
+		})),
+	},
+	{
+		Name:        "testimonials",
+		Label:       "Testimonials",
+		Description: "Quotes from clients and colleagues",
+		Schema: withItems(schema.Object([]string{"quote", "author"}, map[string]*schema.Schema{
+			"quote":   schema.String("Quote", 2000),
+			"author":  schema.String("Author", 200),
+			"role":    schema.String("Role", 200),
+			"company": schema.String("Company", 200),
+		})),
+	},
+	{
+		Name:        "awards",
+		Label:       "Awards",
+		Description: "Awards, grants and honours",
+		Schema: withItems(schema.Object([]string{"title"}, map[string]*schema.Schema{
+			"title":       schema.String("Title", 200),
+			"issuer":      schema.String("Issuer", 200),
+			"date":        schema.DateString("Date"),
+			"description": schema.Markdown("Description", 2000),
+		})),
+	},
+	{
+		Name:        "publications",
+		Label:       "Publications",
+		Description: "Articles, books, papers and talks",
+		Schema: withItems(schema.Object([]string{"title"}, map[string]*schema.Schema{
+			"title":     schema.String("Title", 300),
+			"publisher": schema.String("Publisher", 200),
+			"date":      schema.DateString("Date"),
+			"url":       schema.Format("Link", "uri"),
+		})),
+	},
+	{
+		Name:        "contact",
+		Label:       "Contact",
+		Description: "Ways to get in touch",
+		Schema: schema.Object(nil, map[string]*schema.Schema{
+			"text":     introText,
+			"email":    schema.Format("Email", "email"),
+			"phone":    schema.String("Phone", 50),
+			"location": schema.String("Location", 200),
+			"links": schema.Array("Links", 20, schema.Object([]string{"url"}, map[string]*schema.Schema{
+				"label": schema.String("Label", 100),
+				"url":   schema.Format("URL", "uri"),
+			})),
+		}),
+	},
+}
+
+// Types returns all registered section types
+func Types() []Type {
+	types := make([]Type, len(registry))
+	copy(types, registry)
+	return types
+}
+
+// Lookup finds a registered section type by name
+func Lookup(name string) (Type, bool) {
+	for _, t := range registry {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Type{}, false
+}
+
+// Validate checks content against the schema of the named section type
+func Validate(typeName string, content Content) error {
+	t, ok := Lookup(typeName)
+	if !ok {
+		return ErrUnknownType
+	}
+	if content == nil {
+		content = Content{}
+	}
+	return t.Schema.Validate("content", content)
+}
+
+// Convert fits content to the named section type, e.g. when a section is
+// retyped: fields its schema doesn't describe are dropped and Markdown fields
+// are sanitized. It returns an error if the rest isn't valid content of that
+// type.
+func Convert(typeName string, content Content) (Content, error) {
+	t, ok := Lookup(typeName)
+	if !ok {
+		return nil, ErrUnknownType
+	}
+
+	converted := Content{}
+	for key, value := range content {
+		if _, ok := t.Schema.Properties[key]; ok {
+			converted[key] = value
+		}
+	}
+	converted = Sanitize(typeName, converted)
+	if err := Validate(typeName, converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// withItems builds the content schema of a list section: an optional intro
+// text followed by items of the given shape
+func withItems(item *schema.Schema) *schema.Schema {
+	return schema.Object([]string{"items"}, map[string]*schema.Schema{
+		"text":  introText,
+		"items": schema.Array("Items", 100, item),
+	})
+}
@@ -0,0 +1,126 @@
+package section
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     string
+		content Content
+		wantErr bool
+	}{
+		{"text", "text", TextContent("Hello"), false},
+		{"text missing", "text", nil, true},
+		{"skills", "skills", Content{"items": []interface{}{
+			map[string]interface{}{"name": "Go", "level": float64(4)},
+		}}, false},
+		{"skill level out of range", "skills", Content{"items": []interface{}{
+			map[string]interface{}{"name": "Go", "level": float64(9)},
+		}}, true},
+		{"experience missing organization", "experience", Content{"items": []interface{}{
+			map[string]interface{}{"role": "Engineer"},
+		}}, true},
+		{"experience bad date", "experience", Content{"items": []interface{}{
+			map[string]interface{}{"role": "Engineer", "organization": "Acme", "startDate": "May 2020"},
+		}}, true},
+		{"unknown property", "about", Content{"text": "Hi", "color": "red"}, true},
+		{"contact email", "contact", Content{"email": "not an email"}, true},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.typ, tt.content)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if err := Validate("gallery-of-doom", nil); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("unknown type: got %v, want ErrUnknownType", err)
+	}
+}
+
+func TestContentDecodesLegacyStrings(t *testing.T) {
+	data, err := bson.Marshal(bson.M{"content": "Plain text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Content Content `bson:"content"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if doc.Content.Text() != "Plain text" {
+		t.Errorf("got %v, want text content", doc.Content)
+	}
+
+	data, err = bson.Marshal(bson.M{"content": Content{"items": []interface{}{bson.M{"name": "Go", "level": 3}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if err := Validate("skills", doc.Content); err != nil {
+		t.Errorf("round-tripped content no longer validates: %v", err)
+	}
+}
+
+func TestMarkdownFields(t *testing.T) {
+	content := Content{
+		"text": "Hi <b onclick=\"x()\">there</b>",
+		"items": []interface{}{
+			map[string]interface{}{"role": "<b onclick=\"x()\">Lead</b>", "organization": "Acme", "description": "*Shipped*"},
+			map[string]interface{}{"role": "Intern", "organization": "Acme"},
+		},
+	}
+
+	sanitized := Sanitize("experience", content)
+	if sanitized.Text() != "Hi <b>there</b>" {
+		t.Errorf("text = %q", sanitized.Text())
+	}
+	first := sanitized["items"].([]interface{})[0].(map[string]interface{})
+	if first["role"] != "<b onclick=\"x()\">Lead</b>" {
+		t.Errorf("plain field was changed: %q", first["role"])
+	}
+	if content.Text() != "Hi <b onclick=\"x()\">there</b>" {
+		t.Error("Sanitize modified its input")
+	}
+
+	rendered := Render("experience", content)
+	items := rendered["items"].([]interface{})
+	if rendered.Text() != "<p>Hi <b>there</b></p>\n" || len(items) != 2 {
+		t.Fatalf("rendered = %v", rendered)
+	}
+	if items[0].(map[string]interface{})["description"] != "<p><em>Shipped</em></p>\n" || len(items[1].(map[string]interface{})) != 0 {
+		t.Errorf("rendered items = %v", items)
+	}
+	if _, ok := items[0].(map[string]interface{})["role"]; ok {
+		t.Error("plain field rendered")
+	}
+
+	if Render("skills", Content{"items": []interface{}{map[string]interface{}{"name": "Go"}}}) != nil {
+		t.Error("content without Markdown rendered")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	converted, err := Convert("text", Content{"headline": "Hi", "text": "Me <script>x</script>"})
+	if err != nil || len(converted) != 1 || strings.Contains(converted.Text(), "<script>") {
+		t.Errorf("Convert(about to text) = %v, %v", converted, err)
+	}
+
+	if _, err := Convert("testimonials", Content{"items": []interface{}{map[string]interface{}{"name": "Go"}}}); err == nil {
+		t.Errorf("Convert(skills to testimonials) succeeded")
+	}
+	if _, err := Convert("gallery", TextContent("Hi")); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Convert(unknown type) = %v, want ErrUnknownType", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package seed loads the template and theme catalog that ships with each
+// release into the database.
+package seed
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/musefolio/backend/internal/template"
+	"github.com/musefolio/backend/internal/theme"
+)
+
+//go:embed data/templates/*.json data/themes/*.json
+var dataFS embed.FS
+
+// TemplateDefinition is a versioned template as stored in data/templates
+type TemplateDefinition struct {
+	Version int `json:"version"`
+	template.CreateTemplateInput
+}
+
+// ThemeDefinition is a versioned preset theme as stored in data/themes
+type ThemeDefinition struct {
+	Slug    string `json:"slug"`
+	Version int    `json:"version"`
+	theme.CreateThemeInput
+}
+
+// Catalog holds all embedded definitions
+type Catalog struct {
+	Templates []TemplateDefinition
+	Themes    []ThemeDefinition
+}
+
+// Result counts the definitions that were written and those that were
+// already up to date
+type Result struct {
+	TemplatesApplied int
+	TemplatesSkipped int
+	ThemesApplied    int
+	ThemesSkipped    int
+}
+
+// Load reads the embedded catalog
+func Load() (*Catalog, error) {
+	catalog := &Catalog{}
+	if err := loadDir("data/templates", &catalog.Templates); err != nil {
+		return nil, err
+	}
+	if err := loadDir("data/themes", &catalog.Themes); err != nil {
+		return nil, err
+	}
+
+	for _, def := range catalog.Templates {
+		if def.Slug == "" || def.Version < 1 {
+			return nil, fmt.Errorf("template %q: slug and a positive version are required", def.Slug)
+		}
+	}
+	for _, def := range catalog.Themes {
+		if def.Slug == "" || def.Version < 1 {
+			return nil, fmt.Errorf("theme %q: slug and a positive version are required", def.Slug)
+		}
+	}
+
+	return catalog, nil
+}
+
+// loadDir decodes every JSON file in dir into an element of out, in file
+// name order
+func loadDir[T any](dir string, out *[]T) error {
+	entries, err := fs.ReadDir(dataFS, dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		data, err := dataFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		var def T
+		if err := json.Unmarshal(data, &def); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*out = append(*out, def)
+	}
+	return nil
+}
+
+// Run writes the catalog to the database. Themes go first so that templates
+// never reference a preset that doesn't exist yet. Running it again is a
+// no-op until a definition's version is bumped.
+func Run(ctx context.Context, catalog *Catalog, templates *template.Service, themes *theme.Service) (*Result, error) {
+	result := &Result{}
+	if err := seedThemes(ctx, catalog, themes, result); err != nil {
+		return result, err
+	}
+
+	for _, def := range catalog.Templates {
+		applied, err := templates.Seed(ctx, def.Version, def.CreateTemplateInput)
+		if err != nil {
+			return result, fmt.Errorf("template %s: %w", def.Slug, err)
+		}
+		if applied {
+			result.TemplatesApplied++
+		} else {
+			result.TemplatesSkipped++
+		}
+	}
+
+	return result, nil
+}
+
+// Themes writes only the preset themes of the catalog. The API server runs it
+// on startup because portfolios, public pages and exports fall back to
+// theme.DefaultPreset, which must exist even if the full seed never ran.
+func Themes(ctx context.Context, catalog *Catalog, themes *theme.Service) (*Result, error) {
+	result := &Result{}
+	return result, seedThemes(ctx, catalog, themes, result)
+}
+
+// seedThemes writes the preset themes of the catalog, counting them in result
+func seedThemes(ctx context.Context, catalog *Catalog, themes *theme.Service, result *Result) error {
+	for _, def := range catalog.Themes {
+		applied, err := themes.SeedPreset(ctx, def.Slug, def.Version, def.CreateThemeInput)
+		if err != nil {
+			return fmt.Errorf("theme %s: %w", def.Slug, err)
+		}
+		if applied {
+			result.ThemesApplied++
+		} else {
+			result.ThemesSkipped++
+		}
+	}
+	return nil
+}
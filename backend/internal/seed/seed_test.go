@@ -0,0 +1,110 @@
+package seed
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/audit"
+	"github.com/musefolio/backend/internal/database/databasetest"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/section"
+	"github.com/musefolio/backend/internal/storage"
+	"github.com/musefolio/backend/internal/template"
+	"github.com/musefolio/backend/internal/theme"
+)
+
+func TestCatalogIsConsistent(t *testing.T) {
+	catalog, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if len(catalog.Templates) != 20 {
+		t.Errorf("got %d templates, want 20", len(catalog.Templates))
+	}
+
+	themes := map[string]bool{}
+	themeService := theme.NewService(nil)
+	for _, def := range catalog.Themes {
+		themes[def.Slug] = true
+		if _, err := themeService.Preview(def.Tokens); err != nil {
+			t.Errorf("theme %s has invalid tokens: %v", def.Slug, err)
+		}
+	}
+	if !themes[theme.DefaultPreset] {
+		t.Errorf("default preset %s is missing", theme.DefaultPreset)
+	}
+
+	slugs := map[string]bool{}
+	professions := map[string]bool{}
+	for _, def := range catalog.Templates {
+		if slugs[def.Slug] {
+			t.Errorf("duplicate template slug %s", def.Slug)
+		}
+		slugs[def.Slug] = true
+
+		if professions[def.Profession] {
+			t.Errorf("duplicate profession %s", def.Profession)
+		}
+		professions[def.Profession] = true
+
+		if def.Theme != "" && !themes[def.Theme] {
+			t.Errorf("template %s references unknown theme %s", def.Slug, def.Theme)
+		}
+		if len(def.Sections) == 0 {
+			t.Errorf("template %s has no sections", def.Slug)
+		}
+		for _, sec := range def.Sections {
+			if err := section.Validate(sec.Type, sec.Content); err != nil {
+				t.Errorf("template %s, section %s: %v", def.Slug, sec.Label, err)
+			}
+		}
+	}
+}
+
+func TestThemesLetPortfoliosUseTheDefaultPreset(t *testing.T) {
+	ctx := context.Background()
+	db := databasetest.New(t)
+	if err := db.EnsureIndexes(ctx); err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only what the API server stores on startup, without the full seed
+	themes := theme.NewService(theme.NewRepository(db))
+	if _, err := Themes(ctx, catalog, themes); err != nil {
+		t.Fatalf("Themes: %v", err)
+	}
+	portfolios := portfolio.NewService(
+		portfolio.NewRepository(db, portfolio.RevisionRetention{}),
+		audit.NewRepository(db),
+		storage.NewLocal(t.TempDir(), "/media"),
+		template.NewService(template.NewRepository(db)),
+		themes,
+	)
+
+	p, err := portfolios.Create(ctx, primitive.NewObjectID(), portfolio.CreatePortfolioInput{
+		Title:       "Work",
+		Description: "My work",
+		Theme:       theme.DefaultPreset,
+		Layout:      "grid",
+		Subdomain:   "work",
+	})
+	if err != nil {
+		t.Fatalf("Create with the default preset: %v", err)
+	}
+	if _, err := themes.ResolveOrDefault(ctx, ""); err != nil {
+		t.Errorf("ResolveOrDefault for %s: %v", p.Subdomain, err)
+	}
+
+	// Running it again, as every restart does, changes nothing
+	result, err := Themes(ctx, catalog, themes)
+	if err != nil || result.ThemesApplied != 0 || result.ThemesSkipped != len(catalog.Themes) {
+		t.Errorf("Themes again = %+v, %v; want every preset skipped", result, err)
+	}
+}
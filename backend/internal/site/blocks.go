@@ -0,0 +1,68 @@
+package site
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/markdown"
+)
+
+// funcs are the helpers available to the page templates
+var funcs = template.FuncMap{
+	"placement":   placement,
+	"aspectRatio": aspectRatio,
+	"markdown":    renderMarkdown,
+}
+
+// placement renders the layout of a block as CSS custom properties, which
+// the base stylesheet maps onto grid placement per breakpoint
+func placement(layout *block.Layout) template.CSS {
+	var b strings.Builder
+	for _, bp := range block.Breakpoints {
+		p := layout.At(bp)
+		if p == nil {
+			continue
+		}
+		prefix := bp[:1]
+		if p.Column > 0 {
+			fmt.Fprintf(&b, "--%s-col:%d;", prefix, p.Column)
+		}
+		if p.Span > 0 {
+			fmt.Fprintf(&b, "--%s-span:%d;", prefix, p.Span)
+		}
+		if p.Row > 0 {
+			fmt.Fprintf(&b, "--%s-row:%d;", prefix, p.Row)
+		}
+		if p.RowSpan > 0 {
+			fmt.Fprintf(&b, "--%s-row-span:%d;", prefix, p.RowSpan)
+		}
+		if p.Align != "" {
+			fmt.Fprintf(&b, "--%s-align:%s;", prefix, p.Align)
+		}
+		if p.Hidden {
+			fmt.Fprintf(&b, "--%s-display:none;", prefix)
+		} else {
+			fmt.Fprintf(&b, "--%s-display:block;", prefix)
+		}
+	}
+	return template.CSS(b.String())
+}
+
+// aspectRatio converts an embed aspect ratio such as "16:9" to CSS
+func aspectRatio(ratio interface{}) template.CSS {
+	s, _ := ratio.(string)
+	w, h, ok := strings.Cut(s, ":")
+	if !ok {
+		return "16 / 9"
+	}
+	return template.CSS(w + " / " + h)
+}
+
+// renderMarkdown renders Markdown text, such as a project's content or a
+// field of section content, to sanitized HTML
+func renderMarkdown(text interface{}) template.HTML {
+	s, _ := text.(string)
+	return markdown.Render(s)
+}
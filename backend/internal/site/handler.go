@@ -0,0 +1,111 @@
+package site
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/theme"
+)
+
+// Handler serves published portfolios as public web pages
+type Handler struct {
+	portfolios *portfolio.Service
+	themes     *theme.Service
+	cvs        *cv.Service
+	renderer   *Renderer
+	// stylesheetBase is the path the theme CSS endpoint is mounted at
+	stylesheetBase string
+	viewHooks      []ViewHook
+}
+
+// ViewHook is called after a published portfolio has been served to a
+// visitor
+type ViewHook func(ctx context.Context, p *portfolio.Portfolio)
+
+// NewHandler creates a new site handler
+func NewHandler(portfolios *portfolio.Service, themes *theme.Service, cvs *cv.Service, renderer *Renderer, stylesheetBase string) *Handler {
+	return &Handler{
+		portfolios:     portfolios,
+		themes:         themes,
+		cvs:            cvs,
+		renderer:       renderer,
+		stylesheetBase: stylesheetBase,
+	}
+}
+
+// OnView registers a hook that is called whenever a published portfolio is
+// served
+func (h *Handler) OnView(hook ViewHook) {
+	h.viewHooks = append(h.viewHooks, hook)
+}
+
+// RegisterRoutes registers the public site routes
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/sites/{subdomain}", h.Show)
+}
+
+// Show handles rendering a published portfolio
+func (h *Handler) Show(w http.ResponseWriter, r *http.Request) {
+	p, err := h.portfolios.GetBySubdomain(r.Context(), chi.URLParam(r, "subdomain"))
+	if err != nil {
+		if errors.Is(err, portfolio.ErrPortfolioNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !p.IsPublished {
+		http.NotFound(w, r)
+		return
+	}
+
+	t, err := h.themes.ResolveOrDefault(r.Context(), p.Theme)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page := Page{
+		Portfolio:     p,
+		StylesheetURL: h.stylesheetBase + "/" + themeRef(t) + "/theme.css",
+	}
+
+	// CV portfolios are rendered from the owner's structured CV
+	if p.Type == "cv" {
+		page.CV, err = h.cvs.Get(r.Context(), p.UserID)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := h.renderer.Render(&buf, page); err != nil {
+		slog.Error("failed to render site", "subdomain", p.Subdomain, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+
+	for _, hook := range h.viewHooks {
+		hook(r.Context(), p)
+	}
+}
+
+// themeRef returns the reference the theme CSS endpoint resolves the theme by
+func themeRef(t *theme.Theme) string {
+	if t.IsPreset {
+		return t.Slug
+	}
+	return t.ID.Hex()
+}
@@ -0,0 +1,60 @@
+package site
+
+import (
+	"embed"
+	"html/template"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Page is the data a portfolio page is rendered from
+type Page struct {
+	Portfolio *portfolio.Portfolio
+	// StylesheetURL points at the compiled theme CSS
+	StylesheetURL string
+	// CV is the owner's structured CV, set for portfolios of type "cv"
+	CV *cv.CV
+	// Project is the project shown on a project page
+	Project *portfolio.Project
+	// HomeURL links project pages back to the portfolio page
+	HomeURL string
+	// ProjectPages maps projects to the URLs of their own pages, for sites
+	// that have them
+	ProjectPages map[primitive.ObjectID]string
+}
+
+// ProjectURL returns the URL of a project's own page, or "" if it has none
+func (p Page) ProjectURL(id primitive.ObjectID) string {
+	return p.ProjectPages[id]
+}
+
+// Renderer renders portfolios as HTML pages
+type Renderer struct {
+	templates *template.Template
+}
+
+// NewRenderer creates a new renderer from the embedded page templates
+func NewRenderer() (*Renderer, error) {
+	templates, err := template.New("site").Funcs(funcs).ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{templates: templates}, nil
+}
+
+// Render writes the HTML page of a portfolio
+func (r *Renderer) Render(w io.Writer, page Page) error {
+	return r.templates.ExecuteTemplate(w, "page.html", page)
+}
+
+// RenderProject writes the HTML page of page.Project
+func (r *Renderer) RenderProject(w io.Writer, page Page) error {
+	return r.templates.ExecuteTemplate(w, "project.html", page)
+}
@@ -0,0 +1,164 @@
+package site
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/musefolio/backend/internal/block"
+	"github.com/musefolio/backend/internal/cv"
+	"github.com/musefolio/backend/internal/portfolio"
+	"github.com/musefolio/backend/internal/section"
+)
+
+func TestRenderEscapesContentAndLinksStylesheet(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+
+	p := &portfolio.Portfolio{
+		Title:    "Jane <Doe>",
+		Layout:   "grid",
+		Type:     "portfolio",
+		Sections: []portfolio.Section{{Title: "About", Type: "text", Content: section.TextContent("<script>alert(1)</script>\n\nI *draw* things")}},
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, Page{Portfolio: p, StylesheetURL: "/api/v1/themes/modern/theme.css"}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	html := buf.String()
+
+	if strings.Contains(html, "<script>alert(1)</script>") {
+		t.Error("section content was not escaped")
+	}
+	if !strings.Contains(html, "<p>I <em>draw</em> things</p>") {
+		t.Error("section Markdown not rendered")
+	}
+	if !strings.Contains(html, `href="/api/v1/themes/modern/theme.css"`) {
+		t.Error("stylesheet link missing")
+	}
+	if !strings.Contains(html, "Jane &lt;Doe&gt;") {
+		t.Error("title missing or not escaped")
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+
+	p := &portfolio.Portfolio{
+		Title: "Jane",
+		Projects: []portfolio.Project{{
+			Title:   "Canvas",
+			Content: "legacy content",
+			Blocks: []block.Block{{
+				Type:   "grid",
+				Layout: &block.Layout{Desktop: &block.Placement{Column: 2, Span: 6}},
+				Children: []block.Block{
+					{Type: "code", Props: block.Props{"code": "<b>", "language": "go"}},
+					{Type: "embed", Props: block.Props{"url": "https://player.vimeo.com/video/1", "aspectRatio": "4:3"}},
+				},
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, Page{Portfolio: p}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"--d-col:2;--d-span:6;",
+		`<code class="language-go">&lt;b&gt;</code>`,
+		`src="https://player.vimeo.com/video/1"`,
+		"aspect-ratio: 4 / 3",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered page missing %q", want)
+		}
+	}
+	if strings.Contains(html, "legacy content") {
+		t.Error("content rendered although the project has blocks")
+	}
+}
+
+func TestRenderCaseStudy(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+
+	p := &portfolio.Portfolio{
+		Title: "Jane",
+		Projects: []portfolio.Project{{
+			Kind:  portfolio.ProjectKindCaseStudy,
+			Title: "Checkout redesign",
+			CaseStudy: &portfolio.CaseStudy{
+				Role: "Lead designer",
+				Team: []string{"Ana", "Ben"},
+				Stages: []portfolio.Stage{
+					{Type: portfolio.StageProblem, Title: "Drop-off", Body: "Users **left** at payment<script>alert(1)</script>"},
+					{Type: portfolio.StageImpact, Metrics: []portfolio.Metric{{Label: "Conversion", Value: "+32%"}}},
+				},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, Page{Portfolio: p}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		`class="project case-study"`,
+		"<dd>Lead designer</dd>",
+		"<dd>Ana, Ben</dd>",
+		`class="stage stage-problem"`,
+		"<p>Users <strong>left</strong> at payment",
+		`<strong class="metric-value">&#43;32%</strong>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered page missing %q", want)
+		}
+	}
+	if strings.Contains(html, "<script>alert") || strings.Contains(html, "**left**") {
+		t.Errorf("stage body not rendered as sanitized Markdown")
+	}
+}
+
+func TestRenderCV(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+
+	page := Page{
+		Portfolio: &portfolio.Portfolio{Title: "Jane", Type: "cv"},
+		CV: &cv.CV{
+			Experience: []cv.Experience{{Role: "Designer", Organization: "Acme", StartDate: "2021-05", Current: true}},
+			Skills:     []cv.Skill{{Name: "Figma", Proficiency: 4}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, page); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"<strong>Designer</strong> · Acme",
+		"May 2021 – Present",
+		`data-level="4"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered page missing %q", want)
+		}
+	}
+}
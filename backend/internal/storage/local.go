@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores files on the local filesystem
+type Local struct {
+	root      string
+	publicURL string
+}
+
+// NewLocal creates a local storage rooted at root whose files are served
+// under publicURL
+func NewLocal(root, publicURL string) *Local {
+	return &Local{
+		root:      root,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// Save stores the content of r under key
+func (l *Local) Save(ctx context.Context, key string, r io.Reader) (string, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+
+	dst, err := os.Create(p)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, r); err != nil {
+		os.Remove(p)
+		return "", err
+	}
+
+	return l.URL(key), nil
+}
+
+// Open opens the file stored under key
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// Copy duplicates the file stored under src to dst
+func (l *Local) Copy(ctx context.Context, src, dst string) (string, error) {
+	f, err := l.Open(ctx, src)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return l.Save(ctx, dst, f)
+}
+
+// Delete removes the file stored under key
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// URL returns the public URL of key
+func (l *Local) URL(key string) string {
+	return l.publicURL + "/" + key
+}
+
+// KeyFromURL returns the key of a public URL produced by this storage
+func (l *Local) KeyFromURL(url string) (string, bool) {
+	prefix := l.publicURL + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, prefix), true
+}
+
+// path maps a key to a file path, rejecting keys that would escape the root
+func (l *Local) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+key {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.root, filepath.FromSlash(cleaned)), nil
+}
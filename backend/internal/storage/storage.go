@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/musefolio/backend/internal/config"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid storage key")
+)
+
+// Storage stores uploaded media files under slash-separated keys
+type Storage interface {
+	// Save stores the content of r under key and returns its public URL
+	Save(ctx context.Context, key string, r io.Reader) (string, error)
+	// Open opens the file stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Copy duplicates the file stored under src to dst and returns the public URL of the copy
+	Copy(ctx context.Context, src, dst string) (string, error)
+	// Delete removes the file stored under key
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of key
+	URL(key string) string
+	// KeyFromURL returns the key of a public URL produced by this storage
+	KeyFromURL(url string) (string, bool)
+}
+
+// New creates the storage backend selected by the configuration
+func New(cfg *config.StorageConfig) (Storage, error) {
+	switch cfg.Provider {
+	case "local":
+		return NewLocal(cfg.LocalDir, cfg.PublicURL), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage provider %q", cfg.Provider)
+	}
+}
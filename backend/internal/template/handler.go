@@ -0,0 +1,167 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Handler handles HTTP requests for templates
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new template handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterPublicRoutes registers the template catalog routes
+func (h *Handler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/templates", h.List)
+	r.Get("/templates/{id}", h.GetByID)
+}
+
+// RegisterAdminRoutes registers the template management routes. The router
+// is expected to restrict access to administrators.
+func (h *Handler) RegisterAdminRoutes(r chi.Router) {
+	r.Get("/admin/templates", h.ListAll)
+	r.Post("/admin/templates", h.Create)
+	r.Put("/admin/templates/{id}", h.Update)
+	r.Delete("/admin/templates/{id}", h.Delete)
+}
+
+// List handles listing published templates
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.List(r.Context(), r.URL.Query().Get("profession"))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// ListAll handles listing all templates including unpublished ones
+func (h *Handler) ListAll(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.ListAll(r.Context(), r.URL.Query().Get("profession"))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// GetByID handles getting a published template by ID
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.GetPublished(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+// Create handles template creation
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var input CreateTemplateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.Create(r.Context(), input)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, ErrSlugTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, ErrInvalidSection):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.As(err, &validationErrors):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// Update handles template updates
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	var input UpdateTemplateInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	template, err := h.service.Update(r.Context(), id, input)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, ErrTemplateNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrInvalidSection):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.As(err, &validationErrors):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+// Delete handles template deletion
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
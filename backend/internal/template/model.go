@@ -0,0 +1,80 @@
+package template
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/section"
+)
+
+// Template represents a profession-specific starting point for a portfolio
+type Template struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Slug        string             `bson:"slug" json:"slug"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	Profession  string             `bson:"profession" json:"profession"`
+	Layout      string             `bson:"layout" json:"layout"`
+	Theme       string             `bson:"theme,omitempty" json:"theme,omitempty"`
+	Type        string             `bson:"type" json:"type"`
+	Image       string             `bson:"image,omitempty" json:"image,omitempty"`
+	Thumbnail   string             `bson:"thumbnail,omitempty" json:"thumbnail,omitempty"`
+	Sections    []Section          `bson:"sections" json:"sections"`
+	Projects    []Project          `bson:"projects" json:"projects"`
+	IsPublished bool               `bson:"isPublished" json:"isPublished"`
+	// Version is the version of the seed definition the template was last
+	// loaded from, zero for templates created through the API
+	Version   int       `bson:"version,omitempty" json:"version,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Section describes a section a template seeds into new portfolios
+type Section struct {
+	Type     string          `bson:"type" json:"type" validate:"required"`
+	Label    string          `bson:"label" json:"label" validate:"required"`
+	Content  section.Content `bson:"content" json:"content"`
+	Required bool            `bson:"required" json:"required"`
+	Order    int             `bson:"order" json:"order"`
+}
+
+// Project describes a placeholder project a template seeds into new portfolios
+type Project struct {
+	Title       string   `bson:"title" json:"title" validate:"required"`
+	Description string   `bson:"description" json:"description"`
+	Content     string   `bson:"content" json:"content"`
+	Tags        []string `bson:"tags" json:"tags"`
+	Order       int      `bson:"order" json:"order"`
+}
+
+// CreateTemplateInput represents the input for creating a new template
+type CreateTemplateInput struct {
+	Slug        string    `json:"slug" validate:"required,min=3,max=64"`
+	Name        string    `json:"name" validate:"required"`
+	Description string    `json:"description"`
+	Profession  string    `json:"profession" validate:"required"`
+	Layout      string    `json:"layout" validate:"required"`
+	Theme       string    `json:"theme"`
+	Type        string    `json:"type" validate:"omitempty,oneof=about cv portfolio"`
+	Image       string    `json:"image"`
+	Thumbnail   string    `json:"thumbnail"`
+	Sections    []Section `json:"sections" validate:"dive"`
+	Projects    []Project `json:"projects" validate:"dive"`
+	IsPublished bool      `json:"isPublished"`
+}
+
+// UpdateTemplateInput represents the input for updating a template
+type UpdateTemplateInput struct {
+	Name        *string    `json:"name,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Profession  *string    `json:"profession,omitempty"`
+	Layout      *string    `json:"layout,omitempty"`
+	Theme       *string    `json:"theme,omitempty"`
+	Type        *string    `json:"type,omitempty" validate:"omitempty,oneof=about cv portfolio"`
+	Image       *string    `json:"image,omitempty"`
+	Thumbnail   *string    `json:"thumbnail,omitempty"`
+	Sections    *[]Section `json:"sections,omitempty" validate:"omitempty,dive"`
+	Projects    *[]Project `json:"projects,omitempty" validate:"omitempty,dive"`
+	IsPublished *bool      `json:"isPublished,omitempty"`
+}
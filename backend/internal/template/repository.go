@@ -0,0 +1,229 @@
+package template
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/musefolio/backend/internal/database"
+)
+
+// Repository handles template data operations
+type Repository struct {
+	db         *database.DB
+	collection *mongo.Collection
+}
+
+// NewRepository creates a new template repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{
+		db:         db,
+		collection: db.Collection(database.TemplatesCollection),
+	}
+}
+
+// Create creates a new template
+func (r *Repository) Create(ctx context.Context, input CreateTemplateInput) (*Template, error) {
+	now := time.Now()
+	template := &Template{
+		ID:          primitive.NewObjectID(),
+		Slug:        input.Slug,
+		Name:        input.Name,
+		Description: input.Description,
+		Profession:  input.Profession,
+		Layout:      input.Layout,
+		Theme:       input.Theme,
+		Type:        input.Type,
+		Image:       input.Image,
+		Thumbnail:   input.Thumbnail,
+		Sections:    input.Sections,
+		Projects:    input.Projects,
+		IsPublished: input.IsPublished,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if template.Sections == nil {
+		template.Sections = []Section{}
+	}
+	if template.Projects == nil {
+		template.Projects = []Project{}
+	}
+
+	_, err := r.collection.InsertOne(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// Upsert stores a versioned template definition under its slug. Templates
+// already stored at the same or a newer version are left untouched, in which
+// case it returns false.
+func (r *Repository) Upsert(ctx context.Context, version int, input CreateTemplateInput) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"slug": input.Slug,
+		"$or": bson.A{
+			bson.M{"version": bson.M{"$lt": version}},
+			bson.M{"version": bson.M{"$exists": false}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"name":        input.Name,
+			"description": input.Description,
+			"profession":  input.Profession,
+			"layout":      input.Layout,
+			"theme":       input.Theme,
+			"type":        input.Type,
+			"image":       input.Image,
+			"thumbnail":   input.Thumbnail,
+			"sections":    input.Sections,
+			"projects":    input.Projects,
+			"isPublished": input.IsPublished,
+			"version":     version,
+			"updatedAt":   now,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		// The upsert collides with the existing template when it is up to date
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// FindByID finds a template by ID
+func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*Template, error) {
+	var template Template
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// FindBySlug finds a template by slug
+func (r *Repository) FindBySlug(ctx context.Context, slug string) (*Template, error) {
+	var template Template
+	err := r.collection.FindOne(ctx, bson.M{"slug": slug}).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// List lists templates, optionally filtered by profession and restricted to
+// published ones
+func (r *Repository) List(ctx context.Context, profession string, publishedOnly bool) ([]*Template, error) {
+	filter := bson.M{}
+	if profession != "" {
+		filter["profession"] = profession
+	}
+	if publishedOnly {
+		filter["isPublished"] = true
+	}
+
+	opts := options.Find().SetSort(bson.D{
+		{Key: "profession", Value: 1},
+		{Key: "name", Value: 1},
+	})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []*Template{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// Update updates a template
+func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, input UpdateTemplateInput) (*Template, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"updatedAt": time.Now(),
+		},
+	}
+
+	if input.Name != nil {
+		update["$set"].(bson.M)["name"] = *input.Name
+	}
+	if input.Description != nil {
+		update["$set"].(bson.M)["description"] = *input.Description
+	}
+	if input.Profession != nil {
+		update["$set"].(bson.M)["profession"] = *input.Profession
+	}
+	if input.Layout != nil {
+		update["$set"].(bson.M)["layout"] = *input.Layout
+	}
+	if input.Theme != nil {
+		update["$set"].(bson.M)["theme"] = *input.Theme
+	}
+	if input.Type != nil {
+		update["$set"].(bson.M)["type"] = *input.Type
+	}
+	if input.Image != nil {
+		update["$set"].(bson.M)["image"] = *input.Image
+	}
+	if input.Thumbnail != nil {
+		update["$set"].(bson.M)["thumbnail"] = *input.Thumbnail
+	}
+	if input.Sections != nil {
+		update["$set"].(bson.M)["sections"] = *input.Sections
+	}
+	if input.Projects != nil {
+		update["$set"].(bson.M)["projects"] = *input.Projects
+	}
+	if input.IsPublished != nil {
+		update["$set"].(bson.M)["isPublished"] = *input.IsPublished
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var template Template
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Delete deletes a template
+func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/section"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrSlugTaken        = errors.New("template slug already taken")
+	ErrInvalidSection   = errors.New("invalid template section")
+)
+
+// Service handles template business logic
+type Service struct {
+	repo     *Repository
+	validate *validator.Validate
+}
+
+// NewService creates a new template service
+func NewService(repo *Repository) *Service {
+	return &Service{
+		repo:     repo,
+		validate: validator.New(),
+	}
+}
+
+// Create creates a new template
+func (s *Service) Create(ctx context.Context, input CreateTemplateInput) (*Template, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+	if err := validateSections(input.Sections); err != nil {
+		return nil, err
+	}
+
+	// Check if slug is taken
+	existingTemplate, err := s.repo.FindBySlug(ctx, input.Slug)
+	if err != nil {
+		return nil, err
+	}
+	if existingTemplate != nil {
+		return nil, ErrSlugTaken
+	}
+
+	if input.Type == "" {
+		input.Type = "portfolio"
+	}
+
+	return s.repo.Create(ctx, input)
+}
+
+// Seed stores a versioned template definition unless the stored template is
+// already at that version or newer. It reports whether the template changed.
+func (s *Service) Seed(ctx context.Context, version int, input CreateTemplateInput) (bool, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return false, err
+	}
+	if err := validateSections(input.Sections); err != nil {
+		return false, err
+	}
+
+	if input.Type == "" {
+		input.Type = "portfolio"
+	}
+	if input.Sections == nil {
+		input.Sections = []Section{}
+	}
+	if input.Projects == nil {
+		input.Projects = []Project{}
+	}
+
+	return s.repo.Upsert(ctx, version, input)
+}
+
+// GetByID gets a template by ID, including unpublished ones
+func (s *Service) GetByID(ctx context.Context, id primitive.ObjectID) (*Template, error) {
+	template, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+// GetPublished gets a published template by ID
+func (s *Service) GetPublished(ctx context.Context, id primitive.ObjectID) (*Template, error) {
+	template, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !template.IsPublished {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+// List lists published templates, optionally filtered by profession
+func (s *Service) List(ctx context.Context, profession string) ([]*Template, error) {
+	return s.repo.List(ctx, profession, true)
+}
+
+// ListAll lists all templates including unpublished ones
+func (s *Service) ListAll(ctx context.Context, profession string) ([]*Template, error) {
+	return s.repo.List(ctx, profession, false)
+}
+
+// Update updates a template
+func (s *Service) Update(ctx context.Context, id primitive.ObjectID, input UpdateTemplateInput) (*Template, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+	if input.Sections != nil {
+		if err := validateSections(*input.Sections); err != nil {
+			return nil, err
+		}
+	}
+
+	template, err := s.repo.Update(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+// Delete deletes a template
+func (s *Service) Delete(ctx context.Context, id primitive.ObjectID) error {
+	existingTemplate, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existingTemplate == nil {
+		return ErrTemplateNotFound
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+// validateSections checks the placeholder content of each section against
+// the schema of its type, so portfolios start out with valid content
+func validateSections(sections []Section) error {
+	for i, sec := range sections {
+		if err := section.Validate(sec.Type, sec.Content); err != nil {
+			return fmt.Errorf("%w: sections[%d]: %v", ErrInvalidSection, i, err)
+		}
+	}
+	return nil
+}
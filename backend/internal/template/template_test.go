@@ -0,0 +1,93 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/musefolio/backend/internal/database/databasetest"
+	"github.com/musefolio/backend/internal/section"
+)
+
+func TestHandlerRejectsInvalidTemplates(t *testing.T) {
+	// Invalid input is rejected before the repository is used
+	router := chi.NewRouter()
+	NewHandler(NewService(nil)).RegisterAdminRoutes(router)
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"missing slug", http.MethodPost, "/admin/templates", `{"name":"Designer","profession":"design","layout":"grid"}`},
+		{"unknown type", http.MethodPost, "/admin/templates", `{"slug":"designer","name":"Designer","profession":"design","layout":"grid","type":"blog"}`},
+		{"section without label", http.MethodPost, "/admin/templates", `{"slug":"designer","name":"Designer","profession":"design","layout":"grid","sections":[{"type":"about"}]}`},
+		{"invalid section content", http.MethodPost, "/admin/templates", `{"slug":"designer","name":"Designer","profession":"design","layout":"grid","sections":[{"type":"skills","label":"Skills","content":{"items":"Go"}}]}`},
+		{"malformed body", http.MethodPost, "/admin/templates", `{`},
+		{"update with unknown type", http.MethodPut, "/admin/templates/" + id, `{"type":"blog"}`},
+		{"update with invalid section", http.MethodPut, "/admin/templates/" + id, `{"sections":[{"type":"gallery","label":"Gallery"}]}`},
+		{"update with invalid ID", http.MethodPut, "/admin/templates/nope", `{}`},
+		{"delete with invalid ID", http.MethodDelete, "/admin/templates/nope", ``},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400 (%s)", tt.name, w.Code, strings.TrimSpace(w.Body.String()))
+		}
+	}
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(NewRepository(databasetest.New(t)))
+
+	input := CreateTemplateInput{
+		Slug:       "illustrator",
+		Name:       "Illustrator",
+		Profession: "illustration",
+		Layout:     "grid",
+		Sections:   []Section{{Type: "about", Label: "About", Content: section.TextContent("Hi")}},
+	}
+	created, err := s.Create(ctx, input)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Type != "portfolio" {
+		t.Errorf("type = %q, want the portfolio default", created.Type)
+	}
+	if _, err := s.Create(ctx, input); !errors.Is(err, ErrSlugTaken) {
+		t.Errorf("Create with a taken slug = %v, want ErrSlugTaken", err)
+	}
+
+	name := "Children's illustrator"
+	bad := []Section{{Type: "skills", Label: "Skills", Content: section.Content{"items": "x"}}}
+	if _, err := s.Update(ctx, created.ID, UpdateTemplateInput{Name: &name, Sections: &bad}); !errors.Is(err, ErrInvalidSection) {
+		t.Errorf("Update with invalid sections = %v, want ErrInvalidSection", err)
+	}
+	updated, err := s.Update(ctx, created.ID, UpdateTemplateInput{Name: &name})
+	if err != nil || updated.Name != name {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	if _, err := s.GetPublished(ctx, created.ID); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("GetPublished of a draft = %v, want ErrTemplateNotFound", err)
+	}
+
+	if err := s.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete(ctx, created.ID); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Delete again = %v, want ErrTemplateNotFound", err)
+	}
+	if _, err := s.Update(ctx, created.ID, UpdateTemplateInput{Name: &name}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Update of a deleted template = %v, want ErrTemplateNotFound", err)
+	}
+}
@@ -0,0 +1,124 @@
+package theme
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	hexColorPattern  = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	rgbColorPattern  = regexp.MustCompile(`^rgba?\(\s*(\d{1,3})\s*,\s*(\d{1,3})\s*,\s*(\d{1,3})\s*(?:,\s*(0|1|0?\.\d+)\s*)?\)$`)
+	cssLengthPattern = regexp.MustCompile(`^(0|\d+(\.\d+)?(px|rem|em))$`)
+	cssFontPattern   = regexp.MustCompile(`^[A-Za-z0-9 ,'-]+$`)
+)
+
+// newValidator returns a validator that knows the theme token tags
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterValidation("themecolor", func(fl validator.FieldLevel) bool {
+		return isColor(fl.Field().String())
+	})
+	validate.RegisterValidation("csslength", func(fl validator.FieldLevel) bool {
+		return cssLengthPattern.MatchString(fl.Field().String())
+	})
+	validate.RegisterValidation("cssfont", func(fl validator.FieldLevel) bool {
+		return cssFontPattern.MatchString(fl.Field().String())
+	})
+	return validate
+}
+
+// isColor reports whether s is a HEX color or an rgb()/rgba() color with
+// channels in range
+func isColor(s string) bool {
+	if hexColorPattern.MatchString(s) {
+		return true
+	}
+
+	m := rgbColorPattern.FindStringSubmatch(s)
+	if m == nil {
+		return false
+	}
+	// rgb() takes exactly three channels, rgba() four
+	if strings.HasPrefix(s, "rgba") != (m[4] != "") {
+		return false
+	}
+	for _, channel := range m[1:4] {
+		if n, err := strconv.Atoi(channel); err != nil || n > 255 {
+			return false
+		}
+	}
+	return true
+}
+
+// CSS compiles a theme to a stylesheet of CSS custom properties. The dark
+// variant applies when the visitor prefers a dark color scheme or when an
+// ancestor element carries data-theme="dark".
+func CSS(t *Theme) string {
+	var b strings.Builder
+	tokens := t.Tokens
+
+	fmt.Fprintf(&b, "/* %s */\n", strings.ReplaceAll(t.Name, "*/", ""))
+	b.WriteString(":root {\n")
+	writePalette(&b, tokens.Palette)
+
+	typography := tokens.Typography
+	fmt.Fprintf(&b, "  --font-heading: %s;\n", typography.HeadingFont)
+	fmt.Fprintf(&b, "  --font-body: %s;\n", typography.BodyFont)
+	fmt.Fprintf(&b, "  --font-size-base: %s;\n", typography.BaseSize)
+	for i, name := range []string{"h6", "h5", "h4", "h3", "h2", "h1"} {
+		fmt.Fprintf(&b, "  --font-size-%s: calc(var(--font-size-base) * %s);\n", name, formatFloat(math.Pow(typography.Scale, float64(i))))
+	}
+	fmt.Fprintf(&b, "  --line-height: %s;\n", formatFloat(typography.LineHeight))
+
+	fmt.Fprintf(&b, "  --space-unit: %s;\n", tokens.Spacing.Unit)
+	for i, factor := range []string{"0.5", "1", "2", "3", "4", "6"} {
+		fmt.Fprintf(&b, "  --space-%d: calc(var(--space-unit) * %s);\n", i+1, factor)
+	}
+
+	fmt.Fprintf(&b, "  --radius-sm: %s;\n", tokens.Radius.Small)
+	fmt.Fprintf(&b, "  --radius-md: %s;\n", tokens.Radius.Medium)
+	fmt.Fprintf(&b, "  --radius-lg: %s;\n", tokens.Radius.Large)
+	b.WriteString("}\n")
+
+	if tokens.Dark != nil {
+		b.WriteString("@media (prefers-color-scheme: dark) {\n  :root:not([data-theme=\"light\"]) {\n")
+		writeIndentedPalette(&b, *tokens.Dark, "  ")
+		b.WriteString("  }\n}\n")
+		b.WriteString("[data-theme=\"dark\"] {\n")
+		writePalette(&b, *tokens.Dark)
+		b.WriteString("}\n")
+	}
+
+	return b.String()
+}
+
+func writePalette(b *strings.Builder, p Palette) {
+	writeIndentedPalette(b, p, "")
+}
+
+func writeIndentedPalette(b *strings.Builder, p Palette, indent string) {
+	colors := []struct{ name, value string }{
+		{"primary", p.Primary},
+		{"secondary", p.Secondary},
+		{"background", p.Background},
+		{"surface", p.Surface},
+		{"text", p.Text},
+		{"muted", p.Muted},
+		{"accent", p.Accent},
+	}
+	for _, c := range colors {
+		if c.value != "" {
+			fmt.Fprintf(b, "%s  --color-%s: %s;\n", indent, c.name, c.value)
+		}
+	}
+}
+
+// formatFloat formats f with at most three decimals
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
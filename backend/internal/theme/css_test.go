@@ -0,0 +1,56 @@
+package theme
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsColor(t *testing.T) {
+	tests := map[string]bool{
+		"#fff":                     true,
+		"#1a2B3c":                  true,
+		"#1a2b3c80":                true,
+		"rgb(0, 128, 255)":         true,
+		"rgba(0,128,255,0.5)":      true,
+		"#12":                      false,
+		"rgb(0, 128, 256)":         false,
+		"rgb(0, 128, 255, 0.5)":    false,
+		"rgba(0, 128, 255)":        false,
+		"red; } body { color: x }": false,
+	}
+	for input, want := range tests {
+		if got := isColor(input); got != want {
+			t.Errorf("isColor(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestCSSIncludesDarkVariant(t *testing.T) {
+	css := CSS(&Theme{
+		Name: "Test",
+		Tokens: Tokens{
+			Palette: Palette{Primary: "#2563eb", Secondary: "#7c3aed", Background: "#fff", Text: "#000"},
+			Dark:    &Palette{Primary: "#60a5fa", Secondary: "#a78bfa", Background: "#000", Text: "#fff"},
+			Typography: Typography{
+				HeadingFont: "'Inter', sans-serif",
+				BodyFont:    "'Inter', sans-serif",
+				BaseSize:    "16px",
+				Scale:       1.25,
+				LineHeight:  1.6,
+			},
+			Spacing: Spacing{Unit: "8px"},
+			Radius:  Radius{Small: "4px", Medium: "8px", Large: "16px"},
+		},
+	})
+	for _, want := range []string{
+		"--color-primary: #2563eb;",
+		"--font-size-h1: calc(var(--font-size-base) * 3.052);",
+		"--radius-lg: 16px;",
+		"@media (prefers-color-scheme: dark)",
+		"[data-theme=\"dark\"]",
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("CSS missing %q:\n%s", want, css)
+		}
+	}
+}
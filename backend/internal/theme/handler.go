@@ -0,0 +1,236 @@
+package theme
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/musefolio/backend/internal/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Handler handles HTTP requests for themes
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new theme handler
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterPublicRoutes registers the routes that public sites depend on
+func (h *Handler) RegisterPublicRoutes(r chi.Router) {
+	r.Get("/themes", h.ListPresets)
+	r.Get("/themes/{ref}/theme.css", h.Stylesheet)
+}
+
+// RegisterRoutes registers the custom theme routes
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/themes/custom", h.ListCustom)
+	r.Post("/themes", h.Create)
+	r.Post("/themes/preview.css", h.Preview)
+	r.Get("/themes/{id}", h.GetByID)
+	r.Put("/themes/{id}", h.Update)
+	r.Delete("/themes/{id}", h.Delete)
+}
+
+// ListPresets handles listing preset themes
+func (h *Handler) ListPresets(w http.ResponseWriter, r *http.Request) {
+	themes, err := h.service.ListPresets(r.Context())
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(themes)
+}
+
+// Stylesheet handles compiling a theme to CSS custom properties
+func (h *Handler) Stylesheet(w http.ResponseWriter, r *http.Request) {
+	theme, err := h.service.Resolve(r.Context(), chi.URLParam(r, "ref"))
+	if err != nil {
+		if errors.Is(err, ErrThemeNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeCSS(w, CSS(theme))
+}
+
+// Preview handles compiling unsaved tokens to CSS for the editor
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	var tokens Tokens
+	if err := json.NewDecoder(r.Body).Decode(&tokens); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	css, err := h.service.Preview(tokens)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeCSS(w, css)
+}
+
+// ListCustom handles listing the current user's custom themes
+func (h *Handler) ListCustom(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	themes, err := h.service.ListByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(themes)
+}
+
+// Create handles custom theme creation
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var input CreateThemeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	theme, err := h.service.Create(r.Context(), userID, input)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(theme)
+}
+
+// GetByID handles getting a preset or one of the user's custom themes
+func (h *Handler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid theme ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	theme, err := h.service.GetByID(r.Context(), id, userID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(theme)
+}
+
+// Update handles custom theme updates
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid theme ID", http.StatusBadRequest)
+		return
+	}
+
+	var input UpdateThemeInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	theme, err := h.service.Update(r.Context(), id, userID, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(theme)
+}
+
+// Delete handles custom theme deletion
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid theme ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(auth.UserIDKey).(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id, userID); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.Is(err, ErrThemeNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.As(err, &validationErrors):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeCSS(w http.ResponseWriter, css string) {
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
+	w.Write([]byte(css))
+}
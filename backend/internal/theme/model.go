@@ -0,0 +1,81 @@
+package theme
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Theme represents a set of design tokens a portfolio is rendered with.
+// Presets are shared by everyone and referenced by slug, custom themes
+// belong to a single user and are referenced by ID.
+type Theme struct {
+	ID       primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID   *primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
+	Slug     string              `bson:"slug,omitempty" json:"slug,omitempty"`
+	Name     string              `bson:"name" json:"name"`
+	IsPreset bool                `bson:"isPreset" json:"isPreset"`
+	Tokens   Tokens              `bson:"tokens" json:"tokens"`
+	// Version is the version of the seed definition a preset was last
+	// loaded from
+	Version   int       `bson:"version,omitempty" json:"version,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// DefaultPreset is the slug of the preset used when a portfolio's theme
+// cannot be resolved
+const DefaultPreset = "modern"
+
+// Tokens holds the design tokens of a theme
+type Tokens struct {
+	Palette    Palette    `bson:"palette" json:"palette"`
+	Dark       *Palette   `bson:"dark,omitempty" json:"dark,omitempty"`
+	Typography Typography `bson:"typography" json:"typography"`
+	Spacing    Spacing    `bson:"spacing" json:"spacing"`
+	Radius     Radius     `bson:"radius" json:"radius"`
+}
+
+// Palette holds theme colors as HEX or rgb()/rgba() values
+type Palette struct {
+	Primary    string `bson:"primary" json:"primary" validate:"required,themecolor"`
+	Secondary  string `bson:"secondary" json:"secondary" validate:"required,themecolor"`
+	Background string `bson:"background" json:"background" validate:"required,themecolor"`
+	Surface    string `bson:"surface,omitempty" json:"surface,omitempty" validate:"omitempty,themecolor"`
+	Text       string `bson:"text" json:"text" validate:"required,themecolor"`
+	Muted      string `bson:"muted,omitempty" json:"muted,omitempty" validate:"omitempty,themecolor"`
+	Accent     string `bson:"accent,omitempty" json:"accent,omitempty" validate:"omitempty,themecolor"`
+}
+
+// Typography holds a heading/body font pairing and the type scale
+type Typography struct {
+	HeadingFont string  `bson:"headingFont" json:"headingFont" validate:"required,cssfont"`
+	BodyFont    string  `bson:"bodyFont" json:"bodyFont" validate:"required,cssfont"`
+	BaseSize    string  `bson:"baseSize" json:"baseSize" validate:"required,csslength"`
+	Scale       float64 `bson:"scale" json:"scale" validate:"gte=1,lte=2"`
+	LineHeight  float64 `bson:"lineHeight" json:"lineHeight" validate:"gte=1,lte=3"`
+}
+
+// Spacing holds the base spacing unit that other spacings are derived from
+type Spacing struct {
+	Unit string `bson:"unit" json:"unit" validate:"required,csslength"`
+}
+
+// Radius holds corner radii
+type Radius struct {
+	Small  string `bson:"small" json:"small" validate:"required,csslength"`
+	Medium string `bson:"medium" json:"medium" validate:"required,csslength"`
+	Large  string `bson:"large" json:"large" validate:"required,csslength"`
+}
+
+// CreateThemeInput represents the input for creating a custom theme
+type CreateThemeInput struct {
+	Name   string `json:"name" validate:"required"`
+	Tokens Tokens `json:"tokens"`
+}
+
+// UpdateThemeInput represents the input for updating a custom theme
+type UpdateThemeInput struct {
+	Name   *string `json:"name,omitempty" validate:"omitempty,min=1"`
+	Tokens *Tokens `json:"tokens,omitempty"`
+}
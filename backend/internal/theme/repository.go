@@ -0,0 +1,170 @@
+package theme
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/musefolio/backend/internal/database"
+)
+
+// Repository handles theme data operations
+type Repository struct {
+	db         *database.DB
+	collection *mongo.Collection
+}
+
+// NewRepository creates a new theme repository
+func NewRepository(db *database.DB) *Repository {
+	return &Repository{
+		db:         db,
+		collection: db.Collection(database.ThemesCollection),
+	}
+}
+
+// UpsertPreset stores a versioned preset definition under its slug. Presets
+// already stored at the same or a newer version are left untouched, in which
+// case it returns false.
+func (r *Repository) UpsertPreset(ctx context.Context, slug string, version int, input CreateThemeInput) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"slug": slug,
+		"$or": bson.A{
+			bson.M{"version": bson.M{"$lt": version}},
+			bson.M{"version": bson.M{"$exists": false}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"name":      input.Name,
+			"tokens":    input.Tokens,
+			"isPreset":  true,
+			"version":   version,
+			"updatedAt": now,
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		// The upsert collides with the existing preset when it is up to date
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Create creates a new custom theme
+func (r *Repository) Create(ctx context.Context, userID primitive.ObjectID, input CreateThemeInput) (*Theme, error) {
+	now := time.Now()
+	theme := &Theme{
+		ID:        primitive.NewObjectID(),
+		UserID:    &userID,
+		Name:      input.Name,
+		Tokens:    input.Tokens,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err := r.collection.InsertOne(ctx, theme)
+	if err != nil {
+		return nil, err
+	}
+
+	return theme, nil
+}
+
+// FindByID finds a theme by ID
+func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*Theme, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// FindPresetBySlug finds a preset by slug
+func (r *Repository) FindPresetBySlug(ctx context.Context, slug string) (*Theme, error) {
+	return r.findOne(ctx, bson.M{"slug": slug, "isPreset": true})
+}
+
+// FindPresets finds all presets
+func (r *Repository) FindPresets(ctx context.Context) ([]*Theme, error) {
+	return r.find(ctx, bson.M{"isPreset": true})
+}
+
+// FindByUserID finds all custom themes of a user
+func (r *Repository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]*Theme, error) {
+	return r.find(ctx, bson.M{"userId": userID})
+}
+
+// Update updates a custom theme
+func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, input UpdateThemeInput) (*Theme, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"updatedAt": time.Now(),
+		},
+	}
+
+	if input.Name != nil {
+		update["$set"].(bson.M)["name"] = *input.Name
+	}
+	if input.Tokens != nil {
+		update["$set"].(bson.M)["tokens"] = *input.Tokens
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var theme Theme
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&theme)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &theme, nil
+}
+
+// Delete deletes a theme
+func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *Repository) findOne(ctx context.Context, filter bson.M) (*Theme, error) {
+	var theme Theme
+	err := r.collection.FindOne(ctx, filter).Decode(&theme)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &theme, nil
+}
+
+func (r *Repository) find(ctx context.Context, filter bson.M) ([]*Theme, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	themes := []*Theme{}
+	if err := cursor.All(ctx, &themes); err != nil {
+		return nil, err
+	}
+	return themes, nil
+}
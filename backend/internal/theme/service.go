@@ -0,0 +1,172 @@
+package theme
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrThemeNotFound = errors.New("theme not found")
+	ErrUnauthorized  = errors.New("unauthorized")
+)
+
+// Service handles theme business logic
+type Service struct {
+	repo     *Repository
+	validate *validator.Validate
+}
+
+// NewService creates a new theme service
+func NewService(repo *Repository) *Service {
+	return &Service{
+		repo:     repo,
+		validate: newValidator(),
+	}
+}
+
+// SeedPreset stores a versioned preset definition unless the stored preset is
+// already at that version or newer. It reports whether the preset changed.
+func (s *Service) SeedPreset(ctx context.Context, slug string, version int, input CreateThemeInput) (bool, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return false, err
+	}
+
+	return s.repo.UpsertPreset(ctx, slug, version, input)
+}
+
+// ListPresets lists the preset themes
+func (s *Service) ListPresets(ctx context.Context) ([]*Theme, error) {
+	return s.repo.FindPresets(ctx)
+}
+
+// ListByUser lists the custom themes of a user
+func (s *Service) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]*Theme, error) {
+	return s.repo.FindByUserID(ctx, userID)
+}
+
+// Resolve finds a theme by reference, which is either a preset slug or the
+// ID of a theme
+func (s *Service) Resolve(ctx context.Context, ref string) (*Theme, error) {
+	var theme *Theme
+	var err error
+	if id, idErr := primitive.ObjectIDFromHex(ref); idErr == nil {
+		theme, err = s.repo.FindByID(ctx, id)
+	} else {
+		theme, err = s.repo.FindPresetBySlug(ctx, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if theme == nil {
+		return nil, ErrThemeNotFound
+	}
+	return theme, nil
+}
+
+// ResolveOrDefault resolves a theme reference, falling back to the default
+// preset for references that don't resolve, such as free-form theme names
+// stored before themes existed
+func (s *Service) ResolveOrDefault(ctx context.Context, ref string) (*Theme, error) {
+	theme, err := s.Resolve(ctx, ref)
+	if errors.Is(err, ErrThemeNotFound) {
+		return s.Resolve(ctx, DefaultPreset)
+	}
+	return theme, err
+}
+
+// CanUse checks that a theme reference resolves to a preset or to a custom
+// theme owned by the user
+func (s *Service) CanUse(ctx context.Context, ref string, userID primitive.ObjectID) error {
+	theme, err := s.Resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if !theme.IsPreset && (theme.UserID == nil || *theme.UserID != userID) {
+		return ErrThemeNotFound
+	}
+	return nil
+}
+
+// Create creates a custom theme
+func (s *Service) Create(ctx context.Context, userID primitive.ObjectID, input CreateThemeInput) (*Theme, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Create(ctx, userID, input)
+}
+
+// GetByID gets a preset or a custom theme owned by the user
+func (s *Service) GetByID(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (*Theme, error) {
+	theme, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if theme == nil {
+		return nil, ErrThemeNotFound
+	}
+	if !theme.IsPreset && (theme.UserID == nil || *theme.UserID != userID) {
+		return nil, ErrUnauthorized
+	}
+	return theme, nil
+}
+
+// Update updates a custom theme owned by the user
+func (s *Service) Update(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, input UpdateThemeInput) (*Theme, error) {
+	// Validate input
+	if err := s.validate.Struct(input); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.getOwned(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	theme, err := s.repo.Update(ctx, id, input)
+	if err != nil {
+		return nil, err
+	}
+	if theme == nil {
+		return nil, ErrThemeNotFound
+	}
+	return theme, nil
+}
+
+// Delete deletes a custom theme owned by the user
+func (s *Service) Delete(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) error {
+	if _, err := s.getOwned(ctx, id, userID); err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, id)
+}
+
+// Preview validates unsaved tokens and compiles them to CSS, so the editor
+// can show changes before they are stored
+func (s *Service) Preview(tokens Tokens) (string, error) {
+	if err := s.validate.Struct(tokens); err != nil {
+		return "", err
+	}
+	return CSS(&Theme{Name: "Preview", Tokens: tokens}), nil
+}
+
+// getOwned gets a custom theme and checks that the user owns it. Presets are
+// not owned by anyone and therefore read-only.
+func (s *Service) getOwned(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID) (*Theme, error) {
+	theme, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if theme == nil {
+		return nil, ErrThemeNotFound
+	}
+	if theme.UserID == nil || *theme.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return theme, nil
+}
@@ -9,10 +9,10 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/pagination"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -151,24 +151,37 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// List handles listing users
-func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	page, _ := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
-	if page < 1 {
-		page = 1
-	}
+// listOptions describe the pages of the user list
+var listOptions = pagination.Options{
+	DefaultLimit: 10,
+	MaxLimit:     100,
+	Sorts: []pagination.Sort{
+		{Name: "-createdAt", Field: "createdAt", Desc: true},
+		{Name: "createdAt", Field: "createdAt"},
+		{Name: "username", Field: "username"},
+	},
+	Filters: []pagination.Filter{
+		{Param: "profession", Field: "profession", Type: pagination.FilterString},
+		{Param: "role", Field: "role", Type: pagination.FilterString},
+	},
+}
 
-	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
-	if limit < 1 {
-		limit = 10
+// List handles listing a page of users. The next page is linked from the
+// Link header.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	p, err := listOptions.Parse(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	users, err := h.service.List(r.Context(), page, limit)
+	users, next, err := h.service.List(r.Context(), p)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	pagination.SetNext(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(users)
 }
@@ -6,6 +6,9 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// RoleAdmin is the role granting access to administrative endpoints
+const RoleAdmin = "admin"
+
 // SocialLinks represents user's social media links
 type SocialLinks struct {
 	LinkedIn  string `bson:"linkedin,omitempty" json:"linkedin,omitempty"`
@@ -26,6 +29,7 @@ type User struct {
 	Profession  string             `bson:"profession,omitempty" json:"profession,omitempty"`
 	Bio         string             `bson:"bio,omitempty" json:"bio,omitempty"`
 	SocialLinks *SocialLinks       `bson:"socialLinks,omitempty" json:"socialLinks,omitempty"`
+	Role        string             `bson:"role,omitempty" json:"role,omitempty"`
 	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
@@ -11,6 +11,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/musefolio/backend/internal/database"
+	"github.com/musefolio/backend/internal/pagination"
 )
 
 // Repository handles user data operations
@@ -136,6 +137,25 @@ func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, input Up
 	return &user, nil
 }
 
+// SetRoleByEmail sets the role of the user with the given email. It returns
+// mongo.ErrNoDocuments if there is no such user.
+func (r *Repository) SetRoleByEmail(ctx context.Context, email, role string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"role":      role,
+			"updatedAt": time.Now(),
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"email": email}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 // Delete deletes a user
 func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
@@ -148,20 +168,15 @@ func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	return nil
 }
 
-// List lists all users with pagination
-func (r *Repository) List(ctx context.Context, page, limit int64) ([]*User, error) {
-	opts := options.Find().
-		SetSkip((page - 1) * limit).
-		SetLimit(limit).
-		SetSort(bson.M{"createdAt": -1})
-
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+// List lists a page of users
+func (r *Repository) List(ctx context.Context, p pagination.Params) ([]*User, error) {
+	cursor, err := r.collection.Find(ctx, p.Query(bson.M{}), p.FindOptions())
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var users []*User
+	users := []*User{}
 	if err := cursor.All(ctx, &users); err != nil {
 		return nil, err
 	}
@@ -169,6 +184,22 @@ func (r *Repository) List(ctx context.Context, page, limit int64) ([]*User, erro
 	return users, nil
 }
 
+// FindIDsUpdatedSince finds the users updated at or after a point in time
+func (r *Repository) FindIDsUpdatedSince(ctx context.Context, since time.Time) ([]primitive.ObjectID, error) {
+	values, err := r.collection.Distinct(ctx, "_id", bson.M{"updatedAt": bson.M{"$gte": since}})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 // Count returns the total number of users
 func (r *Repository) Count(ctx context.Context) (int64, error) {
 	return r.collection.CountDocuments(ctx, bson.M{})
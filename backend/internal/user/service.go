@@ -3,11 +3,14 @@ package user
 import (
 	"context"
 	"errors"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/musefolio/backend/internal/auth"
+	"github.com/musefolio/backend/internal/pagination"
 )
 
 var (
@@ -102,6 +105,30 @@ func (s *Service) GetByUsername(ctx context.Context, username string) (*User, er
 	return user, nil
 }
 
+// IsAdmin reports whether the user has the admin role
+func (s *Service) IsAdmin(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if user == nil {
+		return false, nil
+	}
+	return user.Role == RoleAdmin, nil
+}
+
+// GrantAdmin gives the user with the given email the admin role. There is no
+// endpoint for it; administrators are appointed with cmd/seed.
+func (s *Service) GrantAdmin(ctx context.Context, email string) error {
+	if err := s.repo.SetRoleByEmail(ctx, email, RoleAdmin); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
 // Update updates a user
 func (s *Service) Update(ctx context.Context, id primitive.ObjectID, input UpdateUserInput) (*User, error) {
 	// Check if user exists
@@ -162,9 +189,19 @@ func (s *Service) Delete(ctx context.Context, id primitive.ObjectID) error {
 	return s.repo.Delete(ctx, id)
 }
 
-// List lists all users with pagination
-func (s *Service) List(ctx context.Context, page, limit int64) ([]*User, error) {
-	return s.repo.List(ctx, page, limit)
+// List lists a page of users. It returns the cursor of the next page, or ""
+// on the last page.
+func (s *Service) List(ctx context.Context, p pagination.Params) ([]*User, string, error) {
+	users, err := s.repo.List(ctx, p)
+	if err != nil {
+		return nil, "", err
+	}
+	return pagination.Trim(users, p)
+}
+
+// ListUpdatedSince lists the users updated at or after a point in time
+func (s *Service) ListUpdatedSince(ctx context.Context, since time.Time) ([]primitive.ObjectID, error) {
+	return s.repo.FindIDsUpdatedSince(ctx, since)
 }
 
 // Count returns the total number of users